@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const (
+	ChaosFaultLatency  = "latency"
+	ChaosFaultError    = "error"
+	ChaosFaultPanic    = "panic"
+	ChaosFaultSlowBody = "slow_body"
+	ChaosFaultDrop     = "drop"
+)
+
+const (
+	ChaosDistributionFixed       = "fixed"
+	ChaosDistributionUniform     = "uniform"
+	ChaosDistributionNormal      = "normal"
+	ChaosDistributionExponential = "exponential"
+)
+
+// chaosDuration is a time.Duration written as "100ms" in the rules.
+type chaosDuration time.Duration
+
+func (d chaosDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *chaosDuration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = chaosDuration(v)
+	return nil
+}
+
+// ChaosRule is the faults of the requests matching Method and Path. Only the first matching rule applies.
+// The rates are probabilities between 0 and 1, every fault is drawn independently.
+type ChaosRule struct {
+	// Method is empty to match any method.
+	Method string `json:"method,omitempty"`
+
+	// Path is an exact path like "/login", a prefix like "/api/*", or "*" for any path.
+	Path string `json:"path"`
+
+	Latency   *ChaosLatency  `json:"latency,omitempty"`
+	Error     *ChaosError    `json:"error,omitempty"`
+	SlowBody  *ChaosSlowBody `json:"slow_body,omitempty"`
+	PanicRate float64        `json:"panic_rate,omitempty"`
+	DropRate  float64        `json:"drop_rate,omitempty"`
+}
+
+// ChaosLatency delays the request before the handler runs.
+type ChaosLatency struct {
+	Rate float64 `json:"rate"`
+
+	// Distribution is fixed (Mean), uniform (between Min and Max), normal (Mean and StdDev)
+	// or exponential (Min plus an exponential delay of mean Mean). Max caps the delay when set.
+	Distribution string        `json:"distribution"`
+	Min          chaosDuration `json:"min,omitempty"`
+	Max          chaosDuration `json:"max,omitempty"`
+	Mean         chaosDuration `json:"mean,omitempty"`
+	StdDev       chaosDuration `json:"stddev,omitempty"`
+}
+
+// ChaosError responds one of Statuses, picked randomly, instead of calling the handler.
+type ChaosError struct {
+	Rate     float64 `json:"rate"`
+	Statuses []int   `json:"statuses"`
+}
+
+// ChaosSlowBody delays every read of the request body, the handler sees a slow client.
+type ChaosSlowBody struct {
+	Rate  float64       `json:"rate"`
+	Delay chaosDuration `json:"delay"`
+}
+
+// ParseChaosRules decodes and validates a JSON array of rules.
+func ParseChaosRules(data []byte) ([]ChaosRule, error) {
+	var rules []ChaosRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("decode chaos rules: %w", err)
+	}
+
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("chaos rule %d: %w", i, err)
+		}
+	}
+
+	return rules, nil
+}
+
+func (r *ChaosRule) validate() error {
+	if r.Path == "" {
+		return fmt.Errorf("path is required, use * for any path")
+	}
+
+	rates := []float64{r.PanicRate, r.DropRate}
+	if r.Latency != nil {
+		rates = append(rates, r.Latency.Rate)
+
+		switch r.Latency.Distribution {
+		case "":
+			r.Latency.Distribution = ChaosDistributionFixed
+		case ChaosDistributionFixed, ChaosDistributionUniform, ChaosDistributionNormal, ChaosDistributionExponential:
+		default:
+			return fmt.Errorf("unknown latency distribution %q", r.Latency.Distribution)
+		}
+	}
+
+	if r.Error != nil {
+		rates = append(rates, r.Error.Rate)
+
+		if len(r.Error.Statuses) == 0 {
+			r.Error.Statuses = []int{http.StatusInternalServerError}
+		}
+		for _, status := range r.Error.Statuses {
+			if status < 400 || status > 599 {
+				return fmt.Errorf("error status %d must be between 400 and 599", status)
+			}
+		}
+	}
+
+	if r.SlowBody != nil {
+		rates = append(rates, r.SlowBody.Rate)
+	}
+
+	for _, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("rate %v must be between 0 and 1", rate)
+		}
+	}
+
+	return nil
+}
+
+func (r *ChaosRule) match(req *http.Request) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return false
+	}
+
+	switch {
+	case r.Path == "*":
+		return true
+	case strings.HasSuffix(r.Path, "*"):
+		return strings.HasPrefix(req.URL.Path, strings.TrimSuffix(r.Path, "*"))
+	default:
+		return req.URL.Path == r.Path
+	}
+}
+
+// chaosGlobalSource draws from the global source of math/rand/v2, safe for concurrent use unlike the seeded sources of the tests.
+type chaosGlobalSource struct{}
+
+func (chaosGlobalSource) Uint64() uint64 { return rand.Uint64() }
+
+var chaosGlobalRand = rand.New(chaosGlobalSource{})
+
+func chaosDraw(rng *rand.Rand, rate float64) bool {
+	return rate > 0 && rng.Float64() < rate
+}
+
+// sample draws one delay of the distribution, never negative.
+func (l *ChaosLatency) sample(rng *rand.Rand) time.Duration {
+	var d float64
+	switch l.Distribution {
+	case ChaosDistributionUniform:
+		d = float64(l.Min) + rng.Float64()*float64(l.Max-l.Min)
+	case ChaosDistributionNormal:
+		d = float64(l.Mean) + rng.NormFloat64()*float64(l.StdDev)
+	case ChaosDistributionExponential:
+		d = float64(l.Min) + rng.ExpFloat64()*float64(l.Mean)
+	default:
+		d = float64(l.Mean)
+	}
+
+	if l.Max > 0 {
+		d = math.Min(d, float64(l.Max))
+	}
+	return time.Duration(math.Max(d, 0))
+}
+
+func (e *ChaosError) status(rng *rand.Rand) int {
+	return e.Statuses[rng.IntN(len(e.Statuses))]
+}
+
+// ChaosInjector injects faults into the requests matching its rules, the rules can be replaced at runtime with the AdminHandler.
+// It is the same fault injection as pkg/chaos of otel-sdk: the faults are tagged on the chi span with chaos.injected:true,
+// and counted as chaos.injected by fault and path through DogStatsD.
+type ChaosInjector struct {
+	StatsdClient *statsd.Client
+
+	// Skip excludes requests from any rule, for example the probes and the admin endpoint itself.
+	Skip func(r *http.Request) bool
+
+	mu    sync.RWMutex
+	rules []ChaosRule
+
+	// rng is nil to draw from the global source.
+	rng *rand.Rand
+}
+
+// Rules returns the current rules.
+func (i *ChaosInjector) Rules() []ChaosRule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return append([]ChaosRule{}, i.rules...)
+}
+
+// SetRules replaces the rules, nil disables the injection.
+func (i *ChaosInjector) SetRules(rules []ChaosRule) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rules = rules
+}
+
+func (i *ChaosInjector) find(r *http.Request) *ChaosRule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for idx := range i.rules {
+		if i.rules[idx].match(r) {
+			rule := i.rules[idx]
+			return &rule
+		}
+	}
+	return nil
+}
+
+// Middleware applies the first matching rule. It must be installed after the chi tracer middleware to tag the faults on its span.
+func (i *ChaosInjector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if i.Skip != nil && i.Skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rule := i.find(r)
+		if rule == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rng := i.rng
+		if rng == nil {
+			rng = chaosGlobalRand
+		}
+
+		if chaosDraw(rng, rule.DropRate) {
+			i.record(r, ChaosFaultDrop, nil)
+			chaosDrop(w)
+			return
+		}
+
+		if chaosDraw(rng, rule.PanicRate) {
+			i.record(r, ChaosFaultPanic, nil)
+			panic(fmt.Sprintf("chaos: injected panic on %s %s", r.Method, r.URL.Path))
+		}
+
+		if rule.Latency != nil && chaosDraw(rng, rule.Latency.Rate) {
+			delay := rule.Latency.sample(rng)
+			i.record(r, ChaosFaultLatency, map[string]any{"chaos.latency_ms": delay.Milliseconds()})
+
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if rule.Error != nil && chaosDraw(rng, rule.Error.Rate) {
+			status := rule.Error.status(rng)
+			i.record(r, ChaosFaultError, map[string]any{"chaos.status_code": status})
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = fmt.Fprintf(w, `{"error":"chaos: injected %d"}`+"\n", status)
+			return
+		}
+
+		if rule.SlowBody != nil && chaosDraw(rng, rule.SlowBody.Rate) {
+			delay := time.Duration(rule.SlowBody.Delay)
+			i.record(r, ChaosFaultSlowBody, map[string]any{"chaos.read_delay_ms": delay.Milliseconds()})
+			r.Body = &chaosSlowReader{ReadCloser: r.Body, delay: delay}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// record tags the span with chaos.injected:true and the details, Datadog spans have no events, and counts the fault.
+func (i *ChaosInjector) record(r *http.Request, fault string, tags map[string]any) {
+	ctx := r.Context()
+
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		span.SetTag("chaos.injected", true)
+		span.SetTag("chaos.fault", fault)
+		for k, v := range tags {
+			span.SetTag(k, v)
+		}
+	}
+
+	if err := i.StatsdClient.Incr("chaos.injected", []string{"fault:" + fault, "path:" + r.URL.Path}, 1); err != nil {
+		slog.ErrorContext(ctx, "failed to increment chaos.injected counter", slog.Any("error", err))
+	}
+
+	slog.WarnContext(ctx, "chaos fault injected", slog.String("fault", fault), slog.String("path", r.URL.Path))
+}
+
+// chaosDrop closes the connection without response. http.ErrAbortHandler also aborts HTTP/2 streams which cannot be hijacked.
+func chaosDrop(w http.ResponseWriter) {
+	if hijacker, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hijacker.Hijack(); err == nil {
+			_ = conn.Close()
+			return
+		}
+	}
+
+	panic(http.ErrAbortHandler)
+}
+
+type chaosSlowReader struct {
+	io.ReadCloser
+	delay time.Duration
+}
+
+func (s *chaosSlowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.ReadCloser.Read(p)
+}
+
+// AdminHandler serves the rules: GET returns them, PUT replaces them with a JSON array, DELETE removes them.
+func (i *ChaosInjector) AdminHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rules, err := ParseChaosRules(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		i.SetRules(rules)
+		slog.WarnContext(r.Context(), "chaos rules replaced", slog.Int("rules", len(rules)))
+
+	case http.MethodDelete:
+		i.SetRules(nil)
+		slog.WarnContext(r.Context(), "chaos rules removed")
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(i.Rules()); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write chaos rules", slog.Any("error", err))
+	}
+}
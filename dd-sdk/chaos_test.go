@@ -0,0 +1,167 @@
+package main
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var chaosOK = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+// newTestChaosInjector returns a ChaosInjector drawing from a seeded source, the faults are the same at every run.
+func newTestChaosInjector(t *testing.T, rules ...ChaosRule) *ChaosInjector {
+	t.Helper()
+
+	return &ChaosInjector{StatsdClient: newTestStatsd(t), rules: rules, rng: rand.New(rand.NewPCG(1, 2))}
+}
+
+func TestParseChaosRules(t *testing.T) {
+	tests := map[string]struct {
+		json string
+		err  string
+	}{
+		"valid":                {`[{"path":"/login","error":{"rate":0.5,"statuses":[503]}}]`, ""},
+		"missing path":         {`[{"drop_rate":0.1}]`, "path is required"},
+		"rate above 1":         {`[{"path":"*","panic_rate":1.5}]`, "rate 1.5"},
+		"negative rate":        {`[{"path":"*","latency":{"rate":-0.1,"mean":"10ms"}}]`, "rate -0.1"},
+		"status below 400":     {`[{"path":"*","error":{"rate":1,"statuses":[302]}}]`, "error status 302"},
+		"unknown distribution": {`[{"path":"*","latency":{"rate":1,"distribution":"pareto"}}]`, `"pareto"`},
+		"invalid duration":     {`[{"path":"*","slow_body":{"rate":1,"delay":"soon"}}]`, "decode chaos rules"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseChaosRules([]byte(test.json))
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("ParseChaosRules() = %v", err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("ParseChaosRules() = %v, want an error containing %q", err, test.err)
+			}
+		})
+	}
+
+	rules, err := ParseChaosRules([]byte(`[{"path":"*","latency":{"rate":1,"mean":"10ms"},"error":{"rate":1}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules[0].Latency.Distribution != ChaosDistributionFixed || len(rules[0].Error.Statuses) != 1 || rules[0].Error.Statuses[0] != http.StatusInternalServerError {
+		t.Errorf("defaults = %+v %+v, want a fixed latency and 500", rules[0].Latency, rules[0].Error)
+	}
+}
+
+func TestChaosMiddlewareErrorRate(t *testing.T) {
+	i := newTestChaosInjector(t, ChaosRule{Method: http.MethodPost, Path: "/api/*", Error: &ChaosError{Rate: 0.3, Statuses: []int{500, 503}}})
+	handler := i.Middleware(chaosOK)
+
+	const requests = 2000
+	codes := map[int]int{}
+	for range requests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/login", nil))
+		codes[w.Code]++
+	}
+
+	// The rate is drawn for each request, both statuses are picked.
+	errors := codes[500] + codes[503]
+	if errors < requests*25/100 || errors > requests*35/100 {
+		t.Errorf("%d errors out of %d requests, want about 30%%: %v", errors, requests, codes)
+	}
+	if codes[500] == 0 || codes[503] == 0 || codes[200]+errors != requests {
+		t.Errorf("status codes = %v, want 200, 500 and 503 only", codes)
+	}
+
+	// The requests not matching the method or the path are never faulted.
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/login", nil),
+		httptest.NewRequest(http.MethodPost, "/login", nil),
+	} {
+		for range 100 {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s %s = %d, want no fault", r.Method, r.URL.Path, w.Code)
+			}
+		}
+	}
+}
+
+func TestChaosMiddlewareDrop(t *testing.T) {
+	i := newTestChaosInjector(t, ChaosRule{Path: "*", DropRate: 1})
+	i.Skip = func(r *http.Request) bool { return r.URL.Path == "/healthz" }
+
+	server := httptest.NewServer(i.Middleware(chaosOK))
+	defer server.Close()
+
+	// The connection is closed without any response.
+	resp, err := http.Get(server.URL + "/login")
+	if err == nil {
+		_ = resp.Body.Close()
+		t.Fatalf("GET /login = %d, want the connection dropped", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz = %v, want the skipped request served", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /healthz = %d", resp.StatusCode)
+	}
+}
+
+func TestChaosLatencySample(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	ms := func(n int) chaosDuration { return chaosDuration(time.Duration(n) * time.Millisecond) }
+
+	tests := map[string]struct {
+		latency  ChaosLatency
+		min, max time.Duration
+	}{
+		"fixed":       {ChaosLatency{Distribution: ChaosDistributionFixed, Mean: ms(50)}, 50 * time.Millisecond, 50 * time.Millisecond},
+		"uniform":     {ChaosLatency{Distribution: ChaosDistributionUniform, Min: ms(10), Max: ms(20)}, 10 * time.Millisecond, 20 * time.Millisecond},
+		"normal":      {ChaosLatency{Distribution: ChaosDistributionNormal, Mean: ms(10), StdDev: ms(50), Max: ms(30)}, 0, 30 * time.Millisecond},
+		"exponential": {ChaosLatency{Distribution: ChaosDistributionExponential, Min: ms(5), Mean: ms(10), Max: ms(100)}, 5 * time.Millisecond, 100 * time.Millisecond},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			for range 1000 {
+				if d := test.latency.sample(rng); d < test.min || d > test.max {
+					t.Fatalf("sample() = %s, want between %s and %s", d, test.min, test.max)
+				}
+			}
+		})
+	}
+}
+
+func TestChaosAdminHandler(t *testing.T) {
+	i := newTestChaosInjector(t)
+
+	w := httptest.NewRecorder()
+	i.AdminHandler(w, httptest.NewRequest(http.MethodPut, "/admin/chaos", strings.NewReader(`[{"path":"*","drop_rate":2}]`)))
+	if w.Code != http.StatusBadRequest || len(i.Rules()) != 0 {
+		t.Errorf("PUT of an invalid rule = %d with %d rules, want 400 and no rule", w.Code, len(i.Rules()))
+	}
+
+	w = httptest.NewRecorder()
+	i.AdminHandler(w, httptest.NewRequest(http.MethodPut, "/admin/chaos", strings.NewReader(`[{"path":"/login","error":{"rate":1}}]`)))
+	if w.Code != http.StatusOK || len(i.Rules()) != 1 {
+		t.Fatalf("PUT = %d with %d rules, want 200 and 1 rule", w.Code, len(i.Rules()))
+	}
+
+	w = httptest.NewRecorder()
+	i.Middleware(chaosOK).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("GET /login = %d after the PUT, want 500", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	i.AdminHandler(w, httptest.NewRequest(http.MethodDelete, "/admin/chaos", nil))
+	if w.Code != http.StatusOK || len(i.Rules()) != 0 {
+		t.Errorf("DELETE = %d with %d rules, want 200 and no rule", w.Code, len(i.Rules()))
+	}
+}
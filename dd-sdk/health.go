@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// HealthCheck returns an error when the dependency is not ready.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Health serves the liveness, readiness and telemetry status endpoints.
+type Health struct {
+	// Resource is the service, version, env and team reported with every span and metric.
+	Resource map[string]string
+
+	// TraceAgentAddr and StatsdAddr are the Datadog Agent addresses, for example "127.0.0.1:8126" and "127.0.0.1:8125".
+	TraceAgentAddr string
+	StatsdAddr     string
+
+	StatsdClient *statsd.Client
+
+	// ReadinessChecks run on every /readyz request, empty means always ready.
+	ReadinessChecks []HealthCheck
+}
+
+// Liveness always responds 200, the process is alive as long as it serves HTTP.
+func (h *Health) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// Readiness responds 200 when every readiness check passes, otherwise 503.
+func (h *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	checks, ready := runChecks(r.Context(), h.ReadinessChecks)
+
+	statusCode, status := http.StatusOK, "ok"
+	if !ready {
+		statusCode, status = http.StatusServiceUnavailable, "unavailable"
+	}
+
+	writeJSON(w, r, statusCode, map[string]any{"status": status, "checks": checks})
+}
+
+// Telemetry responds the resource, the exporters, the sampler and the statsd client counters.
+// The agent reachability is always checked here, even when it is not part of the readiness.
+func (h *Health) Telemetry(w http.ResponseWriter, r *http.Request) {
+	checks, _ := runChecks(r.Context(), []HealthCheck{
+		{Name: "trace_agent", Check: traceAgentCheck(h.TraceAgentAddr)},
+		{Name: "dogstatsd", Check: statsdCheck(h.StatsdAddr)},
+	})
+
+	sampler := "agent_rates"
+	if rate := os.Getenv("DD_TRACE_SAMPLE_RATE"); rate != "" {
+		sampler = fmt.Sprintf("rule(sample_rate=%s)", rate)
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"resource": h.Resource,
+		"exporters": []map[string]string{
+			{"name": "trace_agent", "address": h.TraceAgentAddr, "status": checks["trace_agent"]},
+			{"name": "dogstatsd", "address": h.StatsdAddr, "transport": h.StatsdClient.GetTransport(), "status": checks["dogstatsd"]},
+		},
+		"sampler": sampler,
+		"statsd":  h.StatsdClient.GetTelemetry(),
+	})
+}
+
+// runChecks runs the checks concurrently with a 2 seconds timeout.
+func runChecks(ctx context.Context, checks []HealthCheck) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check.Check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	out := make(map[string]string, len(checks))
+	ready := true
+	for i, err := range results {
+		out[checks[i].Name] = "ok"
+		if err != nil {
+			out[checks[i].Name] = err.Error()
+			ready = false
+		}
+	}
+
+	return out, ready
+}
+
+// traceAgentCheck calls the /info endpoint of the trace agent.
+func traceAgentCheck(addr string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/info", addr), nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("trace agent %s: %w", addr, err)
+		}
+
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("trace agent %s responded %d", addr, resp.StatusCode)
+		}
+
+		return nil
+	}
+}
+
+// statsdCheck sends an empty datagram to DogStatsD, which ignores it.
+// UDP has no handshake, but a closed port is reported by the kernel as "connection refused" on the next read.
+// No answer before the deadline means the port is open.
+func statsdCheck(addr string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "udp", addr)
+		if err != nil {
+			return fmt.Errorf("dogstatsd %s: %w", addr, err)
+		}
+
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		if _, err = conn.Write(nil); err != nil {
+			return fmt.Errorf("dogstatsd %s: %w", addr, err)
+		}
+
+		_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if _, err = conn.Read(make([]byte, 1)); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil
+			}
+			return fmt.Errorf("dogstatsd %s: %w", addr, err)
+		}
+
+		return nil
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write response", slog.Any("error", err))
+	}
+}
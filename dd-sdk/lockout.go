@@ -0,0 +1,343 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const (
+	// LockoutScopeUsername is the lock of an account, the login is rejected with 423 Locked.
+	LockoutScopeUsername = "username"
+
+	// LockoutScopeClientIP is the block of a client IP trying many usernames, the login is rejected with 429 Too Many Requests.
+	LockoutScopeClientIP = "client_ip"
+)
+
+const (
+	// SecurityEventAccountLocked is sent when a username reaches the failures to be locked.
+	SecurityEventAccountLocked = "security.account_locked"
+
+	// SecurityEventClientBlocked is sent when a client IP reaches the failures to be blocked, a brute-force or a credential stuffing.
+	SecurityEventClientBlocked = "security.client_blocked"
+
+	// SecurityEventLockedLoginRejected is sent for every login rejected while the username or the client IP is locked.
+	SecurityEventLockedLoginRejected = "security.locked_login_rejected"
+)
+
+// LockoutConfig is the configuration of the LockoutTracker, a zero MaxFailures disables the lock of its scope.
+type LockoutConfig struct {
+	// MaxUsernameFailures locks the username after this number of failures in the Window.
+	MaxUsernameFailures int
+
+	// MaxClientIPFailures blocks the client IP after this number of failures in the Window, whatever the usernames are.
+	MaxClientIPFailures int
+
+	// Window is the duration in which the failures are counted, by default 5 minutes.
+	Window time.Duration
+
+	// Duration of the lock, by default 15 minutes.
+	Duration time.Duration
+
+	// MaxEntries is the number of usernames, and of client IPs, kept. The least recently failed one is evicted, with its lock.
+	// By default it is 10000.
+	MaxEntries int
+}
+
+// LockoutDecision tells whether the login is locked, and until when.
+type LockoutDecision struct {
+	// Scope is LockoutScopeUsername or LockoutScopeClientIP, empty when the login is not locked.
+	Scope string
+
+	// RetryAfter is the remaining duration of the lock.
+	RetryAfter time.Duration
+
+	// Failures is the number of failures in the window of the scope, only set by Failure.
+	Failures int
+}
+
+// Locked reports whether the login is rejected.
+func (d LockoutDecision) Locked() bool {
+	return d.Scope != ""
+}
+
+type lockoutEntry struct {
+	value       string
+	failures    []time.Time
+	lockedUntil time.Time
+}
+
+// expired reports whether the entry has neither failure in the window nor lock.
+func (e *lockoutEntry) expired(now time.Time, window time.Duration) bool {
+	return (len(e.failures) == 0 || !e.failures[len(e.failures)-1].After(now.Add(-window))) && !now.Before(e.lockedUntil)
+}
+
+// lockoutEntries of a scope, the list is ordered by the last failure, the most recent first.
+type lockoutEntries struct {
+	values map[string]*list.Element
+	lru    *list.List
+}
+
+func (s *lockoutEntries) get(value string) (*lockoutEntry, bool) {
+	elem, ok := s.values[value]
+	if !ok {
+		return nil, false
+	}
+	return elem.Value.(*lockoutEntry), true
+}
+
+func (s *lockoutEntries) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.values, elem.Value.(*lockoutEntry).value)
+}
+
+// LockoutTracker tracks the failed logins in a sliding window, per username and per client IP, it is safe for concurrent use.
+// The entries are kept in a LRU by scope, so the memory is bounded whatever the number of attempted usernames is.
+// Every call removes the expired entries from the end of the LRU, up to the first one which is still failing or locked.
+type LockoutTracker struct {
+	cfg LockoutConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*lockoutEntries
+}
+
+// NewLockoutTracker creates a LockoutTracker, it sends the gauge "lockout.locked" of the locked usernames and blocked client IPs
+// by scope every 10 seconds.
+func NewLockoutTracker(cfg LockoutConfig, statsdClient *statsd.Client) *LockoutTracker {
+	if cfg.Window <= 0 {
+		cfg.Window = 5 * time.Minute
+	}
+
+	if cfg.Duration <= 0 {
+		cfg.Duration = 15 * time.Minute
+	}
+
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+
+	t := &LockoutTracker{
+		cfg: cfg,
+		now: time.Now,
+		entries: map[string]*lockoutEntries{
+			LockoutScopeUsername: {values: map[string]*list.Element{}, lru: list.New()},
+			LockoutScopeClientIP: {values: map[string]*list.Element{}, lru: list.New()},
+		},
+	}
+
+	// The gauge is sent periodically, the locks end without any request.
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			for scope, count := range t.Locked() {
+				if err := statsdClient.Gauge("lockout.locked", float64(count), []string{"scope:" + scope}, 1); err != nil {
+					slog.Error("failed to send lockout.locked gauge", slog.Any("error", err))
+				}
+			}
+		}
+	}()
+
+	return t
+}
+
+// Check returns whether the username or the client IP is locked, the username first.
+func (t *LockoutTracker) Check(username, clientIP string) LockoutDecision {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(now)
+
+	for _, key := range [...]struct{ scope, value string }{{LockoutScopeUsername, username}, {LockoutScopeClientIP, clientIP}} {
+		if e, ok := t.entries[key.scope].get(key.value); ok && now.Before(e.lockedUntil) {
+			return LockoutDecision{Scope: key.scope, RetryAfter: e.lockedUntil.Sub(now)}
+		}
+	}
+
+	return LockoutDecision{}
+}
+
+// Failure records a failed login, and returns the lock it started, if any. The username lock is returned first when both start.
+func (t *LockoutTracker) Failure(username, clientIP string) LockoutDecision {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(now)
+
+	usernameLock := t.record(LockoutScopeUsername, username, t.cfg.MaxUsernameFailures, now)
+	clientIPLock := t.record(LockoutScopeClientIP, clientIP, t.cfg.MaxClientIPFailures, now)
+
+	switch {
+	case usernameLock.Locked():
+		return usernameLock
+	case clientIPLock.Locked():
+		return clientIPLock
+	default:
+		return usernameLock
+	}
+}
+
+// Success forgets the failures of the username, the failures of the client IP are kept.
+func (t *LockoutTracker) Success(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if elem, ok := t.entries[LockoutScopeUsername].values[username]; ok {
+		t.entries[LockoutScopeUsername].remove(elem)
+	}
+}
+
+// Locked returns the number of the locked usernames and client IPs by scope.
+func (t *LockoutTracker) Locked() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	locked := map[string]int{LockoutScopeUsername: 0, LockoutScopeClientIP: 0}
+	for scope, entries := range t.entries {
+		for elem := entries.lru.Front(); elem != nil; elem = elem.Next() {
+			if now.Before(elem.Value.(*lockoutEntry).lockedUntil) {
+				locked[scope]++
+			}
+		}
+	}
+
+	return locked
+}
+
+func (t *LockoutTracker) record(scope, value string, maxFailures int, now time.Time) LockoutDecision {
+	if maxFailures <= 0 || value == "" {
+		return LockoutDecision{}
+	}
+
+	e := t.entry(scope, value)
+
+	since := now.Add(-t.cfg.Window)
+	i := 0
+	for i < len(e.failures) && !e.failures[i].After(since) {
+		i++
+	}
+	e.failures = append(e.failures[i:], now)
+	if len(e.failures) < maxFailures || now.Before(e.lockedUntil) {
+		return LockoutDecision{Failures: len(e.failures)}
+	}
+
+	// The failures are forgotten, the next lock needs maxFailures again after this one ends.
+	e.lockedUntil = now.Add(t.cfg.Duration)
+	failures := len(e.failures)
+	e.failures = nil
+
+	return LockoutDecision{Scope: scope, RetryAfter: t.cfg.Duration, Failures: failures}
+}
+
+// entry returns the entry of the value as the most recently failed, a new one evicts the least recently failed when the scope is full.
+func (t *LockoutTracker) entry(scope, value string) *lockoutEntry {
+	entries := t.entries[scope]
+	if elem, ok := entries.values[value]; ok {
+		entries.lru.MoveToFront(elem)
+		return elem.Value.(*lockoutEntry)
+	}
+
+	if entries.lru.Len() >= t.cfg.MaxEntries {
+		entries.remove(entries.lru.Back())
+	}
+
+	e := &lockoutEntry{value: value}
+	entries.values[value] = entries.lru.PushFront(e)
+	return e
+}
+
+// prune removes the expired entries from the end of the LRU, and stops at the first one which is not expired.
+// A lock longer than the window can keep older entries a while, they are still bounded by MaxEntries.
+func (t *LockoutTracker) prune(now time.Time) {
+	for _, entries := range t.entries {
+		for elem := entries.lru.Back(); elem != nil && elem.Value.(*lockoutEntry).expired(now, t.cfg.Window); elem = entries.lru.Back() {
+			entries.remove(elem)
+		}
+	}
+}
+
+// securityEvent sends a Datadog event through DogStatsD, tags the request span with the event, and logs it as a warning.
+func (h *Handler) securityEvent(ctx context.Context, name, username, clientIP string, decision LockoutDecision) {
+	tags := []string{
+		"event_name:" + name,
+		"lockout_scope:" + decision.Scope,
+	}
+
+	logAttrs := []any{
+		slog.String("event.name", name),
+		slog.String("usr.id", username),
+		slog.String("network.client.ip", clientIP),
+		slog.String("lockout.scope", decision.Scope),
+		slog.Int64("lockout.retry_after_s", retryAfterSeconds(decision.RetryAfter)),
+	}
+
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		span.SetTag("security.event", name)
+		span.SetTag("usr.id", username)
+		span.SetTag("network.client.ip", clientIP)
+
+		logAttrs = append(logAttrs,
+			slog.Uint64("dd.trace_id", span.Context().TraceID()),
+			slog.Uint64("dd.span_id", span.Context().SpanID()),
+		)
+	}
+
+	event := &statsd.Event{
+		Title: name,
+		Text: fmt.Sprintf("%s of user %q from %s, retry after %s (%d failures).",
+			decision.Scope, username, clientIP, decision.RetryAfter, decision.Failures),
+		AggregationKey: name,
+		AlertType:      statsd.Warning,
+		Tags:           tags,
+	}
+	if err := h.StatsdClient.Event(event); err != nil {
+		slog.ErrorContext(ctx, "failed to send security event", slog.Any("error", err))
+	}
+
+	slog.WarnContext(ctx, "security event", logAttrs...)
+}
+
+// writeLocked responds 423 Locked for a locked username, 429 Too Many Requests for a blocked client IP, with Retry-After.
+func writeLocked(w http.ResponseWriter, decision LockoutDecision) {
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfterSeconds(decision.RetryAfter), 10))
+
+	if decision.Scope == LockoutScopeClientIP {
+		http.Error(w, "Too many failed logins from this client, retry later (from dd-sdk example).", http.StatusTooManyRequests)
+		return
+	}
+	http.Error(w, "The account is locked, retry later (from dd-sdk example).", http.StatusLocked)
+}
+
+// retryAfterSeconds rounds up, so the client does not retry before the end of the lock.
+func retryAfterSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// clientIP is the client address resolved by the rate limiter middleware, with its trusted proxies.
+// Without the middleware, it is the host of the remote address, the X-Forwarded-For header is not trusted.
+func clientIP(r *http.Request) string {
+	if client, ok := rateLimitClient(r.Context()); ok {
+		return client
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestLockoutTrackerMaxEntries(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewLockoutTracker(LockoutConfig{MaxUsernameFailures: 2, Window: time.Minute, MaxEntries: 3}, nil)
+	tracker.now = func() time.Time { return now }
+
+	if d := tracker.Failure("user1", "10.0.0.1"); d.Locked() || d.Failures != 1 {
+		t.Fatalf("first failure = %+v", d)
+	}
+	for i := range 10 {
+		now = now.Add(time.Second)
+		tracker.Failure(fmt.Sprintf("unknown%d", i), "10.0.0.1")
+	}
+
+	entries := tracker.entries[LockoutScopeUsername]
+	if entries.lru.Len() != 3 || len(entries.values) != 3 {
+		t.Fatalf("%d usernames tracked, want 3", entries.lru.Len())
+	}
+	if _, ok := entries.get("user1"); ok {
+		t.Error("the least recently failed username is not evicted")
+	}
+
+	// The failures out of the window are pruned from the end of the LRU.
+	now = now.Add(time.Minute - time.Second)
+	tracker.Check("", "")
+	if entries.lru.Len() != 1 || entries.lru.Front().Value.(*lockoutEntry).value != "unknown9" {
+		t.Errorf("%d usernames tracked after the window, want unknown9", entries.lru.Len())
+	}
+
+	if d := tracker.Failure("unknown9", "10.0.0.1"); d.Scope != LockoutScopeUsername {
+		t.Errorf("second failure of unknown9 = %+v, want the username lock", d)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}, nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	r.RemoteAddr = "10.0.0.1:4000"
+	r.Header.Set("X-Forwarded-For", "1.1.1.1, 192.0.2.1")
+
+	// Without the middleware, the lockout keys the client on the trusted proxy.
+	if got := clientIP(r); got != "10.0.0.1" {
+		t.Errorf("clientIP() without the rate limiter = %q, want the remote address", got)
+	}
+
+	var got string
+	limiter.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = clientIP(r)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	if got != "192.0.2.1" {
+		t.Errorf("clientIP() = %q, want the client address of the rate limiter", got)
+	}
+}
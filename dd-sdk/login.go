@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// countLoginFailure increments login.failure and tags the request span with the reason, an invalid reason is LoginOtherFailure.
+func (h *Handler) countLoginFailure(ctx context.Context, reason LoginFailureReason) {
+	if !reason.Valid() {
+		reason = LoginOtherFailure
+	}
+
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		span.SetTag(FailureReasonTag, string(reason))
+	}
+
+	if err := RecordLoginFailure(h.StatsdClient, reason); err != nil {
+		slog.ErrorContext(ctx, "failed to increment login failure counter", slog.Any("error", err))
+	}
+}
+
+// loginRequest is the body of /login.
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loginMaxBodyBytes is the limit of the /login body, the credentials are far smaller.
+const loginMaxBodyBytes = 4 << 10
+
+// decodeLogin validates the Content-Type, the size and the fields of the login body.
+// On error, it returns the failure reason and the HTTP status of the response.
+// A request without Content-Type is accepted, like a plain curl.
+func decodeLogin(w http.ResponseWriter, r *http.Request, user *loginRequest) (LoginFailureReason, int, error) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return LoginUnsupportedMediaType, http.StatusUnsupportedMediaType,
+				fmt.Errorf("content type must be application/json, got %q", contentType)
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, loginMaxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(user); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return LoginBodyTooLarge, http.StatusRequestEntityTooLarge,
+				fmt.Errorf("request body larger than %d bytes", maxBytesErr.Limit)
+		}
+		return LoginInvalidPayload, http.StatusBadRequest, fmt.Errorf("invalid request payload: %w", err)
+	}
+
+	if user.Username == "" || user.Password == "" {
+		return LoginMissingFields, http.StatusBadRequest, errors.New("username and password are required")
+	}
+
+	return "", 0, nil
+}
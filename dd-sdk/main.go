@@ -1,11 +1,14 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	// Go-Chi router
 	"github.com/go-chi/chi/v5"
@@ -14,7 +17,12 @@ import (
 	// Datadog client
 	"github.com/DataDog/datadog-go/v5/statsd"
 	chitrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/go-chi/chi.v5"
+	ddotel "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/opentelemetry"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	// OpenTelemetry API, see TRACING_API
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func main() {
@@ -24,30 +32,112 @@ func main() {
 		// DatadogAgentHost MUST open port 8125 (statsd) and 8126 (trace-agent) on Datadog Agent
 		// For example, 127.0.0.1
 		DatadogAgentHost = os.Getenv("DATADOG_AGENT_HOST")
+
+		// ReadinessCheckAgent makes /readyz fail while the Datadog Agent ports 8125 and 8126 are not reachable,
+		// by default it is false.
+		ReadinessCheckAgent = os.Getenv("READINESS_CHECK_AGENT")
+
+		// TracingAPI is the API used to instrument the login handler, by default it is "native" (tracer.StartSpan).
+		// Set it to "otel" to use the OpenTelemetry API backed by the Datadog tracer.
+		TracingAPI = os.Getenv("TRACING_API")
+
+		// ChaosRules is a JSON array of fault injection rules, see ChaosRule, for example:
+		// [{"path": "/login", "latency": {"rate": 0.2, "distribution": "uniform", "min": "100ms", "max": "2s"}, "error": {"rate": 0.05, "statuses": [500, 503]}}]
+		// Empty means no fault is injected.
+		ChaosRules = os.Getenv("CHAOS_RULES")
+
+		// ChaosAdminEnabled exposes "/debug/chaos" to read and replace the chaos rules at runtime, by default it is false.
+		ChaosAdminEnabled = os.Getenv("CHAOS_ADMIN_ENABLED")
+
+		// UserStoreFile is a JSON or YAML file of the users with their bcrypt or argon2id password hash, see users.yaml.
+		// Empty uses the demo users user1, user2 and user3.
+		UserStoreFile = os.Getenv("USER_STORE_FILE")
+
+		// UserStoreLatency delays every user lookup by a random duration in a range like "5ms-50ms", or a fixed one like "20ms".
+		// Empty means no delay.
+		UserStoreLatency = os.Getenv("USER_STORE_LATENCY")
+
+		// LockoutMaxFailures locks a username for LockoutDuration after this number of failed logins in LockoutWindow,
+		// the login is then rejected with 423 Locked. Empty or 0 disables it.
+		LockoutMaxFailures = os.Getenv("LOCKOUT_MAX_FAILURES")
+
+		// LockoutMaxClientIPFailures blocks a client IP for LockoutDuration after this number of failed logins in LockoutWindow,
+		// whatever the usernames are, the login is then rejected with 429 Too Many Requests. Empty or 0 disables it.
+		LockoutMaxClientIPFailures = os.Getenv("LOCKOUT_MAX_CLIENT_IP_FAILURES")
+
+		// LockoutWindow is the sliding window of the failed logins, for example: "10m". By default it is 5 minutes.
+		LockoutWindow = os.Getenv("LOCKOUT_WINDOW")
+
+		// LockoutDuration is how long a username is locked or a client IP is blocked, for example: "1h". By default it is 15 minutes.
+		LockoutDuration = os.Getenv("LOCKOUT_DURATION")
+
+		// LockoutMaxEntries is the number of usernames, and of client IPs, tracked in memory, the least recently failed is evicted.
+		// By default it is 10000.
+		LockoutMaxEntries = os.Getenv("LOCKOUT_MAX_ENTRIES")
+
+		// RateLimitGlobalRPS is the number of /login requests per second of all the clients, RateLimitGlobalBurst is the size
+		// of the token bucket (by default the rate rounded up). Empty or 0 disables the global limit.
+		RateLimitGlobalRPS   = os.Getenv("RATE_LIMIT_GLOBAL_RPS")
+		RateLimitGlobalBurst = os.Getenv("RATE_LIMIT_GLOBAL_BURST")
+
+		// RateLimitClientRPS is the number of /login requests per second of one client address, RateLimitClientBurst is the size
+		// of its token bucket (by default the rate rounded up). Empty or 0 disables the limit per client.
+		RateLimitClientRPS   = os.Getenv("RATE_LIMIT_CLIENT_RPS")
+		RateLimitClientBurst = os.Getenv("RATE_LIMIT_CLIENT_BURST")
+
+		// RateLimitMaxClients is the number of client buckets kept in memory, the least recently used is evicted. By default it is 10000.
+		RateLimitMaxClients = os.Getenv("RATE_LIMIT_MAX_CLIENTS")
+
+		// RateLimitTrustedProxies is a comma separated list of CIDR whose X-Forwarded-For header gives the client address,
+		// for example: "10.0.0.0/8,127.0.0.1". Empty means the remote address is the client.
+		RateLimitTrustedProxies = os.Getenv("RATE_LIMIT_TRUSTED_PROXIES")
 	)
 
 	const (
-		teamName       = "go_sandbox"
-		serviceName    = "poc_dd_sdk_statsd"
-		serviceVersion = "0.1.0"
-		serviceEnv     = "dev"
+		teamName    = "go_sandbox"
+		serviceName = "poc_dd_sdk_statsd"
+		serviceEnv  = "dev"
 	)
 
-	// Start the tracer
-	tracer.Start(
-		tracer.WithAgentAddr(fmt.Sprintf("%s:8126", DatadogAgentHost)),
+	// serviceVersion is injected at build time, see the Dockerfile.
+	buildInfo := getBuildInfo()
+	serviceVersion := buildInfo.Version
+
+	traceAgentAddr := fmt.Sprintf("%s:8126", DatadogAgentHost)
+	statsdAddr := fmt.Sprintf("%s:8125", DatadogAgentHost)
+
+	tracerOpts := []tracer.StartOption{
+		tracer.WithAgentAddr(traceAgentAddr),
 		tracer.WithGlobalTag("team", teamName), // Adding a global tag
 		tracer.WithService(serviceName),
 		tracer.WithUniversalVersion(serviceVersion),
 		tracer.WithEnv(serviceEnv),
 		tracer.WithLogStartup(false),
 		tracer.WithDebugMode(false),
-	)
-	defer tracer.Stop()
+	}
+
+	// Start the tracer
+	var tracerProvider *ddotel.TracerProvider
+	switch TracingAPI {
+	case "", TracingAPINative:
+		tracer.Start(tracerOpts...)
+		defer tracer.Stop()
+
+	case TracingAPIOtel:
+		// The TracerProvider starts the same Datadog tracer, the spans created with the OpenTelemetry API become Datadog spans.
+		tracerProvider = ddotel.NewTracerProvider(tracerOpts...)
+		otel.SetTracerProvider(tracerProvider)
+		defer func() {
+			_ = tracerProvider.Shutdown()
+		}()
+
+	default:
+		panic(fmt.Sprintf("unknown TRACING_API %q, must be %q or %q", TracingAPI, TracingAPINative, TracingAPIOtel))
+	}
 
 	var err error
 	statsdClient, err := statsd.New(
-		fmt.Sprintf("%s:8125", DatadogAgentHost),
+		statsdAddr,
 		statsd.WithNamespace(serviceName),
 		statsd.WithTags([]string{
 			fmt.Sprintf("service:%s", serviceName),
@@ -59,8 +149,116 @@ func main() {
 		panic(err)
 	}
 
+	go reportBuildInfo(statsdClient, buildInfo, 10*time.Second)
+
+	var userStore UserStore = DefaultUserStore()
+	if UserStoreFile != "" {
+		fileStore, fileStoreErr := LoadUserStoreFile(UserStoreFile)
+		if fileStoreErr != nil {
+			panic(fmt.Errorf("invalid UserStoreFile: %w", fileStoreErr))
+		}
+		userStore = fileStore
+	}
+
+	if UserStoreLatency != "" {
+		minLatency, maxLatency, latencyErr := parseLatencyRange(UserStoreLatency)
+		if latencyErr != nil {
+			slog.Warn("failed to parse UserStoreLatency", slog.Any("error", latencyErr))
+		} else {
+			userStore = &LatencyUserStore{Store: userStore, Min: minLatency, Max: maxLatency}
+		}
+	}
+
+	var lockoutCfg LockoutConfig
+	if LockoutMaxFailures != "" {
+		var lockoutErr error
+		lockoutCfg.MaxUsernameFailures, lockoutErr = strconv.Atoi(LockoutMaxFailures)
+		if lockoutErr != nil {
+			slog.Warn("failed to parse LockoutMaxFailures", slog.Any("error", lockoutErr))
+		}
+	}
+
+	if LockoutMaxClientIPFailures != "" {
+		var lockoutErr error
+		lockoutCfg.MaxClientIPFailures, lockoutErr = strconv.Atoi(LockoutMaxClientIPFailures)
+		if lockoutErr != nil {
+			slog.Warn("failed to parse LockoutMaxClientIPFailures", slog.Any("error", lockoutErr))
+		}
+	}
+
+	if LockoutWindow != "" {
+		var lockoutErr error
+		lockoutCfg.Window, lockoutErr = time.ParseDuration(LockoutWindow)
+		if lockoutErr != nil {
+			slog.Warn("failed to parse LockoutWindow", slog.Any("error", lockoutErr))
+		}
+	}
+
+	if LockoutDuration != "" {
+		var lockoutErr error
+		lockoutCfg.Duration, lockoutErr = time.ParseDuration(LockoutDuration)
+		if lockoutErr != nil {
+			slog.Warn("failed to parse LockoutDuration", slog.Any("error", lockoutErr))
+		}
+	}
+
+	if LockoutMaxEntries != "" {
+		var lockoutErr error
+		lockoutCfg.MaxEntries, lockoutErr = strconv.Atoi(LockoutMaxEntries)
+		if lockoutErr != nil {
+			slog.Warn("failed to parse LockoutMaxEntries", slog.Any("error", lockoutErr))
+		}
+	}
+
 	handler := &Handler{
 		StatsdClient: statsdClient,
+		Tracer:       otel.Tracer(serviceName),
+		Users:        NewAuthenticator(userStore, statsdClient),
+		Lockout:      NewLockoutTracker(lockoutCfg, statsdClient),
+	}
+
+	healthHandler := &Health{
+		Resource: map[string]string{
+			"service": serviceName,
+			"version": serviceVersion,
+			"env":     serviceEnv,
+			"team":    teamName,
+		},
+		TraceAgentAddr: traceAgentAddr,
+		StatsdAddr:     statsdAddr,
+		StatsdClient:   statsdClient,
+	}
+
+	checkAgent, checkAgentErr := strconv.ParseBool(ReadinessCheckAgent)
+	if ReadinessCheckAgent != "" && checkAgentErr != nil {
+		slog.Warn("failed to parse ReadinessCheckAgent", slog.Any("error", checkAgentErr))
+	}
+
+	if checkAgent {
+		healthHandler.ReadinessChecks = []HealthCheck{
+			{Name: "trace_agent", Check: traceAgentCheck(traceAgentAddr)},
+			{Name: "dogstatsd", Check: statsdCheck(statsdAddr)},
+		}
+	}
+
+	chaosInjector := &ChaosInjector{
+		StatsdClient: statsdClient,
+		Skip: func(r *http.Request) bool {
+			return r.URL.Path == "/healthz" || r.URL.Path == "/readyz" || strings.HasPrefix(r.URL.Path, "/debug/")
+		},
+	}
+
+	if ChaosRules != "" {
+		chaosRules, chaosRulesErr := ParseChaosRules([]byte(ChaosRules))
+		if chaosRulesErr != nil {
+			slog.Warn("failed to parse ChaosRules", slog.Any("error", chaosRulesErr))
+		}
+		chaosInjector.SetRules(chaosRules)
+	}
+
+	chaosAdmin, chaosAdminErr := strconv.ParseBool(ChaosAdminEnabled)
+	if ChaosAdminEnabled != "" && chaosAdminErr != nil {
+		slog.Warn("failed to parse ChaosAdminEnabled", slog.Any("error", chaosAdminErr))
 	}
 
 	// Create a chi Router
@@ -71,11 +269,93 @@ func main() {
 	// Use the tracer middleware with the default service name "chi.router".
 	router.Use(chitrace.Middleware(
 		chitrace.WithServiceName(serviceName),
+		// RecoveryMiddleware sets the error of the 5xx responses, so it keeps the tags of a recovered panic.
+		chitrace.WithStatusCheck(NoStatusError),
+		// Do not trace the probes of the orchestrator.
+		chitrace.WithIgnoreRequest(func(r *http.Request) bool {
+			return r.URL.Path == "/healthz" || r.URL.Path == "/readyz"
+		}),
 	))
+	router.Use(MetricsMiddleware(statsdClient))
+
+	// Recover the panics inside the tracer and metrics middlewares, so they see the 500 response.
+	router.Use(RecoveryMiddleware(statsdClient))
+
+	// The faults are injected after the tracer and metrics middlewares, so they show up in the spans and the RED metrics.
+	router.Use(chaosInjector.Middleware)
+
+	var rateLimitCfg RateLimitConfig
+	if RateLimitGlobalRPS != "" {
+		var rateLimitErr error
+		rateLimitCfg.GlobalRate, rateLimitErr = strconv.ParseFloat(RateLimitGlobalRPS, 64)
+		if rateLimitErr != nil {
+			slog.Warn("failed to parse RateLimitGlobalRPS", slog.Any("error", rateLimitErr))
+		}
+	}
+
+	if RateLimitGlobalBurst != "" {
+		var rateLimitErr error
+		rateLimitCfg.GlobalBurst, rateLimitErr = strconv.Atoi(RateLimitGlobalBurst)
+		if rateLimitErr != nil {
+			slog.Warn("failed to parse RateLimitGlobalBurst", slog.Any("error", rateLimitErr))
+		}
+	}
+
+	if RateLimitClientRPS != "" {
+		var rateLimitErr error
+		rateLimitCfg.ClientRate, rateLimitErr = strconv.ParseFloat(RateLimitClientRPS, 64)
+		if rateLimitErr != nil {
+			slog.Warn("failed to parse RateLimitClientRPS", slog.Any("error", rateLimitErr))
+		}
+	}
+
+	if RateLimitClientBurst != "" {
+		var rateLimitErr error
+		rateLimitCfg.ClientBurst, rateLimitErr = strconv.Atoi(RateLimitClientBurst)
+		if rateLimitErr != nil {
+			slog.Warn("failed to parse RateLimitClientBurst", slog.Any("error", rateLimitErr))
+		}
+	}
+
+	if RateLimitMaxClients != "" {
+		var rateLimitErr error
+		rateLimitCfg.MaxClients, rateLimitErr = strconv.Atoi(RateLimitMaxClients)
+		if rateLimitErr != nil {
+			slog.Warn("failed to parse RateLimitMaxClients", slog.Any("error", rateLimitErr))
+		}
+	}
+
+	if RateLimitTrustedProxies != "" {
+		var proxiesErr error
+		rateLimitCfg.TrustedProxies, proxiesErr = ParseTrustedProxies(RateLimitTrustedProxies)
+		if proxiesErr != nil {
+			slog.Warn("failed to parse RateLimitTrustedProxies", slog.Any("error", proxiesErr))
+		}
+	}
+
+	// Only /login is limited, the limiter decision is tagged on its request span.
+	rateLimiter := NewRateLimiter(rateLimitCfg, statsdClient)
+	rateLimiter.OnReject = func(r *http.Request, _ RateLimitDecision) {
+		handler.countLoginFailure(r.Context(), LoginRateLimited)
+	}
 
 	// Set up some endpoints.
 	router.Get("/", handler.Homepage)
-	router.Post("/login", handler.Login)
+	if tracerProvider != nil {
+		router.With(rateLimiter.Middleware).Post("/login", handler.LoginOtel)
+	} else {
+		router.With(rateLimiter.Middleware).Post("/login", handler.Login)
+	}
+
+	router.Method(http.MethodGet, "/version", buildInfo)
+
+	router.Get("/healthz", healthHandler.Liveness)
+	router.Get("/readyz", healthHandler.Readiness)
+	router.Get("/debug/telemetry", healthHandler.Telemetry)
+
+	if chaosAdmin {
+		router.HandleFunc("/debug/chaos", chaosInjector.AdminHandler)
+	}
 
 	// Start the HTTP server
 	http.ListenAndServe(Port, router)
@@ -84,6 +364,15 @@ func main() {
 type Handler struct {
 	// Datadog StatsD client
 	StatsdClient *statsd.Client
+
+	// Tracer is used by LoginOtel, with TRACING_API=otel it is backed by the Datadog tracer.
+	Tracer trace.Tracer
+
+	// Users checks the login credentials.
+	Users *Authenticator
+
+	// Lockout locks the usernames and blocks the client IPs after too many failed logins.
+	Lockout *LockoutTracker
 }
 
 func (*Handler) Homepage(w http.ResponseWriter, _ *http.Request) {
@@ -91,24 +380,19 @@ func (*Handler) Homepage(w http.ResponseWriter, _ *http.Request) {
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
-	// User represents the login credentials structure
-	type User struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-
 	ctx := r.Context()
 	var err error
 
 	// Create a span which will be the child of the span in the Context ctx, if there is a span in the context.
-	parentSpan, _ := tracer.StartSpanFromContext(ctx, "Login Handler [DD-SDK]",
+	parentSpan, parentCtx := tracer.StartSpanFromContext(ctx, "Login Handler [DD-SDK]",
 		tracer.ResourceName("login-handler"),
 	)
-	defer parentSpan.Finish(
-		tracer.WithError(err),
-	)
+	// The error is read when the handler returns, not when the defer is declared.
+	defer func() {
+		parentSpan.Finish(tracer.WithError(err))
+	}()
 
-	var user User
+	var user loginRequest
 	{
 		// Creating a children to this new span
 		decodeBodySpan := tracer.StartSpan("Decode Body [DD-SDK]",
@@ -116,14 +400,14 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 			tracer.ChildOf(parentSpan.Context()),
 		)
 
-		err = json.NewDecoder(r.Body).Decode(&user)
+		var reason LoginFailureReason
+		var status int
+		reason, status, err = decodeLogin(w, r, &user)
 		if err != nil {
-			if _err := h.StatsdClient.Incr("login.failure", []string{"reason:invalid_payload"}, 1); _err != nil {
-				slog.ErrorContext(ctx, "failed to increment login failure counter", slog.Any("error", _err))
-			}
+			h.countLoginFailure(ctx, reason)
 
-			http.Error(w, "Invalid request payload (from dd-sdk example).", http.StatusBadRequest)
-			decodeBodySpan.Finish()
+			http.Error(w, fmt.Sprintf("Invalid login request, %s (from dd-sdk example).", err), status)
+			decodeBodySpan.Finish(tracer.WithError(err))
 			return
 		}
 
@@ -135,23 +419,34 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		decodeBodySpan.Finish()
 	}
 
+	// The lock is checked before the password, so a locked account cannot be brute-forced.
+	remoteIP := clientIP(r)
+	if decision := h.Lockout.Check(user.Username, remoteIP); decision.Locked() {
+		h.countLoginFailure(ctx, LoginLocked)
+
+		h.securityEvent(ctx, SecurityEventLockedLoginRejected, user.Username, remoteIP, decision)
+
+		writeLocked(w, decision)
+		return
+	}
+
 	{
-		checkCredentialsSpan := tracer.StartSpan("Check Credentials [DD-SDK]",
+		// The parent comes from parentCtx, StartSpanFromContext overrides tracer.ChildOf with the span of the context.
+		checkCredentialsSpan, checkCtx := tracer.StartSpanFromContext(parentCtx, "Check Credentials [DD-SDK]",
 			tracer.ResourceName("check-credentials"),
-			tracer.ChildOf(parentSpan.Context()),
 		)
 		defer checkCredentialsSpan.Finish()
 
-		// In-memory user store
-		var users = map[string]string{
-			"user1": "password1",
-			"user2": "password2",
-			"user3": "password3",
+		_, err = h.Users.Authenticate(checkCtx, user.Username, user.Password)
+		if err != nil && !errors.Is(err, ErrInvalidCredentials) {
+			http.Error(w, "Failed to check the credentials (from dd-sdk example).", http.StatusInternalServerError)
+			return
 		}
 
-		// Validate the user credentials
-		if password, exists := users[user.Username]; exists && password == user.Password {
-			if _err := h.StatsdClient.Incr("login.success", nil, 1); _err != nil {
+		if err == nil {
+			h.Lockout.Success(user.Username)
+
+			if _err := RecordLoginSuccess(h.StatsdClient); _err != nil {
 				slog.ErrorContext(ctx, "failed to increment login success counter", slog.Any("error", _err))
 			}
 
@@ -161,10 +456,20 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if _err := h.StatsdClient.Incr("login.failure", []string{"reason:invalid_credentials"}, 1); _err != nil {
-		slog.ErrorContext(ctx, "failed to increment login failure counter", slog.Any("error", _err))
+	// The reason tells the unknown users apart from the wrong passwords, the response does not.
+	if errors.Is(err, ErrUnknownUser) {
+		h.countLoginFailure(ctx, LoginUnknownUser)
+	} else {
+		h.countLoginFailure(ctx, LoginWrongPassword)
+	}
+
+	// This attempt is still answered 401, the next ones are rejected by the lock.
+	switch decision := h.Lockout.Failure(user.Username, remoteIP); decision.Scope {
+	case LockoutScopeUsername:
+		h.securityEvent(ctx, SecurityEventAccountLocked, user.Username, remoteIP, decision)
+	case LockoutScopeClientIP:
+		h.securityEvent(ctx, SecurityEventClientBlocked, user.Username, remoteIP, decision)
 	}
 
-	err = fmt.Errorf("invalid credentials") // for defer function error tracer.RecordError(err)
 	http.Error(w, "Invalid username or password (from dd-sdk example).", http.StatusUnauthorized)
 }
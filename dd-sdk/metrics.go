@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// MetricsMiddleware records the request count, the latency distribution and the in-flight requests through DogStatsD,
+// using the metric names of the MetricsMiddleware of otel-sdk:
+//
+//   - http_server_requests_total, a count
+//   - http_server_request_duration_ms, a distribution in milliseconds
+//   - http_server_requests_in_flight, a gauge
+//
+// The count and the distribution are tagged with method, route (the chi route pattern) and status_code.
+func MetricsMiddleware(statsdClient *statsd.Client) func(http.Handler) http.Handler {
+	var inFlight atomic.Int64
+
+	gaugeInFlight := func(r *http.Request, n int64) {
+		if err := statsdClient.Gauge("http_server_requests_in_flight", float64(n), nil, 1); err != nil {
+			slog.ErrorContext(r.Context(), "failed to send in-flight requests gauge", slog.Any("error", err))
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			startTime := time.Now()
+			gaugeInFlight(r, inFlight.Add(1))
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				gaugeInFlight(r, inFlight.Add(-1))
+
+				// The status is 200 when the handler writes the body without calling WriteHeader.
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				// The route pattern is complete only after chi routed the request, it is empty when nothing matched.
+				route := chi.RouteContext(r.Context()).RoutePattern()
+				if route == "" {
+					route = "unmatched"
+				}
+
+				tags := []string{
+					"method:" + r.Method,
+					"route:" + route,
+					"status_code:" + strconv.Itoa(status),
+				}
+
+				if err := statsdClient.Incr("http_server_requests_total", tags, 1); err != nil {
+					slog.ErrorContext(r.Context(), "failed to increment http_server_requests_total", slog.Any("error", err))
+				}
+
+				duration := float64(time.Since(startTime).Microseconds()) / 1000
+				if err := statsdClient.Distribution("http_server_request_duration_ms", duration, tags, 1); err != nil {
+					slog.ErrorContext(r.Context(), "failed to send http_server_request_duration_ms", slog.Any("error", err))
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+const (
+	TracingAPINative = "native"
+	TracingAPIOtel   = "otel"
+)
+
+// startOtelSpan makes the span of the OpenTelemetry API look like the span of tracer.StartSpan in the Datadog agent.
+// dd-trace-go uses the OpenTelemetry span name as the resource name unless "resource.name" is set,
+// and the operation name is the "operation.name" attribute in lower case. The attribute is only remapped
+// to the operation name by SetAttributes, as a start option it would also be sent as a tag.
+func (h *Handler) startOtelSpan(ctx context.Context, operationName, resourceName string) (context.Context, trace.Span) {
+	ctx, span := h.Tracer.Start(ctx, operationName, trace.WithAttributes(
+		attribute.String(ext.ResourceName, resourceName),
+	))
+	span.SetAttributes(attribute.String("operation.name", operationName))
+	return ctx, span
+}
+
+// LoginOtel is Login instrumented with the OpenTelemetry API instead of tracer.StartSpan,
+// the spans are still sent by the Datadog tracer through the TracerProvider of dd-trace-go.
+// The span hierarchy, the resource names and the errors are the same as Login.
+func (h *Handler) LoginOtel(w http.ResponseWriter, r *http.Request) {
+	// The span of the chi middleware is in the context, dd-trace-go uses it as the parent.
+	ctx := r.Context()
+	var err error
+
+	parentCtx, parentSpan := h.startOtelSpan(ctx, "Login Handler [DD-SDK]", "login-handler")
+	defer func() {
+		// Only the status, RecordError adds an "events" tag which tracer.WithError does not.
+		if err != nil {
+			parentSpan.SetStatus(codes.Error, err.Error())
+		}
+		parentSpan.End()
+	}()
+
+	var user loginRequest
+	{
+		_, decodeBodySpan := h.startOtelSpan(parentCtx, "Decode Body [DD-SDK]", "decode-body")
+
+		var reason LoginFailureReason
+		var status int
+		reason, status, err = decodeLogin(w, r, &user)
+		if err != nil {
+			h.countLoginFailure(ctx, reason)
+
+			http.Error(w, fmt.Sprintf("Invalid login request, %s (from dd-sdk example).", err), status)
+			decodeBodySpan.SetStatus(codes.Error, err.Error())
+			decodeBodySpan.End()
+			return
+		}
+
+		defer func() {
+			if _err := r.Body.Close(); _err != nil {
+				slog.ErrorContext(ctx, "failed to close request body", slog.Any("error", _err))
+			}
+		}()
+		decodeBodySpan.End()
+	}
+
+	// The lock is checked before the password, so a locked account cannot be brute-forced.
+	remoteIP := clientIP(r)
+	if decision := h.Lockout.Check(user.Username, remoteIP); decision.Locked() {
+		h.countLoginFailure(ctx, LoginLocked)
+
+		h.securityEvent(ctx, SecurityEventLockedLoginRejected, user.Username, remoteIP, decision)
+
+		writeLocked(w, decision)
+		return
+	}
+
+	{
+		checkCtx, checkCredentialsSpan := h.startOtelSpan(parentCtx, "Check Credentials [DD-SDK]", "check-credentials")
+		defer checkCredentialsSpan.End()
+
+		_, err = h.Users.Authenticate(checkCtx, user.Username, user.Password)
+		if err != nil && !errors.Is(err, ErrInvalidCredentials) {
+			http.Error(w, "Failed to check the credentials (from dd-sdk example).", http.StatusInternalServerError)
+			return
+		}
+
+		if err == nil {
+			h.Lockout.Success(user.Username)
+
+			if _err := RecordLoginSuccess(h.StatsdClient); _err != nil {
+				slog.ErrorContext(ctx, "failed to increment login success counter", slog.Any("error", _err))
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("Login successful (from dd-sdk example).\n"))
+			return
+		}
+	}
+
+	// The reason tells the unknown users apart from the wrong passwords, the response does not.
+	if errors.Is(err, ErrUnknownUser) {
+		h.countLoginFailure(ctx, LoginUnknownUser)
+	} else {
+		h.countLoginFailure(ctx, LoginWrongPassword)
+	}
+
+	// This attempt is still answered 401, the next ones are rejected by the lock.
+	switch decision := h.Lockout.Failure(user.Username, remoteIP); decision.Scope {
+	case LockoutScopeUsername:
+		h.securityEvent(ctx, SecurityEventAccountLocked, user.Username, remoteIP, decision)
+	case LockoutScopeClientIP:
+		h.securityEvent(ctx, SecurityEventClientBlocked, user.Username, remoteIP, decision)
+	}
+
+	http.Error(w, "Invalid username or password (from dd-sdk example).", http.StatusUnauthorized)
+}
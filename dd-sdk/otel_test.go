@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/go-chi/chi/v5"
+	chitrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/go-chi/chi.v5"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	ddotel "gopkg.in/DataDog/dd-trace-go.v1/ddtrace/opentelemetry"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// discardWriter drops the datagrams of the statsd client.
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func (discardWriter) Close() error { return nil }
+
+func newTestStatsd(t *testing.T) *statsd.Client {
+	t.Helper()
+
+	client, err := statsd.NewWithWriter(discardWriter{}, statsd.WithoutTelemetry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// testSpan is a finished span without the IDs and the timings, so the spans of Login and LoginOtel can be compared.
+type testSpan struct {
+	Name     string
+	Resource string
+	Parent   string
+	Error    string
+	Tags     string
+}
+
+// spanName is the operation name seen by the Datadog agent.
+// The Datadog tracer renames the span with the "span.name" tag set by the OpenTelemetry API, the mock tracer only records it.
+func spanName(span mocktracer.Span) string {
+	if name, ok := span.Tag(ext.SpanName).(string); ok {
+		return name
+	}
+	return strings.ToLower(span.OperationName())
+}
+
+// otelOnlyTags are set by the OpenTelemetry API of dd-trace-go on top of the tags of the span,
+// the real tracer sets "error.message" from tracer.WithError as well.
+var otelOnlyTags = []string{ext.SpanName, ext.ErrorMsg}
+
+func toTestSpans(spans []mocktracer.Span) []testSpan {
+	names := map[uint64]string{}
+	for _, span := range spans {
+		names[span.SpanID()] = spanName(span)
+	}
+
+	out := make([]testSpan, 0, len(spans))
+	for _, span := range spans {
+		var tags []string
+		for k, v := range span.Tags() {
+			if k == ext.ResourceName || k == ext.Error || slices.Contains(otelOnlyTags, k) {
+				continue
+			}
+			tags = append(tags, fmt.Sprintf("%s=%v", k, v))
+		}
+		slices.Sort(tags)
+
+		s := testSpan{
+			Name:     spanName(span),
+			Resource: fmt.Sprint(span.Tag(ext.ResourceName)),
+			Parent:   names[span.ParentID()],
+			Tags:     strings.Join(tags, " "),
+		}
+		if err, ok := span.Tag(ext.Error).(error); ok {
+			s.Error = err.Error()
+		}
+		out = append(out, s)
+	}
+
+	// The spans finish in the same order, but sorting keeps the comparison independent of the defers.
+	slices.SortFunc(out, func(a, b testSpan) int {
+		return strings.Compare(a.Parent+"/"+a.Name, b.Parent+"/"+b.Name)
+	})
+	return out
+}
+
+// loginSpans sends the bodies to the login handler behind the chi tracer middleware, and returns the spans of every request.
+func loginSpans(t *testing.T, login func(*Handler) http.HandlerFunc, bodies ...string) [][]testSpan {
+	t.Helper()
+
+	// The TracerProvider starts a Datadog tracer, it is replaced by the mock tracer right after.
+	tracerProvider := ddotel.NewTracerProvider(tracer.WithLogStartup(false))
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	statsdClient := newTestStatsd(t)
+	handler := &Handler{
+		StatsdClient: statsdClient,
+		Tracer:       tracerProvider.Tracer("dd-sdk"),
+		Users:        NewAuthenticator(DefaultUserStore(), statsdClient),
+		Lockout:      NewLockoutTracker(LockoutConfig{MaxUsernameFailures: 1}, statsdClient),
+	}
+
+	router := chi.NewRouter()
+	router.Use(chitrace.Middleware(chitrace.WithServiceName("dd-sdk")))
+	router.Post("/login", login(handler))
+
+	var out [][]testSpan
+	for _, body := range bodies {
+		mt.Reset()
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+		out = append(out, toTestSpans(mt.FinishedSpans()))
+	}
+	return out
+}
+
+func TestLoginOtelSpansMatchLogin(t *testing.T) {
+	tests := map[string][]string{
+		"success":         {`{"username":"user1","password":"password1"}`},
+		"argon2id":        {`{"username":"user2","password":"password2"}`},
+		"wrong password":  {`{"username":"user1","password":"wrong"}`},
+		"unknown user":    {`{"username":"nobody","password":"password1"}`},
+		"invalid payload": {`{"username":`},
+		"missing fields":  {`{"username":"user1"}`},
+		"locked": {
+			`{"username":"user3","password":"wrong"}`,
+			`{"username":"user3","password":"password3"}`,
+		},
+	}
+
+	for name, bodies := range tests {
+		t.Run(name, func(t *testing.T) {
+			native := loginSpans(t, func(h *Handler) http.HandlerFunc { return h.Login }, bodies...)
+			otel := loginSpans(t, func(h *Handler) http.HandlerFunc { return h.LoginOtel }, bodies...)
+
+			for i := range bodies {
+				if len(native[i]) < 2 {
+					t.Fatalf("request %d: %d spans, the login handler is not traced", i, len(native[i]))
+				}
+				if !slices.Equal(native[i], otel[i]) {
+					t.Errorf("request %d:\nLogin:\n%s\nLoginOtel:\n%s", i, formatSpans(native[i]), formatSpans(otel[i]))
+				}
+			}
+		})
+	}
+}
+
+func formatSpans(spans []testSpan) string {
+	var b strings.Builder
+	for _, s := range spans {
+		fmt.Fprintf(&b, "  %+v\n", s)
+	}
+	return b.String()
+}
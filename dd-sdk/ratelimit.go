@@ -0,0 +1,299 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const (
+	RateLimitScopeGlobal = "global"
+	RateLimitScopeClient = "client"
+
+	RateLimitOutcomeAllowed     = "allowed"
+	RateLimitOutcomeRateLimited = "rate_limited"
+)
+
+// RateLimitConfig is the configuration of the RateLimiter, a zero rate disables the bucket of its scope.
+type RateLimitConfig struct {
+	// GlobalRate is the number of requests per second of all the clients, GlobalBurst is the size of the bucket.
+	GlobalRate  float64
+	GlobalBurst int
+
+	// ClientRate is the number of requests per second of one client address, ClientBurst is the size of its bucket.
+	ClientRate  float64
+	ClientBurst int
+
+	// MaxClients is the number of client buckets kept, the least recently used one is evicted. By default it is 10000.
+	MaxClients int
+
+	// TrustedProxies are the proxies whose X-Forwarded-For header is read to find the client address.
+	TrustedProxies []netip.Prefix
+}
+
+// ParseTrustedProxies parses a comma separated list of CIDR or IP addresses, for example "10.0.0.0/8,127.0.0.1".
+func ParseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket since the last call and takes one token, or returns how long to wait for one.
+func (b *tokenBucket) take(now time.Time, rate float64, burst int) (bool, time.Duration) {
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+type clientTokenBucket struct {
+	address string
+	tokenBucket
+}
+
+// RateLimitDecision is the decision of the RateLimiter for one request.
+type RateLimitDecision struct {
+	Allowed bool
+
+	// Scope is the bucket which rejected the request, empty when it is allowed.
+	Scope string
+
+	// Client is the resolved client address.
+	Client string
+
+	// RetryAfter is the time until the rejecting bucket has a token.
+	RetryAfter time.Duration
+}
+
+// RateLimiter limits the requests with token buckets, a global one and one per client address, it is safe for concurrent use.
+// The client buckets are kept in a LRU, so the memory is bounded whatever the number of clients is.
+type RateLimiter struct {
+	// OnReject is called for every rejected request before the response, for example to count it as a failure of the endpoint.
+	OnReject func(r *http.Request, decision RateLimitDecision)
+
+	cfg          RateLimitConfig
+	statsdClient *statsd.Client
+
+	mu      sync.Mutex
+	global  tokenBucket
+	clients map[string]*list.Element
+	lru     *list.List
+}
+
+// NewRateLimiter creates a RateLimiter, it sends the count "ratelimit.requests" of the decisions by outcome (and scope when rate limited),
+// and the gauge "ratelimit.tracked_clients" every 10 seconds.
+func NewRateLimiter(cfg RateLimitConfig, statsdClient *statsd.Client) *RateLimiter {
+	if cfg.MaxClients <= 0 {
+		cfg.MaxClients = 10000
+	}
+
+	if cfg.GlobalBurst <= 0 {
+		cfg.GlobalBurst = max(1, int(math.Ceil(cfg.GlobalRate)))
+	}
+
+	if cfg.ClientBurst <= 0 {
+		cfg.ClientBurst = max(1, int(math.Ceil(cfg.ClientRate)))
+	}
+
+	l := &RateLimiter{
+		cfg:          cfg,
+		statsdClient: statsdClient,
+		global:       tokenBucket{tokens: float64(cfg.GlobalBurst), last: time.Now()},
+		clients:      map[string]*list.Element{},
+		lru:          list.New(),
+	}
+
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := statsdClient.Gauge("ratelimit.tracked_clients", float64(l.TrackedClients()), nil, 1); err != nil {
+				slog.Error("failed to send ratelimit.tracked_clients gauge", slog.Any("error", err))
+			}
+		}
+	}()
+
+	return l
+}
+
+// TrackedClients is the number of the client buckets.
+func (l *RateLimiter) TrackedClients() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lru.Len()
+}
+
+// Allow takes a token from the bucket of the client, then from the global bucket.
+func (l *RateLimiter) Allow(client string) RateLimitDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	if l.cfg.ClientRate > 0 {
+		if ok, wait := l.clientBucket(client, now).take(now, l.cfg.ClientRate, l.cfg.ClientBurst); !ok {
+			return RateLimitDecision{Scope: RateLimitScopeClient, Client: client, RetryAfter: wait}
+		}
+	}
+
+	if l.cfg.GlobalRate > 0 {
+		if ok, wait := l.global.take(now, l.cfg.GlobalRate, l.cfg.GlobalBurst); !ok {
+			return RateLimitDecision{Scope: RateLimitScopeGlobal, Client: client, RetryAfter: wait}
+		}
+	}
+
+	return RateLimitDecision{Allowed: true, Client: client}
+}
+
+// clientBucket returns the bucket of the client as the most recently used, a new one evicts the least recently used.
+func (l *RateLimiter) clientBucket(client string, now time.Time) *tokenBucket {
+	if elem, ok := l.clients[client]; ok {
+		l.lru.MoveToFront(elem)
+		return &elem.Value.(*clientTokenBucket).tokenBucket
+	}
+
+	if l.lru.Len() >= l.cfg.MaxClients {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.clients, oldest.Value.(*clientTokenBucket).address)
+	}
+
+	b := &clientTokenBucket{address: client, tokenBucket: tokenBucket{tokens: float64(l.cfg.ClientBurst), last: now}}
+	l.clients[client] = l.lru.PushFront(b)
+	return &b.tokenBucket
+}
+
+// ClientAddress is the remote address, or the X-Forwarded-For address added by the last trusted proxy.
+// The header is read from the right, the addresses before the first untrusted one could be forged by the client.
+func (l *RateLimiter) ClientAddress(r *http.Request) string {
+	remote, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	addr := remote.Addr().Unmap()
+	if !l.trusted(addr) {
+		return addr.String()
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+
+		addr = hop.Unmap()
+		if !l.trusted(addr) {
+			break
+		}
+	}
+
+	return addr.String()
+}
+
+func (l *RateLimiter) trusted(addr netip.Addr) bool {
+	for _, prefix := range l.cfg.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+type rateLimitClientKey struct{}
+
+// rateLimitClient returns the client address resolved by the Middleware, so the handler keys the client the same way as the limiter.
+func rateLimitClient(ctx context.Context) (string, bool) {
+	client, ok := ctx.Value(rateLimitClientKey{}).(string)
+	return client, ok
+}
+
+// Middleware rejects the requests over the limit with 429 Too Many Requests and Retry-After.
+// The decision is tagged on the span of the request, and the client address of an allowed request is in its context, see rateLimitClient.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		decision := l.Allow(l.ClientAddress(r))
+
+		outcome := RateLimitOutcomeAllowed
+		if !decision.Allowed {
+			outcome = RateLimitOutcomeRateLimited
+		}
+
+		tags := []string{"outcome:" + outcome}
+		if !decision.Allowed {
+			tags = append(tags, "scope:"+decision.Scope)
+		}
+
+		if span, ok := tracer.SpanFromContext(ctx); ok {
+			span.SetTag("ratelimit.outcome", outcome)
+			span.SetTag("ratelimit.client", decision.Client)
+			if !decision.Allowed {
+				span.SetTag("ratelimit.scope", decision.Scope)
+				span.SetTag("ratelimit.retry_after_s", decision.RetryAfter.Seconds())
+			}
+		}
+
+		if err := l.statsdClient.Incr("ratelimit.requests", tags, 1); err != nil {
+			slog.ErrorContext(ctx, "failed to increment ratelimit.requests", slog.Any("error", err))
+		}
+
+		if !decision.Allowed {
+			if l.OnReject != nil {
+				l.OnReject(r, decision)
+			}
+
+			// Retry-After is in seconds, rounded up so the client does not retry too early.
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(decision.RetryAfter.Seconds())), 10))
+			http.Error(w, fmt.Sprintf("Too many requests (%s limit), retry later.", decision.Scope), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, rateLimitClientKey{}, decision.Client)))
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// RecoveryMiddleware recovers a panic of the next handlers and responds a JSON 500, like the recovery middleware of otel-sdk.
+// The panic value and the stack are set as the error.message, error.type and error.stack tags of the span in the request context,
+// panics_total is incremented by method and route, and the panic is logged with dd.trace_id and dd.span_id.
+// The span of the other 5xx responses gets the error "<status>: <status text>", like the chi tracer middleware sets it.
+//
+// It must be installed after the chi tracer and metrics middlewares, and the chi tracer middleware must use NoStatusError.
+// http.ErrAbortHandler is not recovered, it is the way to abort a response on purpose.
+func RecoveryMiddleware(statsdClient *statsd.Client) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				value := recover()
+				if value == nil {
+					if status := ww.Status(); status >= 500 && status < 600 {
+						if span, ok := tracer.SpanFromContext(r.Context()); ok {
+							span.SetTag(ext.Error, fmt.Errorf("%d: %s", status, http.StatusText(status)))
+						}
+					}
+					return
+				}
+
+				if value == http.ErrAbortHandler {
+					panic(value)
+				}
+
+				stack := string(debug.Stack())
+				ctx := r.Context()
+
+				// The route pattern is complete only after chi routed the request, it is empty when the panic happened before.
+				route := ""
+				if rctx := chi.RouteContext(ctx); rctx != nil {
+					route = rctx.RoutePattern()
+				}
+				if route == "" {
+					route = "unmatched"
+				}
+
+				logAttrs := []any{slog.Any("panic", value), slog.String("stack", stack)}
+
+				// The span is finished by the chi tracer middleware, with the route and the status code.
+				if span, ok := tracer.SpanFromContext(ctx); ok {
+					span.SetTag(ext.Error, true)
+					span.SetTag(ext.ErrorMsg, fmt.Sprint(value))
+					span.SetTag(ext.ErrorType, fmt.Sprintf("%T", value))
+					span.SetTag(ext.ErrorStack, stack)
+
+					logAttrs = append(logAttrs,
+						slog.Uint64("dd.trace_id", span.Context().TraceID()),
+						slog.Uint64("dd.span_id", span.Context().SpanID()),
+					)
+				}
+
+				tags := []string{"method:" + r.Method, "route:" + route}
+				if err := statsdClient.Incr("panics_total", tags, 1); err != nil {
+					slog.ErrorContext(ctx, "failed to increment panics_total", slog.Any("error", err))
+				}
+
+				slog.ErrorContext(ctx, "panic recovered", logAttrs...)
+
+				w.Header().Set("Content-Type", "application/json")
+				ww.WriteHeader(http.StatusInternalServerError)
+				_, _ = ww.Write([]byte(`{"error":"internal server error"}` + "\n"))
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+// NoStatusError is the status check of the chi tracer middleware. RecoveryMiddleware sets the error of the 5xx responses instead,
+// the chi tracer middleware would replace the tags of a recovered panic with "500: Internal Server Error" and its own stack.
+func NoStatusError(int) bool {
+	return false
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/go-chi/chi/v5"
+	chitrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/go-chi/chi.v5"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+)
+
+// statsdWriter collects the datagrams sent by the statsd client.
+type statsdWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *statsdWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *statsdWriter) Close() error { return nil }
+
+func (w *statsdWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func newRecoveryRouter(t *testing.T) (http.Handler, mocktracer.Tracer, *statsd.Client, *statsdWriter) {
+	t.Helper()
+
+	mt := mocktracer.Start()
+	t.Cleanup(mt.Stop)
+
+	writer := &statsdWriter{}
+	statsdClient, err := statsd.NewWithWriter(writer, statsd.WithoutTelemetry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = statsdClient.Close() })
+
+	router := chi.NewRouter()
+	router.Use(chitrace.Middleware(chitrace.WithServiceName("dd-sdk"), chitrace.WithStatusCheck(NoStatusError)))
+	router.Use(RecoveryMiddleware(statsdClient))
+	router.Get("/panic/{id}", func(http.ResponseWriter, *http.Request) { panic(errors.New("boom")) })
+	router.Get("/abort", func(http.ResponseWriter, *http.Request) { panic(http.ErrAbortHandler) })
+	router.Get("/unavailable", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) })
+	router.Get("/ok", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) })
+
+	return router, mt, statsdClient, writer
+}
+
+func serve(router http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func requestSpan(t *testing.T, mt mocktracer.Tracer) mocktracer.Span {
+	t.Helper()
+
+	spans := mt.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans finished, want 1", len(spans))
+	}
+	return spans[0]
+}
+
+func TestRecoveryMiddlewarePanic(t *testing.T) {
+	router, mt, statsdClient, writer := newRecoveryRouter(t)
+
+	w := serve(router, "/panic/1")
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/json" ||
+		w.Body.String() != `{"error":"internal server error"}`+"\n" {
+		t.Errorf("response = %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	// The tags of the panic are kept, and the chi tracer middleware still sets the route after the recovery.
+	span := requestSpan(t, mt)
+	for tag, want := range map[string]any{
+		ext.Error:        true,
+		ext.ErrorMsg:     "boom",
+		ext.ErrorType:    "*errors.errorString",
+		ext.HTTPCode:     "500",
+		ext.HTTPRoute:    "/panic/{id}",
+		ext.ResourceName: "GET /panic/{id}",
+	} {
+		if got := span.Tag(tag); got != want {
+			t.Errorf("tag %s = %v, want %v", tag, got, want)
+		}
+	}
+	if stack, _ := span.Tag(ext.ErrorStack).(string); !strings.Contains(stack, "newRecoveryRouter.func") {
+		t.Errorf("error.stack is not the stack of the panic:\n%s", stack)
+	}
+
+	if err := statsdClient.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "panics_total:1|c|#method:GET,route:/panic/{id}"; !strings.Contains(writer.String(), want) {
+		t.Errorf("datagrams %q, want %q", writer.String(), want)
+	}
+}
+
+func TestRecoveryMiddlewareStatusError(t *testing.T) {
+	tests := map[string]any{
+		"/unavailable": fmt.Errorf("503: %s", http.StatusText(http.StatusServiceUnavailable)),
+		"/ok":          nil,
+	}
+
+	for path, want := range tests {
+		t.Run(path, func(t *testing.T) {
+			router, mt, _, _ := newRecoveryRouter(t)
+			serve(router, path)
+
+			got := requestSpan(t, mt).Tag(ext.Error)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("tag %s = %v, want %v", ext.Error, got, want)
+			}
+		})
+	}
+}
+
+func TestRecoveryMiddlewareAbortHandler(t *testing.T) {
+	router, _, _, writer := newRecoveryRouter(t)
+
+	defer func() {
+		if value := recover(); value != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", value)
+		}
+		if strings.Contains(writer.String(), "panics_total") {
+			t.Error("http.ErrAbortHandler is counted as a panic")
+		}
+	}()
+
+	serve(router, "/abort")
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrUnknownUser and ErrWrongPassword are ErrInvalidCredentials, they are only told apart in the telemetry, never in the response.
+	ErrUnknownUser   = fmt.Errorf("%w: unknown user", ErrInvalidCredentials)
+	ErrWrongPassword = fmt.Errorf("%w: wrong password", ErrInvalidCredentials)
+)
+
+// StoredUser is one entry of the user file, the password hash is generated by the hash-password command of otel-sdk.
+type StoredUser struct {
+	Username     string `json:"username" yaml:"username"`
+	PasswordHash string `json:"password_hash" yaml:"password_hash"`
+}
+
+// UserStore finds the users by username.
+type UserStore interface {
+	// Lookup returns ErrUserNotFound when the user does not exist.
+	Lookup(ctx context.Context, username string) (StoredUser, error)
+}
+
+// MemoryUserStore is a UserStore in memory, it is read only after it is loaded.
+type MemoryUserStore struct {
+	users map[string]StoredUser
+}
+
+var _ UserStore = (*MemoryUserStore)(nil)
+
+//go:embed users.yaml
+var defaultUsers []byte
+
+// DefaultUserStore returns the demo users: user1, user2 and user3 with the passwords password1, password2 and password3.
+func DefaultUserStore() *MemoryUserStore {
+	store, err := ParseUserStore(defaultUsers, "yaml")
+	if err != nil {
+		panic(fmt.Errorf("invalid embedded users.yaml: %w", err))
+	}
+	return store
+}
+
+// LoadUserStoreFile reads a JSON (".json") or YAML (".yaml", ".yml") file: a list of users with their username and password_hash.
+func LoadUserStoreFile(path string) (*MemoryUserStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := strings.TrimPrefix(filepath.Ext(path), ".")
+	if format == "yml" {
+		format = "yaml"
+	}
+
+	store, err := ParseUserStore(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return store, nil
+}
+
+// ParseUserStore decodes the users in the "json" or "yaml" format, every password hash must be bcrypt or argon2id, see validatePasswordHash.
+// The usernames must be unique.
+func ParseUserStore(data []byte, format string) (*MemoryUserStore, error) {
+	var users []StoredUser
+
+	var err error
+	switch format {
+	case "json":
+		err = json.Unmarshal(data, &users)
+	case "yaml":
+		err = yaml.Unmarshal(data, &users)
+	default:
+		return nil, fmt.Errorf("unsupported user file format %q, must be json or yaml", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode users: %w", err)
+	}
+
+	store := &MemoryUserStore{users: make(map[string]StoredUser, len(users))}
+	for _, user := range users {
+		if user.Username == "" {
+			return nil, fmt.Errorf("user without username")
+		}
+		if _, ok := store.users[user.Username]; ok {
+			return nil, fmt.Errorf("duplicate user %s", user.Username)
+		}
+		if err = validatePasswordHash(user.PasswordHash); err != nil {
+			return nil, fmt.Errorf("user %s: %w", user.Username, err)
+		}
+		store.users[user.Username] = user
+	}
+
+	return store, nil
+}
+
+func (s *MemoryUserStore) Lookup(_ context.Context, username string) (StoredUser, error) {
+	user, ok := s.users[username]
+	if !ok {
+		return StoredUser{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// PasswordHashes returns the password hashes of all the users, see dummyPasswordHash.
+func (s *MemoryUserStore) PasswordHashes() []string {
+	hashes := make([]string, 0, len(s.users))
+	for _, user := range s.users {
+		hashes = append(hashes, user.PasswordHash)
+	}
+	return hashes
+}
+
+// LatencyUserStore delays every lookup by a random duration between Min and Max, like a remote database would.
+type LatencyUserStore struct {
+	Store    UserStore
+	Min, Max time.Duration
+}
+
+var _ UserStore = (*LatencyUserStore)(nil)
+
+func (s *LatencyUserStore) Lookup(ctx context.Context, username string) (StoredUser, error) {
+	delay := s.Min
+	if s.Max > s.Min {
+		delay += rand.N(s.Max - s.Min)
+	}
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return StoredUser{}, ctx.Err()
+	}
+
+	return s.Store.Lookup(ctx, username)
+}
+
+// PasswordHashes returns the password hashes of Store, if it knows them.
+func (s *LatencyUserStore) PasswordHashes() []string {
+	return passwordHashes(s.Store)
+}
+
+// passwordHashes returns the password hashes of the store when it has a PasswordHashes method, like MemoryUserStore.
+func passwordHashes(store UserStore) []string {
+	if store, ok := store.(interface{ PasswordHashes() []string }); ok {
+		return store.PasswordHashes()
+	}
+	return nil
+}
+
+// parseLatencyRange parses "5ms-50ms" as a range, or "20ms" as a fixed latency.
+func parseLatencyRange(value string) (time.Duration, time.Duration, error) {
+	minValue, maxValue, isRange := strings.Cut(value, "-")
+
+	minLatency, err := time.ParseDuration(strings.TrimSpace(minValue))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if !isRange {
+		return minLatency, minLatency, nil
+	}
+
+	maxLatency, err := time.ParseDuration(strings.TrimSpace(maxValue))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if minLatency < 0 || maxLatency < minLatency {
+		return 0, 0, fmt.Errorf("invalid latency range %q, the minimum must be positive and not above the maximum", value)
+	}
+
+	return minLatency, maxLatency, nil
+}
+
+// Authenticator checks the credentials against the UserStore, with a span and a statsd distribution for the lookup and for the hash verification.
+type Authenticator struct {
+	Store        UserStore
+	StatsdClient *statsd.Client
+
+	// dummyHash is verified when the user does not exist, so an unknown username takes as long as a wrong password.
+	// It has the algorithm and the parameters of the most expensive hash of the store.
+	dummyHash string
+}
+
+// NewAuthenticator sends the distributions "userstore.lookup_duration_ms" and "userstore.verify_duration_ms".
+func NewAuthenticator(store UserStore, statsdClient *statsd.Client) *Authenticator {
+	dummyHash, err := dummyPasswordHash("dummy password of the unknown users", passwordHashes(store))
+	if err != nil {
+		panic(err)
+	}
+
+	return &Authenticator{
+		Store:        store,
+		StatsdClient: statsdClient,
+		dummyHash:    dummyHash,
+	}
+}
+
+// Authenticate returns the user when the password matches, ErrUnknownUser when the user does not exist, or ErrWrongPassword.
+// Both errors are ErrInvalidCredentials, and both cases verify one hash, so the response time does not tell whether the username exists.
+// The spans are children of the span in ctx.
+func (a *Authenticator) Authenticate(ctx context.Context, username, password string) (StoredUser, error) {
+	user, err := a.lookup(ctx, username)
+	found := err == nil
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return StoredUser{}, err
+	}
+
+	hash := a.dummyHash
+	if found {
+		hash = user.PasswordHash
+	}
+
+	match, err := a.verify(ctx, hash, password)
+	if err != nil {
+		return StoredUser{}, err
+	}
+
+	if !found {
+		return StoredUser{}, ErrUnknownUser
+	}
+	if !match {
+		return StoredUser{}, ErrWrongPassword
+	}
+	return user, nil
+}
+
+func (a *Authenticator) lookup(ctx context.Context, username string) (user StoredUser, err error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "Lookup User [DD-SDK]",
+		tracer.ResourceName("lookup-user"),
+	)
+
+	start := time.Now()
+	user, err = a.Store.Lookup(ctx, username)
+	found := err == nil
+
+	span.SetTag("user.found", found)
+	if errors.Is(err, ErrUserNotFound) {
+		span.Finish()
+	} else {
+		span.Finish(tracer.WithError(err))
+	}
+
+	tags := []string{fmt.Sprintf("found:%t", found)}
+	if _err := a.StatsdClient.Distribution("userstore.lookup_duration_ms", float64(time.Since(start).Microseconds())/1000, tags, 1); _err != nil {
+		slog.ErrorContext(ctx, "failed to send userstore.lookup_duration_ms", slog.Any("error", _err))
+	}
+
+	return user, err
+}
+
+func (a *Authenticator) verify(ctx context.Context, hash, password string) (bool, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "Verify Password [DD-SDK]",
+		tracer.ResourceName("verify-password"),
+	)
+
+	algorithm, _ := passwordAlgorithm(hash)
+	span.SetTag("password.algorithm", algorithm)
+
+	start := time.Now()
+	match, err := verifyPassword(hash, password)
+	span.Finish(tracer.WithError(err))
+
+	tags := []string{"algorithm:" + algorithm, fmt.Sprintf("match:%t", match)}
+	if _err := a.StatsdClient.Distribution("userstore.verify_duration_ms", float64(time.Since(start).Microseconds())/1000, tags, 1); _err != nil {
+		slog.ErrorContext(ctx, "failed to send userstore.verify_duration_ms", slog.Any("error", _err))
+	}
+
+	return match, err
+}
+
+// passwordAlgorithm detects the algorithm of the hash, bcrypt ("$2a$", "$2b$", "$2y$") or argon2id (PHC string "$argon2id$").
+func passwordAlgorithm(hash string) (string, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return PasswordAlgorithmBcrypt, nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return PasswordAlgorithmArgon2id, nil
+	default:
+		return "", fmt.Errorf("unsupported password hash, must be bcrypt or argon2id")
+	}
+}
+
+// validatePasswordHash checks that verifyPassword can verify the hash: a bcrypt hash, or an argon2id hash with parameters in bounds.
+func validatePasswordHash(hash string) error {
+	algorithm, err := passwordAlgorithm(hash)
+	if err != nil {
+		return err
+	}
+
+	if algorithm == PasswordAlgorithmBcrypt {
+		_, err = bcrypt.Cost([]byte(hash))
+		return err
+	}
+
+	_, _, _, err = parseArgon2id(hash)
+	return err
+}
+
+// verifyPassword compares the password with the hash in constant time.
+func verifyPassword(hash, password string) (bool, error) {
+	algorithm, err := passwordAlgorithm(hash)
+	if err != nil {
+		return false, err
+	}
+
+	if algorithm == PasswordAlgorithmBcrypt {
+		err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+// The bounds of the argon2id parameters of a stored hash, the same as in otel-sdk. argon2.IDKey panics without iteration or thread,
+// a larger cost would let every login exhaust the memory or the CPU, and an empty key would match any password.
+const (
+	argon2MaxMemory  = 1024 * 1024 // KiB, 1 GiB
+	argon2MaxTime    = 10
+	argon2MinSaltLen = 8
+	argon2MinKeyLen  = 16
+)
+
+type argon2Params struct {
+	memory, iterations uint32
+	threads            uint8
+}
+
+// parseArgon2id decodes $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>, and checks the parameters against the bounds.
+func parseArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %q", parts[2])
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters %q", parts[3])
+	}
+
+	switch {
+	case params.iterations < 1 || params.iterations > argon2MaxTime:
+		return params, nil, nil, fmt.Errorf("argon2id iterations t=%d must be between 1 and %d", params.iterations, argon2MaxTime)
+	case params.threads < 1:
+		return params, nil, nil, fmt.Errorf("argon2id parallelism p=%d must be at least 1", params.threads)
+	case params.memory < 8*uint32(params.threads) || params.memory > argon2MaxMemory:
+		return params, nil, nil, fmt.Errorf("argon2id memory m=%d must be between 8*p and %d KiB", params.memory, argon2MaxMemory)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if len(salt) < argon2MinSaltLen {
+		return params, nil, nil, fmt.Errorf("argon2id salt of %d bytes, must be at least %d", len(salt), argon2MinSaltLen)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+	if len(key) < argon2MinKeyLen {
+		return params, nil, nil, fmt.Errorf("argon2id key of %d bytes, must be at least %d", len(key), argon2MinKeyLen)
+	}
+
+	return params, salt, key, nil
+}
+
+// dummyPasswordHash hashes the password like the most expensive of the hashes, measured by verifying once every algorithm and parameters.
+// An unknown user is then verified against it, and takes at least as long as the users of the store. Without hashes, it is a bcrypt hash.
+func dummyPasswordHash(password string, hashes []string) (string, error) {
+	var dummy string
+	var slowest time.Duration
+	seen := map[string]bool{}
+
+	for _, hash := range hashes {
+		params, err := passwordHashParams(hash)
+		if err != nil {
+			return "", err
+		}
+		if seen[params] {
+			continue
+		}
+		seen[params] = true
+
+		candidate, err := hashPasswordLike(hash, password)
+		if err != nil {
+			return "", err
+		}
+
+		start := time.Now()
+		if _, err = verifyPassword(candidate, password); err != nil {
+			return "", err
+		}
+		if elapsed := time.Since(start); dummy == "" || elapsed > slowest {
+			dummy, slowest = candidate, elapsed
+		}
+	}
+
+	if dummy == "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		return string(hash), err
+	}
+	return dummy, nil
+}
+
+// passwordHashParams returns the algorithm and the parameters of the hash, without the salt and the key.
+func passwordHashParams(hash string) (string, error) {
+	algorithm, err := passwordAlgorithm(hash)
+	if err != nil {
+		return "", err
+	}
+
+	if algorithm == PasswordAlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return fmt.Sprintf("bcrypt cost=%d", cost), err
+	}
+
+	params, salt, key, err := parseArgon2id(hash)
+	return fmt.Sprintf("argon2id m=%d,t=%d,p=%d salt=%d key=%d", params.memory, params.iterations, params.threads, len(salt), len(key)), err
+}
+
+// hashPasswordLike hashes the password with the algorithm and the parameters of the hash, and a new salt.
+func hashPasswordLike(hash, password string) (string, error) {
+	algorithm, err := passwordAlgorithm(hash)
+	if err != nil {
+		return "", err
+	}
+
+	if algorithm == PasswordAlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return "", err
+		}
+
+		dummy, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+		return string(dummy), err
+	}
+
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return "", err
+	}
+
+	newSalt := make([]byte, len(salt))
+	if _, err = cryptorand.Read(newSalt); err != nil {
+		return "", err
+	}
+
+	dummyKey := argon2.IDKey([]byte(password), newSalt, params.iterations, params.memory, params.threads, uint32(len(key)))
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.memory, params.iterations, params.threads,
+		base64.RawStdEncoding.EncodeToString(newSalt), base64.RawStdEncoding.EncodeToString(dummyKey)), nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The hash of password2 in users.yaml.
+const argon2idHash = "$argon2id$v=19$m=19456,t=2,p=1$CjJMOiYpBesYRcCDpL9mWA$fuPIbln2RiVzrWJJlMzAfSpn73PYq7n/3BiX05qeBgg"
+
+func TestParseUserStore(t *testing.T) {
+	const bcryptHash = "$2a$10$.4gtWDzcfc3tdvCxMQNnveyMU3SEXGiDTn2CpV2l3.AJaC.nQxoSy"
+
+	tests := map[string]struct {
+		data string
+		err  string
+	}{
+		"valid":       {data: `[{"username":"user1","password_hash":"` + bcryptHash + `"},{"username":"user2","password_hash":"` + argon2idHash + `"}]`},
+		"duplicate":   {data: `[{"username":"user1","password_hash":"` + bcryptHash + `"},{"username":"user1","password_hash":"` + argon2idHash + `"}]`, err: "duplicate user user1"},
+		"no thread":   {data: `[{"username":"user2","password_hash":"` + strings.Replace(argon2idHash, "p=1", "p=0", 1) + `"}]`, err: "p=0"},
+		"huge memory": {data: `[{"username":"user2","password_hash":"` + strings.Replace(argon2idHash, "m=19456", "m=4194304", 1) + `"}]`, err: "m=4194304"},
+		"empty key":   {data: `[{"username":"user2","password_hash":"` + argon2idHash[:strings.LastIndex(argon2idHash, "$")+1] + `"}]`, err: "key of 0 bytes"},
+		"truncated":   {data: `[{"username":"user1","password_hash":"` + bcryptHash[:20] + `"}]`, err: "user user1"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, err := ParseUserStore([]byte(test.data), "json")
+			if test.err == "" {
+				if err != nil || len(store.PasswordHashes()) != 2 {
+					t.Fatalf("ParseUserStore() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ParseUserStore() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestDummyPasswordHash(t *testing.T) {
+	cheap, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The argon2id hash of 19 MiB takes far longer than a bcrypt hash of the minimum cost.
+	store := &LatencyUserStore{Store: &MemoryUserStore{users: map[string]StoredUser{
+		"user1": {Username: "user1", PasswordHash: string(cheap)},
+		"user2": {Username: "user2", PasswordHash: argon2idHash},
+	}}}
+	dummy := NewAuthenticator(store, nil).dummyHash
+
+	want, _ := passwordHashParams(argon2idHash)
+	if got, _ := passwordHashParams(dummy); got != want || dummy == argon2idHash {
+		t.Errorf("dummy hash %s %q, want %s", got, dummy, want)
+	}
+	if match, err := verifyPassword(dummy, "dummy password of the unknown users"); err != nil || !match {
+		t.Errorf("verifyPassword(dummy) = %v, %v", match, err)
+	}
+
+	dummy, err = dummyPasswordHash("dummy", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := passwordHashParams(dummy); got != "bcrypt cost=10" {
+		t.Errorf("dummy hash without hashes %s, want the bcrypt default cost", got)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// Set at build time, see the Dockerfile:
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Modified  bool   `json:"modified"`
+}
+
+// getBuildInfo returns the ldflags values, falling back to the VCS information recorded by the Go toolchain.
+func getBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+
+		for _, setting := range bi.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			case setting.Key == "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+
+	return info
+}
+
+// ServeHTTP responds the build information as JSON.
+func (b BuildInfo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(b); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write build info", slog.Any("error", err))
+	}
+}
+
+// reportBuildInfo sends the "build_info" gauge, always 1 and tagged with the build information, every interval.
+// A DogStatsD gauge is only kept until the next Agent flush, so it must be sent again periodically.
+func reportBuildInfo(statsdClient *statsd.Client, b BuildInfo, interval time.Duration) {
+	tags := []string{
+		fmt.Sprintf("commit:%s", b.Commit),
+		fmt.Sprintf("build_date:%s", b.BuildDate),
+		fmt.Sprintf("go_version:%s", b.GoVersion),
+	}
+
+	for {
+		if err := statsdClient.Gauge("build_info", 1, tags, 1); err != nil {
+			slog.Error("failed to send build info gauge", slog.Any("error", err))
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/authtoken"
+)
+
+const (
+	// EnduserIDPlain records the username as enduser.id on the spans.
+	EnduserIDPlain = "plain"
+
+	// EnduserIDHash records the first 16 hex characters of the SHA-256 of the username, the same user is still correlated across traces.
+	EnduserIDHash = "hash"
+
+	// EnduserIDOmit does not record enduser.id.
+	EnduserIDOmit = "omit"
+)
+
+const enduserIDKey = attribute.Key("enduser.id")
+
+// enduserID returns the enduser.id attribute according to Handler.EnduserID, none when it is omitted.
+func (h *Handler) enduserID(username string) []attribute.KeyValue {
+	switch h.EnduserID {
+	case EnduserIDOmit:
+		return nil
+	case EnduserIDHash:
+		sum := sha256.Sum256([]byte(username))
+		return []attribute.KeyValue{enduserIDKey.String(hex.EncodeToString(sum[:])[:16])}
+	default:
+		return []attribute.KeyValue{enduserIDKey.String(username)}
+	}
+}
+
+type claimsKey struct{}
+
+// AuthMiddleware validates the bearer token in a "Validate Token [Otel SDK]" span,
+// and responds 401 when the token is missing, invalid, expired or revoked.
+func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		serverSpan := trace.SpanFromContext(ctx)
+
+		_, span := serverSpan.TracerProvider().Tracer(instrumentationName).Start(ctx, "Validate Token [Otel SDK]")
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			span.SetStatus(codes.Error, "missing bearer token")
+			span.End()
+
+			writeUnauthorized(w, "missing bearer token")
+			return
+		}
+
+		claims, err := h.Tokens.Validate(token)
+		switch {
+		case errors.Is(err, authtoken.ErrExpired):
+			appmetrics.NewRecorder(h.ServiceName).RecordTokenExpired(ctx)
+		case err == nil:
+			appmetrics.NewRecorder(h.ServiceName).RecordTokenValidated(ctx)
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.End()
+
+			writeUnauthorized(w, err.Error())
+			return
+		}
+
+		enduser := h.enduserID(claims.Subject)
+		span.SetAttributes(enduser...)
+		serverSpan.SetAttributes(enduser...)
+		span.End()
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, claimsKey{}, claims)))
+	})
+}
+
+// writeUnauthorized responds 401 with the RFC 6750 challenge.
+func writeUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	writeJSON(w, http.StatusUnauthorized, map[string]string{"error": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("failed to write response", slog.Any("error", err))
+	}
+}
+
+// Me responds the user of the token, it must be behind the AuthMiddleware.
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(claimsKey{}).(authtoken.Claims)
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"username":   claims.Subject,
+		"expires_at": claims.Expiry().UTC().Format(time.RFC3339),
+	})
+}
+
+// Logout revokes the token, it must be behind the AuthMiddleware.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := ctx.Value(claimsKey{}).(authtoken.Claims)
+
+	h.Tokens.Revoke(claims)
+	appmetrics.NewRecorder(h.ServiceName).RecordTokenRevoked(ctx)
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Logout successful (from otel-sdk example)."})
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/buildinfo"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/loadgen"
+)
+
+const loadgenServiceName = "poc_otel_sdk_loadgen"
+
+// loadgenCommand runs the load scenario, prints a k6 like summary, and fails when a threshold is crossed.
+// The client spans and metrics are sent to OTEL_EXPORTER_OTLP_ENDPOINT when it is set.
+//
+//	app.bin loadgen -scenario scenarios/login.json -target http://localhost:8082
+func loadgenCommand(args []string) int {
+	flags := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+
+	var (
+		scenarioFile = flags.String("scenario", "scenarios/login.json", "scenario file")
+		target       = flags.String("target", "", "base URL overriding the target of the scenario, for example http://localhost:8082")
+		endpoint     = flags.String("endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTLP HTTP endpoint without scheme of the client spans and metrics, empty disables them")
+		insecure     = flags.Bool("insecure", true, "use http:// instead of https:// for the OTLP endpoint")
+		timeout      = flags.Duration("timeout", 30*time.Second, "timeout of one request")
+	)
+
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s loadgen [flags]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	scenario, err := loadgen.LoadScenario(*scenarioFile)
+	if err != nil {
+		slog.Error("invalid scenario", slog.String("file", *scenarioFile), slog.Any("error", err))
+		return 2
+	}
+
+	if *target != "" {
+		scenario.Target = *target
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	shutdown, err := initLoadgenTelemetry(ctx, *endpoint, *insecure)
+	if err != nil {
+		slog.Error("failed to initialize the load generator telemetry", slog.Any("error", err))
+		return 2
+	}
+	defer func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer shutdownCancel()
+
+		if _err := shutdown(shutdownCtx); _err != nil {
+			slog.Error("failed to flush the load generator telemetry", slog.Any("error", _err))
+		}
+	}()
+
+	runner := &loadgen.Runner{
+		Scenario:     scenario,
+		MetricPrefix: loadgenServiceName,
+		Timeout:      *timeout,
+	}
+
+	stats, err := runner.Run(ctx)
+	if err != nil {
+		slog.Error("failed to run the scenario", slog.Any("error", err))
+		return 2
+	}
+
+	stats.Write(os.Stdout)
+
+	if stats.Failed() > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// initLoadgenTelemetry sets the global providers. Without endpoint the spans are still sampled but not exported,
+// so the trace context is injected into the requests and the server spans can be found by trace id.
+func initLoadgenTelemetry(ctx context.Context, endpoint string, insecure bool) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(loadgenServiceName),
+		semconv.ServiceVersion(buildinfo.Get().Version),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	tracerProviderOpts := []otelSdkTrace.TracerProviderOption{otelSdkTrace.WithResource(res)}
+	meterProviderOpts := []otelSdkMetric.Option{otelSdkMetric.WithResource(res)}
+
+	if endpoint != "" {
+		traceOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+		metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(endpoint)}
+		if insecure {
+			traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+			metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+		}
+
+		traceExporter, traceExporterErr := otlptracehttp.New(ctx, traceOpts...)
+		if traceExporterErr != nil {
+			return nil, fmt.Errorf("create trace exporter: %w", traceExporterErr)
+		}
+
+		metricExporter, metricExporterErr := otlpmetrichttp.New(ctx, metricOpts...)
+		if metricExporterErr != nil {
+			return nil, fmt.Errorf("create metric exporter: %w", metricExporterErr)
+		}
+
+		tracerProviderOpts = append(tracerProviderOpts, otelSdkTrace.WithBatcher(traceExporter))
+		meterProviderOpts = append(meterProviderOpts,
+			otelSdkMetric.WithReader(otelSdkMetric.NewPeriodicReader(metricExporter, otelSdkMetric.WithInterval(10*time.Second))),
+		)
+	}
+
+	tracerProvider := otelSdkTrace.NewTracerProvider(tracerProviderOpts...)
+	meterProvider := otelSdkMetric.NewMeterProvider(meterProviderOpts...)
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+)
+
+// loginRequest is the body of /login.
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loginMaxBodyBytes is the limit of the /login body, the credentials are far smaller.
+const loginMaxBodyBytes = 4 << 10
+
+// decodeLogin validates the Content-Type, the size and the fields of the login body.
+// On error, it returns the failure reason and the HTTP status of the response.
+// A request without Content-Type is accepted, like a plain curl.
+func decodeLogin(w http.ResponseWriter, r *http.Request, user *loginRequest) (appmetrics.LoginFailureReason, int, error) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return appmetrics.LoginUnsupportedMediaType, http.StatusUnsupportedMediaType,
+				fmt.Errorf("content type must be application/json, got %q", contentType)
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, loginMaxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(user); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return appmetrics.LoginBodyTooLarge, http.StatusRequestEntityTooLarge,
+				fmt.Errorf("request body larger than %d bytes", maxBytesErr.Limit)
+		}
+		return appmetrics.LoginInvalidPayload, http.StatusBadRequest, fmt.Errorf("invalid request payload: %w", err)
+	}
+
+	if user.Username == "" || user.Password == "" {
+		return appmetrics.LoginMissingFields, http.StatusBadRequest, errors.New("username and password are required")
+	}
+
+	return "", 0, nil
+}
@@ -2,15 +2,19 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-logr/logr"
+
 	// Go-Chi Router and OpenTelemetry HTTP Middleware
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -19,17 +23,27 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
 
 	// OpenTelemetry Traces
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
 	otelTraceNoop "go.opentelemetry.io/otel/trace/noop"
 
+	// OpenTelemetry Logs
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	otelLogGlobal "go.opentelemetry.io/otel/log/global"
+	otelSdkLog "go.opentelemetry.io/otel/sdk/log"
+
 	// OpenTelemetry Metrics
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
@@ -40,12 +54,44 @@ import (
 	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
 
 	// Internal package
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/applog"
 	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/authtoken"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/buildinfo"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/chaos"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/exportqueue"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/failover"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/health"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/lockout"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/metriccatalog"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/otlpauth"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/otlpclient"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/otlpfile"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/ratelimit"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/recovery"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/reqrecord"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/resourcedetect"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/selftelemetry"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/tracebuffer"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/userstore"
 )
 
 const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/main.go"
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			os.Exit(replayCommand(os.Args[2:]))
+		case "replay-requests":
+			os.Exit(replayRequestsCommand(os.Args[2:]))
+		case "loadgen":
+			os.Exit(loadgenCommand(os.Args[2:]))
+		case "hash-password":
+			os.Exit(hashPasswordCommand(os.Args[2:]))
+		}
+	}
+
 	var (
 		Port = os.Getenv("PORT")
 
@@ -53,7 +99,7 @@ func main() {
 		// No need scheme "http://" or "https://" prefix.
 		OpenTemeletryHTTPEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
 
-		// OtlpTraceHTTPEnabled Disable the HTTP exporter (only expose /traces endpoint as traces)
+		// OtlpTraceHTTPEnabled Disable the HTTP exporter (only expose /debug/traces endpoint as traces)
 		OtlpTraceHTTPEnabled = os.Getenv("OTLP_TRACE_HTTP_ENABLED")
 
 		// OtlpMetricHTTPEnabled Disable the HTTP exporter (only expose /metrics Prometheus endpoint as metrics)
@@ -64,25 +110,319 @@ func main() {
 
 		// OtlpMetricsPath is the path for the metrics endpoint, by default it is "/v1/metrics"
 		OtlpMetricsPath = os.Getenv("OTLP_METRICS_PATH")
+
+		// OtlpExportQueueDir enables the on-disk export queue in front of the OTLP HTTP exporters,
+		// for example: "/var/lib/otel-sdk/queue". Each signal is spooled in its own sub-directory
+		// and replayed in order when the endpoint recovers, also after restart.
+		// Leave it empty to send directly using the OpenTelemetry HTTP exporters.
+		OtlpExportQueueDir = os.Getenv("OTLP_EXPORT_QUEUE_DIR")
+
+		// OtlpExportQueueMaxBytes is the size limit of each signal queue in bytes, by default it is 64 MiB.
+		// When the limit is reached, the oldest batches are dropped.
+		OtlpExportQueueMaxBytes = os.Getenv("OTLP_EXPORT_QUEUE_MAX_BYTES")
+
+		// OtlpLogHTTPEnabled Disable the HTTP exporter of the logs written with slog
+		OtlpLogHTTPEnabled = os.Getenv("OTLP_LOG_HTTP_ENABLED")
+
+		// OtlpLogsPath is the path for the logs endpoint, by default it is "/v1/logs"
+		OtlpLogsPath = os.Getenv("OTLP_LOGS_PATH")
+
+		// OtlpFileDir enables the OTLP file exporter, for example: "/var/log/otel-sdk".
+		// Traces, metrics and logs are written as OTLP JSON lines into "traces.jsonl", "metrics.jsonl" and "logs.jsonl".
+		// When the HTTP exporter is enabled, the file is the fallback of the HTTP exporter, otherwise it is the primary exporter.
+		OtlpFileDir = os.Getenv("OTLP_FILE_DIR")
+
+		// OtlpFileMaxBytes rotates the OTLP file when it reaches this size in bytes, by default it is 100 MiB.
+		OtlpFileMaxBytes = os.Getenv("OTLP_FILE_MAX_BYTES")
+
+		// OtlpFileMaxAge rotates the OTLP file when it is older than this duration, for example: "24h". Disabled by default.
+		OtlpFileMaxAge = os.Getenv("OTLP_FILE_MAX_AGE")
+
+		// OtlpFileMaxBackups is the number of rotated OTLP files to keep, by default it is 5.
+		OtlpFileMaxBackups = os.Getenv("OTLP_FILE_MAX_BACKUPS")
+
+		// OtlpFileGzip compresses the rotated OTLP files, by default it is true.
+		OtlpFileGzip = os.Getenv("OTLP_FILE_GZIP")
+
+		// OtlpFailoverThreshold is the number of consecutive OTLP export failures before falling back
+		// to the stdout exporter, by default it is 3.
+		OtlpFailoverThreshold = os.Getenv("OTLP_FAILOVER_THRESHOLD")
+
+		// OtlpFailoverProbeInterval is how long to wait before trying the OTLP exporter again after falling back,
+		// for example: "30s" (default).
+		OtlpFailoverProbeInterval = os.Getenv("OTLP_FAILOVER_PROBE_INTERVAL")
+
+		// OtlpInsecure sends the OTLP requests using "http://" instead of "https://".
+		// By default, it is true unless one of the TLS variables below is set.
+		OtlpInsecure = os.Getenv("OTLP_INSECURE")
+
+		// OtlpCAFile is the PEM bundle used to verify the OTLP endpoint certificate, by default the system pool is used.
+		OtlpCAFile = os.Getenv("OTLP_CA_FILE")
+
+		// OtlpClientCertFile and OtlpClientKeyFile are the PEM client certificate and key for mTLS.
+		OtlpClientCertFile = os.Getenv("OTLP_CLIENT_CERT_FILE")
+		OtlpClientKeyFile  = os.Getenv("OTLP_CLIENT_KEY_FILE")
+
+		// OtlpTLSServerName overrides the server name used to verify the OTLP endpoint certificate.
+		OtlpTLSServerName = os.Getenv("OTLP_TLS_SERVER_NAME")
+
+		// OtlpInsecureSkipVerify disables the OTLP endpoint certificate verification, only for testing.
+		OtlpInsecureSkipVerify = os.Getenv("OTLP_INSECURE_SKIP_VERIFY")
+
+		// OtlpHeaders are sent with every OTLP request, for example: "X-Scope-OrgID=tenant-1,X-Api-Key=secret".
+		OtlpHeaders = os.Getenv("OTLP_HEADERS")
+
+		// OtlpBearerTokenFile contains the token sent as "Authorization: Bearer <token>" with every OTLP request,
+		// for example: "/var/run/secrets/tokens/otlp". The file is read again when it changes.
+		OtlpBearerTokenFile = os.Getenv("OTLP_BEARER_TOKEN_FILE")
+
+		// ReadinessCheckExporters makes /readyz fail while the OTLP endpoint is not reachable, by default it is false.
+		// It only applies when at least one OTLP HTTP exporter is enabled.
+		ReadinessCheckExporters = os.Getenv("READINESS_CHECK_EXPORTERS")
+
+		// DebugTracesBufferSize is the number of recent spans kept in memory for /debug/traces, by default it is 1000.
+		DebugTracesBufferSize = os.Getenv("DEBUG_TRACES_BUFFER_SIZE")
+
+		// OtelResourceDetectors is the comma separated list of resource detectors: "k8s,container,host,process,service_instance".
+		// By default, all detectors are enabled, use "none" to disable them.
+		// OTEL_RESOURCE_ATTRIBUTES (for example "team=payments,region=eu") overrides the detected attributes.
+		OtelResourceDetectors = os.Getenv("OTEL_RESOURCE_DETECTORS")
+
+		// RequestRecordFile enables the recording of the incoming requests as JSON lines,
+		// for example: "/var/log/otel-sdk/requests.jsonl". Replay them with the "replay-requests" command.
+		RequestRecordFile = os.Getenv("REQUEST_RECORD_FILE")
+
+		// RequestRecordHeaders is the comma separated allow-list of recorded headers, by default "Accept,Content-Type,User-Agent".
+		RequestRecordHeaders = os.Getenv("REQUEST_RECORD_HEADERS")
+
+		// RequestRecordPassword is "mask" (default) to record passwords as "***",
+		// or "reference" to record "${password:<username>}" which the replay resolves from a credentials file.
+		RequestRecordPassword = os.Getenv("REQUEST_RECORD_PASSWORD")
+
+		// ChaosRules is a JSON array of fault injection rules, see pkg/chaos, for example:
+		// [{"path": "/login", "latency": {"rate": 0.2, "distribution": "uniform", "min": "100ms", "max": "2s"}, "error": {"rate": 0.05, "statuses": [500, 503]}}]
+		// Empty means no fault is injected.
+		ChaosRules = os.Getenv("CHAOS_RULES")
+
+		// ChaosAdminEnabled exposes "/debug/chaos" to read and replace the chaos rules at runtime, by default it is false.
+		ChaosAdminEnabled = os.Getenv("CHAOS_ADMIN_ENABLED")
+
+		// SessionTokenSecret signs the session tokens issued by /login, it must be at least 32 bytes.
+		// Empty generates a random secret, the tokens are then invalid after restart.
+		SessionTokenSecret = os.Getenv("SESSION_TOKEN_SECRET")
+
+		// SessionTokenTTL is the validity of the session tokens, for example: "1h". By default it is 15 minutes.
+		SessionTokenTTL = os.Getenv("SESSION_TOKEN_TTL")
+
+		// EnduserID is how the username is recorded as enduser.id on the spans:
+		// "plain" (default), "hash" (truncated SHA-256) or "omit".
+		EnduserID = os.Getenv("ENDUSER_ID")
+
+		// UserStoreFile is a JSON or YAML file of the users with their bcrypt or argon2id password hash, see pkg/userstore.
+		// Empty uses the demo users user1, user2 and user3. Generate a hash with: app.bin hash-password
+		UserStoreFile = os.Getenv("USER_STORE_FILE")
+
+		// UserStoreLatency delays every user lookup by a random duration in a range like "5ms-50ms", or a fixed one like "20ms".
+		// Empty means no delay.
+		UserStoreLatency = os.Getenv("USER_STORE_LATENCY")
+
+		// LockoutMaxFailures locks a username for LockoutDuration after this number of failed logins in LockoutWindow,
+		// the login is then rejected with 423 Locked. Empty or 0 disables it.
+		LockoutMaxFailures = os.Getenv("LOCKOUT_MAX_FAILURES")
+
+		// LockoutMaxClientIPFailures blocks a client IP for LockoutDuration after this number of failed logins in LockoutWindow,
+		// whatever the usernames are, the login is then rejected with 429 Too Many Requests. Empty or 0 disables it.
+		LockoutMaxClientIPFailures = os.Getenv("LOCKOUT_MAX_CLIENT_IP_FAILURES")
+
+		// LockoutWindow is the sliding window of the failed logins, for example: "10m". By default it is 5 minutes.
+		LockoutWindow = os.Getenv("LOCKOUT_WINDOW")
+
+		// LockoutDuration is how long a username is locked or a client IP is blocked, for example: "1h". By default it is 15 minutes.
+		LockoutDuration = os.Getenv("LOCKOUT_DURATION")
+
+		// LockoutMaxEntries is the number of usernames, and of client IPs, tracked in memory, the least recently failed is evicted.
+		// By default it is 10000.
+		LockoutMaxEntries = os.Getenv("LOCKOUT_MAX_ENTRIES")
+
+		// RateLimitGlobalRPS is the number of /login requests per second of all the clients, RateLimitGlobalBurst is the size
+		// of the token bucket (by default the rate rounded up). Empty or 0 disables the global limit.
+		RateLimitGlobalRPS   = os.Getenv("RATE_LIMIT_GLOBAL_RPS")
+		RateLimitGlobalBurst = os.Getenv("RATE_LIMIT_GLOBAL_BURST")
+
+		// RateLimitClientRPS is the number of /login requests per second of one client address, RateLimitClientBurst is the size
+		// of its token bucket (by default the rate rounded up). Empty or 0 disables the limit per client.
+		RateLimitClientRPS   = os.Getenv("RATE_LIMIT_CLIENT_RPS")
+		RateLimitClientBurst = os.Getenv("RATE_LIMIT_CLIENT_BURST")
+
+		// RateLimitMaxClients is the number of client buckets kept in memory, the least recently used is evicted. By default it is 10000.
+		RateLimitMaxClients = os.Getenv("RATE_LIMIT_MAX_CLIENTS")
+
+		// RateLimitTrustedProxies is a comma separated list of CIDR whose X-Forwarded-For header gives the client address,
+		// for example: "10.0.0.0/8,127.0.0.1". Empty means the remote address is the client.
+		RateLimitTrustedProxies = os.Getenv("RATE_LIMIT_TRUSTED_PROXIES")
 	)
 
 	const (
-		teamName       = "go_sandbox"
-		serviceName    = "poc_otel_sdk"
-		serviceVersion = "0.1.0"
-		serviceEnv     = "dev"
+		teamName    = "go_sandbox"
+		serviceName = "poc_otel_sdk"
+		serviceEnv  = "dev"
 	)
 
+	// serviceVersion is injected at build time, see the Dockerfile.
+	serviceVersion := buildinfo.Get().Version
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	otelSdkResources := resource.NewWithAttributes(
-		semconv.SchemaURL,
+	resourceDetectors, resourceDetectorsErr := resourcedetect.ParseDetectors(OtelResourceDetectors)
+	if resourceDetectorsErr != nil {
+		slog.WarnContext(ctx, "failed to parse OtelResourceDetectors", slog.Any("error", resourceDetectorsErr))
+		resourceDetectors = resourcedetect.AllDetectors
+	}
+
+	otelSdkResources, otelSdkResourcesErr := resourcedetect.New(ctx, resourceDetectors,
 		semconv.ServiceName(serviceName),
 		semconv.ServiceVersion(serviceVersion),
 		semconv.DeploymentEnvironmentName(serviceEnv),
 		attribute.String("team", teamName),
 	)
+	if otelSdkResourcesErr != nil {
+		slog.WarnContext(ctx, "failed to detect some resource attributes", slog.Any("error", otelSdkResourcesErr))
+	}
+
+	exportQueue := exportQueueConfig{
+		Dir:          strings.TrimSpace(OtlpExportQueueDir),
+		MetricPrefix: serviceName,
+	}
+
+	if OtlpExportQueueMaxBytes != "" {
+		maxBytes, maxBytesErr := strconv.ParseInt(OtlpExportQueueMaxBytes, 10, 64)
+		if maxBytesErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpExportQueueMaxBytes", slog.Any("error", maxBytesErr))
+		} else {
+			exportQueue.MaxBytes = maxBytes
+		}
+	}
+
+	var failoverCfg failover.Config
+	if OtlpFailoverThreshold != "" {
+		threshold, thresholdErr := strconv.Atoi(OtlpFailoverThreshold)
+		if thresholdErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpFailoverThreshold", slog.Any("error", thresholdErr))
+		} else {
+			failoverCfg.FailureThreshold = threshold
+		}
+	}
+
+	if OtlpFailoverProbeInterval != "" {
+		probeInterval, probeIntervalErr := time.ParseDuration(OtlpFailoverProbeInterval)
+		if probeIntervalErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpFailoverProbeInterval", slog.Any("error", probeIntervalErr))
+		} else {
+			failoverCfg.ProbeInterval = probeInterval
+		}
+	}
+
+	// Route the OpenTelemetry SDK errors and warnings to stderr only: through the OpenTelemetry logs,
+	// a failing log exporter would report its own errors. The SDK writes its warnings with V(1), which is slog level -1.
+	sdkLogHandler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.Level(-1)})
+	otel.SetLogger(logr.FromSlogHandler(sdkLogHandler))
+	otel.SetErrorHandler(selftelemetry.NewErrorHandler(slog.New(sdkLogHandler), time.Minute, 10))
+
+	// Continue the traces of the callers, for example the replayed requests, from the W3C traceparent header.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if _err := selftelemetry.RegisterMetrics(serviceName); _err != nil {
+		slog.ErrorContext(ctx, "failed to register telemetry self-observability metrics", slog.Any("error", _err))
+	}
+
+	if _err := buildinfo.RegisterMetrics(serviceName); _err != nil {
+		slog.ErrorContext(ctx, "failed to register build info metric", slog.Any("error", _err))
+	}
+
+	if _err := failover.RegisterMetrics(serviceName); _err != nil {
+		slog.ErrorContext(ctx, "failed to register exporter failover metrics", slog.Any("error", _err))
+	}
+
+	otlpAuth := otlpauth.Config{
+		Insecure:        OtlpCAFile == "" && OtlpClientCertFile == "" && OtlpTLSServerName == "",
+		CAFile:          strings.TrimSpace(OtlpCAFile),
+		CertFile:        strings.TrimSpace(OtlpClientCertFile),
+		KeyFile:         strings.TrimSpace(OtlpClientKeyFile),
+		ServerName:      strings.TrimSpace(OtlpTLSServerName),
+		BearerTokenFile: strings.TrimSpace(OtlpBearerTokenFile),
+	}
+
+	if OtlpInsecure != "" {
+		insecure, insecureErr := strconv.ParseBool(OtlpInsecure)
+		if insecureErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpInsecure", slog.Any("error", insecureErr))
+		} else {
+			otlpAuth.Insecure = insecure
+		}
+	}
+
+	if OtlpInsecureSkipVerify != "" {
+		skipVerify, skipVerifyErr := strconv.ParseBool(OtlpInsecureSkipVerify)
+		if skipVerifyErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpInsecureSkipVerify", slog.Any("error", skipVerifyErr))
+		} else {
+			otlpAuth.InsecureSkipVerify = skipVerify
+		}
+	}
+
+	if OtlpHeaders != "" {
+		headers, headersErr := otlpauth.ParseHeaders(OtlpHeaders)
+		if headersErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpHeaders", slog.Any("error", headersErr))
+		} else {
+			otlpAuth.Headers = headers
+		}
+	}
+
+	exportQueue.Auth = otlpAuth
+
+	fileExporter := fileExporterConfig{
+		Dir:        strings.TrimSpace(OtlpFileDir),
+		MaxBackups: 5,
+		Gzip:       true,
+	}
+
+	if OtlpFileMaxBytes != "" {
+		maxBytes, maxBytesErr := strconv.ParseInt(OtlpFileMaxBytes, 10, 64)
+		if maxBytesErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpFileMaxBytes", slog.Any("error", maxBytesErr))
+		} else {
+			fileExporter.MaxBytes = maxBytes
+		}
+	}
+
+	if OtlpFileMaxAge != "" {
+		maxAge, maxAgeErr := time.ParseDuration(OtlpFileMaxAge)
+		if maxAgeErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpFileMaxAge", slog.Any("error", maxAgeErr))
+		} else {
+			fileExporter.MaxAge = maxAge
+		}
+	}
+
+	if OtlpFileMaxBackups != "" {
+		maxBackups, maxBackupsErr := strconv.Atoi(OtlpFileMaxBackups)
+		if maxBackupsErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpFileMaxBackups", slog.Any("error", maxBackupsErr))
+		} else {
+			fileExporter.MaxBackups = maxBackups
+		}
+	}
+
+	if OtlpFileGzip != "" {
+		gzipEnabled, gzipEnabledErr := strconv.ParseBool(OtlpFileGzip)
+		if gzipEnabledErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpFileGzip", slog.Any("error", gzipEnabledErr))
+		} else {
+			fileExporter.Gzip = gzipEnabled
+		}
+	}
 
 	otelTraceEnabled, otelTraceEnabledErr := strconv.ParseBool(OtlpTraceHTTPEnabled)
 	if otelTraceEnabledErr != nil {
@@ -94,7 +434,18 @@ func main() {
 		OtlpTracesPath = "/v1/traces"
 	}
 
-	tracerCloser := initTracer(ctx, otelSdkResources, otelTraceEnabled, OpenTemeletryHTTPEndpoint, OtlpTracesPath)
+	var traceBufferSize int
+	if DebugTracesBufferSize != "" {
+		bufferSize, bufferSizeErr := strconv.Atoi(DebugTracesBufferSize)
+		if bufferSizeErr != nil {
+			slog.WarnContext(ctx, "failed to parse DebugTracesBufferSize", slog.Any("error", bufferSizeErr))
+		}
+		traceBufferSize = bufferSize
+	}
+
+	traceBuffer := tracebuffer.New(traceBufferSize)
+
+	tracerCloser := initTracer(ctx, otelSdkResources, otelTraceEnabled, OpenTemeletryHTTPEndpoint, OtlpTracesPath, otlpAuth, exportQueue, failoverCfg, fileExporter, traceBuffer)
 	defer func() {
 		if _err := tracerCloser(ctx); _err != nil {
 			slog.ErrorContext(ctx, "shutdown otel tracer error", slog.Any("error", _err))
@@ -111,15 +462,135 @@ func main() {
 		OtlpMetricsPath = "/v1/metrics"
 	}
 
-	meterCloser := initMeter(ctx, otelSdkResources, otelMetricEnabled, OpenTemeletryHTTPEndpoint, OtlpMetricsPath)
+	metricCatalog := metriccatalog.New(serviceName)
+	meterCloser := initMeter(ctx, otelSdkResources, otelMetricEnabled, OpenTemeletryHTTPEndpoint, OtlpMetricsPath, otlpAuth, exportQueue, failoverCfg, fileExporter, metricCatalog)
 	defer func() {
 		if _err := meterCloser(ctx); _err != nil {
 			slog.ErrorContext(ctx, "shutdown otel meter error", slog.Any("error", _err))
 		}
 	}()
 
+	otelLogEnabled, otelLogEnabledErr := strconv.ParseBool(OtlpLogHTTPEnabled)
+	if otelLogEnabledErr != nil {
+		slog.WarnContext(ctx, "failed to parse OtlpLogHTTPEnabled", slog.Any("error", otelLogEnabledErr))
+		otelLogEnabled = false
+	}
+
+	if OtlpLogsPath == "" {
+		OtlpLogsPath = "/v1/logs"
+	}
+
+	loggerCloser := initLogger(ctx, otelSdkResources, otelLogEnabled, OpenTemeletryHTTPEndpoint, OtlpLogsPath, otlpAuth, exportQueue, failoverCfg, fileExporter)
+	defer func() {
+		if _err := loggerCloser(ctx); _err != nil {
+			slog.ErrorContext(ctx, "shutdown otel logger error", slog.Any("error", _err))
+		}
+	}()
+
+	tokenSecret := []byte(SessionTokenSecret)
+	if SessionTokenSecret == "" {
+		slog.WarnContext(ctx, "SessionTokenSecret is empty, using a random secret, the session tokens are invalid after restart")
+		tokenSecret = authtoken.RandomSecret()
+	}
+
+	var tokenTTL time.Duration
+	if SessionTokenTTL != "" {
+		var tokenTTLErr error
+		tokenTTL, tokenTTLErr = time.ParseDuration(SessionTokenTTL)
+		if tokenTTLErr != nil {
+			slog.WarnContext(ctx, "failed to parse SessionTokenTTL", slog.Any("error", tokenTTLErr))
+		}
+	}
+
+	tokens, tokensErr := authtoken.NewManager(tokenSecret, tokenTTL, serviceName)
+	if tokensErr != nil {
+		panic(fmt.Errorf("invalid SessionTokenSecret: %w", tokensErr))
+	}
+
+	switch EnduserID {
+	case "", EnduserIDPlain, EnduserIDHash, EnduserIDOmit:
+	default:
+		slog.WarnContext(ctx, "unknown EnduserID, fallback to omit", slog.String("value", EnduserID))
+		EnduserID = EnduserIDOmit
+	}
+
+	var userStore userstore.UserStore = userstore.Default()
+	if UserStoreFile != "" {
+		fileStore, fileStoreErr := userstore.LoadFile(UserStoreFile)
+		if fileStoreErr != nil {
+			panic(fmt.Errorf("invalid UserStoreFile: %w", fileStoreErr))
+		}
+		userStore = fileStore
+	}
+
+	if UserStoreLatency != "" {
+		minLatency, maxLatency, latencyErr := parseLatencyRange(UserStoreLatency)
+		if latencyErr != nil {
+			slog.WarnContext(ctx, "failed to parse UserStoreLatency", slog.Any("error", latencyErr))
+		} else {
+			userStore = &userstore.LatencyStore{Store: userStore, Min: minLatency, Max: maxLatency}
+		}
+	}
+
+	var lockoutCfg lockout.Config
+	if LockoutMaxFailures != "" {
+		var lockoutErr error
+		lockoutCfg.MaxUsernameFailures, lockoutErr = strconv.Atoi(LockoutMaxFailures)
+		if lockoutErr != nil {
+			slog.WarnContext(ctx, "failed to parse LockoutMaxFailures", slog.Any("error", lockoutErr))
+		}
+	}
+
+	if LockoutMaxClientIPFailures != "" {
+		var lockoutErr error
+		lockoutCfg.MaxClientIPFailures, lockoutErr = strconv.Atoi(LockoutMaxClientIPFailures)
+		if lockoutErr != nil {
+			slog.WarnContext(ctx, "failed to parse LockoutMaxClientIPFailures", slog.Any("error", lockoutErr))
+		}
+	}
+
+	if LockoutWindow != "" {
+		var lockoutErr error
+		lockoutCfg.Window, lockoutErr = time.ParseDuration(LockoutWindow)
+		if lockoutErr != nil {
+			slog.WarnContext(ctx, "failed to parse LockoutWindow", slog.Any("error", lockoutErr))
+		}
+	}
+
+	if LockoutDuration != "" {
+		var lockoutErr error
+		lockoutCfg.Duration, lockoutErr = time.ParseDuration(LockoutDuration)
+		if lockoutErr != nil {
+			slog.WarnContext(ctx, "failed to parse LockoutDuration", slog.Any("error", lockoutErr))
+		}
+	}
+
+	if LockoutMaxEntries != "" {
+		var lockoutErr error
+		lockoutCfg.MaxEntries, lockoutErr = strconv.Atoi(LockoutMaxEntries)
+		if lockoutErr != nil {
+			slog.WarnContext(ctx, "failed to parse LockoutMaxEntries", slog.Any("error", lockoutErr))
+		}
+	}
+
 	handler := &Handler{
 		ServiceName: serviceName,
+		Tokens:      tokens,
+		EnduserID:   EnduserID,
+		Users:       userstore.NewAuthenticator(userStore, serviceName),
+		Lockout:     lockout.NewTracker(lockoutCfg, serviceName),
+	}
+
+	readiness := &health.Readiness{}
+	if ReadinessCheckExporters != "" {
+		checkExporters, checkExportersErr := strconv.ParseBool(ReadinessCheckExporters)
+		if checkExportersErr != nil {
+			slog.WarnContext(ctx, "failed to parse ReadinessCheckExporters", slog.Any("error", checkExportersErr))
+		}
+
+		if checkExporters && (otelTraceEnabled || otelMetricEnabled || otelLogEnabled) {
+			readiness.Add("otlp", health.DialCheck(strings.TrimSpace(OpenTemeletryHTTPEndpoint)))
+		}
 	}
 
 	router := chi.NewRouter()
@@ -134,15 +605,159 @@ func main() {
 			return fmt.Sprintf("%s %s", r.Method, r.URL.Path)
 		}),
 		otelhttp.WithMeterProvider(otel.GetMeterProvider()),
+		// Do not trace the probes of the orchestrator, nor the debug pages which would fill /debug/traces.
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/healthz" && r.URL.Path != "/readyz" && !strings.HasPrefix(r.URL.Path, "/debug/")
+		}),
 	))
 	router.Use(MetricsMiddleware(serviceName))
 
+	if RequestRecordFile != "" {
+		requestRecordWriter, requestRecordErr := otlpfile.NewRotatingWriter(otlpfile.RotateConfig{Path: RequestRecordFile})
+		if requestRecordErr != nil {
+			slog.ErrorContext(ctx, "failed to open request record file", slog.Any("error", requestRecordErr))
+		} else {
+			defer func() {
+				_ = requestRecordWriter.Close()
+			}()
+
+			var recordHeaders []string
+			for _, h := range strings.Split(RequestRecordHeaders, ",") {
+				if h = strings.TrimSpace(h); h != "" {
+					recordHeaders = append(recordHeaders, h)
+				}
+			}
+
+			recorder := reqrecord.NewRecorder(requestRecordWriter, reqrecord.Config{
+				Headers:  recordHeaders,
+				Password: RequestRecordPassword,
+				// Only the application traffic, not the probes, the scrapes nor the debug pages.
+				Skip: func(r *http.Request) bool {
+					return r.URL.Path == "/healthz" || r.URL.Path == "/readyz" || r.URL.Path == "/metrics" ||
+						strings.HasPrefix(r.URL.Path, "/debug/") || strings.HasPrefix(r.URL.Path, "/health/")
+				},
+			})
+			router.Use(recorder.Middleware)
+			slog.InfoContext(ctx, "recording requests", slog.String("file", RequestRecordFile))
+		}
+	}
+
+	// Recover the panics inside the tracing, metrics and recording middlewares, so they see the 500 response.
+	router.Use(recovery.Middleware(serviceName))
+
+	var chaosRules []chaos.Rule
+	if ChaosRules != "" {
+		var chaosRulesErr error
+		chaosRules, chaosRulesErr = chaos.ParseRules([]byte(ChaosRules))
+		if chaosRulesErr != nil {
+			slog.WarnContext(ctx, "failed to parse ChaosRules", slog.Any("error", chaosRulesErr))
+		}
+	}
+
+	chaosAdmin, chaosAdminErr := strconv.ParseBool(ChaosAdminEnabled)
+	if ChaosAdminEnabled != "" && chaosAdminErr != nil {
+		slog.WarnContext(ctx, "failed to parse ChaosAdminEnabled", slog.Any("error", chaosAdminErr))
+	}
+
+	// The faults are injected after the tracing and metrics middlewares, so they show up in the spans and the RED metrics.
+	chaosInjector := chaos.New(serviceName, chaosRules)
+	chaosInjector.Skip = func(r *http.Request) bool {
+		return r.URL.Path == "/healthz" || r.URL.Path == "/readyz" || r.URL.Path == "/metrics" ||
+			strings.HasPrefix(r.URL.Path, "/debug/") || strings.HasPrefix(r.URL.Path, "/health/")
+	}
+	router.Use(chaosInjector.Middleware)
+
+	var rateLimitCfg ratelimit.Config
+	if RateLimitGlobalRPS != "" {
+		var rateLimitErr error
+		rateLimitCfg.GlobalRate, rateLimitErr = strconv.ParseFloat(RateLimitGlobalRPS, 64)
+		if rateLimitErr != nil {
+			slog.WarnContext(ctx, "failed to parse RateLimitGlobalRPS", slog.Any("error", rateLimitErr))
+		}
+	}
+
+	if RateLimitGlobalBurst != "" {
+		var rateLimitErr error
+		rateLimitCfg.GlobalBurst, rateLimitErr = strconv.Atoi(RateLimitGlobalBurst)
+		if rateLimitErr != nil {
+			slog.WarnContext(ctx, "failed to parse RateLimitGlobalBurst", slog.Any("error", rateLimitErr))
+		}
+	}
+
+	if RateLimitClientRPS != "" {
+		var rateLimitErr error
+		rateLimitCfg.ClientRate, rateLimitErr = strconv.ParseFloat(RateLimitClientRPS, 64)
+		if rateLimitErr != nil {
+			slog.WarnContext(ctx, "failed to parse RateLimitClientRPS", slog.Any("error", rateLimitErr))
+		}
+	}
+
+	if RateLimitClientBurst != "" {
+		var rateLimitErr error
+		rateLimitCfg.ClientBurst, rateLimitErr = strconv.Atoi(RateLimitClientBurst)
+		if rateLimitErr != nil {
+			slog.WarnContext(ctx, "failed to parse RateLimitClientBurst", slog.Any("error", rateLimitErr))
+		}
+	}
+
+	if RateLimitMaxClients != "" {
+		var rateLimitErr error
+		rateLimitCfg.MaxClients, rateLimitErr = strconv.Atoi(RateLimitMaxClients)
+		if rateLimitErr != nil {
+			slog.WarnContext(ctx, "failed to parse RateLimitMaxClients", slog.Any("error", rateLimitErr))
+		}
+	}
+
+	if RateLimitTrustedProxies != "" {
+		var proxiesErr error
+		rateLimitCfg.TrustedProxies, proxiesErr = ratelimit.ParsePrefixes(RateLimitTrustedProxies)
+		if proxiesErr != nil {
+			slog.WarnContext(ctx, "failed to parse RateLimitTrustedProxies", slog.Any("error", proxiesErr))
+		}
+	}
+
+	// Only /login is limited, the limiter decision is recorded on its server span.
+	rateLimiter := ratelimit.New(rateLimitCfg, serviceName)
+	rateLimiter.OnReject = func(r *http.Request, _ ratelimit.Decision) {
+		ctx := r.Context()
+		appmetrics.NewRecorder(serviceName).RecordLoginFailure(ctx, appmetrics.LoginRateLimited)
+		trace.SpanFromContext(ctx).SetAttributes(appmetrics.FailureReason(appmetrics.LoginRateLimited))
+	}
+
 	router.Get("/", handler.Homepage)
-	router.Post("/login", handler.Login)
+	router.With(rateLimiter.Middleware).Post("/login", handler.Login)
+
+	router.Group(func(r chi.Router) {
+		r.Use(handler.AuthMiddleware)
+		r.Get("/me", handler.Me)
+		r.Post("/logout", handler.Logout)
+	})
 
 	// Expose metrics at /metrics
 	router.Handle("/metrics", promhttp.Handler())
 
+	// Expose which exporter (OTLP or the stdout fallback) currently receives the telemetry.
+	router.Handle("/health/exporters", failover.HealthHandler())
+
+	router.Handle("/version", buildinfo.Handler())
+
+	router.Handle("/healthz", health.LivenessHandler())
+	router.Handle("/readyz", readiness.Handler())
+
+	// Expose the resource, the exporters and the last export status of every signal.
+	router.Handle("/debug/telemetry", selftelemetry.StatusHandler(otelSdkResources))
+
+	// Browse the recent spans without any tracing backend, also when the trace HTTP exporter is disabled.
+	router.Get("/debug/traces", traceBuffer.ListHandler)
+	router.Get("/debug/traces/{traceID}", traceBuffer.TraceHandler)
+
+	// List the instruments with the series names in Prometheus, Grafana Mimir, Datadog and DogStatsD.
+	router.Get("/debug/metrics/catalog", metricCatalog.Handler)
+
+	if chaosAdmin {
+		router.HandleFunc("/debug/chaos", chaosInjector.AdminHandler)
+	}
+
 	server := &http.Server{
 		Addr:    Port,
 		Handler: router,
@@ -160,6 +775,11 @@ func initMeter(
 	otelHTTPMetricEnabled bool,
 	otelHTTPEndpoint string,
 	otelHTTPPath string,
+	otlpAuth otlpauth.Config,
+	exportQueue exportQueueConfig,
+	failoverCfg failover.Config,
+	fileExporter fileExporterConfig,
+	metricCatalog *metriccatalog.Catalog,
 ) func(ctx context.Context) error {
 
 	metricExporterStdout, metricExporterStdoutErr := stdoutmetric.New()
@@ -174,23 +794,50 @@ func initMeter(
 	}
 
 	var metricExporter = metricExporterStdout
-	if otelHTTPMetricEnabled {
+	if otelHTTPMetricEnabled && exportQueue.Enabled() {
+		slog.InfoContext(ctx, "OpenTelemetry metric HTTP Exporter enabled with on-disk export queue")
+
+		queue, queueErr := exportQueue.Open("metrics", otelHTTPEndpoint, otelHTTPPath)
+		if queueErr != nil {
+			slog.WarnContext(ctx, "failed to open the metric export queue", slog.Any("error", queueErr))
+			slog.WarnContext(ctx, "fallback using stdout metric exporter")
+		} else {
+			metricExporter = exportqueue.NewMetricExporter(queue)
+			slog.WarnContext(ctx, "using OpenTelemetry export queue", slog.String("endpoint", otelHTTPEndpoint))
+		}
+	} else if otelHTTPMetricEnabled {
 		slog.InfoContext(ctx, "OpenTelemetry metric HTTP Exporter enabled")
 		var metricExporterErr error
 
 		otelHTTPEndpoint = strings.TrimSpace(otelHTTPEndpoint)
-		metricExporter, metricExporterErr = otlpmetrichttp.New(ctx,
-			otlpmetrichttp.WithInsecure(),
-			otlpmetrichttp.WithEndpoint(otelHTTPEndpoint),
-			otlpmetrichttp.WithURLPath(otelHTTPPath),
-			otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression),
-			otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{
-				Enabled:         true,
-				InitialInterval: 5 * time.Second,
-				MaxInterval:     15 * time.Second,
-				MaxElapsedTime:  3 * time.Minute,
-			}),
-		)
+		tlsCfg, tlsCfgErr := otlpAuth.TLSConfig()
+		metricExporterErr = tlsCfgErr
+		if tlsCfgErr == nil {
+			// The exporter is built again with the new headers when the bearer token file is rotated.
+			metricExporter, metricExporterErr = otlpauth.NewMetricExporter(otlpAuth.HeaderFunc(), func(headers map[string]string) (otelSdkMetric.Exporter, error) {
+				opts := []otlpmetrichttp.Option{
+					otlpmetrichttp.WithEndpoint(otelHTTPEndpoint),
+					otlpmetrichttp.WithURLPath(otelHTTPPath),
+					otlpmetrichttp.WithHeaders(headers),
+					otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression),
+					// Retry shortly, the circuit breaker of the failover chain decides when the collector is down.
+					otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{
+						Enabled:         true,
+						InitialInterval: 500 * time.Millisecond,
+						MaxInterval:     1 * time.Second,
+						MaxElapsedTime:  2 * time.Second,
+					}),
+				}
+
+				if tlsCfg == nil {
+					opts = append(opts, otlpmetrichttp.WithInsecure())
+				} else {
+					opts = append(opts, otlpmetrichttp.WithTLSClientConfig(tlsCfg))
+				}
+
+				return otlpmetrichttp.New(context.WithoutCancel(ctx), opts...)
+			})
+		}
 
 		if metricExporterErr != nil {
 			slog.WarnContext(ctx, "failed to create the OpenTelemetry metric HTTP exporter", slog.Any("error", metricExporterErr))
@@ -201,6 +848,22 @@ func initMeter(
 		}
 	}
 
+	// Fail over from OTLP to the OTLP file and then to the stdout exporter, when an exporter keeps failing at runtime.
+	var metricTargets []failover.Target[otelSdkMetric.Exporter]
+	if metricExporter != nil && metricExporter != metricExporterStdout {
+		metricTargets = append(metricTargets, failover.Target[otelSdkMetric.Exporter]{Name: "otlp", Exporter: metricExporter})
+	}
+
+	if fileExporter.Enabled() {
+		writer, writerErr := fileExporter.Open("metrics")
+		if writerErr != nil {
+			slog.WarnContext(ctx, "failed to open the OpenTelemetry metric file", slog.Any("error", writerErr))
+		} else {
+			slog.InfoContext(ctx, "OpenTelemetry metric file exporter enabled", slog.String("dir", fileExporter.Dir))
+			metricTargets = append(metricTargets, failover.Target[otelSdkMetric.Exporter]{Name: "file", Exporter: otlpfile.NewMetricExporter(writer)})
+		}
+	}
+
 	if metricExporter == nil {
 		slog.ErrorContext(ctx, "cannot prepare OpenTelemetry Exporter because it is nil")
 		otel.SetMeterProvider(otelMetricNoop.NewMeterProvider())
@@ -210,6 +873,28 @@ func initMeter(
 	}
 
 	// metricExporter must not nil here
+	if len(metricTargets) > 0 {
+		metricTargets = append(metricTargets, failover.Target[otelSdkMetric.Exporter]{Name: "stdout", Exporter: metricExporterStdout})
+	} else {
+		metricTargets = []failover.Target[otelSdkMetric.Exporter]{{Name: "stdout", Exporter: metricExporter}}
+	}
+
+	// Record the export status of every exporter, the fallbacks of the chain included.
+	for i, target := range metricTargets {
+		metricTargets[i].Exporter = selftelemetry.NewMetricExporter(target.Name, target.Exporter)
+	}
+
+	metricPipeline := selftelemetry.Pipeline{
+		Signal:    "metrics",
+		Exporters: targetNames(metricTargets),
+		Readers:   []string{"periodic(interval=3s)"},
+	}
+
+	metricExporter = metricTargets[0].Exporter
+	if len(metricTargets) > 1 {
+		failoverCfg.Signal = "metrics"
+		metricExporter = failover.NewMetricExporter(failoverCfg, metricTargets[0], metricTargets[1:]...)
+	}
 	meterProviderOpts := []otelSdkMetric.Option{
 		otelSdkMetric.WithResource(otelResources),
 		otelSdkMetric.WithReader(
@@ -219,6 +904,8 @@ func initMeter(
 				otelSdkMetric.WithTimeout(1*time.Minute),
 			),
 		),
+		// The catalog reads the attribute keys of the recorded values.
+		otelSdkMetric.WithReader(metricCatalog.Reader()),
 	}
 
 	// By default, add prometheus exporter to the meter provider.
@@ -229,11 +916,18 @@ func initMeter(
 	} else {
 		slog.InfoContext(ctx, "Prometheus exporter enabled")
 		meterProviderOpts = append(meterProviderOpts, otelSdkMetric.WithReader(prometheusExporter))
+		metricPipeline.Readers = append(metricPipeline.Readers, "prometheus")
 	}
 
+	selftelemetry.RegisterPipeline(metricPipeline)
+	metricCatalog.SetBackends(metriccatalog.Backends{
+		Prometheus: prometheusExporterErr == nil,
+		OTLP:       len(metricTargets) > 0 && metricTargets[0].Name == "otlp",
+	})
+
 	meterProvider := otelSdkMetric.NewMeterProvider(meterProviderOpts...)
 	if meterProvider != nil {
-		otel.SetMeterProvider(meterProvider)
+		otel.SetMeterProvider(metricCatalog.MeterProvider(meterProvider))
 
 		return func(ctx context.Context) error {
 			var cumulativeErr error
@@ -267,27 +961,56 @@ func initTracer(
 	otelHTTPTraceEnabled bool,
 	otelHTTPEndpoint string,
 	otelHTTPPath string,
+	otlpAuth otlpauth.Config,
+	exportQueue exportQueueConfig,
+	failoverCfg failover.Config,
+	fileExporter fileExporterConfig,
+	traceBuffer *tracebuffer.Buffer,
 ) func(ctx context.Context) error {
 	var tracerExporter otelSdkTrace.SpanExporter = tracetest.NewNoopExporter()
 	var tracerErr error
 
+	var batchSpans bool
+
 	otelHTTPEndpoint = strings.TrimSpace(otelHTTPEndpoint)
-	if otelHTTPTraceEnabled {
-		tracerExporter, tracerErr = otlptrace.New(
-			ctx,
-			otlptracehttp.NewClient(
-				otlptracehttp.WithInsecure(),
-				otlptracehttp.WithEndpoint(otelHTTPEndpoint),
-				otlptracehttp.WithURLPath(otelHTTPPath),
-				otlptracehttp.WithCompression(otlptracehttp.GzipCompression),
-				otlptracehttp.WithRetry(otlptracehttp.RetryConfig{
-					Enabled:         true,
-					InitialInterval: 5 * time.Second,
-					MaxInterval:     15 * time.Second,
-					MaxElapsedTime:  3 * time.Minute,
-				}),
-			),
-		)
+	if otelHTTPTraceEnabled && exportQueue.Enabled() {
+		var queue *exportqueue.Queue
+		queue, tracerErr = exportQueue.Open("traces", otelHTTPEndpoint, otelHTTPPath)
+		if tracerErr == nil {
+			tracerExporter, tracerErr = otlptrace.New(ctx, exportqueue.NewTraceClient(queue))
+
+			// Spans are persisted on disk by the queue, so batch them instead of writing one file per span.
+			batchSpans = true
+		}
+	} else if otelHTTPTraceEnabled {
+		var tlsCfg *tls.Config
+		tlsCfg, tracerErr = otlpAuth.TLSConfig()
+		if tracerErr == nil {
+			// The exporter is built again with the new headers when the bearer token file is rotated.
+			tracerExporter, tracerErr = otlpauth.NewSpanExporter(otlpAuth.HeaderFunc(), func(headers map[string]string) (otelSdkTrace.SpanExporter, error) {
+				opts := []otlptracehttp.Option{
+					otlptracehttp.WithEndpoint(otelHTTPEndpoint),
+					otlptracehttp.WithURLPath(otelHTTPPath),
+					otlptracehttp.WithHeaders(headers),
+					otlptracehttp.WithCompression(otlptracehttp.GzipCompression),
+					// Retry shortly, the circuit breaker of the failover chain decides when the collector is down.
+					otlptracehttp.WithRetry(otlptracehttp.RetryConfig{
+						Enabled:         true,
+						InitialInterval: 500 * time.Millisecond,
+						MaxInterval:     1 * time.Second,
+						MaxElapsedTime:  2 * time.Second,
+					}),
+				}
+
+				if tlsCfg == nil {
+					opts = append(opts, otlptracehttp.WithInsecure())
+				} else {
+					opts = append(opts, otlptracehttp.WithTLSClientConfig(tlsCfg))
+				}
+
+				return otlptrace.New(context.WithoutCancel(ctx), otlptracehttp.NewClient(opts...))
+			})
+		}
 	} else {
 		slog.WarnContext(ctx, "OpenTelemetry trace HTTP Exporter disabled")
 	}
@@ -300,11 +1023,77 @@ func initTracer(
 		}
 	}
 
+	// Fail over from OTLP to the OTLP file and then to the stdout exporter, when an exporter keeps failing at runtime.
+	var tracerTargets []failover.Target[otelSdkTrace.SpanExporter]
+	if otelHTTPTraceEnabled {
+		tracerTargets = append(tracerTargets, failover.Target[otelSdkTrace.SpanExporter]{Name: "otlp", Exporter: tracerExporter})
+	}
+
+	if fileExporter.Enabled() {
+		writer, writerErr := fileExporter.Open("traces")
+		if writerErr != nil {
+			slog.WarnContext(ctx, "failed to open the OpenTelemetry trace file", slog.Any("error", writerErr))
+		} else if tracerExporterFile, tracerExporterFileErr := otlptrace.New(ctx, otlpfile.NewTraceClient(writer)); tracerExporterFileErr != nil {
+			slog.ErrorContext(ctx, "failed to create the OpenTelemetry trace file exporter", slog.Any("error", tracerExporterFileErr))
+			_ = writer.Close()
+		} else {
+			slog.InfoContext(ctx, "OpenTelemetry trace file exporter enabled", slog.String("dir", fileExporter.Dir))
+			tracerTargets = append(tracerTargets, failover.Target[otelSdkTrace.SpanExporter]{Name: "file", Exporter: tracerExporterFile})
+
+			// Writing a file per span is expensive, batch them when the file is the primary exporter.
+			batchSpans = batchSpans || !otelHTTPTraceEnabled
+		}
+	}
+
+	if len(tracerTargets) > 0 {
+		tracerExporterStdout, tracerExporterStdoutErr := stdouttrace.New()
+		if tracerExporterStdoutErr != nil {
+			slog.WarnContext(ctx, "failed to create the OpenTelemetry trace stdout exporter, no trace exporter fallback", slog.Any("error", tracerExporterStdoutErr))
+		} else {
+			tracerTargets = append(tracerTargets, failover.Target[otelSdkTrace.SpanExporter]{Name: "stdout", Exporter: tracerExporterStdout})
+		}
+
+		// Record the export status of every exporter, the fallbacks of the chain included.
+		for i, target := range tracerTargets {
+			tracerTargets[i].Exporter = selftelemetry.NewSpanExporter(target.Name, target.Exporter)
+		}
+		tracerExporter = tracerTargets[0].Exporter
+	} else {
+		tracerExporter = selftelemetry.NewSpanExporter("noop", tracerExporter)
+	}
+
+	if len(tracerTargets) > 1 {
+		failoverCfg.Signal = "traces"
+		tracerExporter = failover.NewSpanExporter(failoverCfg, tracerTargets[0], tracerTargets[1:]...)
+	}
+
+	sampler := otelSdkTrace.AlwaysSample()
+	tracerPipeline := selftelemetry.Pipeline{
+		Signal:    "traces",
+		Exporters: targetNames(tracerTargets),
+		Processor: "sync",
+		Sampler:   sampler.Description(),
+	}
+
+	if len(tracerTargets) == 0 {
+		tracerPipeline.Exporters = []string{"noop"}
+	}
+
+	// use sync operation to make sure every span persisted before CLI done
+	spanProcessorOpt := otelSdkTrace.WithSyncer(tracerExporter)
+	if batchSpans {
+		spanProcessorOpt = otelSdkTrace.WithBatcher(tracerExporter)
+		tracerPipeline.Processor = "batch"
+	}
+
+	selftelemetry.RegisterPipeline(tracerPipeline)
+
 	tracerProvider := otelSdkTrace.NewTracerProvider(
-		// use sync operation to make sure every span persisted before CLI done
-		otelSdkTrace.WithSyncer(tracerExporter),
+		spanProcessorOpt,
+		otelSdkTrace.WithSpanProcessor(selftelemetry.SpanProcessor{}),
+		otelSdkTrace.WithSpanProcessor(otelSdkTrace.NewSimpleSpanProcessor(traceBuffer)),
 		otelSdkTrace.WithResource(otelResources),
-		otelSdkTrace.WithSampler(otelSdkTrace.AlwaysSample()),
+		otelSdkTrace.WithSampler(sampler),
 	)
 
 	// Set as global OpenTelemetry tracer provider.
@@ -331,6 +1120,211 @@ func initTracer(
 	}
 }
 
+func initLogger(
+	ctx context.Context,
+	otelResources *resource.Resource,
+	otelHTTPLogEnabled bool,
+	otelHTTPEndpoint string,
+	otelHTTPPath string,
+	otlpAuth otlpauth.Config,
+	exportQueue exportQueueConfig,
+	failoverCfg failover.Config,
+	fileExporter fileExporterConfig,
+) func(ctx context.Context) error {
+	var logTargets []failover.Target[otelSdkLog.Exporter]
+
+	otelHTTPEndpoint = strings.TrimSpace(otelHTTPEndpoint)
+	if otelHTTPLogEnabled && exportQueue.Enabled() {
+		queue, queueErr := exportQueue.Open("logs", otelHTTPEndpoint, otelHTTPPath)
+		if queueErr != nil {
+			slog.WarnContext(ctx, "failed to open the log export queue", slog.Any("error", queueErr))
+		} else {
+			logTargets = append(logTargets, failover.Target[otelSdkLog.Exporter]{Name: "otlp", Exporter: exportqueue.NewLogExporter(queue)})
+		}
+	} else if otelHTTPLogEnabled {
+		tlsCfg, logExporterErr := otlpAuth.TLSConfig()
+
+		var logExporter otelSdkLog.Exporter
+		if logExporterErr == nil {
+			// The exporter is built again with the new headers when the bearer token file is rotated.
+			logExporter, logExporterErr = otlpauth.NewLogExporter(otlpAuth.HeaderFunc(), func(headers map[string]string) (otelSdkLog.Exporter, error) {
+				opts := []otlploghttp.Option{
+					otlploghttp.WithEndpoint(otelHTTPEndpoint),
+					otlploghttp.WithURLPath(otelHTTPPath),
+					otlploghttp.WithHeaders(headers),
+					otlploghttp.WithCompression(otlploghttp.GzipCompression),
+					// Retry shortly, the circuit breaker of the failover chain decides when the collector is down.
+					otlploghttp.WithRetry(otlploghttp.RetryConfig{
+						Enabled:         true,
+						InitialInterval: 500 * time.Millisecond,
+						MaxInterval:     1 * time.Second,
+						MaxElapsedTime:  2 * time.Second,
+					}),
+				}
+
+				if tlsCfg == nil {
+					opts = append(opts, otlploghttp.WithInsecure())
+				} else {
+					opts = append(opts, otlploghttp.WithTLSClientConfig(tlsCfg))
+				}
+
+				return otlploghttp.New(context.WithoutCancel(ctx), opts...)
+			})
+		}
+
+		if logExporterErr != nil {
+			slog.WarnContext(ctx, "failed to create the OpenTelemetry log HTTP exporter", slog.Any("error", logExporterErr))
+		} else {
+			logTargets = append(logTargets, failover.Target[otelSdkLog.Exporter]{Name: "otlp", Exporter: logExporter})
+		}
+	} else {
+		slog.WarnContext(ctx, "OpenTelemetry log HTTP Exporter disabled")
+	}
+
+	if fileExporter.Enabled() {
+		writer, writerErr := fileExporter.Open("logs")
+		if writerErr != nil {
+			slog.WarnContext(ctx, "failed to open the OpenTelemetry log file", slog.Any("error", writerErr))
+		} else {
+			slog.InfoContext(ctx, "OpenTelemetry log file exporter enabled", slog.String("dir", fileExporter.Dir))
+			logTargets = append(logTargets, failover.Target[otelSdkLog.Exporter]{Name: "file", Exporter: otlpfile.NewLogExporter(writer)})
+		}
+	}
+
+	// Logs are still written to stderr by slog, so there is nothing to set up without OTLP or file exporter.
+	if len(logTargets) == 0 {
+		return func(context.Context) error {
+			return nil
+		}
+	}
+
+	logExporterStdout, logExporterStdoutErr := stdoutlog.New()
+	if logExporterStdoutErr != nil {
+		slog.WarnContext(ctx, "failed to create the OpenTelemetry log stdout exporter, no log exporter fallback", slog.Any("error", logExporterStdoutErr))
+	} else {
+		logTargets = append(logTargets, failover.Target[otelSdkLog.Exporter]{Name: "stdout", Exporter: logExporterStdout})
+	}
+
+	// Record the export status of every exporter, the fallbacks of the chain included.
+	for i, target := range logTargets {
+		logTargets[i].Exporter = selftelemetry.NewLogExporter(target.Name, target.Exporter)
+	}
+
+	logExporter := logTargets[0].Exporter
+	if len(logTargets) > 1 {
+		failoverCfg.Signal = "logs"
+		logExporter = failover.NewLogExporter(failoverCfg, logTargets[0], logTargets[1:]...)
+	}
+
+	selftelemetry.RegisterPipeline(selftelemetry.Pipeline{
+		Signal:    "logs",
+		Exporters: targetNames(logTargets),
+		Processor: "batch",
+	})
+
+	loggerProvider := otelSdkLog.NewLoggerProvider(
+		otelSdkLog.WithResource(otelResources),
+		otelSdkLog.WithProcessor(otelSdkLog.NewBatchProcessor(logExporter)),
+	)
+	otelLogGlobal.SetLoggerProvider(loggerProvider)
+
+	// Send every slog record to stderr and to the OpenTelemetry logs, with the trace and span ID of the context.
+	slog.SetDefault(slog.New(applog.Fanout(
+		slog.NewTextHandler(os.Stderr, nil),
+		otelslog.NewHandler(instrumentationName, otelslog.WithLoggerProvider(loggerProvider)),
+	)))
+
+	return func(ctx context.Context) error {
+		// Restore the stderr only logger, the provider cannot receive records anymore.
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+		if _err := loggerProvider.Shutdown(ctx); _err != nil {
+			return fmt.Errorf("failed to stop the logger provider: %w", _err)
+		}
+
+		return nil
+	}
+}
+
+// targetNames returns the names of the exporters in failover order.
+func targetNames[E any](targets []failover.Target[E]) []string {
+	names := make([]string, 0, len(targets))
+	for _, target := range targets {
+		names = append(names, target.Name)
+	}
+	return names
+}
+
+// exportQueueConfig enables the on-disk export queue when Dir is not empty.
+type exportQueueConfig struct {
+	Dir          string
+	MaxBytes     int64
+	MetricPrefix string
+	Auth         otlpauth.Config
+}
+
+func (c exportQueueConfig) Enabled() bool {
+	return c.Dir != ""
+}
+
+// Open opens the queue of one signal, replaying the pending batches to the OTLP HTTP endpoint.
+func (c exportQueueConfig) Open(signal, otelHTTPEndpoint, otelHTTPPath string) (*exportqueue.Queue, error) {
+	tlsCfg, err := c.Auth.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client := otlpclient.New(otlpclient.Config{
+		Endpoint:  otelHTTPEndpoint,
+		URLPath:   otelHTTPPath,
+		Insecure:  c.Auth.Insecure,
+		Gzip:      true,
+		TLSConfig: tlsCfg,
+		Headers:   c.Auth.HeaderFunc(),
+	})
+
+	return exportqueue.Open(exportqueue.Config{
+		Dir:            filepath.Join(c.Dir, signal),
+		Signal:         signal,
+		MaxBytes:       c.MaxBytes,
+		InitialBackoff: 5 * time.Second,
+		MaxBackoff:     15 * time.Second,
+		MetricPrefix:   c.MetricPrefix,
+	}, client)
+}
+
+// fileExporterConfig enables the OTLP file exporter when Dir is not empty.
+type fileExporterConfig struct {
+	Dir        string
+	MaxBytes   int64
+	MaxAge     time.Duration
+	MaxBackups int
+	Gzip       bool
+}
+
+func (c fileExporterConfig) Enabled() bool {
+	return c.Dir != ""
+}
+
+// Open opens the rotating file of one signal, for example "traces.jsonl".
+func (c fileExporterConfig) Open(signal string) (*otlpfile.RotatingWriter, error) {
+	return otlpfile.NewRotatingWriter(otlpfile.RotateConfig{
+		Path:       filepath.Join(c.Dir, signal+".jsonl"),
+		MaxBytes:   c.MaxBytes,
+		MaxAge:     c.MaxAge,
+		MaxBackups: c.MaxBackups,
+		Gzip:       c.Gzip,
+	})
+}
+
+// MetricsMiddleware records the request count, the latency histogram and the in-flight requests,
+// using the metric names and the attribute keys of the MetricsMiddleware of dd-sdk:
+//
+//   - <svcName>.http_server_requests_total, a counter
+//   - <svcName>.http_server_request_duration_ms, a histogram in milliseconds
+//   - <svcName>.http_server_requests_in_flight, an up-down counter
+//
+// The counter and the histogram have the attributes method, route (the chi route pattern) and status_code.
 func MetricsMiddleware(svcName string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		meterProvider := otel.GetMeterProvider().Meter(instrumentationName)
@@ -338,6 +1332,7 @@ func MetricsMiddleware(svcName string) func(http.Handler) http.Handler {
 		var err error
 		var requestCount metric.Int64Counter
 		var requestLatency metric.Int64Histogram
+		var requestsInFlight metric.Int64UpDownCounter
 
 		// Define metrics
 		requestCount, err = meterProvider.Int64Counter(svcName + ".http_server_requests_total")
@@ -352,30 +1347,67 @@ func MetricsMiddleware(svcName string) func(http.Handler) http.Handler {
 			requestLatency = &otelMetricNoop.Int64Histogram{}
 		}
 
+		requestsInFlight, err = meterProvider.Int64UpDownCounter(svcName + ".http_server_requests_in_flight")
+		if err != nil {
+			slog.Error("failed to create http_server_requests_in_flight counter", slog.Any("error", err))
+			requestsInFlight = &otelMetricNoop.Int64UpDownCounter{}
+		}
+
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			startTime := time.Now()
+			requestsInFlight.Add(r.Context(), 1)
 
 			// Process the request
-			next.ServeHTTP(w, r)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				requestsInFlight.Add(r.Context(), -1)
+
+				// The status is 200 when the handler writes the body without calling WriteHeader.
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				// The route pattern is complete only after chi routed the request, it is empty when nothing matched.
+				route := chi.RouteContext(r.Context()).RoutePattern()
+				if route == "" {
+					route = "unmatched"
+				}
 
-			// Record metrics
-			duration := time.Since(startTime).Milliseconds()
+				// Record metrics
+				duration := time.Since(startTime).Milliseconds()
 
-			tags := []attribute.KeyValue{
-				attribute.String("http.method", r.Method),
-				attribute.String("http.route", r.URL.Path),
-			}
+				tags := []attribute.KeyValue{
+					attribute.String("method", r.Method),
+					attribute.String("route", route),
+					attribute.String("status_code", strconv.Itoa(status)),
+				}
 
-			// Increment the request count
-			requestCount.Add(r.Context(), 1, metric.WithAttributes(tags...))
+				// Increment the request count
+				requestCount.Add(r.Context(), 1, metric.WithAttributes(tags...))
 
-			requestLatency.Record(r.Context(), duration, metric.WithAttributes(tags...))
+				requestLatency.Record(r.Context(), duration, metric.WithAttributes(tags...))
+			}()
+
+			next.ServeHTTP(ww, r)
 		})
 	}
 }
 
 type Handler struct {
 	ServiceName string
+
+	// Tokens issues the session token of a successful login, and validates it in the AuthMiddleware.
+	Tokens *authtoken.Manager
+
+	// EnduserID is EnduserIDPlain, EnduserIDHash or EnduserIDOmit.
+	EnduserID string
+
+	// Users checks the login credentials.
+	Users *userstore.Authenticator
+
+	// Lockout locks the usernames and blocks the client IPs after too many failed logins.
+	Lockout *lockout.Tracker
 }
 
 func (*Handler) Homepage(w http.ResponseWriter, _ *http.Request) {
@@ -383,11 +1415,6 @@ func (*Handler) Homepage(w http.ResponseWriter, _ *http.Request) {
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
-	type User struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-
 	ctx := r.Context()
 	span := trace.SpanFromContext(ctx)
 	defer span.End()
@@ -395,22 +1422,21 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	parentCtx, parentSpan := span.TracerProvider().Tracer(instrumentationName).Start(ctx, "Login Handler [Otel SDK]")
 	defer parentSpan.End()
 
-	loginFailureCtr := appmetrics.LoginFailureCounter(ctx, h.ServiceName)
-	loginSuccessCtr := appmetrics.LoginSuccessCounter(ctx, h.ServiceName)
+	metrics := appmetrics.NewRecorder(h.ServiceName)
 
-	var user User
+	var user loginRequest
 	{
-		_, decodeBodySpan := parentSpan.TracerProvider().Tracer(instrumentationName).Start(ctx, "Decode Body [Otel SDK]")
+		_, decodeBodySpan := parentSpan.TracerProvider().Tracer(instrumentationName).Start(parentCtx, "Decode Body [Otel SDK]")
 
-		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-			loginFailureCtr.Add(ctx, 1, metric.WithAttributes(
-				attribute.String(appmetrics.FailureReasonKey, appmetrics.LoginInvalidPayload),
-			))
+		if reason, status, err := decodeLogin(w, r, &user); err != nil {
+			metrics.RecordLoginFailure(parentCtx, reason)
 
 			decodeBodySpan.RecordError(err)
-			decodeBodySpan.SetAttributes(attribute.String(appmetrics.FailureReasonKey, appmetrics.LoginInvalidPayload))
+			decodeBodySpan.SetAttributes(appmetrics.FailureReason(reason))
+			parentSpan.RecordError(err)
+			parentSpan.SetAttributes(appmetrics.FailureReason(reason))
 
-			http.Error(w, "Invalid request payload (from otel-sdk example).", http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("Invalid login request, %s (from otel-sdk example).", err), status)
 
 			decodeBodySpan.End()
 			return
@@ -425,34 +1451,74 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		decodeBodySpan.End()
 	}
 
+	// The lock is checked before the password, so a locked account cannot be brute-forced.
+	remoteIP := clientIP(r)
+	if decision := h.Lockout.Check(user.Username, remoteIP); decision.Locked() {
+		metrics.RecordLoginFailure(parentCtx, appmetrics.LoginLocked)
+
+		parentSpan.SetAttributes(appmetrics.FailureReason(appmetrics.LoginLocked))
+		securityEvent(parentCtx, parentSpan, SecurityEventLockedLoginRejected, h.lockoutAttributes(user.Username, remoteIP, decision)...)
+
+		writeLocked(w, decision)
+		return
+	}
+
+	var err error
 	{
-		_, checkCredentialsSpan := parentSpan.TracerProvider().Tracer(instrumentationName).Start(ctx, "Check Credentials [Otel SDK]")
+		checkCtx, checkCredentialsSpan := parentSpan.TracerProvider().Tracer(instrumentationName).Start(parentCtx, "Check Credentials [Otel SDK]")
 		defer checkCredentialsSpan.End()
 
-		// In-memory user store
-		var users = map[string]string{
-			"user1": "password1",
-			"user2": "password2",
-			"user3": "password3",
+		_, err = h.Users.Authenticate(checkCtx, user.Username, user.Password)
+		if err != nil && !errors.Is(err, userstore.ErrInvalidCredentials) {
+			checkCredentialsSpan.RecordError(err)
+			checkCredentialsSpan.SetStatus(codes.Error, err.Error())
+			http.Error(w, "Failed to check the credentials (from otel-sdk example).", http.StatusInternalServerError)
+			return
 		}
 
-		// Validate the user credentials
-		if password, exists := users[user.Username]; exists && password == user.Password {
-			loginSuccessCtr.Add(parentCtx, 1)
-
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte("Login successful (from otel-sdk example).\n"))
+		if err == nil {
+			h.Lockout.Success(user.Username)
+			metrics.RecordLoginSuccess(parentCtx)
+			parentSpan.SetAttributes(h.enduserID(user.Username)...)
+			span.SetAttributes(h.enduserID(user.Username)...)
+
+			token, claims, err := h.Tokens.Issue(user.Username)
+			if err != nil {
+				parentSpan.RecordError(err)
+				parentSpan.SetStatus(codes.Error, err.Error())
+				http.Error(w, "Failed to issue the session token (from otel-sdk example).", http.StatusInternalServerError)
+				return
+			}
+			metrics.RecordTokenIssued(parentCtx)
+
+			writeJSON(w, http.StatusOK, map[string]any{
+				"message":      "Login successful (from otel-sdk example).",
+				"access_token": token,
+				"token_type":   "Bearer",
+				"expires_in":   claims.ExpiresAt - claims.IssuedAt,
+			})
 			return
 		}
 	}
 
-	loginFailureCtr.Add(parentCtx, 1, metric.WithAttributes(
-		attribute.String(appmetrics.FailureReasonKey, appmetrics.LoginInvalidCredentials),
-	))
+	// The reason tells the unknown users apart from the wrong passwords, the response does not.
+	reason := appmetrics.LoginWrongPassword
+	if errors.Is(err, userstore.ErrUnknownUser) {
+		reason = appmetrics.LoginUnknownUser
+	}
+
+	metrics.RecordLoginFailure(parentCtx, reason)
 
-	err := fmt.Errorf("invalid credentials")
 	parentSpan.RecordError(err)
-	parentSpan.SetAttributes(attribute.String(appmetrics.FailureReasonKey, appmetrics.LoginInvalidCredentials))
+	parentSpan.SetAttributes(appmetrics.FailureReason(reason))
+
+	// This attempt is still answered 401, the next ones are rejected by the lock.
+	switch decision := h.Lockout.Failure(user.Username, remoteIP); decision.Scope {
+	case lockout.ScopeUsername:
+		securityEvent(parentCtx, parentSpan, SecurityEventAccountLocked, h.lockoutAttributes(user.Username, remoteIP, decision)...)
+	case lockout.ScopeClientIP:
+		securityEvent(parentCtx, parentSpan, SecurityEventClientBlocked, h.lockoutAttributes(user.Username, remoteIP, decision)...)
+	}
 
 	http.Error(w, "Invalid username or password (from otel-sdk example).", http.StatusUnauthorized)
 }
@@ -1,3 +1,4 @@
 package appmetrics
 
-const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+// InstrumentationName is the scope of the instruments created by the Registry.
+const InstrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
@@ -0,0 +1,3 @@
+package appmetrics
+
+//go:generate go run ./internal/metricsgen -schema metrics.yaml -go appmetrics_gen.go -dogstatsd ../../../dd-sdk/appmetrics_gen.go -readme ../../../README.md
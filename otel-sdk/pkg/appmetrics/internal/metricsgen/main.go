@@ -0,0 +1,111 @@
+// Command metricsgen generates the appmetrics instruments, the dd-sdk DogStatsD helpers and the README section from metrics.yaml.
+//
+// It is run by go generate in otel-sdk/pkg/appmetrics:
+//
+//	go generate ./pkg/appmetrics
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const (
+	readmeBegin = "<!-- BEGIN appmetrics, generated by metricsgen from otel-sdk/pkg/appmetrics/metrics.yaml, DO NOT EDIT. -->"
+	readmeEnd   = "<!-- END appmetrics -->"
+)
+
+func main() {
+	var (
+		schemaPath    = flag.String("schema", "metrics.yaml", "path of the schema")
+		goPath        = flag.String("go", "appmetrics_gen.go", "path of the generated appmetrics file")
+		dogstatsdPath = flag.String("dogstatsd", "", "path of the generated dd-sdk file, empty to skip it")
+		readmePath    = flag.String("readme", "", "path of the README whose appmetrics section is replaced, empty to skip it")
+	)
+	flag.Parse()
+
+	if err := run(context.Background(), *schemaPath, *goPath, *dogstatsdPath, *readmePath); err != nil {
+		slog.Error("metricsgen failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, schemaPath, goPath, dogstatsdPath, readmePath string) error {
+	schema, err := loadSchema(schemaPath)
+	if err != nil {
+		return err
+	}
+
+	if err = writeGo(goPath, otelTemplate, schema); err != nil {
+		return err
+	}
+
+	if dogstatsdPath != "" {
+		if err = writeGo(dogstatsdPath, dogstatsdTemplate, schema); err != nil {
+			return err
+		}
+	}
+
+	if readmePath != "" {
+		if err = writeReadme(ctx, readmePath, schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeGo executes the template and writes the gofmt-ed source.
+func writeGo(path string, tmpl *template.Template, schema *Schema) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, schema); err != nil {
+		return fmt.Errorf("generate %s: %w", path, err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("format %s: %w\n%s", path, err, buf.Bytes())
+	}
+
+	return os.WriteFile(path, src, 0o644)
+}
+
+// writeReadme replaces the text between readmeBegin and readmeEnd, the markers are kept.
+func writeReadme(ctx context.Context, path string, schema *Schema) error {
+	readme, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	begin := bytes.Index(readme, []byte(readmeBegin))
+	end := bytes.Index(readme, []byte(readmeEnd))
+	if begin < 0 || end < begin {
+		return errors.New("the README has no appmetrics markers, add them where the section goes:\n" + readmeBegin + "\n" + readmeEnd)
+	}
+
+	rows, err := previewRows(ctx, schema)
+	if err != nil {
+		return err
+	}
+
+	var section strings.Builder
+	if err = readmeTemplate.Execute(&section, readmeData{Schema: schema, Rows: rows}); err != nil {
+		return fmt.Errorf("generate the README section: %w", err)
+	}
+
+	var out bytes.Buffer
+	out.Write(readme[:begin+len(readmeBegin)])
+	out.WriteString("\n")
+	out.WriteString(section.String())
+	out.Write(readme[end:])
+
+	return os.WriteFile(path, out.Bytes(), 0o644)
+}
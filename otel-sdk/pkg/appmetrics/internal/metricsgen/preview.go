@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/metricname"
+)
+
+// previewRow is the series names of one instrument in every backend.
+type previewRow struct {
+	Name       string
+	OTLP       string
+	Prometheus []metricname.Series
+
+	// DogStatsD is empty when dd-sdk does not emit the metric.
+	DogStatsD []metricname.Series
+}
+
+type readmeData struct {
+	Schema *Schema
+	Rows   []previewRow
+}
+
+func previewRows(ctx context.Context, schema *Schema) ([]previewRow, error) {
+	rows := make([]previewRow, 0, len(schema.Metrics))
+	for _, m := range schema.Metrics {
+		inst := metricname.Instrument{
+			Name: schema.ServiceNames.OtelSDK + "." + m.Name,
+			Kind: kinds[m.Instrument],
+			Unit: m.Unit,
+		}
+
+		prometheus, err := metricname.Prometheus(ctx, inst)
+		if err != nil {
+			return nil, fmt.Errorf("prometheus names of %q: %w", m.Name, err)
+		}
+
+		row := previewRow{
+			Name:       m.Name,
+			OTLP:       inst.Name,
+			Prometheus: prometheus,
+		}
+
+		if m.DogStatsD {
+			row.DogStatsD, err = metricname.DogStatsD(schema.ServiceNames.DDSDK, metricname.Instrument{Name: m.Name, Kind: kinds[m.Instrument]})
+			if err != nil {
+				return nil, fmt.Errorf("dogstatsd names of %q: %w", m.Name, err)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v3"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+)
+
+const (
+	AttributeTypeString = "string"
+	AttributeTypeInt    = "int"
+	AttributeTypeBool   = "bool"
+	AttributeTypeEnum   = "enum"
+)
+
+// Schema is metrics.yaml.
+type Schema struct {
+	ServiceNames struct {
+		OtelSDK string `yaml:"otel_sdk"`
+		DDSDK   string `yaml:"dd_sdk"`
+	} `yaml:"service_names"`
+
+	Attributes []*Attribute `yaml:"attributes"`
+	Metrics    []*Metric    `yaml:"metrics"`
+}
+
+// Attribute is an attribute of the metrics, a tag in DogStatsD.
+type Attribute struct {
+	ID       string    `yaml:"id"`
+	Type     string    `yaml:"type"`
+	Brief    string    `yaml:"brief"`
+	GoName   string    `yaml:"go_name"`
+	GoType   string    `yaml:"go_type"`
+	Fallback string    `yaml:"fallback"`
+	Members  []*Member `yaml:"members"`
+}
+
+// Member is a value of an enum attribute.
+type Member struct {
+	Value  string `yaml:"value"`
+	GoName string `yaml:"go_name"`
+	Brief  string `yaml:"brief"`
+}
+
+// Metric is an instrument, named without the service name prefix.
+type Metric struct {
+	Name       string   `yaml:"name"`
+	GoName     string   `yaml:"go_name"`
+	Instrument string   `yaml:"instrument"`
+	Unit       string   `yaml:"unit"`
+	Brief      string   `yaml:"brief"`
+	Attributes []string `yaml:"attributes"`
+	DogStatsD  bool     `yaml:"dogstatsd"`
+
+	// Attrs are the resolved Attributes.
+	Attrs []*Attribute `yaml:"-"`
+}
+
+// kinds maps the instrument of the schema to the appmetrics kind.
+var kinds = map[string]appmetrics.Kind{
+	"counter":       appmetrics.KindInt64Counter,
+	"updowncounter": appmetrics.KindInt64UpDownCounter,
+	"histogram":     appmetrics.KindFloat64Histogram,
+}
+
+// loadSchema reads and validates the schema, and resolves the attributes of the metrics.
+func loadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema Schema
+	if err = yaml.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if err = schema.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+
+	return &schema, nil
+}
+
+func (s *Schema) validate() error {
+	if s.ServiceNames.OtelSDK == "" || s.ServiceNames.DDSDK == "" {
+		return errors.New("service_names.otel_sdk and service_names.dd_sdk are required")
+	}
+
+	goNames := map[string]bool{}
+	declareGoName := func(name string) error {
+		if !isExported(name) {
+			return fmt.Errorf("go name %q is not an exported Go identifier", name)
+		}
+		if goNames[name] {
+			return fmt.Errorf("go name %q is used twice", name)
+		}
+		goNames[name] = true
+		return nil
+	}
+
+	attributes := map[string]*Attribute{}
+	for _, attr := range s.Attributes {
+		if attr.ID == "" {
+			return errors.New("attribute without id")
+		}
+		if _, ok := attributes[attr.ID]; ok {
+			return fmt.Errorf("attribute %q declared twice", attr.ID)
+		}
+		attributes[attr.ID] = attr
+
+		if err := declareGoName(attr.GoName); err != nil {
+			return fmt.Errorf("attribute %q: %w", attr.ID, err)
+		}
+
+		switch attr.Type {
+		case AttributeTypeString, AttributeTypeInt, AttributeTypeBool:
+			if attr.GoType != "" || attr.Fallback != "" || len(attr.Members) > 0 {
+				return fmt.Errorf("attribute %q: go_type, fallback and members are only for the enums", attr.ID)
+			}
+		case AttributeTypeEnum:
+			if err := declareGoName(attr.GoType); err != nil {
+				return fmt.Errorf("attribute %q: %w", attr.ID, err)
+			}
+			if len(attr.Members) == 0 {
+				return fmt.Errorf("attribute %q: an enum needs members", attr.ID)
+			}
+
+			values := map[string]bool{}
+			for _, member := range attr.Members {
+				if member.Value == "" || values[member.Value] {
+					return fmt.Errorf("attribute %q: empty or duplicated member %q", attr.ID, member.Value)
+				}
+				values[member.Value] = true
+
+				if err := declareGoName(member.GoName); err != nil {
+					return fmt.Errorf("attribute %q: %w", attr.ID, err)
+				}
+			}
+
+			if !values[attr.Fallback] {
+				return fmt.Errorf("attribute %q: fallback %q is not a member", attr.ID, attr.Fallback)
+			}
+		default:
+			return fmt.Errorf("attribute %q: unknown type %q", attr.ID, attr.Type)
+		}
+	}
+
+	names := map[string]bool{}
+	for _, m := range s.Metrics {
+		if m.Name == "" || names[m.Name] {
+			return fmt.Errorf("empty or duplicated metric name %q", m.Name)
+		}
+		names[m.Name] = true
+
+		if err := declareGoName(m.GoName); err != nil {
+			return fmt.Errorf("metric %q: %w", m.Name, err)
+		}
+
+		if _, ok := kinds[m.Instrument]; !ok {
+			return fmt.Errorf("metric %q: unknown instrument %q", m.Name, m.Instrument)
+		}
+
+		if m.Brief == "" {
+			return fmt.Errorf("metric %q: brief is required", m.Name)
+		}
+
+		for _, id := range m.Attributes {
+			attr, ok := attributes[id]
+			if !ok {
+				return fmt.Errorf("metric %q: unknown attribute %q", m.Name, id)
+			}
+			if slices.Contains(m.Attrs, attr) {
+				return fmt.Errorf("metric %q: attribute %q listed twice", m.Name, id)
+			}
+			m.Attrs = append(m.Attrs, attr)
+		}
+	}
+
+	return nil
+}
+
+// Declaration is the appmetrics declaration of the metric.
+func (m *Metric) Declaration() appmetrics.Instrument {
+	inst := appmetrics.Instrument{
+		Name:        m.Name,
+		Kind:        kinds[m.Instrument],
+		Unit:        m.Unit,
+		Description: m.Brief,
+	}
+	for _, attr := range m.Attrs {
+		inst.AttributeKeys = append(inst.AttributeKeys, attribute.Key(attr.ID))
+	}
+	return inst
+}
+
+// FallbackMember is the member replacing the invalid values of the enum.
+func (a *Attribute) FallbackMember() *Member {
+	for _, member := range a.Members {
+		if member.Value == a.Fallback {
+			return member
+		}
+	}
+	return nil
+}
+
+// ParamName is the name of the parameter of the generated helpers, for example failureReason.
+func (a *Attribute) ParamName() string {
+	runes := []rune(a.GoName)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// ParamType is the Go type of the parameter of the generated helpers.
+func (a *Attribute) ParamType() string {
+	switch a.Type {
+	case AttributeTypeEnum:
+		return a.GoType
+	case AttributeTypeInt:
+		return "int64"
+	case AttributeTypeBool:
+		return "bool"
+	default:
+		return "string"
+	}
+}
+
+func isExported(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if i == 0 && !unicode.IsUpper(r) {
+			return false
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// MetricsWithAttributes are the metrics recorded with attributes.
+func (s *Schema) MetricsWithAttributes() []*Metric {
+	var metrics []*Metric
+	for _, m := range s.Metrics {
+		if len(m.Attrs) > 0 {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics
+}
+
+// DogStatsDMetrics are the metrics emitted by dd-sdk too.
+func (s *Schema) DogStatsDMetrics() []*Metric {
+	var metrics []*Metric
+	for _, m := range s.Metrics {
+		if m.DogStatsD {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics
+}
+
+// DogStatsDAttributes are the attributes of DogStatsDMetrics, in the order of the schema.
+func (s *Schema) DogStatsDAttributes() []*Attribute {
+	var attrs []*Attribute
+	for _, attr := range s.Attributes {
+		for _, m := range s.DogStatsDMetrics() {
+			if slices.Contains(m.Attrs, attr) {
+				attrs = append(attrs, attr)
+				break
+			}
+		}
+	}
+	return attrs
+}
+
+// DogStatsDNeedsStrconv reports whether a tag of DogStatsDAttributes is formatted with strconv.
+func (s *Schema) DogStatsDNeedsStrconv() bool {
+	return slices.ContainsFunc(s.DogStatsDAttributes(), func(attr *Attribute) bool {
+		return attr.Type == AttributeTypeInt || attr.Type == AttributeTypeBool
+	})
+}
+
+// AttributeSetter is the method of attribute.Key creating the attribute.
+func (a *Attribute) AttributeSetter() string {
+	switch a.Type {
+	case AttributeTypeInt:
+		return "Int64"
+	case AttributeTypeBool:
+		return "Bool"
+	default:
+		return "String"
+	}
+}
+
+// TagValue formats the parameter v as a tag value.
+func (a *Attribute) TagValue() string {
+	switch a.Type {
+	case AttributeTypeInt:
+		return "strconv.FormatInt(v, 10)"
+	case AttributeTypeBool:
+		return "strconv.FormatBool(v)"
+	default:
+		return "v"
+	}
+}
+
+// KindName is the name of the appmetrics Kind constant.
+func (m *Metric) KindName() string {
+	switch kinds[m.Instrument] {
+	case appmetrics.KindInt64UpDownCounter:
+		return "KindInt64UpDownCounter"
+	case appmetrics.KindFloat64Histogram:
+		return "KindFloat64Histogram"
+	default:
+		return "KindInt64Counter"
+	}
+}
+
+// HelperName is the name of the generated helper, Add for the up down counters and Record otherwise.
+func (m *Metric) HelperName() string {
+	if m.Instrument == "updowncounter" {
+		return "Add" + m.GoName
+	}
+	return "Record" + m.GoName
+}
+
+// HelperVerb starts the doc comment of the generated helper.
+func (m *Metric) HelperVerb() string {
+	switch m.Instrument {
+	case "updowncounter":
+		return "adds incr to"
+	case "histogram":
+		return "records the value of"
+	default:
+		return "adds one to"
+	}
+}
+
+// ValueParam is the value parameter of the generated helper, none for the counters.
+func (m *Metric) ValueParam() string {
+	switch m.Instrument {
+	case "updowncounter":
+		return ", incr int64"
+	case "histogram":
+		return ", value float64"
+	default:
+		return ""
+	}
+}
+
+// Value is the value given to the instrument.
+func (m *Metric) Value() string {
+	switch m.Instrument {
+	case "updowncounter":
+		return "incr"
+	case "histogram":
+		return "value"
+	default:
+		return "1"
+	}
+}
+
+// RegistryMethod is the method of the Registry returning the instrument.
+func (m *Metric) RegistryMethod() string {
+	switch m.Instrument {
+	case "updowncounter":
+		return "Int64UpDownCounter"
+	case "histogram":
+		return "Float64Histogram"
+	default:
+		return "Int64Counter"
+	}
+}
+
+// InstrumentMethod is the method of the instrument recording the value.
+func (m *Metric) InstrumentMethod() string {
+	if m.Instrument == "histogram" {
+		return "Record"
+	}
+	return "Add"
+}
+
+// StatsdMethod is the method of the DogStatsD client sending the value.
+func (m *Metric) StatsdMethod() string {
+	switch m.Instrument {
+	case "updowncounter":
+		return "Count"
+	case "histogram":
+		return "Distribution"
+	default:
+		return "Incr"
+	}
+}
+
+// StatsdValue is the value given to StatsdMethod, none for Incr.
+func (m *Metric) StatsdValue() string {
+	switch m.Instrument {
+	case "updowncounter":
+		return "incr"
+	case "histogram":
+		return "value"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"text/template"
+)
+
+var otelTemplate = template.Must(template.New("appmetrics").Parse(`// Code generated by metricsgen from metrics.yaml. DO NOT EDIT.
+
+package appmetrics
+
+import (
+	"context"
+{{- if .Attributes}}
+
+	"go.opentelemetry.io/otel/attribute"
+{{- end}}
+{{- if .MetricsWithAttributes}}
+	"go.opentelemetry.io/otel/metric"
+{{- end}}
+)
+{{range .Attributes}}
+// {{.GoName}}Key is the key of the {{.ID}} attribute. {{.Brief}}
+const {{.GoName}}Key = attribute.Key("{{.ID}}")
+{{if eq .Type "enum"}}
+// {{.GoType}} is a value of {{.ID}}, only the values of {{.GoType}}s are valid.
+type {{.GoType}} string
+
+const (
+{{- $attr := .}}
+{{- range .Members}}
+	// {{.GoName}} means {{.Brief}}.
+	{{.GoName}} {{$attr.GoType}} = "{{.Value}}"
+{{- end}}
+)
+
+// {{.GoType}}s describes every value of {{.ID}}.
+var {{.GoType}}s = map[{{.GoType}}]string{
+{{- range .Members}}
+	{{.GoName}}: {{printf "%q" .Brief}},
+{{- end}}
+}
+
+// Valid reports whether the value is in {{.GoType}}s.
+func (v {{.GoType}}) Valid() bool {
+	_, ok := {{.GoType}}s[v]
+	return ok
+}
+
+// {{.GoName}} is the {{.ID}} attribute of both the spans and the metrics, an invalid value is recorded as {{.FallbackMember.GoName}}.
+func {{.GoName}}(v {{.GoType}}) attribute.KeyValue {
+	if !v.Valid() {
+		v = {{.FallbackMember.GoName}}
+	}
+	return {{.GoName}}Key.String(string(v))
+}
+{{else}}
+// {{.GoName}} is the {{.ID}} attribute of both the spans and the metrics.
+func {{.GoName}}(v {{.ParamType}}) attribute.KeyValue {
+	return {{.GoName}}Key.{{.AttributeSetter}}(v)
+}
+{{end}}
+{{- end}}
+// DogStatsDNamespace is the namespace of the DogStatsD metrics of dd-sdk, the instruments with DogStatsD are sent in it.
+const DogStatsDNamespace = "{{.ServiceNames.DDSDK}}"
+
+var (
+{{- range $i, $m := .Metrics}}
+{{- if $i}}
+{{end}}
+	// {{.GoName}} is the declaration of {{.Name}}. {{.Brief}}
+	{{.GoName}} = Default.Declare(Instrument{
+		Name:        "{{.Name}}",
+		Kind:        {{.KindName}},
+		Unit:        {{printf "%q" .Unit}},
+		Description: {{printf "%q" .Brief}},
+{{- if .Attrs}}
+		AttributeKeys: []attribute.Key{ {{- range $i, $a := .Attrs}}{{if $i}}, {{end}}{{$a.GoName}}Key{{end -}} },
+{{- end}}
+{{- if .DogStatsD}}
+		DogStatsD: true,
+{{- end}}
+	})
+{{- end}}
+)
+{{range .Metrics}}
+// {{.HelperName}} {{.HelperVerb}} {{.Name}}. {{.Brief}}
+func (r Recorder) {{.HelperName}}(ctx context.Context{{.ValueParam}}{{range .Attrs}}, {{.ParamName}} {{.ParamType}}{{end}}) {
+	r.Registry.{{.RegistryMethod}}(ctx, r.ServiceName, {{.GoName}}).{{.InstrumentMethod}}(ctx, {{.Value}}
+	{{- if .Attrs}}, metric.WithAttributes({{range $i, $a := .Attrs}}{{if $i}}, {{end}}{{$a.GoName}}({{$a.ParamName}}){{end}}){{end}})
+}
+{{end}}`))
+
+var dogstatsdTemplate = template.Must(template.New("dogstatsd").Parse(`// Code generated by metricsgen from otel-sdk/pkg/appmetrics/metrics.yaml. DO NOT EDIT.
+
+package main
+
+import (
+{{- if .DogStatsDNeedsStrconv}}
+	"strconv"
+
+{{- end}}
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+{{range .DogStatsDAttributes}}
+// {{.GoName}}Tag is the tag key of {{.ID}}, the attribute key of otel-sdk. {{.Brief}}
+const {{.GoName}}Tag = "{{.ID}}"
+{{if eq .Type "enum"}}
+// {{.GoType}} is a value of {{.ID}}, only the values of {{.GoType}}s are valid.
+// The values are the ones of appmetrics.{{.GoType}} in otel-sdk.
+type {{.GoType}} string
+
+const (
+{{- $attr := .}}
+{{- range .Members}}
+	// {{.GoName}} means {{.Brief}}.
+	{{.GoName}} {{$attr.GoType}} = "{{.Value}}"
+{{- end}}
+)
+
+// {{.GoType}}s describes every value of {{.ID}}.
+var {{.GoType}}s = map[{{.GoType}}]string{
+{{- range .Members}}
+	{{.GoName}}: {{printf "%q" .Brief}},
+{{- end}}
+}
+
+// Valid reports whether the value is in {{.GoType}}s.
+func (v {{.GoType}}) Valid() bool {
+	_, ok := {{.GoType}}s[v]
+	return ok
+}
+
+// tag{{.GoName}} is the {{.ID}} tag, an invalid value is tagged as {{.FallbackMember.GoName}}.
+func tag{{.GoName}}(v {{.GoType}}) string {
+	if !v.Valid() {
+		v = {{.FallbackMember.GoName}}
+	}
+	return {{.GoName}}Tag + ":" + string(v)
+}
+{{else}}
+// tag{{.GoName}} is the {{.ID}} tag.
+func tag{{.GoName}}(v {{.ParamType}}) string {
+	return {{.GoName}}Tag + ":" + {{.TagValue}}
+}
+{{end}}
+{{- end}}
+const (
+{{- range .DogStatsDMetrics}}
+	// Metric{{.GoName}} is {{.Name}}, the name of the otel-sdk instrument. {{.Brief}}
+	Metric{{.GoName}} = "{{.Name}}"
+{{- end}}
+)
+{{range .DogStatsDMetrics}}
+// {{.HelperName}} {{.HelperVerb}} {{.Name}}. {{.Brief}}
+func {{.HelperName}}(client statsd.ClientInterface{{.ValueParam}}{{range .Attrs}}, {{.ParamName}} {{.ParamType}}{{end}}) error {
+	return client.{{.StatsdMethod}}(Metric{{.GoName}}, {{if .StatsdValue}}{{.StatsdValue}}, {{end}}
+	{{- if .Attrs}}[]string{ {{- range $i, $a := .Attrs}}{{if $i}}, {{end}}tag{{$a.GoName}}({{$a.ParamName}}){{end -}} }{{else}}nil{{end}}, 1)
+}
+{{end}}`))
+
+var readmeTemplate = template.Must(template.New("readme").Parse(`### appmetrics Instruments
+
+The instruments of ` + "`otel-sdk/pkg/appmetrics`" + `, declared in [metrics.yaml](otel-sdk/pkg/appmetrics/metrics.yaml).
+Run ` + "`go generate ./pkg/appmetrics`" + ` in otel-sdk after a change of the schema, it also generates the dd-sdk helpers and this section.
+
+| Instrument | Kind | Unit | Attributes | Description |
+|------------|------|------|------------|-------------|
+{{- range .Schema.Metrics}}
+| ` + "`{{.Name}}`" + ` | {{.Instrument}} | {{if .Unit}}` + "`{{.Unit}}`" + `{{end}} | {{range $i, $a := .Attrs}}{{if $i}}, {{end}}` + "`{{$a.ID}}`" + `{{end}} | {{.Brief}} |
+{{- end}}
+
+The series of every backend, with their type. The Prometheus names are exported by the OpenTelemetry Prometheus exporter of otel-sdk,
+Grafana Mimir stores the same names. The DogStatsD names are sent by the datadog-go client with the namespace of dd-sdk.
+The Datadog exporter of the collector names the OTLP metrics with its own translation code, which is not a dependency of otel-sdk, so they are not listed.
+The running application lists all its instruments the same way, see [Metric Catalog](#metric-catalog).
+
+| Instrument | otel-sdk OTLP | otel-sdk Prometheus / Mimir | dd-sdk DogStatsD |
+|------------|---------------|-----------------------------|------------------|
+{{- range .Rows}}
+| ` + "`{{.Name}}`" + ` | ` + "`{{.OTLP}}`" + ` | {{template "series" .Prometheus}} | {{if .DogStatsD}}{{template "series" .DogStatsD}}{{else}}-{{end}} |
+{{- end}}
+{{range .Schema.Attributes}}{{if eq .Type "enum"}}
+` + "`{{.ID}}`" + `: {{.Brief}} The values are:
+
+| Value | Description |
+|-------|-------------|
+{{- range .Members}}
+| ` + "`{{.Value}}`" + ` | {{.Brief}} |
+{{- end}}
+{{end}}{{end}}
+{{- define "series"}}{{range $i, $s := .}}{{if $i}}, {{end}}` + "`{{$s.Name}}`" + ` ({{$s.Type}}){{end}}{{end}}`))
@@ -0,0 +1,251 @@
+package appmetrics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// Kind is the type of the instrument, the Registry creates the int64 counters, the int64 up down counters and the float64 histograms.
+// The other kinds describe the instruments created without the Registry, for example in the metric catalog.
+type Kind string
+
+const (
+	KindInt64Counter                   Kind = "int64_counter"
+	KindInt64UpDownCounter             Kind = "int64_up_down_counter"
+	KindInt64Histogram                 Kind = "int64_histogram"
+	KindInt64Gauge                     Kind = "int64_gauge"
+	KindInt64ObservableCounter         Kind = "int64_observable_counter"
+	KindInt64ObservableUpDownCounter   Kind = "int64_observable_up_down_counter"
+	KindInt64ObservableGauge           Kind = "int64_observable_gauge"
+	KindFloat64Counter                 Kind = "float64_counter"
+	KindFloat64UpDownCounter           Kind = "float64_up_down_counter"
+	KindFloat64Histogram               Kind = "float64_histogram"
+	KindFloat64Gauge                   Kind = "float64_gauge"
+	KindFloat64ObservableCounter       Kind = "float64_observable_counter"
+	KindFloat64ObservableUpDownCounter Kind = "float64_observable_up_down_counter"
+	KindFloat64ObservableGauge         Kind = "float64_observable_gauge"
+)
+
+// Instrument is the declaration of an instrument, it is created when it is used for the first time.
+type Instrument struct {
+	// Name without the service name prefix, for example "login.success".
+	Name        string
+	Kind        Kind
+	Unit        string
+	Description string
+
+	// AttributeKeys are the only attributes recorded, the others are dropped so the cardinality stays bounded.
+	AttributeKeys []attribute.Key
+
+	// DogStatsD tells whether dd-sdk sends the same metric through DogStatsD, in the namespace DogStatsDNamespace.
+	DogStatsD bool
+}
+
+// Registry declares the instruments once, and creates them lazily with the meter of the MeterProvider.
+// They are created again when the MeterProvider changes, for example after otel.SetMeterProvider.
+type Registry struct {
+	// MeterProvider returns the provider of the instruments, by default otel.GetMeterProvider.
+	MeterProvider func() metric.MeterProvider
+
+	mu          sync.Mutex
+	instruments map[string]Instrument
+
+	provider metric.MeterProvider
+	created  map[string]any
+}
+
+// NewRegistry creates an empty Registry using the global MeterProvider.
+func NewRegistry() *Registry {
+	return &Registry{
+		MeterProvider: otel.GetMeterProvider,
+		instruments:   map[string]Instrument{},
+	}
+}
+
+// Default is the Registry of the instruments of this package.
+var Default = NewRegistry()
+
+// Declare adds the instrument, it panics when the name is already declared or when the kind is unknown,
+// the declarations are package variables.
+func (r *Registry) Declare(inst Instrument) Instrument {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.instruments[inst.Name]; ok {
+		panic(fmt.Errorf("appmetrics: instrument %q declared twice", inst.Name))
+	}
+
+	switch inst.Kind {
+	case KindInt64Counter, KindInt64UpDownCounter, KindFloat64Histogram:
+	default:
+		panic(fmt.Errorf("appmetrics: instrument %q has kind %q, it is not created by the registry", inst.Name, inst.Kind))
+	}
+
+	inst.AttributeKeys = slices.Clone(inst.AttributeKeys)
+	r.instruments[inst.Name] = inst
+	return inst
+}
+
+// Instruments lists every declared instrument sorted by name.
+func (r *Registry) Instruments() []Instrument {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]Instrument, 0, len(r.instruments))
+	for _, inst := range r.instruments {
+		list = append(list, inst)
+	}
+
+	slices.SortFunc(list, func(a, b Instrument) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+// Int64Counter returns the declared counter "<serviceName>.<inst.Name>", a noop counter when it cannot be created.
+func (r *Registry) Int64Counter(ctx context.Context, serviceName string, inst Instrument) metric.Int64Counter {
+	created, ok := r.instrument(ctx, serviceName, inst, KindInt64Counter).(metric.Int64Counter)
+	if !ok {
+		return &noop.Int64Counter{}
+	}
+	return created
+}
+
+// Int64UpDownCounter returns the declared up down counter "<serviceName>.<inst.Name>", a noop one when it cannot be created.
+func (r *Registry) Int64UpDownCounter(ctx context.Context, serviceName string, inst Instrument) metric.Int64UpDownCounter {
+	created, ok := r.instrument(ctx, serviceName, inst, KindInt64UpDownCounter).(metric.Int64UpDownCounter)
+	if !ok {
+		return &noop.Int64UpDownCounter{}
+	}
+	return created
+}
+
+// Float64Histogram returns the declared histogram "<serviceName>.<inst.Name>", a noop histogram when it cannot be created.
+func (r *Registry) Float64Histogram(ctx context.Context, serviceName string, inst Instrument) metric.Float64Histogram {
+	created, ok := r.instrument(ctx, serviceName, inst, KindFloat64Histogram).(metric.Float64Histogram)
+	if !ok {
+		return &noop.Float64Histogram{}
+	}
+	return created
+}
+
+// instrument returns the instrument created for the current MeterProvider, or creates it. Nil means it cannot be created.
+func (r *Registry) instrument(ctx context.Context, serviceName string, inst Instrument, kind Kind) any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	declared, ok := r.instruments[inst.Name]
+	if !ok || declared.Kind != kind {
+		slog.ErrorContext(ctx, "appmetrics: instrument is not declared with this kind",
+			slog.String("name", inst.Name), slog.String("kind", string(kind)))
+		return nil
+	}
+
+	provider := r.MeterProvider()
+	if !sameProvider(provider, r.provider) {
+		r.provider = provider
+		r.created = map[string]any{}
+	}
+
+	name := serviceName + "." + declared.Name
+	if created, ok := r.created[name]; ok {
+		return created
+	}
+
+	meter := provider.Meter(InstrumentationName)
+	allowed := attributeFilter(declared.AttributeKeys)
+
+	var created any
+	var err error
+	switch declared.Kind {
+	case KindInt64Counter:
+		var counter metric.Int64Counter
+		counter, err = meter.Int64Counter(name, metric.WithUnit(declared.Unit), metric.WithDescription(declared.Description))
+		created = &filteredInt64Counter{Int64Counter: counter, allowed: allowed}
+	case KindInt64UpDownCounter:
+		var counter metric.Int64UpDownCounter
+		counter, err = meter.Int64UpDownCounter(name, metric.WithUnit(declared.Unit), metric.WithDescription(declared.Description))
+		created = &filteredInt64UpDownCounter{Int64UpDownCounter: counter, allowed: allowed}
+	case KindFloat64Histogram:
+		var histogram metric.Float64Histogram
+		histogram, err = meter.Float64Histogram(name, metric.WithUnit(declared.Unit), metric.WithDescription(declared.Description))
+		created = &filteredFloat64Histogram{Float64Histogram: histogram, allowed: allowed}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create "+declared.Name+" instrument", slog.Any("error", err))
+		return nil
+	}
+
+	r.created[name] = created
+	return created
+}
+
+// sameProvider compares the providers without the panic of == on the dynamic types which are not comparable,
+// a struct provider holding a slice for example. Those are never the same, their instruments are created again.
+func sameProvider(a, b metric.MeterProvider) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	typ := reflect.TypeOf(a)
+	return typ == reflect.TypeOf(b) && typ.Comparable() && a == b
+}
+
+// attributeFilter keeps only the declared keys.
+func attributeFilter(keys []attribute.Key) attribute.Filter {
+	return func(kv attribute.KeyValue) bool {
+		return slices.Contains(keys, kv.Key)
+	}
+}
+
+type filteredInt64Counter struct {
+	metric.Int64Counter
+	allowed attribute.Filter
+}
+
+func (c *filteredInt64Counter) Add(ctx context.Context, incr int64, options ...metric.AddOption) {
+	set := metric.NewAddConfig(options).Attributes()
+	attrs, _ := set.Filter(c.allowed)
+	c.Int64Counter.Add(ctx, incr, metric.WithAttributeSet(attrs))
+}
+
+type filteredInt64UpDownCounter struct {
+	metric.Int64UpDownCounter
+	allowed attribute.Filter
+}
+
+func (c *filteredInt64UpDownCounter) Add(ctx context.Context, incr int64, options ...metric.AddOption) {
+	set := metric.NewAddConfig(options).Attributes()
+	attrs, _ := set.Filter(c.allowed)
+	c.Int64UpDownCounter.Add(ctx, incr, metric.WithAttributeSet(attrs))
+}
+
+type filteredFloat64Histogram struct {
+	metric.Float64Histogram
+	allowed attribute.Filter
+}
+
+func (h *filteredFloat64Histogram) Record(ctx context.Context, value float64, options ...metric.RecordOption) {
+	set := metric.NewRecordConfig(options).Attributes()
+	attrs, _ := set.Filter(h.allowed)
+	h.Float64Histogram.Record(ctx, value, metric.WithAttributeSet(attrs))
+}
+
+// Recorder records the instruments of one service with a Registry, its typed methods are generated from metrics.yaml.
+type Recorder struct {
+	Registry    *Registry
+	ServiceName string
+}
+
+// NewRecorder creates a Recorder of the Default registry.
+func NewRecorder(serviceName string) Recorder {
+	return Recorder{Registry: Default, ServiceName: serviceName}
+}
@@ -0,0 +1,142 @@
+package appmetrics
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// sums returns the data points of the int64 sum name.
+func sums(t *testing.T, reader *otelSdkMetric.ManualReader, name string) []metricdata.DataPoint[int64] {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data.(metricdata.Sum[int64]).DataPoints
+			}
+		}
+	}
+	return nil
+}
+
+func newTestRegistry(provider *metric.MeterProvider) (*Registry, Instrument) {
+	r := NewRegistry()
+	r.MeterProvider = func() metric.MeterProvider { return *provider }
+
+	inst := r.Declare(Instrument{
+		Name:          "login.failure",
+		Kind:          KindInt64Counter,
+		AttributeKeys: []attribute.Key{"failure_reason"},
+	})
+	return r, inst
+}
+
+func TestRegistryProviderSwap(t *testing.T) {
+	first, second := otelSdkMetric.NewManualReader(), otelSdkMetric.NewManualReader()
+	var provider metric.MeterProvider = otelSdkMetric.NewMeterProvider(otelSdkMetric.WithReader(first))
+	r, inst := newTestRegistry(&provider)
+
+	ctx := context.Background()
+	counter := r.Int64Counter(ctx, "test", inst)
+	if again := r.Int64Counter(ctx, "test", inst); again != counter {
+		t.Error("the counter is created again for the same provider")
+	}
+	counter.Add(ctx, 1)
+
+	// The counter of the new provider records into its reader only.
+	provider = otelSdkMetric.NewMeterProvider(otelSdkMetric.WithReader(second))
+	r.Int64Counter(ctx, "test", inst).Add(ctx, 2)
+
+	if points := sums(t, first, "test.login.failure"); len(points) != 1 || points[0].Value != 1 {
+		t.Errorf("first provider points = %+v, want 1", points)
+	}
+	if points := sums(t, second, "test.login.failure"); len(points) != 1 || points[0].Value != 2 {
+		t.Errorf("second provider points = %+v, want 2", points)
+	}
+}
+
+// uncomparableProvider panics when compared with ==.
+type uncomparableProvider struct {
+	noop.MeterProvider
+	options []string
+}
+
+func TestRegistryUncomparableProvider(t *testing.T) {
+	var provider metric.MeterProvider = uncomparableProvider{}
+	r, inst := newTestRegistry(&provider)
+
+	ctx := context.Background()
+	for range 2 {
+		r.Int64Counter(ctx, "test", inst).Add(ctx, 1)
+	}
+
+	provider = otelSdkMetric.NewMeterProvider(otelSdkMetric.WithReader(otelSdkMetric.NewManualReader()))
+	r.Int64Counter(ctx, "test", inst).Add(ctx, 1)
+}
+
+func TestRegistryAttributeFilter(t *testing.T) {
+	reader := otelSdkMetric.NewManualReader()
+	var provider metric.MeterProvider = otelSdkMetric.NewMeterProvider(otelSdkMetric.WithReader(reader))
+	r, inst := newTestRegistry(&provider)
+
+	ctx := context.Background()
+	r.Int64Counter(ctx, "test", inst).Add(ctx, 1, metric.WithAttributes(
+		attribute.String("failure_reason", "wrong_password"),
+		attribute.String("username", "user1"),
+	))
+
+	points := sums(t, reader, "test.login.failure")
+	if len(points) != 1 {
+		t.Fatalf("points = %+v", points)
+	}
+	if attrs := points[0].Attributes; attrs.Len() != 1 || !attrs.HasValue("failure_reason") {
+		t.Errorf("attributes = %v, want failure_reason only", attrs.ToSlice())
+	}
+
+	// An instrument used with another kind than its declaration is not created.
+	if _, ok := r.Float64Histogram(ctx, "test", inst).(*noop.Float64Histogram); !ok {
+		t.Error("the counter is created as a histogram")
+	}
+}
+
+func TestRegistryDeclare(t *testing.T) {
+	r := NewRegistry()
+	r.Declare(Instrument{Name: "token.issued", Kind: KindInt64Counter})
+	r.Declare(Instrument{Name: "login.duration", Kind: KindFloat64Histogram})
+	r.Declare(Instrument{Name: "lockout.locked", Kind: KindInt64UpDownCounter})
+
+	var names []string
+	for _, inst := range r.Instruments() {
+		names = append(names, inst.Name)
+	}
+	if want := []string{"lockout.locked", "login.duration", "token.issued"}; len(names) != 3 || names[0] != want[0] || names[1] != want[1] || names[2] != want[2] {
+		t.Errorf("Instruments() = %v, want %v", names, want)
+	}
+
+	tests := map[string]Instrument{
+		"declared twice": {Name: "token.issued", Kind: KindInt64Counter},
+		"unknown kind":   {Name: "build_info", Kind: KindInt64ObservableGauge},
+	}
+
+	for name, inst := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Declare(%+v) did not panic", inst)
+				}
+			}()
+			r.Declare(inst)
+		})
+	}
+}
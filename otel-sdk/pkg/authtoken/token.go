@@ -0,0 +1,169 @@
+// Package authtoken issues and validates the session tokens, HS256 JSON Web Tokens signed with the standard library only.
+// The revoked tokens are kept in memory until they expire.
+package authtoken
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalid = errors.New("invalid token")
+	ErrExpired = errors.New("token expired")
+	ErrRevoked = errors.New("token revoked")
+)
+
+// Claims is the payload of the token.
+type Claims struct {
+	Subject   string `json:"sub"`
+	ID        string `json:"jti"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Issuer    string `json:"iss,omitempty"`
+}
+
+// Expiry is the ExpiresAt time.
+func (c Claims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// Manager signs the tokens with the Secret, they are valid during TTL.
+type Manager struct {
+	secret []byte
+	ttl    time.Duration
+	issuer string
+	now    func() time.Time
+
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+// NewManager creates a Manager, the secret must be at least 32 bytes. The default TTL is 15 minutes.
+func NewManager(secret []byte, ttl time.Duration, issuer string) (*Manager, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("token secret must be at least 32 bytes, got %d", len(secret))
+	}
+
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+
+	return &Manager{
+		secret:  secret,
+		ttl:     ttl,
+		issuer:  issuer,
+		now:     time.Now,
+		revoked: map[string]time.Time{},
+	}, nil
+}
+
+// RandomSecret returns a new 32 bytes secret, the tokens do not survive a restart.
+func RandomSecret() []byte {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+	return secret
+}
+
+// TTL is the validity of the issued tokens.
+func (m *Manager) TTL() time.Duration {
+	return m.ttl
+}
+
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Issue signs a new token for the subject.
+func (m *Manager) Issue(subject string) (string, Claims, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", Claims{}, fmt.Errorf("generate token id: %w", err)
+	}
+
+	now := m.now()
+	claims := Claims{
+		Subject:   subject,
+		ID:        hex.EncodeToString(id),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(m.ttl).Unix(),
+		Issuer:    m.issuer,
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + m.sign(unsigned), claims, nil
+}
+
+// Validate checks the signature, the expiry and the revocation. The claims are returned with ErrExpired and ErrRevoked.
+func (m *Manager) Validate(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return Claims{}, ErrInvalid
+	}
+
+	// hmac.Equal compares in constant time.
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, m.mac(parts[0]+"."+parts[1])) {
+		return Claims{}, ErrInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalid
+	}
+
+	var claims Claims
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" || claims.ID == "" {
+		return Claims{}, ErrInvalid
+	}
+
+	if !m.now().Before(claims.Expiry()) {
+		return claims, ErrExpired
+	}
+
+	m.mu.Lock()
+	_, revoked := m.revoked[claims.ID]
+	m.mu.Unlock()
+	if revoked {
+		return claims, ErrRevoked
+	}
+
+	return claims, nil
+}
+
+// Revoke rejects the token until it expires.
+func (m *Manager) Revoke(claims Claims) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Forget the revoked tokens which expired since, they are rejected as expired anyway.
+	now := m.now()
+	for id, expiry := range m.revoked {
+		if !now.Before(expiry) {
+			delete(m.revoked, id)
+		}
+	}
+
+	m.revoked[claims.ID] = claims.Expiry()
+}
+
+func (m *Manager) mac(unsigned string) []byte {
+	h := hmac.New(sha256.New, m.secret)
+	h.Write([]byte(unsigned))
+	return h.Sum(nil)
+}
+
+func (m *Manager) sign(unsigned string) string {
+	return base64.RawURLEncoding.EncodeToString(m.mac(unsigned))
+}
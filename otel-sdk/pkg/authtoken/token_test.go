@@ -0,0 +1,134 @@
+package authtoken
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+// newTestManager returns a Manager with a clock moved by advance.
+func newTestManager(t *testing.T) (*Manager, func(time.Duration)) {
+	t.Helper()
+
+	m, err := NewManager(secret, time.Minute, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return m, func(d time.Duration) { now = now.Add(d) }
+}
+
+func issue(t *testing.T, m *Manager, subject string) (string, Claims) {
+	t.Helper()
+
+	token, claims, err := m.Issue(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, claims
+}
+
+func TestNewManager(t *testing.T) {
+	if _, err := NewManager(secret[:31], time.Minute, ""); err == nil {
+		t.Error("NewManager() accepted a 31 bytes secret")
+	}
+
+	m, err := NewManager(secret, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.TTL() != 15*time.Minute {
+		t.Errorf("TTL() = %s, want the default 15m", m.TTL())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m, _ := newTestManager(t)
+	token, claims := issue(t, m, "user1")
+
+	got, err := m.Validate(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != claims || got.Subject != "user1" || got.Issuer != "test" || got.ExpiresAt-got.IssuedAt != 60 {
+		t.Errorf("claims = %+v, want %+v", got, claims)
+	}
+
+	parts := strings.Split(token, ".")
+	other, err := NewManager([]byte(strings.Repeat("x", 32)), time.Minute, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, _ := issue(t, other, "user1")
+
+	forgedPayload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin","jti":"1","iat":0,"exp":9999999999}`))
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := map[string]string{
+		"empty":                 "",
+		"two parts":             parts[0] + "." + parts[1],
+		"signed by another key": otherToken,
+		"forged payload":        parts[0] + "." + forgedPayload + "." + parts[2],
+		"forged signature":      parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString([]byte("signature")),
+		"invalid signature":     parts[0] + "." + parts[1] + ".!",
+		"alg none":              noneHeader + "." + parts[1] + ".",
+		"alg none signed":       noneHeader + "." + parts[1] + "." + m.sign(noneHeader+"."+parts[1]),
+		"signed invalid json":   parts[0] + ".bm90IGpzb24." + m.sign(parts[0]+".bm90IGpzb24"),
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := m.Validate(token); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Validate() = %v, want %v", err, ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestValidateExpired(t *testing.T) {
+	m, advance := newTestManager(t)
+	token, _ := issue(t, m, "user1")
+
+	advance(59 * time.Second)
+	if _, err := m.Validate(token); err != nil {
+		t.Fatalf("Validate() = %v before the expiry", err)
+	}
+
+	// The claims are returned with the error, to log the subject of the rejected token.
+	advance(time.Second)
+	if claims, err := m.Validate(token); !errors.Is(err, ErrExpired) || claims.Subject != "user1" {
+		t.Errorf("Validate() = %+v, %v at the expiry, want %v", claims, err, ErrExpired)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	m, advance := newTestManager(t)
+	token, claims := issue(t, m, "user1")
+	otherToken, _ := issue(t, m, "user1")
+
+	m.Revoke(claims)
+	if _, err := m.Validate(token); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Validate() = %v after the revocation, want %v", err, ErrRevoked)
+	}
+	if _, err := m.Validate(otherToken); err != nil {
+		t.Errorf("Validate() of another token of the subject = %v", err)
+	}
+
+	// The revoked tokens which expired are forgotten by the next revocation.
+	advance(time.Minute)
+	_, later := issue(t, m, "user2")
+	m.Revoke(later)
+
+	if _, ok := m.revoked[claims.ID]; ok || len(m.revoked) != 1 {
+		t.Errorf("revoked = %v, want the expired token forgotten", m.revoked)
+	}
+	if _, err := m.Validate(token); !errors.Is(err, ErrExpired) {
+		t.Errorf("Validate() = %v of the forgotten token, want %v", err, ErrExpired)
+	}
+}
@@ -0,0 +1,135 @@
+// Package buildinfo reports the version, commit and build date of the binary, so every span and metric
+// can be traced back to a commit. The values are injected at build time:
+//
+//	go build -ldflags "-X github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/buildinfo.Version=v1.2.3 \
+//	  -X github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/buildinfo.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// When they are not injected, the VCS information recorded by the Go toolchain is used.
+package buildinfo
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/buildinfo"
+
+// Set with -ldflags "-X ...", see the package documentation.
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+
+	// Modified is true when the binary was built from a working tree with uncommitted changes.
+	Modified bool `json:"modified"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build information, the ldflags values take precedence over debug.ReadBuildInfo.
+// Without ldflags, the build date is the commit time recorded by the toolchain.
+// Unknown values are "unknown", except the version which defaults to "dev".
+func Get() Info {
+	once.Do(func() {
+		info = Info{
+			Version:   Version,
+			Commit:    Commit,
+			BuildDate: BuildDate,
+			GoVersion: runtime.Version(),
+		}
+
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+				info.Version = bi.Main.Version
+			}
+
+			for _, setting := range bi.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = setting.Value
+					}
+				case "vcs.time":
+					if info.BuildDate == "" {
+						info.BuildDate = setting.Value
+					}
+				case "vcs.modified":
+					info.Modified = setting.Value == "true"
+				}
+			}
+		}
+
+		if info.Version == "" {
+			info.Version = "dev"
+		}
+
+		if info.Commit == "" {
+			info.Commit = "unknown"
+		}
+
+		if info.BuildDate == "" {
+			info.BuildDate = "unknown"
+		}
+	})
+
+	return info
+}
+
+// Handler responds the build information as JSON, for example at "/version".
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(Get()); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write build info", slog.Any("error", err))
+		}
+	})
+}
+
+// RegisterMetrics reports the "<metricPrefix>.build_info" gauge, always 1, with the build information as attributes,
+// similar to the Prometheus target_info. Join on it to get the version of any other series.
+func RegisterMetrics(metricPrefix string) error {
+	meter := otel.Meter(instrumentationName)
+
+	buildInfo, err := meter.Int64ObservableGauge(metricPrefix+".build_info",
+		metric.WithDescription("Always 1, the attributes describe the running binary."),
+	)
+	if err != nil {
+		return err
+	}
+
+	i := Get()
+	attrs := metric.WithAttributes(
+		attribute.String("version", i.Version),
+		attribute.String("commit", i.Commit),
+		attribute.String("build_date", i.BuildDate),
+		attribute.String("go_version", i.GoVersion),
+	)
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(buildInfo, 1, attrs)
+		return nil
+	}, buildInfo)
+
+	return err
+}
@@ -0,0 +1,204 @@
+package chaos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/chaos"
+
+// Injector holds the rules, they can be replaced at runtime with the AdminHandler.
+type Injector struct {
+	// Skip excludes requests from any rule, for example the probes and the admin endpoint itself.
+	Skip func(r *http.Request) bool
+
+	mu       sync.RWMutex
+	rules    []Rule
+	injected metric.Int64Counter
+	rng      *rand.Rand
+}
+
+// New creates the Injector, the faults are counted as "<metricPrefix>.chaos.injected" by fault and path.
+func New(metricPrefix string, rules []Rule) *Injector {
+	injected, err := otel.Meter(instrumentationName).Int64Counter(metricPrefix+".chaos.injected",
+		metric.WithDescription("Number of faults injected by the chaos middleware."),
+	)
+	if err != nil {
+		slog.Error("failed to create chaos.injected counter", slog.Any("error", err))
+		injected = &noop.Int64Counter{}
+	}
+
+	return &Injector{rules: rules, injected: injected, rng: rand.New(globalSource{})}
+}
+
+// Rules returns the current rules.
+func (i *Injector) Rules() []Rule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return append([]Rule{}, i.rules...)
+}
+
+// SetRules replaces the rules, nil disables the injection.
+func (i *Injector) SetRules(rules []Rule) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rules = rules
+}
+
+func (i *Injector) find(r *http.Request) *Rule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for idx := range i.rules {
+		if i.rules[idx].match(r) {
+			rule := i.rules[idx]
+			return &rule
+		}
+	}
+	return nil
+}
+
+// Middleware applies the first matching rule. It must be installed after the tracing middleware to record the faults on the server span.
+func (i *Injector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if i.Skip != nil && i.Skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rule := i.find(r)
+		if rule == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if draw(i.rng, rule.DropRate) {
+			i.record(r, FaultDrop)
+			drop(w)
+			return
+		}
+
+		if draw(i.rng, rule.PanicRate) {
+			i.record(r, FaultPanic)
+			panic(fmt.Sprintf("chaos: injected panic on %s %s", r.Method, r.URL.Path))
+		}
+
+		if rule.Latency != nil && draw(i.rng, rule.Latency.Rate) {
+			delay := rule.Latency.sample(i.rng)
+			i.record(r, FaultLatency, attribute.Int64("chaos.latency_ms", delay.Milliseconds()))
+
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if rule.Error != nil && draw(i.rng, rule.Error.Rate) {
+			status := rule.Error.status(i.rng)
+			i.record(r, FaultError, attribute.Int("chaos.status_code", status))
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = fmt.Fprintf(w, `{"error":"chaos: injected %d"}`+"\n", status)
+			return
+		}
+
+		if rule.SlowBody != nil && draw(i.rng, rule.SlowBody.Rate) {
+			i.record(r, FaultSlowBody, attribute.Int64("chaos.read_delay_ms", time.Duration(rule.SlowBody.Delay).Milliseconds()))
+			r.Body = &slowReader{ReadCloser: r.Body, delay: time.Duration(rule.SlowBody.Delay)}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// record marks the server span with chaos.injected=true, adds a "chaos.injected" event with the details, and counts the fault.
+func (i *Injector) record(r *http.Request, fault string, attrs ...attribute.KeyValue) {
+	ctx := r.Context()
+
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Bool("chaos.injected", true), attribute.String("chaos.fault", fault))
+	span.AddEvent("chaos.injected", trace.WithAttributes(append(attrs, attribute.String("chaos.fault", fault))...))
+
+	i.injected.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("fault", fault),
+		attribute.String("path", r.URL.Path),
+	))
+
+	slog.WarnContext(ctx, "chaos fault injected", slog.String("fault", fault), slog.String("path", r.URL.Path))
+}
+
+// drop closes the connection without response. http.ErrAbortHandler also aborts HTTP/2 streams which cannot be hijacked.
+func drop(w http.ResponseWriter) {
+	if hijacker, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hijacker.Hijack(); err == nil {
+			_ = conn.Close()
+			return
+		}
+	}
+
+	panic(http.ErrAbortHandler)
+}
+
+type slowReader struct {
+	io.ReadCloser
+	delay time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.ReadCloser.Read(p)
+}
+
+// AdminHandler serves the rules: GET returns them, PUT replaces them with a JSON array, DELETE removes them.
+func (i *Injector) AdminHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rules, err := ParseRules(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		i.SetRules(rules)
+		slog.WarnContext(r.Context(), "chaos rules replaced", slog.Int("rules", len(rules)))
+
+	case http.MethodDelete:
+		i.SetRules(nil)
+		slog.WarnContext(r.Context(), "chaos rules removed")
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(i.Rules()); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write chaos rules", slog.Any("error", err))
+	}
+}
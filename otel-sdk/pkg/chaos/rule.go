@@ -0,0 +1,199 @@
+// Package chaos injects faults into the HTTP requests: latency, error statuses, panics, slow body reads and dropped connections.
+// Every injected fault is recorded on the server span and counted, to validate the alerting rules and the dashboards.
+package chaos
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	FaultLatency  = "latency"
+	FaultError    = "error"
+	FaultPanic    = "panic"
+	FaultSlowBody = "slow_body"
+	FaultDrop     = "drop"
+)
+
+const (
+	DistributionFixed       = "fixed"
+	DistributionUniform     = "uniform"
+	DistributionNormal      = "normal"
+	DistributionExponential = "exponential"
+)
+
+// Duration is a time.Duration written as "100ms" in the rules.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// Rule is the faults of the requests matching Method and Path. Only the first matching rule applies.
+// The rates are probabilities between 0 and 1, every fault is drawn independently.
+type Rule struct {
+	// Method is empty to match any method.
+	Method string `json:"method,omitempty"`
+
+	// Path is an exact path like "/login", a prefix like "/api/*", or "*" for any path.
+	Path string `json:"path"`
+
+	Latency   *Latency  `json:"latency,omitempty"`
+	Error     *Error    `json:"error,omitempty"`
+	SlowBody  *SlowBody `json:"slow_body,omitempty"`
+	PanicRate float64   `json:"panic_rate,omitempty"`
+	DropRate  float64   `json:"drop_rate,omitempty"`
+}
+
+// Latency delays the request before the handler runs.
+type Latency struct {
+	Rate float64 `json:"rate"`
+
+	// Distribution is fixed (Mean), uniform (between Min and Max), normal (Mean and StdDev)
+	// or exponential (Min plus an exponential delay of mean Mean). Max caps the delay when set.
+	Distribution string   `json:"distribution"`
+	Min          Duration `json:"min,omitempty"`
+	Max          Duration `json:"max,omitempty"`
+	Mean         Duration `json:"mean,omitempty"`
+	StdDev       Duration `json:"stddev,omitempty"`
+}
+
+// Error responds one of Statuses, picked randomly, instead of calling the handler.
+type Error struct {
+	Rate     float64 `json:"rate"`
+	Statuses []int   `json:"statuses"`
+}
+
+// SlowBody delays every read of the request body, the handler sees a slow client.
+type SlowBody struct {
+	Rate  float64  `json:"rate"`
+	Delay Duration `json:"delay"`
+}
+
+// ParseRules decodes and validates a JSON array of rules.
+func ParseRules(data []byte) ([]Rule, error) {
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("decode chaos rules: %w", err)
+	}
+
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("chaos rule %d: %w", i, err)
+		}
+	}
+
+	return rules, nil
+}
+
+func (r *Rule) validate() error {
+	if r.Path == "" {
+		return fmt.Errorf("path is required, use * for any path")
+	}
+
+	rates := []float64{r.PanicRate, r.DropRate}
+	if r.Latency != nil {
+		rates = append(rates, r.Latency.Rate)
+
+		switch r.Latency.Distribution {
+		case "":
+			r.Latency.Distribution = DistributionFixed
+		case DistributionFixed, DistributionUniform, DistributionNormal, DistributionExponential:
+		default:
+			return fmt.Errorf("unknown latency distribution %q", r.Latency.Distribution)
+		}
+	}
+
+	if r.Error != nil {
+		rates = append(rates, r.Error.Rate)
+
+		if len(r.Error.Statuses) == 0 {
+			r.Error.Statuses = []int{http.StatusInternalServerError}
+		}
+		for _, status := range r.Error.Statuses {
+			if status < 400 || status > 599 {
+				return fmt.Errorf("error status %d must be between 400 and 599", status)
+			}
+		}
+	}
+
+	if r.SlowBody != nil {
+		rates = append(rates, r.SlowBody.Rate)
+	}
+
+	for _, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("rate %v must be between 0 and 1", rate)
+		}
+	}
+
+	return nil
+}
+
+func (r *Rule) match(req *http.Request) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return false
+	}
+
+	switch {
+	case r.Path == "*":
+		return true
+	case strings.HasSuffix(r.Path, "*"):
+		return strings.HasPrefix(req.URL.Path, strings.TrimSuffix(r.Path, "*"))
+	default:
+		return req.URL.Path == r.Path
+	}
+}
+
+// globalSource draws from the global source of math/rand/v2, safe for concurrent use unlike the seeded sources of the tests.
+type globalSource struct{}
+
+func (globalSource) Uint64() uint64 { return rand.Uint64() }
+
+func draw(rng *rand.Rand, rate float64) bool {
+	return rate > 0 && rng.Float64() < rate
+}
+
+// sample draws one delay of the distribution, never negative.
+func (l *Latency) sample(rng *rand.Rand) time.Duration {
+	var d float64
+	switch l.Distribution {
+	case DistributionUniform:
+		d = float64(l.Min) + rng.Float64()*float64(l.Max-l.Min)
+	case DistributionNormal:
+		d = float64(l.Mean) + rng.NormFloat64()*float64(l.StdDev)
+	case DistributionExponential:
+		d = float64(l.Min) + rng.ExpFloat64()*float64(l.Mean)
+	default:
+		d = float64(l.Mean)
+	}
+
+	if l.Max > 0 {
+		d = math.Min(d, float64(l.Max))
+	}
+	return time.Duration(math.Max(d, 0))
+}
+
+func (e *Error) status(rng *rand.Rand) int {
+	return e.Statuses[rng.IntN(len(e.Statuses))]
+}
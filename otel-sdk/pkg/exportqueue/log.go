@@ -0,0 +1,48 @@
+package exportqueue
+
+import (
+	"context"
+	"fmt"
+
+	otelSdkLog "go.opentelemetry.io/otel/sdk/log"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/otlpconv"
+)
+
+// LogExporter is a log exporter which writes the log records into the Queue instead of sending them directly.
+type LogExporter struct {
+	queue *Queue
+}
+
+var _ otelSdkLog.Exporter = (*LogExporter)(nil)
+
+// NewLogExporter creates a log exporter backed by the Queue and starts the delivery loop.
+func NewLogExporter(queue *Queue) *LogExporter {
+	queue.Start()
+	return &LogExporter{queue: queue}
+}
+
+// Export serializes the log records as an OTLP export request and appends it to the Queue.
+func (e *LogExporter) Export(_ context.Context, records []otelSdkLog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	body, err := proto.Marshal(otlpconv.LogsRequest(records))
+	if err != nil {
+		return fmt.Errorf("marshal otlp logs: %w", err)
+	}
+
+	return e.queue.Enqueue(body)
+}
+
+// ForceFlush is a no-op, the data is already on disk once Export returns.
+func (e *LogExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+// Shutdown stops the delivery loop of the Queue, undelivered log records stay on disk.
+func (e *LogExporter) Shutdown(ctx context.Context) error {
+	return e.queue.Shutdown(ctx)
+}
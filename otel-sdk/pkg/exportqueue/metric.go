@@ -0,0 +1,62 @@
+package exportqueue
+
+import (
+	"context"
+	"fmt"
+
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/otlpconv"
+)
+
+// MetricExporter is a metric exporter which writes the metrics into the Queue instead of sending them directly.
+type MetricExporter struct {
+	queue *Queue
+}
+
+var _ otelSdkMetric.Exporter = (*MetricExporter)(nil)
+
+// NewMetricExporter creates a metric exporter backed by the Queue and starts the delivery loop.
+func NewMetricExporter(queue *Queue) *MetricExporter {
+	queue.Start()
+	return &MetricExporter{queue: queue}
+}
+
+// Temporality uses the same default as the OTLP exporter (cumulative).
+func (e *MetricExporter) Temporality(kind otelSdkMetric.InstrumentKind) metricdata.Temporality {
+	return otelSdkMetric.DefaultTemporalitySelector(kind)
+}
+
+// Aggregation uses the same default as the OTLP exporter.
+func (e *MetricExporter) Aggregation(kind otelSdkMetric.InstrumentKind) otelSdkMetric.Aggregation {
+	return otelSdkMetric.DefaultAggregationSelector(kind)
+}
+
+// Export serializes the metrics as an OTLP export request and appends it to the Queue.
+func (e *MetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	req, convErr := otlpconv.MetricsRequest(rm)
+
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal otlp metrics: %w", err)
+	}
+
+	if err = e.queue.Enqueue(body); err != nil {
+		return err
+	}
+
+	// Partially converted data is still queued, but the SDK should know something was skipped.
+	return convErr
+}
+
+// ForceFlush is a no-op, the data is already on disk once Export returns.
+func (e *MetricExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+// Shutdown stops the delivery loop of the Queue, undelivered metrics stay on disk.
+func (e *MetricExporter) Shutdown(ctx context.Context) error {
+	return e.queue.Shutdown(ctx)
+}
@@ -0,0 +1,59 @@
+package exportqueue
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/exportqueue"
+
+// registerMetrics exposes the queue Stats as observable instruments on the global MeterProvider.
+func registerMetrics(q *Queue) error {
+	meter := otel.Meter(instrumentationName)
+	prefix := q.cfg.MetricPrefix + ".export_queue"
+
+	batches, err := meter.Int64ObservableGauge(prefix+".batches",
+		metric.WithDescription("Number of OTLP batches waiting in the on-disk export queue."),
+	)
+	if err != nil {
+		return err
+	}
+
+	size, err := meter.Int64ObservableGauge(prefix+".size",
+		metric.WithDescription("Size of the OTLP batches waiting in the on-disk export queue."),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return err
+	}
+
+	oldestAge, err := meter.Float64ObservableGauge(prefix+".oldest_age",
+		metric.WithDescription("Age of the oldest OTLP batch waiting in the on-disk export queue."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+
+	dropped, err := meter.Int64ObservableCounter(prefix+".dropped",
+		metric.WithDescription("Number of OTLP batches dropped because the queue is full or the endpoint rejected them."),
+	)
+	if err != nil {
+		return err
+	}
+
+	attrs := metric.WithAttributes(attribute.String("signal", q.cfg.Signal))
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := q.Stats()
+		o.ObserveInt64(batches, int64(stats.Batches), attrs)
+		o.ObserveInt64(size, stats.Bytes, attrs)
+		o.ObserveFloat64(oldestAge, stats.OldestAge.Seconds(), attrs)
+		o.ObserveInt64(dropped, stats.Dropped, attrs)
+		return nil
+	}, batches, size, oldestAge, dropped)
+
+	return err
+}
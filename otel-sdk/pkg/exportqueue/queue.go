@@ -0,0 +1,435 @@
+// Package exportqueue is a write-ahead queue that spools serialized OTLP batches to disk
+// before they are sent, so telemetry survives collector outages and process restarts.
+package exportqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	batchFileExt = ".otlp"
+	tmpFileExt   = ".tmp"
+)
+
+// Sender delivers one serialized OTLP export request.
+// An error which implements `Temporary() bool` returning false is permanent, and the batch is dropped.
+// Any other error is retried with backoff.
+type Sender interface {
+	Send(ctx context.Context, body []byte) error
+}
+
+// Config is the configuration of a Queue.
+type Config struct {
+	// Dir is the directory for this queue, each signal must use its own directory.
+	Dir string
+
+	// Signal is the name used in logs and metric attributes, for example "traces".
+	Signal string
+
+	// MaxBytes bounds the size of the spooled batches, the oldest batches are dropped first.
+	// Default is 64 MiB.
+	MaxBytes int64
+
+	// InitialBackoff is the wait time after the first failed delivery, default is 1 second.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the upper bound of the wait time between delivery attempts, default is 1 minute.
+	MaxBackoff time.Duration
+
+	// MetricPrefix is prepended to the queue metric names, usually the service name.
+	MetricPrefix string
+}
+
+// Stats is the current state of a Queue.
+type Stats struct {
+	Batches   int
+	Bytes     int64
+	OldestAge time.Duration
+	Dropped   int64
+}
+
+type batch struct {
+	seq     uint64
+	size    int64
+	created time.Time
+}
+
+// Queue stores OTLP batches as one file per batch, named by a monotonically increasing sequence number,
+// and replays them in order to the Sender.
+type Queue struct {
+	cfg    Config
+	sender Sender
+
+	mu      sync.Mutex
+	batches []batch
+	bytes   int64
+	dropped int64
+	nextSeq uint64
+
+	notify    chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// Open creates the queue directory if needed and loads the batches left by the previous process.
+func Open(cfg Config, sender Sender) (*Queue, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("export queue directory is required")
+	}
+
+	if sender == nil {
+		return nil, fmt.Errorf("export queue sender is required")
+	}
+
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 64 << 20
+	}
+
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = time.Second
+	}
+
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = max(time.Minute, cfg.InitialBackoff)
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create export queue directory %s: %w", cfg.Dir, err)
+	}
+
+	q := &Queue{
+		cfg:     cfg,
+		sender:  sender,
+		nextSeq: 1,
+		notify:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+
+	if err := registerMetrics(q); err != nil {
+		slog.Error("failed to register export queue metrics", slog.String("signal", cfg.Signal), slog.Any("error", err))
+	}
+
+	return q, nil
+}
+
+// load scans the directory for batches written before a restart.
+func (q *Queue) load() error {
+	entries, err := os.ReadDir(q.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("read export queue directory %s: %w", q.cfg.Dir, err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+
+		// Incomplete write from a crashed process.
+		if strings.HasSuffix(name, tmpFileExt) {
+			_ = os.Remove(filepath.Join(q.cfg.Dir, name))
+			continue
+		}
+
+		if !strings.HasSuffix(name, batchFileExt) {
+			continue
+		}
+
+		seq, _err := strconv.ParseUint(strings.TrimSuffix(name, batchFileExt), 10, 64)
+		if _err != nil {
+			continue
+		}
+
+		info, _err := entry.Info()
+		if _err != nil {
+			continue
+		}
+
+		q.batches = append(q.batches, batch{seq: seq, size: info.Size(), created: info.ModTime()})
+		q.bytes += info.Size()
+		q.nextSeq = max(q.nextSeq, seq+1)
+	}
+
+	sort.Slice(q.batches, func(i, j int) bool {
+		return q.batches[i].seq < q.batches[j].seq
+	})
+
+	if len(q.batches) > 0 {
+		slog.Info("export queue restored pending batches",
+			slog.String("signal", q.cfg.Signal),
+			slog.Int("batches", len(q.batches)),
+			slog.Int64("bytes", q.bytes),
+		)
+	}
+
+	return nil
+}
+
+// Enqueue persists the body and wakes up the delivery loop.
+// It only fails when the batch cannot be written to disk.
+func (q *Queue) Enqueue(body []byte) error {
+	size := int64(len(body))
+	if size > q.cfg.MaxBytes {
+		q.mu.Lock()
+		q.dropped++
+		q.mu.Unlock()
+		return fmt.Errorf("export queue %s: batch of %d bytes exceeds the queue limit %d bytes", q.cfg.Signal, size, q.cfg.MaxBytes)
+	}
+
+	// The sequence is reserved under the lock, and the file written outside it, so the exports do not wait for the disk of each other.
+	q.mu.Lock()
+	seq := q.nextSeq
+	q.nextSeq++
+	q.mu.Unlock()
+
+	if err := writeFileSync(q.path(seq), body); err != nil {
+		return fmt.Errorf("export queue %s: %w", q.cfg.Signal, err)
+	}
+
+	q.mu.Lock()
+	// A concurrent Enqueue may have appended a later sequence first, the batches stay ordered by sequence.
+	i := sort.Search(len(q.batches), func(i int) bool { return q.batches[i].seq > seq })
+	q.batches = slices.Insert(q.batches, i, batch{seq: seq, size: size, created: time.Now()})
+	q.bytes += size
+
+	// Bound the disk usage by dropping the oldest batches.
+	for q.bytes > q.cfg.MaxBytes && len(q.batches) > 1 {
+		oldest := q.batches[0]
+		q.batches = q.batches[1:]
+		q.bytes -= oldest.size
+		q.dropped++
+		_ = os.Remove(q.path(oldest.seq))
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Start runs the delivery loop in background. Calling it more than once has no effect.
+func (q *Queue) Start() {
+	q.startOnce.Do(func() {
+		go q.run()
+	})
+}
+
+// Shutdown stops the delivery loop. Batches which are not delivered yet stay on disk
+// and will be replayed by the next process using the same directory.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.stopOnce.Do(func() {
+		close(q.stop)
+	})
+
+	// Start was never called, nothing to wait.
+	q.startOnce.Do(func() {
+		close(q.done)
+	})
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the current state of the queue.
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := Stats{
+		Batches: len(q.batches),
+		Bytes:   q.bytes,
+		Dropped: q.dropped,
+	}
+
+	if len(q.batches) > 0 {
+		stats.OldestAge = time.Since(q.batches[0].created)
+	}
+
+	return stats
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+
+	// The context is canceled on Shutdown, so an in-flight request does not block the process exit.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-q.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	backoff := q.cfg.InitialBackoff
+	for {
+		next, ok := q.oldest()
+		if !ok {
+			select {
+			case <-q.notify:
+				continue
+			case <-q.stop:
+				return
+			}
+		}
+
+		err := q.deliver(ctx, next)
+		if err == nil {
+			backoff = q.cfg.InitialBackoff
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := backoff
+		var statusErr interface{ Temporary() bool }
+		var serverBackoff interface{ Backoff() time.Duration }
+		if errors.As(err, &serverBackoff) && serverBackoff.Backoff() > 0 {
+			wait = serverBackoff.Backoff()
+		}
+
+		switch {
+		case errors.As(err, &statusErr) && !statusErr.Temporary():
+			slog.Error("export queue dropped a batch rejected by the endpoint",
+				slog.String("signal", q.cfg.Signal),
+				slog.Uint64("seq", next.seq),
+				slog.Any("error", err),
+			)
+			q.remove(next, true)
+			continue
+
+		default:
+			slog.Warn("export queue delivery failed, will retry",
+				slog.String("signal", q.cfg.Signal),
+				slog.Uint64("seq", next.seq),
+				slog.Duration("backoff", wait),
+				slog.Any("error", err),
+			)
+		}
+
+		backoff = min(backoff*2, q.cfg.MaxBackoff)
+		select {
+		case <-time.After(wait):
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+func (q *Queue) deliver(ctx context.Context, b batch) error {
+	body, err := os.ReadFile(q.path(b.seq))
+	if errors.Is(err, os.ErrNotExist) {
+		// Already dropped to make room for newer batches.
+		q.remove(b, false)
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("read batch %d: %w", b.seq, err)
+	}
+
+	if err = q.sender.Send(ctx, body); err != nil {
+		return err
+	}
+
+	q.remove(b, false)
+	return nil
+}
+
+func (q *Queue) oldest() (batch, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.batches) == 0 {
+		return batch{}, false
+	}
+
+	return q.batches[0], true
+}
+
+func (q *Queue) remove(b batch, dropped bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.batches {
+		if q.batches[i].seq != b.seq {
+			continue
+		}
+
+		q.batches = append(q.batches[:i], q.batches[i+1:]...)
+		q.bytes -= b.size
+		if dropped {
+			q.dropped++
+		}
+		break
+	}
+
+	if err := os.Remove(q.path(b.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("export queue failed to remove batch file", slog.String("signal", q.cfg.Signal), slog.Any("error", err))
+	}
+}
+
+func (q *Queue) path(seq uint64) string {
+	return filepath.Join(q.cfg.Dir, fmt.Sprintf("%020d%s", seq, batchFileExt))
+}
+
+// writeFileSync writes to a temporary file first, so a crash never leaves a partial batch behind.
+func writeFileSync(path string, body []byte) error {
+	tmp := path + tmpFileExt
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create batch file: %w", err)
+	}
+
+	if _, err = f.Write(body); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("write batch file: %w", err)
+	}
+
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("sync batch file: %w", err)
+	}
+
+	if err = f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("close batch file: %w", err)
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("commit batch file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package exportqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSender records the delivered bodies, and fails the bodies of errs once.
+type fakeSender struct {
+	mu        sync.Mutex
+	errs      map[string]error
+	delivered []string
+	received  chan string
+}
+
+func newFakeSender() *fakeSender {
+	return &fakeSender{errs: map[string]error{}, received: make(chan string, 100)}
+}
+
+func (s *fakeSender) Send(_ context.Context, body []byte) error {
+	s.mu.Lock()
+	err, ok := s.errs[string(body)]
+	delete(s.errs, string(body))
+	if !ok {
+		s.delivered = append(s.delivered, string(body))
+	}
+	s.mu.Unlock()
+
+	s.received <- string(body)
+	return err
+}
+
+func (s *fakeSender) Delivered() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.delivered...)
+}
+
+// permanentError is rejected by the endpoint, like a 400 Bad Request.
+type permanentError struct{}
+
+func (permanentError) Error() string   { return "bad request" }
+func (permanentError) Temporary() bool { return false }
+
+func openQueue(t *testing.T, cfg Config, sender Sender) *Queue {
+	t.Helper()
+
+	if cfg.Dir == "" {
+		cfg.Dir = t.TempDir()
+	}
+	cfg.Signal = "traces"
+	cfg.InitialBackoff = time.Millisecond
+
+	q, err := Open(cfg, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = q.Shutdown(context.Background()) })
+	return q
+}
+
+// waitDelivered waits until the sender has received n bodies, delivered or failed.
+func waitDelivered(t *testing.T, sender *fakeSender, n int) {
+	t.Helper()
+
+	for range n {
+		select {
+		case <-sender.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the queue did not deliver %d batches in time, delivered %v", n, sender.Delivered())
+		}
+	}
+}
+
+// waitEmpty waits until the delivered batches are removed from the queue.
+func waitEmpty(t *testing.T, q *Queue) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for q.Stats().Batches != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("the queue is not empty: %+v", q.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func enqueue(t *testing.T, q *Queue, bodies ...string) {
+	t.Helper()
+
+	for _, body := range bodies {
+		if err := q.Enqueue([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQueueDeliversInOrder(t *testing.T) {
+	sender := newFakeSender()
+	q := openQueue(t, Config{}, sender)
+
+	// The batches enqueued concurrently are delivered in the order of their sequence.
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			enqueue(t, q, fmt.Sprintf("batch %02d", i))
+		}()
+	}
+	wg.Wait()
+
+	sequences := make([]uint64, 0, len(q.batches))
+	for _, b := range q.batches {
+		sequences = append(sequences, b.seq)
+	}
+	for i := 1; i < len(sequences); i++ {
+		if sequences[i-1] >= sequences[i] {
+			t.Fatalf("batches not ordered by sequence: %v", sequences)
+		}
+	}
+
+	q.Start()
+	waitDelivered(t, sender, 20)
+	waitEmpty(t, q)
+
+	if stats := q.Stats(); stats.Batches != 0 || stats.Bytes != 0 || stats.Dropped != 0 {
+		t.Errorf("stats = %+v, want an empty queue", stats)
+	}
+	if entries, _ := os.ReadDir(q.cfg.Dir); len(entries) != 0 {
+		t.Errorf("%d files left after the delivery", len(entries))
+	}
+}
+
+func TestQueueReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	// The first process cannot deliver, its batches stay on disk.
+	first := openQueue(t, Config{Dir: dir}, newFakeSender())
+	enqueue(t, first, "one", "two", "three")
+	if err := first.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A batch left half written by a crash is removed.
+	if err := os.WriteFile(first.path(99)+tmpFileExt, []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := newFakeSender()
+	second := openQueue(t, Config{Dir: dir}, sender)
+	if stats := second.Stats(); stats.Batches != 3 || stats.Bytes != int64(len("onetwothree")) {
+		t.Fatalf("restored stats = %+v, want 3 batches", stats)
+	}
+
+	// The new batches follow the restored ones.
+	enqueue(t, second, "four")
+	second.Start()
+	waitDelivered(t, sender, 4)
+
+	if got := strings.Join(sender.Delivered(), ","); got != "one,two,three,four" {
+		t.Errorf("delivered %s, want one,two,three,four", got)
+	}
+	if _, err := os.Stat(first.path(99) + tmpFileExt); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the partial batch is not removed: %v", err)
+	}
+}
+
+func TestQueueMaxBytes(t *testing.T) {
+	sender := newFakeSender()
+	q := openQueue(t, Config{MaxBytes: 10}, sender)
+
+	// The oldest batches are dropped to keep 10 bytes.
+	enqueue(t, q, "aaaa", "bbbb", "cccc")
+	if stats := q.Stats(); stats.Batches != 2 || stats.Bytes != 8 || stats.Dropped != 1 {
+		t.Errorf("stats = %+v, want 2 batches of 8 bytes and 1 dropped", stats)
+	}
+
+	// A batch larger than the queue is rejected, the queue is kept.
+	if err := q.Enqueue([]byte("larger than ten bytes")); err == nil {
+		t.Error("the oversize batch is accepted")
+	}
+	if stats := q.Stats(); stats.Batches != 2 || stats.Dropped != 2 {
+		t.Errorf("stats = %+v, want 2 batches and 2 dropped", stats)
+	}
+
+	q.Start()
+	waitDelivered(t, sender, 2)
+
+	if got := strings.Join(sender.Delivered(), ","); got != "bbbb,cccc" {
+		t.Errorf("delivered %s, want bbbb,cccc", got)
+	}
+}
+
+func TestQueueRetryAndDrop(t *testing.T) {
+	sender := newFakeSender()
+	sender.errs["temporary"] = errors.New("connection refused")
+	sender.errs["permanent"] = fmt.Errorf("export: %w", permanentError{})
+
+	q := openQueue(t, Config{}, sender)
+	enqueue(t, q, "temporary", "permanent", "last")
+	q.Start()
+
+	// temporary is retried, permanent is dropped, last is delivered.
+	waitDelivered(t, sender, 4)
+	waitEmpty(t, q)
+
+	if got := strings.Join(sender.Delivered(), ","); got != "temporary,last" {
+		t.Errorf("delivered %s, want temporary,last", got)
+	}
+	if stats := q.Stats(); stats.Batches != 0 || stats.Dropped != 1 {
+		t.Errorf("stats = %+v, want 1 dropped", stats)
+	}
+}
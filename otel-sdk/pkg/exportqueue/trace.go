@@ -0,0 +1,51 @@
+package exportqueue
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// TraceClient is an otlptrace.Client which writes the spans into the Queue instead of sending them directly.
+// Use it with otlptrace.New to get a regular span exporter.
+type TraceClient struct {
+	queue *Queue
+}
+
+var _ otlptrace.Client = (*TraceClient)(nil)
+
+// NewTraceClient creates an otlptrace.Client backed by the Queue.
+func NewTraceClient(queue *Queue) *TraceClient {
+	return &TraceClient{queue: queue}
+}
+
+// Start starts the delivery loop of the Queue.
+func (c *TraceClient) Start(context.Context) error {
+	c.queue.Start()
+	return nil
+}
+
+// Stop stops the delivery loop of the Queue, undelivered spans stay on disk.
+func (c *TraceClient) Stop(ctx context.Context) error {
+	return c.queue.Shutdown(ctx)
+}
+
+// UploadTraces serializes the spans as an OTLP export request and appends it to the Queue.
+func (c *TraceClient) UploadTraces(_ context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	if len(protoSpans) == 0 {
+		return nil
+	}
+
+	body, err := proto.Marshal(&collectortracepb.ExportTraceServiceRequest{
+		ResourceSpans: protoSpans,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal otlp traces: %w", err)
+	}
+
+	return c.queue.Enqueue(body)
+}
@@ -0,0 +1,255 @@
+// Package failover wraps a primary exporter and ordered fallbacks, for example OTLP then stdout.
+// Every exporter has its own circuit breaker: after N consecutive failures the exporter is skipped,
+// and it is probed again periodically to recover.
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// Config is the circuit breaker configuration shared by every exporter in a chain.
+type Config struct {
+	// Signal is the name used in the health status and metrics, for example "traces".
+	Signal string
+
+	// FailureThreshold is the number of consecutive failures before the exporter is skipped, default is 3.
+	FailureThreshold int
+
+	// ProbeInterval is the wait time before an exporter with an open circuit is tried again, default is 30 seconds.
+	ProbeInterval time.Duration
+}
+
+// Target is one exporter in the chain.
+type Target[E any] struct {
+	Name     string
+	Exporter E
+}
+
+// TargetStatus is the health of one exporter in the chain.
+type TargetStatus struct {
+	Name                string    `json:"name"`
+	State               string    `json:"state"`
+	Active              bool      `json:"active"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastFailure         time.Time `json:"last_failure,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+}
+
+// MarshalJSON omits the zero timestamps.
+func (s TargetStatus) MarshalJSON() ([]byte, error) {
+	type alias TargetStatus
+	return json.Marshal(struct {
+		alias
+		LastFailure *time.Time `json:"last_failure,omitempty"`
+		LastSuccess *time.Time `json:"last_success,omitempty"`
+	}{alias(s), nonZero(s.LastFailure), nonZero(s.LastSuccess)})
+}
+
+func nonZero(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Status is the health of a chain.
+type Status struct {
+	Signal    string         `json:"signal"`
+	Healthy   bool           `json:"healthy"`
+	Failovers int64          `json:"failovers"`
+	Targets   []TargetStatus `json:"targets"`
+}
+
+type breaker struct {
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	lastErr     error
+	lastFailure time.Time
+	lastSuccess time.Time
+}
+
+// chain is the generic implementation shared by the span and metric exporters.
+type chain[E any] struct {
+	cfg     Config
+	targets []Target[E]
+	now     func() time.Time
+
+	mu        sync.Mutex
+	breakers  []breaker
+	failovers int64
+}
+
+func newChain[E any](cfg Config, primary Target[E], fallbacks ...Target[E]) *chain[E] {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+
+	if cfg.ProbeInterval <= 0 {
+		cfg.ProbeInterval = 30 * time.Second
+	}
+
+	targets := append([]Target[E]{primary}, fallbacks...)
+	c := &chain[E]{
+		cfg:      cfg,
+		targets:  targets,
+		now:      time.Now,
+		breakers: make([]breaker, len(targets)),
+	}
+
+	register(c)
+	return c
+}
+
+// export tries the exporters in order, skipping the ones with an open circuit.
+// The last exporter is always tried, so the data has somewhere to go.
+func (c *chain[E]) export(ctx context.Context, fn func(context.Context, E) error) error {
+	var errs []error
+	for i, target := range c.targets {
+		last := i == len(c.targets)-1
+		allowed, probe := c.allow(i, c.now())
+		if !allowed && !last {
+			continue
+		}
+
+		err := fn(ctx, target.Exporter)
+		c.record(i, probe, err)
+		if err == nil {
+			if i > 0 {
+				c.mu.Lock()
+				c.failovers++
+				c.mu.Unlock()
+			}
+			return nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s exporter: %w", target.Name, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// each runs fn on every exporter, used with method expressions such as SpanExporter.Shutdown.
+func (c *chain[E]) each(ctx context.Context, fn func(E, context.Context) error) error {
+	var errs []error
+	for _, target := range c.targets {
+		if err := fn(target.Exporter, ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s exporter: %w", target.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// allow tells whether the exporter can be used. A half-open exporter lets a single probe through,
+// the other exports skip it until the probe is recorded.
+func (c *chain[E]) allow(i int, now time.Time) (allowed, probe bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state(i, now) {
+	case StateClosed:
+		return true, false
+	case StateHalfOpen:
+		if c.breakers[i].probing {
+			return false, false
+		}
+		c.breakers[i].probing = true
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// state must be called while holding the lock.
+func (c *chain[E]) state(i int, now time.Time) string {
+	b := c.breakers[i]
+	if b.failures < c.cfg.FailureThreshold {
+		return StateClosed
+	}
+
+	if now.Sub(b.openedAt) >= c.cfg.ProbeInterval {
+		return StateHalfOpen
+	}
+
+	return StateOpen
+}
+
+func (c *chain[E]) record(i int, probe bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := &c.breakers[i]
+	if probe {
+		b.probing = false
+	}
+
+	now := c.now()
+	if err == nil {
+		b.failures = 0
+		b.lastSuccess = now
+		return
+	}
+
+	b.failures++
+	b.lastErr = err
+	b.lastFailure = now
+
+	// Trip the circuit, or restart the probe interval when the half-open probe failed.
+	if b.failures >= c.cfg.FailureThreshold {
+		b.openedAt = now
+	}
+}
+
+func (c *chain[E]) status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	status := Status{
+		Signal:    c.cfg.Signal,
+		Failovers: c.failovers,
+		Targets:   make([]TargetStatus, 0, len(c.targets)),
+	}
+
+	active := -1
+	for i, target := range c.targets {
+		b := c.breakers[i]
+		state := c.state(i, now)
+		if active < 0 && state != StateOpen {
+			active = i
+		}
+
+		targetStatus := TargetStatus{
+			Name:                target.Name,
+			State:               state,
+			ConsecutiveFailures: b.failures,
+			LastFailure:         b.lastFailure,
+			LastSuccess:         b.lastSuccess,
+		}
+		if b.lastErr != nil {
+			targetStatus.LastError = b.lastErr.Error()
+		}
+		status.Targets = append(status.Targets, targetStatus)
+	}
+
+	// Same as export, the last exporter is used when every circuit is open.
+	if active < 0 {
+		active = len(c.targets) - 1
+	}
+
+	status.Targets[active].Active = true
+	status.Healthy = active == 0
+	return status
+}
@@ -0,0 +1,201 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeExporter fails while err is set, and blocks each export until release is closed when it is set.
+type fakeExporter struct {
+	mu      sync.Mutex
+	err     error
+	calls   int
+	started chan struct{}
+	release chan struct{}
+}
+
+func (e *fakeExporter) export(context.Context) error {
+	e.mu.Lock()
+	e.calls++
+	err, started, release := e.err, e.started, e.release
+	e.mu.Unlock()
+
+	if release != nil {
+		started <- struct{}{}
+		<-release
+	}
+	return err
+}
+
+func (e *fakeExporter) set(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.err = err
+}
+
+func (e *fakeExporter) Calls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestChain(primary, fallback *fakeExporter) (*chain[*fakeExporter], *clock) {
+	clk := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := newChain(Config{Signal: "test", FailureThreshold: 2, ProbeInterval: time.Minute},
+		Target[*fakeExporter]{Name: "primary", Exporter: primary},
+		Target[*fakeExporter]{Name: "fallback", Exporter: fallback},
+	)
+	c.now = clk.Now
+	return c, clk
+}
+
+func export(c *chain[*fakeExporter]) error {
+	return c.export(context.Background(), func(ctx context.Context, e *fakeExporter) error { return e.export(ctx) })
+}
+
+func TestChainTripsAfterThreshold(t *testing.T) {
+	primary, fallback := &fakeExporter{err: errors.New("connection refused")}, &fakeExporter{}
+	c, _ := newTestChain(primary, fallback)
+
+	for range 2 {
+		if err := export(c); err != nil {
+			t.Fatalf("export() = %v, want the fallback to succeed", err)
+		}
+	}
+	if state := c.status().Targets[0].State; state != StateOpen {
+		t.Fatalf("primary state = %s after 2 failures, want %s", state, StateOpen)
+	}
+
+	// The open primary is skipped.
+	if err := export(c); err != nil {
+		t.Fatal(err)
+	}
+	if primary.Calls() != 2 || fallback.Calls() != 3 {
+		t.Errorf("calls primary %d, fallback %d, want 2 and 3", primary.Calls(), fallback.Calls())
+	}
+
+	status := c.status()
+	if status.Healthy || !status.Targets[1].Active || status.Failovers != 3 || status.Targets[0].LastError != "connection refused" {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestChainSingleHalfOpenProbe(t *testing.T) {
+	primary, fallback := &fakeExporter{err: errors.New("connection refused")}, &fakeExporter{}
+	c, clk := newTestChain(primary, fallback)
+
+	_ = export(c)
+	_ = export(c)
+	clk.Advance(time.Minute)
+	if state := c.status().Targets[0].State; state != StateHalfOpen {
+		t.Fatalf("primary state = %s after the probe interval, want %s", state, StateHalfOpen)
+	}
+
+	// The probe blocks in the primary, the concurrent exports go to the fallback.
+	primary.mu.Lock()
+	primary.err = nil
+	primary.started = make(chan struct{})
+	primary.release = make(chan struct{})
+	primary.mu.Unlock()
+
+	probe := make(chan error)
+	go func() { probe <- export(c) }()
+	<-primary.started
+
+	for range 3 {
+		if err := export(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if primary.Calls() != 3 || fallback.Calls() != 5 {
+		t.Errorf("calls primary %d, fallback %d during the probe, want 3 and 5", primary.Calls(), fallback.Calls())
+	}
+
+	close(primary.release)
+	if err := <-probe; err != nil {
+		t.Fatalf("probe = %v", err)
+	}
+
+	// The successful probe closes the circuit, the chain goes back to the primary.
+	primary.mu.Lock()
+	primary.release = nil
+	primary.mu.Unlock()
+
+	if err := export(c); err != nil {
+		t.Fatal(err)
+	}
+	status := c.status()
+	if !status.Healthy || status.Targets[0].State != StateClosed || !status.Targets[0].Active || primary.Calls() != 4 || fallback.Calls() != 5 {
+		t.Errorf("status = %+v after the recovery, calls primary %d fallback %d", status, primary.Calls(), fallback.Calls())
+	}
+}
+
+func TestChainFailedProbeReopens(t *testing.T) {
+	primary, fallback := &fakeExporter{err: errors.New("connection refused")}, &fakeExporter{}
+	c, clk := newTestChain(primary, fallback)
+
+	_ = export(c)
+	_ = export(c)
+	clk.Advance(time.Minute)
+
+	// The failed probe restarts the probe interval.
+	_ = export(c)
+	if primary.Calls() != 3 {
+		t.Fatalf("primary called %d times, want the probe", primary.Calls())
+	}
+	if state := c.status().Targets[0].State; state != StateOpen {
+		t.Errorf("primary state = %s after a failed probe, want %s", state, StateOpen)
+	}
+
+	clk.Advance(59 * time.Second)
+	_ = export(c)
+	if primary.Calls() != 3 {
+		t.Errorf("primary called %d times before the probe interval, want 3", primary.Calls())
+	}
+}
+
+func TestChainAlwaysTriesLast(t *testing.T) {
+	primary, fallback := &fakeExporter{err: errors.New("connection refused")}, &fakeExporter{err: errors.New("disk full")}
+	c, _ := newTestChain(primary, fallback)
+
+	for range 5 {
+		if err := export(c); err == nil {
+			t.Fatal("export() succeeded with every exporter failing")
+		}
+	}
+
+	// Both circuits are open, the last exporter is still tried.
+	if primary.Calls() != 2 || fallback.Calls() != 5 {
+		t.Errorf("calls primary %d, fallback %d, want 2 and 5", primary.Calls(), fallback.Calls())
+	}
+
+	status := c.status()
+	if status.Targets[1].State != StateOpen || !status.Targets[1].Active {
+		t.Errorf("status = %+v, want the open fallback active", status)
+	}
+
+	fallback.set(nil)
+	if err := export(c); err != nil {
+		t.Errorf("export() = %v after the fallback recovered", err)
+	}
+}
@@ -0,0 +1,120 @@
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/failover"
+
+var (
+	registryMu sync.Mutex
+	registry   []interface{ status() Status }
+)
+
+func register(c interface{ status() Status }) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// Statuses returns the health of every chain created in this process.
+func Statuses() []Status {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	out := make([]Status, 0, len(registry))
+	for _, c := range registry {
+		out = append(out, c.status())
+	}
+	return out
+}
+
+// HealthHandler responds the health of every chain as JSON.
+// The status code is 503 when at least one chain is not using its primary exporter.
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := Statuses()
+
+		statusCode := http.StatusOK
+		for _, s := range statuses {
+			if !s.Healthy {
+				statusCode = http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		if err := json.NewEncoder(w).Encode(map[string]any{"exporters": statuses}); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write exporter health", slog.Any("error", err))
+		}
+	})
+}
+
+// RegisterMetrics reports the state of every chain through the global MeterProvider.
+// metricPrefix is prepended to the metric names, usually the service name.
+func RegisterMetrics(metricPrefix string) error {
+	meter := otel.Meter(instrumentationName)
+
+	state, err := meter.Int64ObservableGauge(metricPrefix+".exporter.circuit_open",
+		metric.WithDescription("1 when the circuit breaker of the exporter is open (the exporter is skipped), otherwise 0."),
+	)
+	if err != nil {
+		return err
+	}
+
+	active, err := meter.Int64ObservableGauge(metricPrefix+".exporter.active",
+		metric.WithDescription("1 for the exporter currently receiving the telemetry, otherwise 0."),
+	)
+	if err != nil {
+		return err
+	}
+
+	failovers, err := meter.Int64ObservableCounter(metricPrefix+".exporter.failovers",
+		metric.WithDescription("Number of exports served by a fallback exporter."),
+	)
+	if err != nil {
+		return err
+	}
+
+	lastSuccess, err := meter.Float64ObservableGauge(metricPrefix+".exporter.last_success",
+		metric.WithDescription("Unix timestamp of the last successful export of the exporter."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, s := range Statuses() {
+			signal := attribute.String("signal", s.Signal)
+			o.ObserveInt64(failovers, s.Failovers, metric.WithAttributes(signal))
+
+			for _, t := range s.Targets {
+				attrs := metric.WithAttributes(signal, attribute.String("exporter", t.Name))
+				o.ObserveInt64(state, boolToInt(t.State == StateOpen), attrs)
+				o.ObserveInt64(active, boolToInt(t.Active), attrs)
+				if !t.LastSuccess.IsZero() {
+					o.ObserveFloat64(lastSuccess, float64(t.LastSuccess.UnixNano())/1e9, attrs)
+				}
+			}
+		}
+		return nil
+	}, state, active, failovers, lastSuccess)
+
+	return err
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
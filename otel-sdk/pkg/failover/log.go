@@ -0,0 +1,38 @@
+package failover
+
+import (
+	"context"
+
+	otelSdkLog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// LogExporter is a log exporter which fails over from the primary to the fallbacks.
+type LogExporter struct {
+	chain *chain[otelSdkLog.Exporter]
+}
+
+var _ otelSdkLog.Exporter = (*LogExporter)(nil)
+
+// NewLogExporter creates a log exporter which tries the primary first, then each fallback in order.
+func NewLogExporter(cfg Config, primary Target[otelSdkLog.Exporter], fallbacks ...Target[otelSdkLog.Exporter]) *LogExporter {
+	return &LogExporter{chain: newChain(cfg, primary, fallbacks...)}
+}
+
+// Status returns the health of the chain.
+func (e *LogExporter) Status() Status {
+	return e.chain.status()
+}
+
+func (e *LogExporter) Export(ctx context.Context, records []otelSdkLog.Record) error {
+	return e.chain.export(ctx, func(ctx context.Context, exporter otelSdkLog.Exporter) error {
+		return exporter.Export(ctx, records)
+	})
+}
+
+func (e *LogExporter) ForceFlush(ctx context.Context) error {
+	return e.chain.each(ctx, otelSdkLog.Exporter.ForceFlush)
+}
+
+func (e *LogExporter) Shutdown(ctx context.Context) error {
+	return e.chain.each(ctx, otelSdkLog.Exporter.Shutdown)
+}
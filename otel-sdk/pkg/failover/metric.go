@@ -0,0 +1,48 @@
+package failover
+
+import (
+	"context"
+
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// MetricExporter is a metric exporter which fails over from the primary to the fallbacks.
+// Temporality and aggregation follow the primary exporter.
+type MetricExporter struct {
+	chain *chain[otelSdkMetric.Exporter]
+}
+
+var _ otelSdkMetric.Exporter = (*MetricExporter)(nil)
+
+// NewMetricExporter creates a metric exporter which tries the primary first, then each fallback in order.
+func NewMetricExporter(cfg Config, primary Target[otelSdkMetric.Exporter], fallbacks ...Target[otelSdkMetric.Exporter]) *MetricExporter {
+	return &MetricExporter{chain: newChain(cfg, primary, fallbacks...)}
+}
+
+// Status returns the health of the chain.
+func (e *MetricExporter) Status() Status {
+	return e.chain.status()
+}
+
+func (e *MetricExporter) Temporality(kind otelSdkMetric.InstrumentKind) metricdata.Temporality {
+	return e.chain.targets[0].Exporter.Temporality(kind)
+}
+
+func (e *MetricExporter) Aggregation(kind otelSdkMetric.InstrumentKind) otelSdkMetric.Aggregation {
+	return e.chain.targets[0].Exporter.Aggregation(kind)
+}
+
+func (e *MetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return e.chain.export(ctx, func(ctx context.Context, exporter otelSdkMetric.Exporter) error {
+		return exporter.Export(ctx, rm)
+	})
+}
+
+func (e *MetricExporter) ForceFlush(ctx context.Context) error {
+	return e.chain.each(ctx, otelSdkMetric.Exporter.ForceFlush)
+}
+
+func (e *MetricExporter) Shutdown(ctx context.Context) error {
+	return e.chain.each(ctx, otelSdkMetric.Exporter.Shutdown)
+}
@@ -0,0 +1,34 @@
+package failover
+
+import (
+	"context"
+
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// SpanExporter is a span exporter which fails over from the primary to the fallbacks.
+type SpanExporter struct {
+	chain *chain[otelSdkTrace.SpanExporter]
+}
+
+var _ otelSdkTrace.SpanExporter = (*SpanExporter)(nil)
+
+// NewSpanExporter creates a span exporter which tries the primary first, then each fallback in order.
+func NewSpanExporter(cfg Config, primary Target[otelSdkTrace.SpanExporter], fallbacks ...Target[otelSdkTrace.SpanExporter]) *SpanExporter {
+	return &SpanExporter{chain: newChain(cfg, primary, fallbacks...)}
+}
+
+// Status returns the health of the chain.
+func (e *SpanExporter) Status() Status {
+	return e.chain.status()
+}
+
+func (e *SpanExporter) ExportSpans(ctx context.Context, spans []otelSdkTrace.ReadOnlySpan) error {
+	return e.chain.export(ctx, func(ctx context.Context, exporter otelSdkTrace.SpanExporter) error {
+		return exporter.ExportSpans(ctx, spans)
+	})
+}
+
+func (e *SpanExporter) Shutdown(ctx context.Context) error {
+	return e.chain.each(ctx, otelSdkTrace.SpanExporter.Shutdown)
+}
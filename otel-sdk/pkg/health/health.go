@@ -0,0 +1,117 @@
+// Package health serves the liveness and readiness endpoints, "/healthz" and "/readyz".
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Check returns an error when the dependency is not ready.
+type Check func(ctx context.Context) error
+
+// Response is the JSON body of the liveness and readiness endpoints.
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// LivenessHandler always responds 200, the process is alive as long as it serves HTTP.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, http.StatusOK, Response{Status: StatusOK})
+	})
+}
+
+// Readiness runs the registered checks on every request.
+type Readiness struct {
+	// Timeout of all checks together, default is 2 seconds.
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	names  []string
+	checks []Check
+}
+
+// Add registers a check, the name is used as key in the response.
+func (rd *Readiness) Add(name string, check Check) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	rd.names = append(rd.names, name)
+	rd.checks = append(rd.checks, check)
+}
+
+// Handler responds 200 when every check passes, otherwise 503. The checks run concurrently.
+func (rd *Readiness) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rd.mu.Lock()
+		names := append([]string(nil), rd.names...)
+		checks := append([]Check(nil), rd.checks...)
+		rd.mu.Unlock()
+
+		timeout := rd.Timeout
+		if timeout <= 0 {
+			timeout = 2 * time.Second
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		results := make([]error, len(checks))
+		var wg sync.WaitGroup
+		for i, check := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = check(ctx)
+			}()
+		}
+		wg.Wait()
+
+		resp := Response{Status: StatusOK, Checks: make(map[string]string, len(checks))}
+		statusCode := http.StatusOK
+		for i, err := range results {
+			resp.Checks[names[i]] = StatusOK
+			if err != nil {
+				resp.Checks[names[i]] = err.Error()
+				resp.Status = StatusUnavailable
+				statusCode = http.StatusServiceUnavailable
+			}
+		}
+
+		writeJSON(w, r, statusCode, resp)
+	})
+}
+
+// DialCheck checks that a TCP connection can be opened to the address, for example the OTLP endpoint "localhost:4318".
+func DialCheck(addr string) Check {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("dial %s: %w", addr, err)
+		}
+
+		return conn.Close()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write health response", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serve(t *testing.T, handler http.Handler) (int, Response) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	return w.Code, resp
+}
+
+func TestLivenessHandler(t *testing.T) {
+	if code, resp := serve(t, LivenessHandler()); code != http.StatusOK || resp.Status != StatusOK {
+		t.Errorf("liveness = %d %+v", code, resp)
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	rd := &Readiness{Timeout: 50 * time.Millisecond}
+	rd.Add("otlp", func(context.Context) error { return nil })
+
+	if code, resp := serve(t, rd.Handler()); code != http.StatusOK || resp.Status != StatusOK || resp.Checks["otlp"] != StatusOK {
+		t.Errorf("readiness with passing checks = %d %+v", code, resp)
+	}
+
+	rd.Add("queue", func(context.Context) error { return errors.New("queue full") })
+	rd.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	code, resp := serve(t, rd.Handler())
+	if code != http.StatusServiceUnavailable || resp.Status != StatusUnavailable {
+		t.Errorf("readiness with a failing check = %d %+v, want 503", code, resp)
+	}
+	for name, want := range map[string]string{"otlp": StatusOK, "queue": "queue full", "slow": context.DeadlineExceeded.Error()} {
+		if resp.Checks[name] != want {
+			t.Errorf("check %s = %q, want %q", name, resp.Checks[name], want)
+		}
+	}
+}
+
+func TestDialCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+
+	if err = DialCheck(addr)(context.Background()); err != nil {
+		t.Errorf("DialCheck() of a listening address = %v", err)
+	}
+
+	_ = listener.Close()
+	if err = DialCheck(addr)(context.Background()); err == nil {
+		t.Error("DialCheck() of a closed address succeeded")
+	}
+}
@@ -0,0 +1,282 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/loadgen"
+
+// Runner sends the requests of the scenario.
+// Every request is a span of the global TracerProvider, and its trace context is injected with the global propagator,
+// so the server spans are the children of the load generator spans.
+type Runner struct {
+	Scenario *Scenario
+
+	// MetricPrefix is the prefix of the client side metrics, for example "poc_otel_sdk_loadgen".
+	MetricPrefix string
+
+	// Timeout of one request, default is 30 seconds.
+	Timeout time.Duration
+
+	client    *http.Client
+	tracer    trace.Tracer
+	requests  metric.Int64Counter
+	duration  metric.Float64Histogram
+	stats     *Stats
+	iteration atomic.Int64
+}
+
+// Run blocks until the scenario is done or the context is canceled.
+func (r *Runner) Run(ctx context.Context) (*Stats, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	if r.Scenario.Model == ModelOpen {
+		r.runOpen(ctx)
+	} else {
+		r.runClosed(ctx)
+	}
+
+	r.stats.Elapsed = time.Since(start)
+	return r.stats, nil
+}
+
+func (r *Runner) init() error {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	r.client = &http.Client{
+		Timeout:   timeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+	r.tracer = otel.Tracer(instrumentationName)
+	r.stats = newStats(r.Scenario.thresholds)
+
+	meter := otel.Meter(instrumentationName)
+
+	var err error
+	r.requests, err = meter.Int64Counter(r.MetricPrefix+".requests",
+		metric.WithDescription("Number of requests sent by the load generator."),
+	)
+	if err != nil {
+		return err
+	}
+
+	r.duration, err = meter.Float64Histogram(r.MetricPrefix+".request.duration",
+		metric.WithDescription("Duration of the requests sent by the load generator, as seen by the client."),
+		metric.WithUnit("ms"),
+	)
+	return err
+}
+
+// runOpen starts the requests at the constant Rate, without waiting for the previous responses.
+func (r *Runner) runOpen(ctx context.Context) {
+	s := r.Scenario
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.Duration))
+	defer cancel()
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / s.Rate))
+	defer ticker.Stop()
+
+	slots := make(chan struct{}, s.MaxInFlight)
+	var wg sync.WaitGroup
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			r.stats.mu.Lock()
+			r.stats.Dropped++
+			r.stats.mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			// The requests in flight finish after the duration.
+			r.do(context.WithoutCancel(ctx))
+		}()
+	}
+}
+
+// runClosed ramps the virtual users through the stages, each user sends one request after the other.
+func (r *Runner) runClosed(ctx context.Context) {
+	var (
+		wg    sync.WaitGroup
+		users []chan struct{}
+	)
+
+	// A stopped user finishes its request in flight.
+	stop := func(n int) {
+		for len(users) > n {
+			close(users[len(users)-1])
+			users = users[:len(users)-1]
+		}
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		target, done := r.Scenario.vus(time.Since(start))
+		if done || ctx.Err() != nil {
+			stop(0)
+			wg.Wait()
+			return
+		}
+
+		stop(target)
+		for len(users) < target {
+			quit := make(chan struct{})
+			users = append(users, quit)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r.user(ctx, quit)
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) user(ctx context.Context, quit <-chan struct{}) {
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		r.do(ctx)
+
+		if think := time.Duration(r.Scenario.ThinkTime); think > 0 {
+			select {
+			case <-quit:
+				return
+			case <-ctx.Done():
+				return
+			case <-time.After(think):
+			}
+		}
+	}
+}
+
+// vus is the number of virtual users after elapsed, ramped linearly from the previous stage target, starting at VUs.
+func (s *Scenario) vus(elapsed time.Duration) (int, bool) {
+	from := s.VUs
+	for _, stage := range s.Stages {
+		d := time.Duration(stage.Duration)
+		if elapsed < d {
+			progress := float64(elapsed) / float64(d)
+			return from + int(float64(stage.Target-from)*progress+0.5), false
+		}
+
+		elapsed -= d
+		from = stage.Target
+	}
+
+	return 0, true
+}
+
+func (r *Runner) do(ctx context.Context) {
+	e := r.Scenario.pick()
+	data := TemplateData{User: r.Scenario.randomUser(), Iteration: r.iteration.Add(1)}
+
+	ctx, span := r.tracer.Start(ctx, "loadgen "+e.Name,
+		trace.WithAttributes(
+			attribute.String("loadgen.endpoint", e.Name),
+			attribute.Int64("loadgen.iteration", data.Iteration),
+		),
+	)
+	defer span.End()
+
+	status, duration, err := r.send(ctx, e, data)
+	success := err == nil && e.success(status)
+
+	switch {
+	case err != nil:
+		span.SetStatus(codes.Error, err.Error())
+	case !success:
+		span.SetStatus(codes.Error, fmt.Sprintf("unexpected status %d", status))
+	}
+
+	attrs := metric.WithAttributes(
+		attribute.String("endpoint", e.Name),
+		attribute.String("status_code", strconv.Itoa(status)),
+		attribute.Bool("success", success),
+	)
+	r.requests.Add(ctx, 1, attrs)
+	r.duration.Record(ctx, ms(duration), attrs)
+
+	r.stats.add(e.Name, status, duration, success, err)
+	if err != nil && ctx.Err() == nil {
+		slog.WarnContext(ctx, "failed to send request", slog.String("endpoint", e.Name), slog.Any("error", err))
+	}
+}
+
+func (r *Runner) send(ctx context.Context, e *Endpoint, data TemplateData) (int, time.Duration, error) {
+	body, err := e.render(data)
+	if err != nil {
+		return 0, 0, fmt.Errorf("render body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, e.Method, strings.TrimSuffix(r.Scenario.Target, "/")+e.Path, strings.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, time.Since(start), err
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	return resp.StatusCode, time.Since(start), nil
+}
@@ -0,0 +1,266 @@
+// Package loadgen generates HTTP load from a scenario file: weighted endpoints with payload templates,
+// an open (constant arrival rate) or closed (virtual users) workload model, and k6 like thresholds.
+package loadgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// ModelOpen starts requests at a constant arrival rate, whatever the latency of the target is.
+	ModelOpen = "open"
+
+	// ModelClosed runs virtual users, each one sends its next request when the previous one is done.
+	ModelClosed = "closed"
+)
+
+// Duration is a time.Duration written as "30s" in the scenario file.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// Scenario is the content of the scenario file.
+type Scenario struct {
+	// Target is the base URL, for example "http://localhost:8082".
+	Target string `json:"target"`
+
+	// Model is ModelOpen or ModelClosed.
+	Model string `json:"model"`
+
+	// Rate is the number of requests per second of the open model, MaxInFlight caps the requests waiting for a response.
+	Rate        float64 `json:"rate"`
+	MaxInFlight int     `json:"max_in_flight"`
+
+	// Duration of the open model, or of the closed model without stages.
+	Duration Duration `json:"duration"`
+
+	// VUs is the number of virtual users at the start of the closed model, ThinkTime is the pause between two requests of a user.
+	VUs       int      `json:"vus"`
+	ThinkTime Duration `json:"think_time"`
+
+	// Stages ramp the number of virtual users of the closed model linearly to Target during Duration, like the k6 stages.
+	// Without stages, VUs users run during Duration.
+	Stages []Stage `json:"stages"`
+
+	Endpoints []Endpoint `json:"endpoints"`
+
+	// Users are picked randomly for every request, as {{.User.Username}} and {{.User.Password}} in the templates.
+	Users []User `json:"users"`
+
+	// Thresholds are k6 like expressions by metric, for example {"http_req_duration": ["p(95)<200"], "http_req_failed": ["rate<0.01"]}.
+	Thresholds map[string][]string `json:"thresholds"`
+
+	thresholds []Threshold
+}
+
+type Stage struct {
+	Duration Duration `json:"duration"`
+	Target   int      `json:"target"`
+}
+
+type User struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Endpoint is one weighted request of the scenario.
+type Endpoint struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+
+	// Body is a text/template, see TemplateData and the functions randomString, randomInt and randomUser.
+	Body string `json:"body"`
+
+	// Weight is the relative frequency of the endpoint, default is 1.
+	Weight int `json:"weight"`
+
+	// ExpectStatus are the successful statuses, by default any status below 400.
+	// For example, a login with an unknown user expects 401.
+	ExpectStatus []int `json:"expect_status"`
+
+	body *template.Template
+}
+
+// TemplateData is the data of the body templates.
+type TemplateData struct {
+	User      User
+	Iteration int64
+}
+
+// LoadScenario reads and validates the scenario file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Scenario
+	if err = json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("decode scenario: %w", err)
+	}
+
+	if err = s.validate(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (s *Scenario) validate() error {
+	if s.Target == "" {
+		return fmt.Errorf("target is required")
+	}
+
+	switch s.Model {
+	case ModelOpen:
+		if s.Rate <= 0 || s.Duration <= 0 {
+			return fmt.Errorf("open model requires rate and duration")
+		}
+		if s.MaxInFlight <= 0 {
+			s.MaxInFlight = 1000
+		}
+
+	case ModelClosed:
+		if len(s.Stages) == 0 {
+			if s.VUs <= 0 || s.Duration <= 0 {
+				return fmt.Errorf("closed model requires vus and duration, or stages")
+			}
+			s.Stages = []Stage{{Duration: s.Duration, Target: s.VUs}}
+		}
+
+	default:
+		return fmt.Errorf("model must be %q or %q, got %q", ModelOpen, ModelClosed, s.Model)
+	}
+
+	if len(s.Endpoints) == 0 {
+		return fmt.Errorf("at least one endpoint is required")
+	}
+
+	for i := range s.Endpoints {
+		e := &s.Endpoints[i]
+		if e.Method == "" {
+			e.Method = "GET"
+		}
+		if e.Name == "" {
+			e.Name = fmt.Sprintf("%s %s", e.Method, e.Path)
+		}
+		if e.Weight <= 0 {
+			e.Weight = 1
+		}
+
+		tmpl, err := template.New(e.Name).Funcs(s.templateFuncs()).Parse(e.Body)
+		if err != nil {
+			return fmt.Errorf("endpoint %s body: %w", e.Name, err)
+		}
+		e.body = tmpl
+	}
+
+	metrics := make([]string, 0, len(s.Thresholds))
+	for metric := range s.Thresholds {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+
+	for _, metric := range metrics {
+		for _, expr := range s.Thresholds[metric] {
+			t, err := parseThreshold(metric, expr)
+			if err != nil {
+				return err
+			}
+			s.thresholds = append(s.thresholds, t)
+		}
+	}
+
+	return nil
+}
+
+func (s *Scenario) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"randomString": func(n int) string {
+			const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+			var b strings.Builder
+			for range n {
+				b.WriteByte(letters[rand.IntN(len(letters))])
+			}
+			return b.String()
+		},
+		// The error fails the rendering of the body instead of a panic of rand.IntN.
+		"randomInt": func(minimum, maximum int) (int, error) {
+			if maximum < minimum {
+				return 0, fmt.Errorf("randomInt: maximum %d is less than minimum %d", maximum, minimum)
+			}
+			return minimum + rand.IntN(maximum-minimum+1), nil
+		},
+		"randomUser": func() User {
+			return s.randomUser()
+		},
+	}
+}
+
+func (s *Scenario) randomUser() User {
+	if len(s.Users) == 0 {
+		return User{}
+	}
+	return s.Users[rand.IntN(len(s.Users))]
+}
+
+// pick returns a random endpoint according to the weights.
+func (s *Scenario) pick() *Endpoint {
+	total := 0
+	for _, e := range s.Endpoints {
+		total += e.Weight
+	}
+
+	n := rand.IntN(total)
+	for i := range s.Endpoints {
+		if n < s.Endpoints[i].Weight {
+			return &s.Endpoints[i]
+		}
+		n -= s.Endpoints[i].Weight
+	}
+	return &s.Endpoints[len(s.Endpoints)-1]
+}
+
+func (e *Endpoint) render(data TemplateData) (string, error) {
+	var b bytes.Buffer
+	if err := e.body.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (e *Endpoint) success(status int) bool {
+	if len(e.ExpectStatus) == 0 {
+		return status < 400
+	}
+
+	for _, expected := range e.ExpectStatus {
+		if status == expected {
+			return true
+		}
+	}
+	return false
+}
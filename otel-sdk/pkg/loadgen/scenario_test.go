@@ -0,0 +1,50 @@
+package loadgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func newScenario(t *testing.T, endpoints ...Endpoint) *Scenario {
+	t.Helper()
+
+	s := &Scenario{
+		Target:    "http://localhost:8082",
+		Model:     ModelOpen,
+		Rate:      1,
+		Duration:  Duration(1),
+		Endpoints: endpoints,
+	}
+	if err := s.validate(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestValidateEndpointDefaults(t *testing.T) {
+	s := newScenario(t, Endpoint{Path: "/"}, Endpoint{Name: "login", Method: "POST", Path: "/login", Weight: 3})
+
+	if e := s.Endpoints[0]; e.Method != "GET" || e.Name != "GET /" || e.Weight != 1 {
+		t.Errorf("endpoint = %s %s %s weight %d, want the defaults", e.Name, e.Method, e.Path, e.Weight)
+	}
+
+	if e := s.Endpoints[1]; e.Method != "POST" || e.Name != "login" || e.Weight != 3 {
+		t.Errorf("endpoint = %s %s %s weight %d, want the values of the scenario", e.Name, e.Method, e.Path, e.Weight)
+	}
+}
+
+func TestRenderRandomInt(t *testing.T) {
+	s := newScenario(t,
+		Endpoint{Path: "/valid", Body: `{{randomInt 3 3}} {{randomInt -2 -2}}`},
+		Endpoint{Path: "/invalid", Body: `{{randomInt 10 1}}`},
+	)
+
+	body, err := s.Endpoints[0].render(TemplateData{})
+	if err != nil || body != "3 -2" {
+		t.Errorf("render() = %q, %v, want %q", body, err, "3 -2")
+	}
+
+	if _, err = s.Endpoints[1].render(TemplateData{}); err == nil || !strings.Contains(err.Error(), "maximum 1 is less than minimum 10") {
+		t.Errorf("render() error = %v", err)
+	}
+}
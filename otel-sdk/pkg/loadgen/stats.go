@@ -0,0 +1,210 @@
+package loadgen
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stats collects the results of the requests, by endpoint.
+type Stats struct {
+	Elapsed time.Duration
+
+	// Dropped counts the requests of the open model which were not sent, because MaxInFlight was reached.
+	Dropped int64
+
+	thresholds []Threshold
+
+	mu        sync.Mutex
+	endpoints map[string]*series
+}
+
+type series struct {
+	durations []time.Duration
+	failed    int
+	errors    int
+	status    map[int]int
+	sorted    bool
+}
+
+func newStats(thresholds []Threshold) *Stats {
+	return &Stats{thresholds: thresholds, endpoints: map[string]*series{}}
+}
+
+func (s *Stats) add(endpoint string, status int, duration time.Duration, success bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.endpoints[endpoint]
+	if !ok {
+		e = &series{status: map[int]int{}}
+		s.endpoints[endpoint] = e
+	}
+
+	e.durations = append(e.durations, duration)
+	e.sorted = false
+	if !success {
+		e.failed++
+	}
+	if err != nil {
+		e.errors++
+		return
+	}
+	e.status[status]++
+}
+
+// endpoint must be called after the run, an unknown endpoint has no requests.
+func (s *Stats) endpoint(name string) *series {
+	if e, ok := s.endpoints[name]; ok {
+		return e
+	}
+	return &series{status: map[int]int{}}
+}
+
+// total merges every endpoint.
+func (s *Stats) total() *series {
+	total := &series{status: map[int]int{}}
+	for _, e := range s.endpoints {
+		total.durations = append(total.durations, e.durations...)
+		total.failed += e.failed
+		total.errors += e.errors
+		for code, n := range e.status {
+			total.status[code] += n
+		}
+	}
+	return total
+}
+
+func (e *series) failedRate() float64 {
+	if len(e.durations) == 0 {
+		return 0
+	}
+	return float64(e.failed) / float64(len(e.durations))
+}
+
+func (e *series) avg() time.Duration {
+	if len(e.durations) == 0 {
+		return 0
+	}
+
+	var sum time.Duration
+	for _, d := range e.durations {
+		sum += d
+	}
+	return sum / time.Duration(len(e.durations))
+}
+
+// percentile uses the nearest rank, p is between 0 (min) and 100 (max).
+func (e *series) percentile(p float64) time.Duration {
+	if len(e.durations) == 0 {
+		return 0
+	}
+
+	if !e.sorted {
+		slices.Sort(e.durations)
+		e.sorted = true
+	}
+
+	rank := int(p/100*float64(len(e.durations)) + 0.5)
+	rank = min(max(rank, 1), len(e.durations))
+	return e.durations[rank-1]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Failed evaluates the thresholds and returns the number of failed ones.
+func (s *Stats) Failed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failed := 0
+	for _, t := range s.thresholds {
+		if _, ok := t.Check(s); !ok {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Write prints a k6 like summary: the totals, one line per endpoint, then the thresholds.
+func (s *Stats) Write(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := s.total()
+	rate := 0.0
+	if s.Elapsed > 0 {
+		rate = float64(len(total.durations)) / s.Elapsed.Seconds()
+	}
+
+	_, _ = fmt.Fprintf(w, "%-22s: %d %.2f/s\n", "http_reqs", len(total.durations), rate)
+	_, _ = fmt.Fprintf(w, "%-22s: %s\n", "http_req_duration", durations(total))
+	_, _ = fmt.Fprintf(w, "%-22s: %.2f%% %d of %d\n", "http_req_failed", total.failedRate()*100, total.failed, len(total.durations))
+	_, _ = fmt.Fprintf(w, "%-22s: %d\n", "dropped_iterations", s.Dropped)
+	_, _ = fmt.Fprintf(w, "%-22s: %s\n\n", "elapsed", s.Elapsed.Round(time.Millisecond))
+
+	names := make([]string, 0, len(s.endpoints))
+	for name := range s.endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	_, _ = fmt.Fprintf(w, "%-24s %6s %7s  %s\n", "ENDPOINT", "COUNT", "FAILED", "DURATION / STATUS")
+	for _, name := range names {
+		e := s.endpoints[name]
+		_, _ = fmt.Fprintf(w, "%-24s %6d %6.2f%%  %s\n", name, len(e.durations), e.failedRate()*100, durations(e))
+		_, _ = fmt.Fprintf(w, "%-24s %6s %7s  %s\n", "", "", "", statuses(e))
+	}
+
+	if len(s.thresholds) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "\nthresholds:\n")
+	for _, t := range s.thresholds {
+		actual, ok := t.Check(s)
+		mark := "✓"
+		if !ok {
+			mark = "✗"
+		}
+
+		unit := "ms"
+		if t.Metric == "http_req_failed" {
+			unit = ""
+		}
+		_, _ = fmt.Fprintf(w, "  %s %s (actual %.4g%s)\n", mark, t, actual, unit)
+	}
+}
+
+func durations(e *series) string {
+	round := func(d time.Duration) time.Duration {
+		return d.Round(time.Microsecond)
+	}
+
+	return fmt.Sprintf("avg=%s min=%s med=%s p(90)=%s p(95)=%s p(99)=%s max=%s",
+		round(e.avg()), round(e.percentile(0)), round(e.percentile(50)),
+		round(e.percentile(90)), round(e.percentile(95)), round(e.percentile(99)), round(e.percentile(100)))
+}
+
+func statuses(e *series) string {
+	codes := make([]int, 0, len(e.status))
+	for code := range e.status {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+
+	parts := make([]string, 0, len(codes)+1)
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%d=%d", code, e.status[code]))
+	}
+	if e.errors > 0 {
+		parts = append(parts, fmt.Sprintf("error=%d", e.errors))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,91 @@
+package loadgen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Threshold is one k6 like expression, for example "p(95)<200" on "http_req_duration{endpoint:login}".
+type Threshold struct {
+	Metric   string
+	Endpoint string
+	Expr     string
+
+	agg   string
+	op    string
+	value float64
+}
+
+var (
+	thresholdMetric = regexp.MustCompile(`^(http_req_duration|http_req_failed)(?:\{endpoint:([^}]+)\})?$`)
+	thresholdExpr   = regexp.MustCompile(`^\s*(avg|min|max|med|rate|p\((\d+(?:\.\d+)?)\))\s*(<=|>=|==|<|>)\s*(\d+(?:\.\d+)?)\s*$`)
+)
+
+func parseThreshold(metric, expr string) (Threshold, error) {
+	m := thresholdMetric.FindStringSubmatch(metric)
+	if m == nil {
+		return Threshold{}, fmt.Errorf("unsupported threshold metric %q, must be http_req_duration or http_req_failed, optionally with {endpoint:name}", metric)
+	}
+
+	e := thresholdExpr.FindStringSubmatch(expr)
+	if e == nil {
+		return Threshold{}, fmt.Errorf("invalid threshold %q of %s", expr, metric)
+	}
+
+	t := Threshold{Metric: m[1], Endpoint: m[2], Expr: strings.TrimSpace(expr), agg: e[1], op: e[3]}
+	t.value, _ = strconv.ParseFloat(e[4], 64)
+
+	if (t.Metric == "http_req_failed") != (t.agg == "rate") {
+		return Threshold{}, fmt.Errorf("threshold %q: http_req_failed only supports rate, http_req_duration does not", expr)
+	}
+
+	return t, nil
+}
+
+// Check evaluates the threshold, the durations are in milliseconds and the rate is between 0 and 1.
+func (t Threshold) Check(stats *Stats) (actual float64, ok bool) {
+	s := stats.total()
+	if t.Endpoint != "" {
+		s = stats.endpoint(t.Endpoint)
+	}
+
+	switch {
+	case t.agg == "rate":
+		actual = s.failedRate()
+	case t.agg == "avg":
+		actual = ms(s.avg())
+	case t.agg == "min":
+		actual = ms(s.percentile(0))
+	case t.agg == "max":
+		actual = ms(s.percentile(100))
+	case t.agg == "med":
+		actual = ms(s.percentile(50))
+	default:
+		p, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimPrefix(t.agg, "p("), ")"), 64)
+		actual = ms(s.percentile(p))
+	}
+
+	switch t.op {
+	case "<":
+		ok = actual < t.value
+	case "<=":
+		ok = actual <= t.value
+	case ">":
+		ok = actual > t.value
+	case ">=":
+		ok = actual >= t.value
+	default:
+		ok = actual == t.value
+	}
+
+	return actual, ok
+}
+
+func (t Threshold) String() string {
+	if t.Endpoint != "" {
+		return fmt.Sprintf("%s{endpoint:%s} %s", t.Metric, t.Endpoint, t.Expr)
+	}
+	return t.Metric + " " + t.Expr
+}
@@ -0,0 +1,265 @@
+// Package lockout tracks the failed logins in a sliding window, per username and per client IP,
+// and locks the username or blocks the client IP when there are too many of them.
+// The entries are kept in a LRU by scope, so the memory is bounded whatever the number of attempted usernames is.
+package lockout
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/lockout"
+
+const (
+	// ScopeUsername is the lock of an account, the login is rejected with 423 Locked.
+	ScopeUsername = "username"
+
+	// ScopeClientIP is the block of a client IP trying many usernames, the login is rejected with 429 Too Many Requests.
+	ScopeClientIP = "client_ip"
+)
+
+// Config of the Tracker, a zero MaxFailures disables the lock of its scope.
+type Config struct {
+	// MaxUsernameFailures locks the username after this number of failures in the Window.
+	MaxUsernameFailures int
+
+	// MaxClientIPFailures blocks the client IP after this number of failures in the Window, whatever the usernames are.
+	MaxClientIPFailures int
+
+	// Window is the duration in which the failures are counted, by default 5 minutes.
+	Window time.Duration
+
+	// Duration of the lock, by default 15 minutes.
+	Duration time.Duration
+
+	// MaxEntries is the number of usernames, and of client IPs, kept. The least recently failed one is evicted, with its lock.
+	// By default it is 10000.
+	MaxEntries int
+}
+
+// Decision tells whether the login is locked, and until when.
+type Decision struct {
+	// Scope is ScopeUsername or ScopeClientIP, empty when the login is not locked.
+	Scope string
+
+	// RetryAfter is the remaining duration of the lock.
+	RetryAfter time.Duration
+
+	// Failures is the number of failures in the window of the scope, only set by Failure.
+	Failures int
+}
+
+// Locked reports whether the login is rejected.
+func (d Decision) Locked() bool {
+	return d.Scope != ""
+}
+
+type entry struct {
+	value       string
+	failures    []time.Time
+	lockedUntil time.Time
+}
+
+// expired reports whether the entry has neither failure in the window nor lock.
+func (e *entry) expired(now time.Time, window time.Duration) bool {
+	return (len(e.failures) == 0 || !e.failures[len(e.failures)-1].After(now.Add(-window))) && !now.Before(e.lockedUntil)
+}
+
+// entries of a scope, the list is ordered by the last failure, the most recent first.
+type entries struct {
+	values map[string]*list.Element
+	lru    *list.List
+}
+
+func (s *entries) get(value string) (*entry, bool) {
+	elem, ok := s.values[value]
+	if !ok {
+		return nil, false
+	}
+	return elem.Value.(*entry), true
+}
+
+func (s *entries) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.values, elem.Value.(*entry).value)
+}
+
+// Tracker is safe for concurrent use. Every call removes the expired entries from the end of the LRU,
+// up to the first one which is still failing or locked, so it does not walk all the entries.
+type Tracker struct {
+	cfg Config
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entries
+}
+
+// NewTracker creates a Tracker, and the gauge "<metricPrefix>.lockout.locked" of the locked usernames and blocked client IPs by scope.
+func NewTracker(cfg Config, metricPrefix string) *Tracker {
+	if cfg.Window <= 0 {
+		cfg.Window = 5 * time.Minute
+	}
+
+	if cfg.Duration <= 0 {
+		cfg.Duration = 15 * time.Minute
+	}
+
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+
+	t := &Tracker{
+		cfg: cfg,
+		now: time.Now,
+		entries: map[string]*entries{
+			ScopeUsername: {values: map[string]*list.Element{}, lru: list.New()},
+			ScopeClientIP: {values: map[string]*list.Element{}, lru: list.New()},
+		},
+	}
+
+	_, err := otel.Meter(instrumentationName).Int64ObservableGauge(metricPrefix+".lockout.locked",
+		metric.WithDescription("Number of the locked usernames and the blocked client IPs."),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			for scope, count := range t.Locked() {
+				o.Observe(int64(count), metric.WithAttributes(attribute.String("scope", scope)))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		slog.Error("failed to create lockout.locked gauge", slog.Any("error", err))
+	}
+
+	return t
+}
+
+// Config returns the configuration with the defaults.
+func (t *Tracker) Config() Config {
+	return t.cfg
+}
+
+// Check returns whether the username or the client IP is locked, the username first.
+func (t *Tracker) Check(username, clientIP string) Decision {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(now)
+
+	for _, key := range [...]struct{ scope, value string }{{ScopeUsername, username}, {ScopeClientIP, clientIP}} {
+		if e, ok := t.entries[key.scope].get(key.value); ok && now.Before(e.lockedUntil) {
+			return Decision{Scope: key.scope, RetryAfter: e.lockedUntil.Sub(now)}
+		}
+	}
+
+	return Decision{}
+}
+
+// Failure records a failed login, and returns the lock it started, if any. The username lock is returned first when both start.
+// The Failures of the returned Decision is the one of the username when nothing is locked.
+func (t *Tracker) Failure(username, clientIP string) Decision {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(now)
+
+	usernameLock := t.record(ScopeUsername, username, t.cfg.MaxUsernameFailures, now)
+	clientIPLock := t.record(ScopeClientIP, clientIP, t.cfg.MaxClientIPFailures, now)
+
+	switch {
+	case usernameLock.Locked():
+		return usernameLock
+	case clientIPLock.Locked():
+		return clientIPLock
+	default:
+		return usernameLock
+	}
+}
+
+// Success forgets the failures of the username, the failures of the client IP are kept.
+func (t *Tracker) Success(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if elem, ok := t.entries[ScopeUsername].values[username]; ok {
+		t.entries[ScopeUsername].remove(elem)
+	}
+}
+
+// Locked returns the number of the locked usernames and client IPs by scope.
+func (t *Tracker) Locked() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	locked := map[string]int{ScopeUsername: 0, ScopeClientIP: 0}
+	for scope, entries := range t.entries {
+		for elem := entries.lru.Front(); elem != nil; elem = elem.Next() {
+			if now.Before(elem.Value.(*entry).lockedUntil) {
+				locked[scope]++
+			}
+		}
+	}
+
+	return locked
+}
+
+func (t *Tracker) record(scope, value string, maxFailures int, now time.Time) Decision {
+	if maxFailures <= 0 || value == "" {
+		return Decision{}
+	}
+
+	e := t.entry(scope, value)
+
+	since := now.Add(-t.cfg.Window)
+	i := 0
+	for i < len(e.failures) && !e.failures[i].After(since) {
+		i++
+	}
+	e.failures = append(e.failures[i:], now)
+	if len(e.failures) < maxFailures || now.Before(e.lockedUntil) {
+		return Decision{Failures: len(e.failures)}
+	}
+
+	// The failures are forgotten, the next lock needs maxFailures again after this one ends.
+	e.lockedUntil = now.Add(t.cfg.Duration)
+	failures := len(e.failures)
+	e.failures = nil
+
+	return Decision{Scope: scope, RetryAfter: t.cfg.Duration, Failures: failures}
+}
+
+// entry returns the entry of the value as the most recently failed, a new one evicts the least recently failed when the scope is full.
+func (t *Tracker) entry(scope, value string) *entry {
+	entries := t.entries[scope]
+	if elem, ok := entries.values[value]; ok {
+		entries.lru.MoveToFront(elem)
+		return elem.Value.(*entry)
+	}
+
+	if entries.lru.Len() >= t.cfg.MaxEntries {
+		entries.remove(entries.lru.Back())
+	}
+
+	e := &entry{value: value}
+	entries.values[value] = entries.lru.PushFront(e)
+	return e
+}
+
+// prune removes the expired entries from the end of the LRU, and stops at the first one which is not expired.
+// A lock longer than the window can keep older entries a while, they are still bounded by MaxEntries.
+func (t *Tracker) prune(now time.Time) {
+	for _, entries := range t.entries {
+		for elem := entries.lru.Back(); elem != nil && elem.Value.(*entry).expired(now, t.cfg.Window); elem = entries.lru.Back() {
+			entries.remove(elem)
+		}
+	}
+}
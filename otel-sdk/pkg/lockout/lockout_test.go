@@ -0,0 +1,102 @@
+package lockout
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTracker returns a tracker with a clock moved by the returned function.
+func newTracker(cfg Config) (*Tracker, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t := NewTracker(cfg, "test")
+	t.now = func() time.Time { return now }
+	return t, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestTrackerLock(t *testing.T) {
+	tracker, advance := newTracker(Config{MaxUsernameFailures: 3, Window: time.Minute, Duration: 10 * time.Minute})
+
+	for i := 1; i < 3; i++ {
+		if d := tracker.Failure("user1", "10.0.0.1"); d.Locked() || d.Failures != i {
+			t.Fatalf("failure %d = %+v", i, d)
+		}
+	}
+
+	if d := tracker.Failure("user1", "10.0.0.1"); d.Scope != ScopeUsername || d.RetryAfter != 10*time.Minute || d.Failures != 3 {
+		t.Fatalf("third failure = %+v, want the username lock", d)
+	}
+
+	advance(9 * time.Minute)
+	if d := tracker.Check("user1", "10.0.0.2"); d.Scope != ScopeUsername || d.RetryAfter != time.Minute {
+		t.Errorf("Check() = %+v, want locked for 1 minute", d)
+	}
+
+	advance(time.Minute)
+	if d := tracker.Check("user1", "10.0.0.2"); d.Locked() {
+		t.Errorf("Check() after the lock = %+v", d)
+	}
+}
+
+func TestTrackerWindow(t *testing.T) {
+	tracker, advance := newTracker(Config{MaxUsernameFailures: 2, Window: time.Minute})
+
+	tracker.Failure("user1", "10.0.0.1")
+	advance(time.Minute)
+
+	// The first failure is out of the window, and its entry was pruned.
+	if n := tracker.entries[ScopeUsername].lru.Len(); n != 1 {
+		t.Fatalf("%d usernames tracked, want 1", n)
+	}
+	tracker.Check("", "")
+	if n := tracker.entries[ScopeUsername].lru.Len(); n != 0 {
+		t.Errorf("%d usernames tracked after the window, want 0", n)
+	}
+
+	if d := tracker.Failure("user1", "10.0.0.1"); d.Locked() || d.Failures != 1 {
+		t.Errorf("failure after the window = %+v", d)
+	}
+}
+
+func TestTrackerMaxEntries(t *testing.T) {
+	tracker, advance := newTracker(Config{MaxUsernameFailures: 2, MaxClientIPFailures: 1000, MaxEntries: 3})
+
+	tracker.Failure("user1", "10.0.0.1")
+	for i := range 10 {
+		advance(time.Second)
+		tracker.Failure(fmt.Sprintf("unknown%d", i), "10.0.0.1")
+	}
+
+	entries := tracker.entries[ScopeUsername]
+	if entries.lru.Len() != 3 || len(entries.values) != 3 {
+		t.Fatalf("%d usernames tracked, want 3", entries.lru.Len())
+	}
+	if _, ok := entries.get("user1"); ok {
+		t.Error("the least recently failed username is not evicted")
+	}
+	if e, ok := entries.get("unknown9"); !ok || entries.lru.Front().Value != e {
+		t.Error("the most recently failed username is not the front of the LRU")
+	}
+
+	// The evicted username starts over.
+	if d := tracker.Failure("user1", "10.0.0.1"); d.Locked() || d.Failures != 1 {
+		t.Errorf("failure of the evicted username = %+v", d)
+	}
+}
+
+func TestTrackerSuccess(t *testing.T) {
+	tracker, _ := newTracker(Config{MaxUsernameFailures: 2, MaxClientIPFailures: 2})
+
+	tracker.Failure("user1", "10.0.0.1")
+	tracker.Success("user1")
+
+	if _, ok := tracker.entries[ScopeUsername].get("user1"); ok {
+		t.Error("the failures of the username are kept after a success")
+	}
+	if d := tracker.Failure("user2", "10.0.0.1"); d.Scope != ScopeClientIP {
+		t.Errorf("failure = %+v, want the client IP block", d)
+	}
+	if locked := tracker.Locked(); locked[ScopeUsername] != 0 || locked[ScopeClientIP] != 1 {
+		t.Errorf("Locked() = %v", locked)
+	}
+}
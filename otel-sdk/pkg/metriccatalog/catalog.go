@@ -0,0 +1,267 @@
+// Package metriccatalog lists the instruments created by the application, with the series names written by every metric backend,
+// so the names in the dashboards can be checked without reading the translation rules of the exporters.
+package metriccatalog
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/metricname"
+)
+
+// The keys of Instrument.Series.
+const (
+	BackendPrometheus = "prometheus"
+	BackendDogStatsD  = "dogstatsd"
+)
+
+// Backends are the metric backends configured in the application.
+type Backends struct {
+	// Prometheus is the Prometheus exporter of the /metrics endpoint.
+	Prometheus bool
+
+	// OTLP is the OTLP exporter, the collector writes the metrics to Grafana Mimir and to Datadog.
+	OTLP bool
+}
+
+// Scope is the instrumentation scope of the meter which created the instrument.
+type Scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Instrument is an instrument created by the application, with its series in every backend.
+type Instrument struct {
+	Name        string          `json:"name"`
+	Kind        appmetrics.Kind `json:"kind"`
+	Unit        string          `json:"unit,omitempty"`
+	Description string          `json:"description,omitempty"`
+
+	// AttributeKeys are the keys declared in appmetrics, and the keys of the recorded values.
+	AttributeKeys []string `json:"attribute_keys"`
+	Scope         Scope    `json:"scope"`
+
+	// Series are the series of the instrument by backend, the backends which are not configured are omitted.
+	// DogStatsD is the series sent by dd-sdk, only for the appmetrics instruments which dd-sdk sends too.
+	Series map[string][]metricname.Series `json:"series"`
+}
+
+type instrumentID struct {
+	scope Scope
+	name  string
+}
+
+type seriesKey struct {
+	backend string
+	inst    metricname.Instrument
+}
+
+// Catalog records the instruments created with its MeterProvider, and reads the attribute keys of their values with its Reader.
+// It is safe for concurrent use.
+type Catalog struct {
+	serviceName string
+	reader      *otelSdkMetric.ManualReader
+	registry    *appmetrics.Registry
+
+	mu          sync.Mutex
+	backends    Backends
+	instruments map[instrumentID]Instrument
+	series      map[seriesKey][]metricname.Series
+}
+
+// New creates an empty Catalog of the service, the declarations of the instruments are read from appmetrics.Default.
+func New(serviceName string) *Catalog {
+	return &Catalog{
+		serviceName: serviceName,
+		reader:      otelSdkMetric.NewManualReader(),
+		registry:    appmetrics.Default,
+		instruments: map[instrumentID]Instrument{},
+		series:      map[seriesKey][]metricname.Series{},
+	}
+}
+
+// Reader must be a reader of the SDK MeterProvider, the attribute keys are read from it.
+func (c *Catalog) Reader() otelSdkMetric.Reader {
+	return c.reader
+}
+
+// MeterProvider wraps the provider, the instruments created with its meters are added to the Catalog.
+func (c *Catalog) MeterProvider(provider metric.MeterProvider) metric.MeterProvider {
+	return &meterProvider{MeterProvider: provider, catalog: c}
+}
+
+// SetBackends sets the configured backends.
+func (c *Catalog) SetBackends(backends Backends) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.backends = backends
+}
+
+func (c *Catalog) add(inst Instrument) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.instruments[instrumentID{scope: inst.Scope, name: inst.Name}] = inst
+}
+
+// Instruments returns the instruments sorted by name, then by scope.
+// The instruments declared in appmetrics are listed before they are used for the first time.
+func (c *Catalog) Instruments(ctx context.Context) []Instrument {
+	observed := c.observedKeys(ctx)
+
+	declarations := map[instrumentID]appmetrics.Instrument{}
+	for _, declared := range c.registry.Instruments() {
+		id := instrumentID{scope: Scope{Name: appmetrics.InstrumentationName}, name: c.serviceName + "." + declared.Name}
+		declarations[id] = declared
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	instruments := maps.Clone(c.instruments)
+	for id, declared := range declarations {
+		if _, ok := instruments[id]; !ok {
+			instruments[id] = Instrument{
+				Name:        id.name,
+				Kind:        declared.Kind,
+				Unit:        declared.Unit,
+				Description: declared.Description,
+				Scope:       id.scope,
+			}
+		}
+	}
+
+	list := make([]Instrument, 0, len(instruments))
+	for id, inst := range instruments {
+		keys := slices.Clone(observed[id])
+		declared, isDeclared := declarations[id]
+		for _, key := range declared.AttributeKeys {
+			keys = append(keys, string(key))
+		}
+		slices.Sort(keys)
+		inst.AttributeKeys = slices.Compact(keys)
+		if inst.AttributeKeys == nil {
+			inst.AttributeKeys = []string{}
+		}
+
+		inst.Series = map[string][]metricname.Series{}
+		name := metricname.Instrument{Name: inst.Name, Kind: inst.Kind, Unit: inst.Unit}
+
+		// Grafana Mimir gets the OTLP metrics through the Prometheus remote write exporter of the collector, with the same names as /metrics.
+		if c.backends.Prometheus || c.backends.OTLP {
+			inst.Series[BackendPrometheus] = c.cachedSeries(ctx, BackendPrometheus, name, func() ([]metricname.Series, error) {
+				return metricname.Prometheus(ctx, name)
+			})
+		}
+
+		if isDeclared && declared.DogStatsD {
+			dogstatsdName := metricname.Instrument{Name: declared.Name, Kind: declared.Kind}
+			inst.Series[BackendDogStatsD] = c.cachedSeries(ctx, BackendDogStatsD, dogstatsdName, func() ([]metricname.Series, error) {
+				return metricname.DogStatsD(appmetrics.DogStatsDNamespace, dogstatsdName)
+			})
+		}
+
+		list = append(list, inst)
+	}
+
+	slices.SortFunc(list, func(a, b Instrument) int {
+		if n := strings.Compare(a.Name, b.Name); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Scope.Name, b.Scope.Name)
+	})
+
+	return list
+}
+
+// cachedSeries translates the instrument once per backend, the translation does not change while the application runs.
+func (c *Catalog) cachedSeries(ctx context.Context, backend string, inst metricname.Instrument, translate func() ([]metricname.Series, error)) []metricname.Series {
+	key := seriesKey{backend: backend, inst: inst}
+	if series, ok := c.series[key]; ok {
+		return series
+	}
+
+	series, err := translate()
+	if err != nil {
+		slog.WarnContext(ctx, "failed to translate the metric name", slog.String("backend", backend),
+			slog.String("name", inst.Name), slog.Any("error", err))
+		return nil
+	}
+
+	c.series[key] = series
+	return series
+}
+
+// observedKeys collects the Reader and returns the attribute keys of the recorded values by instrument.
+func (c *Catalog) observedKeys(ctx context.Context) map[instrumentID][]string {
+	var rm metricdata.ResourceMetrics
+	if err := c.reader.Collect(ctx, &rm); err != nil {
+		slog.WarnContext(ctx, "failed to collect the metrics of the catalog", slog.Any("error", err))
+		return nil
+	}
+
+	observed := map[instrumentID][]string{}
+	for _, sm := range rm.ScopeMetrics {
+		scope := Scope{Name: sm.Scope.Name, Version: sm.Scope.Version}
+		for _, m := range sm.Metrics {
+			id := instrumentID{scope: scope, name: m.Name}
+			for _, set := range dataPointAttributes(m.Data) {
+				for _, kv := range set.ToSlice() {
+					observed[id] = append(observed[id], string(kv.Key))
+				}
+			}
+		}
+	}
+
+	return observed
+}
+
+func dataPointAttributes(data metricdata.Aggregation) []attribute.Set {
+	var sets []attribute.Set
+	switch data := data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range data.DataPoints {
+			sets = append(sets, dp.Attributes)
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range data.DataPoints {
+			sets = append(sets, dp.Attributes)
+		}
+	case metricdata.Gauge[int64]:
+		for _, dp := range data.DataPoints {
+			sets = append(sets, dp.Attributes)
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range data.DataPoints {
+			sets = append(sets, dp.Attributes)
+		}
+	case metricdata.Histogram[int64]:
+		for _, dp := range data.DataPoints {
+			sets = append(sets, dp.Attributes)
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range data.DataPoints {
+			sets = append(sets, dp.Attributes)
+		}
+	case metricdata.ExponentialHistogram[int64]:
+		for _, dp := range data.DataPoints {
+			sets = append(sets, dp.Attributes)
+		}
+	case metricdata.ExponentialHistogram[float64]:
+		for _, dp := range data.DataPoints {
+			sets = append(sets, dp.Attributes)
+		}
+	}
+	return sets
+}
@@ -0,0 +1,95 @@
+package metriccatalog
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// newTestCatalog returns a Catalog of the login.failure and token.issued declarations, with an instrument of another scope.
+func newTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+
+	c := New("poc_otel_sdk")
+	provider := c.MeterProvider(otelSdkMetric.NewMeterProvider(otelSdkMetric.WithReader(c.Reader())))
+
+	c.registry = appmetrics.NewRegistry()
+	c.registry.MeterProvider = func() metric.MeterProvider { return provider }
+	loginFailure := c.registry.Declare(appmetrics.LoginFailure)
+	c.registry.Declare(appmetrics.TokenIssued)
+
+	ctx := context.Background()
+	c.registry.Int64Counter(ctx, "poc_otel_sdk", loginFailure).Add(ctx, 1,
+		metric.WithAttributes(appmetrics.FailureReason(appmetrics.LoginWrongPassword)))
+
+	histogram, err := provider.Meter("go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp", metric.WithInstrumentationVersion("0.57.0")).
+		Float64Histogram("http.server.duration", metric.WithUnit("ms"), metric.WithDescription("Measures the duration of inbound HTTP requests."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	histogram.Record(ctx, 12, metric.WithAttributes(attribute.String("http.method", "GET"), attribute.Int("http.status_code", 200)))
+
+	return c
+}
+
+func TestHandlerGolden(t *testing.T) {
+	c := newTestCatalog(t)
+	c.SetBackends(Backends{Prometheus: true})
+
+	w := httptest.NewRecorder()
+	c.Handler(w, httptest.NewRequest(http.MethodGet, "/debug/metrics/catalog", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	golden := filepath.Join("testdata", "catalog.json")
+	if *update {
+		if err := os.WriteFile(golden, w.Body.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Body.String(); got != string(want) {
+		t.Errorf("catalog differs from %s, run go test -update after checking it:\n%s", golden, got)
+	}
+}
+
+func TestInstrumentsBackends(t *testing.T) {
+	c := newTestCatalog(t)
+
+	// The declared instruments list the DogStatsD series of dd-sdk, even without the backends of otel-sdk.
+	for _, inst := range c.Instruments(context.Background()) {
+		if _, ok := inst.Series[BackendPrometheus]; ok {
+			t.Errorf("%s has prometheus series without the backend", inst.Name)
+		}
+
+		_, ok := inst.Series[BackendDogStatsD]
+		if want := inst.Name == "poc_otel_sdk.login.failure"; ok != want {
+			t.Errorf("%s has dogstatsd series %t, want %t", inst.Name, ok, want)
+		}
+	}
+
+	// The OTLP exporter alone lists the Prometheus names, the names stored by Grafana Mimir.
+	c.SetBackends(Backends{OTLP: true})
+	for _, inst := range c.Instruments(context.Background()) {
+		if len(inst.Series[BackendPrometheus]) == 0 {
+			t.Errorf("%s has no prometheus series with the OTLP exporter", inst.Name)
+		}
+	}
+}
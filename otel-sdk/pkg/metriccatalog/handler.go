@@ -0,0 +1,22 @@
+package metriccatalog
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// Handler responds the Instruments as JSON, for example at "/debug/metrics/catalog".
+func (c *Catalog) Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	body := struct {
+		Instruments []Instrument `json:"instruments"`
+	}{c.Instruments(r.Context())}
+
+	if err := encoder.Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write the metric catalog", slog.Any("error", err))
+	}
+}
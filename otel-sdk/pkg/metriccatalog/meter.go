@@ -0,0 +1,123 @@
+package metriccatalog
+
+import (
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+)
+
+// meterProvider records the instruments created with its meters in the Catalog.
+type meterProvider struct {
+	metric.MeterProvider
+	catalog *Catalog
+}
+
+func (p *meterProvider) Meter(name string, opts ...metric.MeterOption) metric.Meter {
+	cfg := metric.NewMeterConfig(opts...)
+	return &meter{
+		Meter:   p.MeterProvider.Meter(name, opts...),
+		catalog: p.catalog,
+		scope:   Scope{Name: name, Version: cfg.InstrumentationVersion()},
+	}
+}
+
+// meter records its instruments in the Catalog, the instruments and RegisterCallback are the ones of the wrapped meter.
+type meter struct {
+	metric.Meter
+	catalog *Catalog
+	scope   Scope
+}
+
+func (m *meter) add(name string, kind appmetrics.Kind, unit, description string) {
+	m.catalog.add(Instrument{
+		Name:        name,
+		Kind:        kind,
+		Unit:        unit,
+		Description: description,
+		Scope:       m.scope,
+	})
+}
+
+func (m *meter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	cfg := metric.NewInt64CounterConfig(options...)
+	m.add(name, appmetrics.KindInt64Counter, cfg.Unit(), cfg.Description())
+	return m.Meter.Int64Counter(name, options...)
+}
+
+func (m *meter) Int64UpDownCounter(name string, options ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	cfg := metric.NewInt64UpDownCounterConfig(options...)
+	m.add(name, appmetrics.KindInt64UpDownCounter, cfg.Unit(), cfg.Description())
+	return m.Meter.Int64UpDownCounter(name, options...)
+}
+
+func (m *meter) Int64Histogram(name string, options ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	cfg := metric.NewInt64HistogramConfig(options...)
+	m.add(name, appmetrics.KindInt64Histogram, cfg.Unit(), cfg.Description())
+	return m.Meter.Int64Histogram(name, options...)
+}
+
+func (m *meter) Int64Gauge(name string, options ...metric.Int64GaugeOption) (metric.Int64Gauge, error) {
+	cfg := metric.NewInt64GaugeConfig(options...)
+	m.add(name, appmetrics.KindInt64Gauge, cfg.Unit(), cfg.Description())
+	return m.Meter.Int64Gauge(name, options...)
+}
+
+func (m *meter) Int64ObservableCounter(name string, options ...metric.Int64ObservableCounterOption) (metric.Int64ObservableCounter, error) {
+	cfg := metric.NewInt64ObservableCounterConfig(options...)
+	m.add(name, appmetrics.KindInt64ObservableCounter, cfg.Unit(), cfg.Description())
+	return m.Meter.Int64ObservableCounter(name, options...)
+}
+
+func (m *meter) Int64ObservableUpDownCounter(name string, options ...metric.Int64ObservableUpDownCounterOption) (metric.Int64ObservableUpDownCounter, error) {
+	cfg := metric.NewInt64ObservableUpDownCounterConfig(options...)
+	m.add(name, appmetrics.KindInt64ObservableUpDownCounter, cfg.Unit(), cfg.Description())
+	return m.Meter.Int64ObservableUpDownCounter(name, options...)
+}
+
+func (m *meter) Int64ObservableGauge(name string, options ...metric.Int64ObservableGaugeOption) (metric.Int64ObservableGauge, error) {
+	cfg := metric.NewInt64ObservableGaugeConfig(options...)
+	m.add(name, appmetrics.KindInt64ObservableGauge, cfg.Unit(), cfg.Description())
+	return m.Meter.Int64ObservableGauge(name, options...)
+}
+
+func (m *meter) Float64Counter(name string, options ...metric.Float64CounterOption) (metric.Float64Counter, error) {
+	cfg := metric.NewFloat64CounterConfig(options...)
+	m.add(name, appmetrics.KindFloat64Counter, cfg.Unit(), cfg.Description())
+	return m.Meter.Float64Counter(name, options...)
+}
+
+func (m *meter) Float64UpDownCounter(name string, options ...metric.Float64UpDownCounterOption) (metric.Float64UpDownCounter, error) {
+	cfg := metric.NewFloat64UpDownCounterConfig(options...)
+	m.add(name, appmetrics.KindFloat64UpDownCounter, cfg.Unit(), cfg.Description())
+	return m.Meter.Float64UpDownCounter(name, options...)
+}
+
+func (m *meter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	cfg := metric.NewFloat64HistogramConfig(options...)
+	m.add(name, appmetrics.KindFloat64Histogram, cfg.Unit(), cfg.Description())
+	return m.Meter.Float64Histogram(name, options...)
+}
+
+func (m *meter) Float64Gauge(name string, options ...metric.Float64GaugeOption) (metric.Float64Gauge, error) {
+	cfg := metric.NewFloat64GaugeConfig(options...)
+	m.add(name, appmetrics.KindFloat64Gauge, cfg.Unit(), cfg.Description())
+	return m.Meter.Float64Gauge(name, options...)
+}
+
+func (m *meter) Float64ObservableCounter(name string, options ...metric.Float64ObservableCounterOption) (metric.Float64ObservableCounter, error) {
+	cfg := metric.NewFloat64ObservableCounterConfig(options...)
+	m.add(name, appmetrics.KindFloat64ObservableCounter, cfg.Unit(), cfg.Description())
+	return m.Meter.Float64ObservableCounter(name, options...)
+}
+
+func (m *meter) Float64ObservableUpDownCounter(name string, options ...metric.Float64ObservableUpDownCounterOption) (metric.Float64ObservableUpDownCounter, error) {
+	cfg := metric.NewFloat64ObservableUpDownCounterConfig(options...)
+	m.add(name, appmetrics.KindFloat64ObservableUpDownCounter, cfg.Unit(), cfg.Description())
+	return m.Meter.Float64ObservableUpDownCounter(name, options...)
+}
+
+func (m *meter) Float64ObservableGauge(name string, options ...metric.Float64ObservableGaugeOption) (metric.Float64ObservableGauge, error) {
+	cfg := metric.NewFloat64ObservableGaugeConfig(options...)
+	m.add(name, appmetrics.KindFloat64ObservableGauge, cfg.Unit(), cfg.Description())
+	return m.Meter.Float64ObservableGauge(name, options...)
+}
@@ -0,0 +1,110 @@
+package metricname
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+)
+
+// dogstatsdTypes are the types of the DogStatsD datagram.
+var dogstatsdTypes = map[string]string{
+	"c": "count",
+	"g": "gauge",
+	"d": "distribution",
+	"h": "histogram",
+}
+
+// DogStatsD returns the series sent by dd-sdk for the instrument named without the service name prefix, for example "login.success".
+// The value is sent with the datadog-go client and the namespace of dd-sdk, the way dd-sdk sends it: Incr for the counters,
+// Count for the up down counters, Gauge for the gauges and Distribution for the histograms. The series is read from the datagram.
+func DogStatsD(namespace string, inst Instrument) ([]Series, error) {
+	var datagrams datagramWriter
+	client, err := statsd.NewWithWriter(&datagrams,
+		statsd.WithNamespace(namespace),
+		statsd.WithoutTelemetry(),
+		statsd.WithoutOriginDetection(),
+		statsd.WithoutClientSideAggregation(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create dogstatsd client: %w", err)
+	}
+
+	switch inst.Kind {
+	case appmetrics.KindInt64Counter, appmetrics.KindFloat64Counter,
+		appmetrics.KindInt64ObservableCounter, appmetrics.KindFloat64ObservableCounter:
+		err = client.Incr(inst.Name, nil, 1)
+	case appmetrics.KindInt64UpDownCounter, appmetrics.KindFloat64UpDownCounter,
+		appmetrics.KindInt64ObservableUpDownCounter, appmetrics.KindFloat64ObservableUpDownCounter:
+		err = client.Count(inst.Name, 1, nil, 1)
+	case appmetrics.KindInt64Histogram, appmetrics.KindFloat64Histogram:
+		err = client.Distribution(inst.Name, 1, nil, 1)
+	default:
+		err = client.Gauge(inst.Name, 1, nil, 1)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("send %q: %w", inst.Name, err)
+	}
+
+	// Close flushes the datagram to the writer.
+	if err = client.Close(); err != nil {
+		return nil, fmt.Errorf("flush dogstatsd client: %w", err)
+	}
+
+	var series []Series
+	for _, datagram := range strings.Split(strings.TrimSpace(datagrams.String()), "\n") {
+		// name:value|type|#tags
+		name, rest, ok := strings.Cut(datagram, ":")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Split(rest, "|")
+		if len(fields) < 2 {
+			continue
+		}
+
+		typ, ok := dogstatsdTypes[fields[1]]
+		if !ok {
+			typ = fields[1]
+		}
+		series = append(series, Series{Name: name, Type: typ})
+	}
+
+	if len(series) == 0 {
+		return nil, fmt.Errorf("instrument %q is not sent", inst.Name)
+	}
+	return series, nil
+}
+
+// datagramWriter keeps the datagrams written by the client.
+type datagramWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *datagramWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	if !bytes.HasSuffix(p, []byte("\n")) {
+		w.buf.WriteByte('\n')
+	}
+	return len(p), nil
+}
+
+func (w *datagramWriter) Close() error {
+	return nil
+}
+
+func (w *datagramWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.String()
+}
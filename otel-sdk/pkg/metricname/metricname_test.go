@@ -0,0 +1,93 @@
+package metricname
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+)
+
+func TestPrometheus(t *testing.T) {
+	tests := map[string]struct {
+		inst Instrument
+		want []Series
+	}{
+		"counter": {
+			Instrument{Name: "poc_otel_sdk.login.success", Kind: appmetrics.KindInt64Counter, Unit: "{login}"},
+			[]Series{{Name: "poc_otel_sdk_login_success_total", Type: "counter"}},
+		},
+		"counter in seconds": {
+			Instrument{Name: "poc_otel_sdk.cpu.time", Kind: appmetrics.KindFloat64ObservableCounter, Unit: "s"},
+			[]Series{{Name: "poc_otel_sdk_cpu_time_seconds_total", Type: "counter"}},
+		},
+		"up down counter": {
+			Instrument{Name: "poc_otel_sdk.ratelimit.tracked_clients", Kind: appmetrics.KindInt64UpDownCounter, Unit: "{client}"},
+			[]Series{{Name: "poc_otel_sdk_ratelimit_tracked_clients", Type: "gauge"}},
+		},
+		"gauge in bytes": {
+			Instrument{Name: "poc_otel_sdk.exportqueue.bytes", Kind: appmetrics.KindInt64ObservableGauge, Unit: "By"},
+			[]Series{{Name: "poc_otel_sdk_exportqueue_bytes", Type: "gauge"}},
+		},
+		"histogram in milliseconds": {
+			Instrument{Name: "poc_otel_sdk.userstore.lookup_duration_ms", Kind: appmetrics.KindFloat64Histogram, Unit: "ms"},
+			[]Series{
+				{Name: "poc_otel_sdk_userstore_lookup_duration_ms_milliseconds_bucket", Type: "histogram"},
+				{Name: "poc_otel_sdk_userstore_lookup_duration_ms_milliseconds_sum", Type: "histogram"},
+				{Name: "poc_otel_sdk_userstore_lookup_duration_ms_milliseconds_count", Type: "histogram"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Prometheus(context.Background(), test.inst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("Prometheus() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := Prometheus(context.Background(), Instrument{Name: "unknown", Kind: "summary"}); err == nil {
+		t.Error("Prometheus() of an unknown kind succeeded")
+	}
+}
+
+func TestDogStatsD(t *testing.T) {
+	tests := map[string]struct {
+		inst Instrument
+		want []Series
+	}{
+		"counter": {
+			Instrument{Name: "login.success", Kind: appmetrics.KindInt64Counter},
+			[]Series{{Name: "poc_dd_sdk_statsd.login.success", Type: "count"}},
+		},
+		"up down counter": {
+			Instrument{Name: "lockout.locked", Kind: appmetrics.KindInt64UpDownCounter},
+			[]Series{{Name: "poc_dd_sdk_statsd.lockout.locked", Type: "count"}},
+		},
+		"histogram": {
+			Instrument{Name: "userstore.lookup_duration_ms", Kind: appmetrics.KindFloat64Histogram},
+			[]Series{{Name: "poc_dd_sdk_statsd.userstore.lookup_duration_ms", Type: "distribution"}},
+		},
+		"gauge": {
+			Instrument{Name: "ratelimit.tracked_clients", Kind: appmetrics.KindInt64ObservableGauge},
+			[]Series{{Name: "poc_dd_sdk_statsd.ratelimit.tracked_clients", Type: "gauge"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := DogStatsD(appmetrics.DogStatsDNamespace, test.inst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("DogStatsD() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
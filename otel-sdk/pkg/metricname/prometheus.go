@@ -0,0 +1,146 @@
+// Package metricname translates the instruments to the series names of the metric backends,
+// with the translation code of their exporters when it is a dependency, instead of a copy of their rules.
+package metricname
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/appmetrics"
+)
+
+// Instrument is the instrument to translate, Name is the full name with the service name prefix, for example "poc_otel_sdk.login.success".
+type Instrument struct {
+	Name string
+	Kind appmetrics.Kind
+	Unit string
+}
+
+// Series is a series written by a backend, with its type in this backend.
+type Series struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Prometheus returns the series of the instrument on the /metrics endpoint, the names stored by Grafana Mimir.
+// The instrument is recorded once with the OpenTelemetry Prometheus exporter configured like otel-sdk, in a registry of its own.
+func Prometheus(ctx context.Context, inst Instrument) ([]Series, error) {
+	registry := promclient.NewRegistry()
+	exporter, err := prometheus.New(prometheus.WithRegisterer(registry), prometheus.WithoutTargetInfo(), prometheus.WithoutScopeInfo())
+	if err != nil {
+		return nil, fmt.Errorf("create prometheus exporter: %w", err)
+	}
+
+	provider := otelSdkMetric.NewMeterProvider(otelSdkMetric.WithReader(exporter))
+	defer func() { _ = provider.Shutdown(ctx) }()
+
+	if err = record(ctx, provider.Meter("metricname"), inst); err != nil {
+		return nil, err
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("gather prometheus metrics: %w", err)
+	}
+
+	var series []Series
+	for _, family := range families {
+		typ := strings.ToLower(family.GetType().String())
+		switch family.GetType() {
+		case dto.MetricType_HISTOGRAM:
+			for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+				series = append(series, Series{Name: family.GetName() + suffix, Type: typ})
+			}
+		default:
+			series = append(series, Series{Name: family.GetName(), Type: typ})
+		}
+	}
+
+	if len(series) == 0 {
+		return nil, fmt.Errorf("instrument %q is not exported", inst.Name)
+	}
+	return series, nil
+}
+
+// record creates the instrument with the meter and records one value.
+func record(ctx context.Context, meter metric.Meter, inst Instrument) error {
+	var err error
+	switch inst.Kind {
+	case appmetrics.KindInt64Counter:
+		var counter metric.Int64Counter
+		if counter, err = meter.Int64Counter(inst.Name, metric.WithUnit(inst.Unit)); err == nil {
+			counter.Add(ctx, 1)
+		}
+	case appmetrics.KindInt64UpDownCounter:
+		var counter metric.Int64UpDownCounter
+		if counter, err = meter.Int64UpDownCounter(inst.Name, metric.WithUnit(inst.Unit)); err == nil {
+			counter.Add(ctx, 1)
+		}
+	case appmetrics.KindInt64Histogram:
+		var histogram metric.Int64Histogram
+		if histogram, err = meter.Int64Histogram(inst.Name, metric.WithUnit(inst.Unit)); err == nil {
+			histogram.Record(ctx, 1)
+		}
+	case appmetrics.KindInt64Gauge:
+		var gauge metric.Int64Gauge
+		if gauge, err = meter.Int64Gauge(inst.Name, metric.WithUnit(inst.Unit)); err == nil {
+			gauge.Record(ctx, 1)
+		}
+	case appmetrics.KindInt64ObservableCounter:
+		_, err = meter.Int64ObservableCounter(inst.Name, metric.WithUnit(inst.Unit), metric.WithInt64Callback(observeInt64))
+	case appmetrics.KindInt64ObservableUpDownCounter:
+		_, err = meter.Int64ObservableUpDownCounter(inst.Name, metric.WithUnit(inst.Unit), metric.WithInt64Callback(observeInt64))
+	case appmetrics.KindInt64ObservableGauge:
+		_, err = meter.Int64ObservableGauge(inst.Name, metric.WithUnit(inst.Unit), metric.WithInt64Callback(observeInt64))
+	case appmetrics.KindFloat64Counter:
+		var counter metric.Float64Counter
+		if counter, err = meter.Float64Counter(inst.Name, metric.WithUnit(inst.Unit)); err == nil {
+			counter.Add(ctx, 1)
+		}
+	case appmetrics.KindFloat64UpDownCounter:
+		var counter metric.Float64UpDownCounter
+		if counter, err = meter.Float64UpDownCounter(inst.Name, metric.WithUnit(inst.Unit)); err == nil {
+			counter.Add(ctx, 1)
+		}
+	case appmetrics.KindFloat64Histogram:
+		var histogram metric.Float64Histogram
+		if histogram, err = meter.Float64Histogram(inst.Name, metric.WithUnit(inst.Unit)); err == nil {
+			histogram.Record(ctx, 1)
+		}
+	case appmetrics.KindFloat64Gauge:
+		var gauge metric.Float64Gauge
+		if gauge, err = meter.Float64Gauge(inst.Name, metric.WithUnit(inst.Unit)); err == nil {
+			gauge.Record(ctx, 1)
+		}
+	case appmetrics.KindFloat64ObservableCounter:
+		_, err = meter.Float64ObservableCounter(inst.Name, metric.WithUnit(inst.Unit), metric.WithFloat64Callback(observeFloat64))
+	case appmetrics.KindFloat64ObservableUpDownCounter:
+		_, err = meter.Float64ObservableUpDownCounter(inst.Name, metric.WithUnit(inst.Unit), metric.WithFloat64Callback(observeFloat64))
+	case appmetrics.KindFloat64ObservableGauge:
+		_, err = meter.Float64ObservableGauge(inst.Name, metric.WithUnit(inst.Unit), metric.WithFloat64Callback(observeFloat64))
+	default:
+		return fmt.Errorf("instrument %q has unknown kind %q", inst.Name, inst.Kind)
+	}
+
+	if err != nil {
+		return fmt.Errorf("create instrument %q: %w", inst.Name, err)
+	}
+	return nil
+}
+
+func observeInt64(_ context.Context, o metric.Int64Observer) error {
+	o.Observe(1)
+	return nil
+}
+
+func observeFloat64(_ context.Context, o metric.Float64Observer) error {
+	o.Observe(1)
+	return nil
+}
@@ -0,0 +1,126 @@
+// Package otlpauth holds the transport security and authentication settings shared by every OTLP exporter:
+// TLS or mTLS, static headers, and a bearer token file which is re-read when it is rotated.
+package otlpauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Config is the connection settings of the OTLP endpoint.
+type Config struct {
+	// Insecure uses plain "http://", every TLS setting below is ignored.
+	Insecure bool
+
+	// CAFile is a PEM bundle used to verify the server certificate, the system pool is used when empty.
+	CAFile string
+
+	// CertFile and KeyFile are the PEM client certificate and key for mTLS.
+	CertFile string
+	KeyFile  string
+
+	// ServerName overrides the name used to verify the server certificate.
+	ServerName string
+
+	// InsecureSkipVerify disables the server certificate verification, only for testing.
+	InsecureSkipVerify bool
+
+	// Headers are sent with every request, for example the tenant header "X-Scope-OrgID" of Grafana Mimir.
+	Headers map[string]string
+
+	// BearerTokenFile contains a token sent as "Authorization: Bearer <token>".
+	// The file is read again when it changes, so a rotated token is picked up without restart.
+	BearerTokenFile string
+}
+
+// TLSConfig builds the tls.Config, it returns nil when Insecure is true.
+func (c Config) TLSConfig() (*tls.Config, error) {
+	if c.Insecure {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify, //nolint:gosec // Explicitly configured, for testing only.
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read otlp ca file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in otlp ca file %s", c.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load otlp client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+// HeaderFunc returns a function building the headers of the next request: the static headers plus the bearer token.
+// The token file is only read again when its modification time or size changes.
+func (c Config) HeaderFunc() func() (map[string]string, error) {
+	static := maps.Clone(c.Headers)
+	if c.BearerTokenFile == "" {
+		return func() (map[string]string, error) {
+			return static, nil
+		}
+	}
+
+	token := NewTokenFile(c.BearerTokenFile)
+	return func() (map[string]string, error) {
+		value, err := token.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		headers := maps.Clone(static)
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers["Authorization"] = "Bearer " + value
+		return headers, nil
+	}
+}
+
+// ParseHeaders parses "key1=value1,key2=value2", the same format as OTEL_EXPORTER_OTLP_HEADERS.
+// Values are URL decoded, so a comma can be written as "%2C".
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid otlp header %q, must be key=value", pair)
+		}
+
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid otlp header value of %q: %w", key, err)
+		}
+		headers[key] = decoded
+	}
+
+	return headers, nil
+}
@@ -0,0 +1,298 @@
+package otlpauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPKI is a CA with a server certificate for "otlp.test" and a client certificate, written as PEM files.
+type testPKI struct {
+	caFile         string
+	serverCert     tls.Certificate
+	clientCertFile string
+	clientKeyFile  string
+	pool           *x509.CertPool
+}
+
+func newTestPKI(t *testing.T, serverIPs ...net.IP) testPKI {
+	t.Helper()
+
+	dir := t.TempDir()
+	caKey, caCert := newCertificate(t, nil, nil, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "otlp test ca"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	})
+
+	serverKey, serverCert := newCertificate(t, caKey, caCert, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "otlp.test"},
+		DNSNames:    []string{"otlp.test"},
+		IPAddresses: serverIPs,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+
+	clientKey, clientCert := newCertificate(t, caKey, caCert, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "otel-sdk"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	pki := testPKI{
+		caFile:         filepath.Join(dir, "ca.pem"),
+		clientCertFile: filepath.Join(dir, "client.pem"),
+		clientKeyFile:  filepath.Join(dir, "client-key.pem"),
+		serverCert:     tls.Certificate{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey},
+		pool:           x509.NewCertPool(),
+	}
+	pki.pool.AddCert(caCert)
+
+	writePEM(t, pki.caFile, "CERTIFICATE", caCert.Raw)
+	writePEM(t, pki.clientCertFile, "CERTIFICATE", clientCert.Raw)
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, pki.clientKeyFile, "EC PRIVATE KEY", keyDER)
+
+	return pki
+}
+
+func newCertificate(t *testing.T, parentKey *ecdsa.PrivateKey, parent, template *x509.Certificate) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTLSServer starts a local TLS server with the server certificate, requiring a client certificate when mTLS is true.
+func newTLSServer(t *testing.T, pki testPKI, mTLS bool, handler http.Handler) *httptest.Server {
+	t.Helper()
+
+	if handler == nil {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	}
+
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{pki.serverCert}}
+	if mTLS {
+		server.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+		server.TLS.ClientCAs = pki.pool
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, cfg Config, url string) error {
+	t.Helper()
+
+	tlsCfg, err := cfg.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig: %v", err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestTLSConfigInsecure(t *testing.T) {
+	tlsCfg, err := Config{Insecure: true, CAFile: "missing.pem"}.TLSConfig()
+	if err != nil || tlsCfg != nil {
+		t.Fatalf("TLSConfig() = %v, %v, want nil, nil", tlsCfg, err)
+	}
+}
+
+func TestTLSConfigCAFile(t *testing.T) {
+	pki := newTestPKI(t, net.IPv4(127, 0, 0, 1))
+	server := newTLSServer(t, pki, false, nil)
+
+	if err := get(t, Config{}, server.URL); err == nil {
+		t.Error("the server certificate is verified without the CA file")
+	}
+
+	if err := get(t, Config{CAFile: pki.caFile}, server.URL); err != nil {
+		t.Errorf("with the CA file: %v", err)
+	}
+}
+
+func TestTLSConfigServerName(t *testing.T) {
+	// The certificate is only valid for "otlp.test", not for the address of the server.
+	pki := newTestPKI(t)
+	server := newTLSServer(t, pki, false, nil)
+
+	if err := get(t, Config{CAFile: pki.caFile}, server.URL); err == nil {
+		t.Error("the server certificate is verified with the address of the server")
+	}
+
+	if err := get(t, Config{CAFile: pki.caFile, ServerName: "otlp.test"}, server.URL); err != nil {
+		t.Errorf("with the server name: %v", err)
+	}
+}
+
+func TestTLSConfigMutualTLS(t *testing.T) {
+	pki := newTestPKI(t, net.IPv4(127, 0, 0, 1))
+	server := newTLSServer(t, pki, true, nil)
+
+	if err := get(t, Config{CAFile: pki.caFile}, server.URL); err == nil {
+		t.Error("the server accepts a client without certificate")
+	}
+
+	cfg := Config{CAFile: pki.caFile, CertFile: pki.clientCertFile, KeyFile: pki.clientKeyFile}
+	if err := get(t, cfg, server.URL); err != nil {
+		t.Errorf("with the client certificate: %v", err)
+	}
+}
+
+func TestTLSConfigInsecureSkipVerify(t *testing.T) {
+	pki := newTestPKI(t)
+	server := newTLSServer(t, pki, false, nil)
+
+	if err := get(t, Config{InsecureSkipVerify: true}, server.URL); err != nil {
+		t.Errorf("with InsecureSkipVerify: %v", err)
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	pki := newTestPKI(t)
+	notPEM := filepath.Join(t.TempDir(), "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]Config{
+		"missing ca file":   {CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"ca file not pem":   {CAFile: notPEM},
+		"cert without key":  {CertFile: pki.clientCertFile},
+		"key without cert":  {KeyFile: pki.clientKeyFile},
+		"key does not pair": {CertFile: pki.clientCertFile, KeyFile: pki.caFile},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := cfg.TLSConfig(); err == nil {
+				t.Error("TLSConfig() succeeded")
+			}
+		})
+	}
+}
+
+func TestHeaderFuncStatic(t *testing.T) {
+	headers, err := Config{Headers: map[string]string{"X-Scope-OrgID": "demo"}}.HeaderFunc()()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(headers) != 1 || headers["X-Scope-OrgID"] != "demo" {
+		t.Errorf("headers = %v", headers)
+	}
+}
+
+func TestHeaderFuncBearerTokenRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	writeToken(t, path, "first\n", time.Now().Add(-time.Minute))
+
+	headerFunc := Config{Headers: map[string]string{"X-Scope-OrgID": "demo"}, BearerTokenFile: path}.HeaderFunc()
+	headers, err := headerFunc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers["Authorization"] != "Bearer first" || headers["X-Scope-OrgID"] != "demo" {
+		t.Errorf("headers = %v", headers)
+	}
+
+	writeToken(t, path, "second", time.Now())
+	if headers, _ = headerFunc(); headers["Authorization"] != "Bearer second" {
+		t.Errorf("after the rotation, Authorization = %q", headers["Authorization"])
+	}
+
+	// The last known token is kept along with the error when the file is gone.
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = headerFunc(); err == nil {
+		t.Error("no error when the token file is removed")
+	}
+}
+
+func TestTokenFileEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	writeToken(t, path, " \n", time.Now())
+
+	if _, err := NewTokenFile(path).Token(); err == nil {
+		t.Error("no error for an empty token file")
+	}
+}
+
+func writeToken(t *testing.T, path, token string, modTime time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(token), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders(" X-Scope-OrgID = demo ,, api-key=a%2Cb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 2 || headers["X-Scope-OrgID"] != "demo" || headers["api-key"] != "a,b" {
+		t.Errorf("headers = %v", headers)
+	}
+
+	for _, s := range []string{"novalue", "=value", "key=%zz"} {
+		if _, err = ParseHeaders(s); err == nil {
+			t.Errorf("ParseHeaders(%q) succeeded", s)
+		}
+	}
+}
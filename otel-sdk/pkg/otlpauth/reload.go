@@ -0,0 +1,217 @@
+package otlpauth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+
+	otelSdkLog "go.opentelemetry.io/otel/sdk/log"
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// generation is one exporter built with a given set of headers.
+type generation[E any] struct {
+	exporter E
+	headers  map[string]string
+	inflight sync.WaitGroup
+}
+
+// reloader rebuilds the exporter when the headers change, because the SDK OTLP exporters only accept static headers.
+// The previous exporter is shut down once its in-flight exports are done.
+type reloader[E any] struct {
+	headers  func() (map[string]string, error)
+	build    func(headers map[string]string) (E, error)
+	shutdown func(E, context.Context) error
+
+	mu      sync.Mutex
+	current *generation[E]
+}
+
+func newReloader[E any](
+	headers func() (map[string]string, error),
+	build func(headers map[string]string) (E, error),
+	shutdown func(E, context.Context) error,
+) (*reloader[E], error) {
+	h, err := headers()
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := build(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reloader[E]{
+		headers:  headers,
+		build:    build,
+		shutdown: shutdown,
+		current:  &generation[E]{exporter: exporter, headers: h},
+	}, nil
+}
+
+// acquire returns the exporter to use for one export, the caller must call release when the export is done.
+// When the headers cannot be read or the new exporter cannot be built, the current exporter is kept.
+func (r *reloader[E]) acquire(ctx context.Context) *generation[E] {
+	headers, headersErr := r.headers()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if headersErr != nil {
+		slog.WarnContext(ctx, "failed to read the otlp headers, keep the current exporter", slog.Any("error", headersErr))
+	} else if !maps.Equal(headers, r.current.headers) {
+		r.swap(ctx, headers)
+	}
+
+	r.current.inflight.Add(1)
+	return r.current
+}
+
+// swap must be called while holding the lock.
+func (r *reloader[E]) swap(ctx context.Context, headers map[string]string) {
+	exporter, err := r.build(headers)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to rebuild the otlp exporter with the new headers, keep the current exporter", slog.Any("error", err))
+		return
+	}
+
+	old := r.current
+	r.current = &generation[E]{exporter: exporter, headers: headers}
+
+	go func() {
+		old.inflight.Wait()
+		if _err := r.shutdown(old.exporter, context.Background()); _err != nil {
+			slog.Warn("failed to stop the previous otlp exporter", slog.Any("error", _err))
+		}
+	}()
+}
+
+func (r *reloader[E]) release(g *generation[E]) {
+	g.inflight.Done()
+}
+
+func (r *reloader[E]) exporter() E {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current.exporter
+}
+
+// export runs fn with the current exporter.
+func (r *reloader[E]) export(ctx context.Context, fn func(E) error) error {
+	g := r.acquire(ctx)
+	defer r.release(g)
+
+	return fn(g.exporter)
+}
+
+// SpanExporter is a span exporter rebuilt when the headers, for example the bearer token, change.
+type SpanExporter struct {
+	reloader *reloader[otelSdkTrace.SpanExporter]
+}
+
+var _ otelSdkTrace.SpanExporter = (*SpanExporter)(nil)
+
+// NewSpanExporter builds the first exporter with the current headers, and builds a new one every time they change.
+func NewSpanExporter(
+	headers func() (map[string]string, error),
+	build func(headers map[string]string) (otelSdkTrace.SpanExporter, error),
+) (*SpanExporter, error) {
+	r, err := newReloader(headers, build, otelSdkTrace.SpanExporter.Shutdown)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp span exporter: %w", err)
+	}
+
+	return &SpanExporter{reloader: r}, nil
+}
+
+func (e *SpanExporter) ExportSpans(ctx context.Context, spans []otelSdkTrace.ReadOnlySpan) error {
+	return e.reloader.export(ctx, func(exporter otelSdkTrace.SpanExporter) error {
+		return exporter.ExportSpans(ctx, spans)
+	})
+}
+
+func (e *SpanExporter) Shutdown(ctx context.Context) error {
+	return e.reloader.exporter().Shutdown(ctx)
+}
+
+// MetricExporter is a metric exporter rebuilt when the headers, for example the bearer token, change.
+type MetricExporter struct {
+	reloader *reloader[otelSdkMetric.Exporter]
+}
+
+var _ otelSdkMetric.Exporter = (*MetricExporter)(nil)
+
+// NewMetricExporter builds the first exporter with the current headers, and builds a new one every time they change.
+func NewMetricExporter(
+	headers func() (map[string]string, error),
+	build func(headers map[string]string) (otelSdkMetric.Exporter, error),
+) (*MetricExporter, error) {
+	r, err := newReloader(headers, build, otelSdkMetric.Exporter.Shutdown)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp metric exporter: %w", err)
+	}
+
+	return &MetricExporter{reloader: r}, nil
+}
+
+func (e *MetricExporter) Temporality(kind otelSdkMetric.InstrumentKind) metricdata.Temporality {
+	return e.reloader.exporter().Temporality(kind)
+}
+
+func (e *MetricExporter) Aggregation(kind otelSdkMetric.InstrumentKind) otelSdkMetric.Aggregation {
+	return e.reloader.exporter().Aggregation(kind)
+}
+
+func (e *MetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return e.reloader.export(ctx, func(exporter otelSdkMetric.Exporter) error {
+		return exporter.Export(ctx, rm)
+	})
+}
+
+func (e *MetricExporter) ForceFlush(ctx context.Context) error {
+	return e.reloader.exporter().ForceFlush(ctx)
+}
+
+func (e *MetricExporter) Shutdown(ctx context.Context) error {
+	return e.reloader.exporter().Shutdown(ctx)
+}
+
+// LogExporter is a log exporter rebuilt when the headers, for example the bearer token, change.
+type LogExporter struct {
+	reloader *reloader[otelSdkLog.Exporter]
+}
+
+var _ otelSdkLog.Exporter = (*LogExporter)(nil)
+
+// NewLogExporter builds the first exporter with the current headers, and builds a new one every time they change.
+func NewLogExporter(
+	headers func() (map[string]string, error),
+	build func(headers map[string]string) (otelSdkLog.Exporter, error),
+) (*LogExporter, error) {
+	r, err := newReloader(headers, build, otelSdkLog.Exporter.Shutdown)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp log exporter: %w", err)
+	}
+
+	return &LogExporter{reloader: r}, nil
+}
+
+func (e *LogExporter) Export(ctx context.Context, records []otelSdkLog.Record) error {
+	return e.reloader.export(ctx, func(exporter otelSdkLog.Exporter) error {
+		return exporter.Export(ctx, records)
+	})
+}
+
+func (e *LogExporter) ForceFlush(ctx context.Context) error {
+	return e.reloader.exporter().ForceFlush(ctx)
+}
+
+func (e *LogExporter) Shutdown(ctx context.Context) error {
+	return e.reloader.exporter().Shutdown(ctx)
+}
@@ -0,0 +1,149 @@
+package otlpauth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeSpanExporter records the headers it was built with and whether it was shut down.
+type fakeSpanExporter struct {
+	headers map[string]string
+
+	mu       sync.Mutex
+	exported int
+	shutdown chan struct{}
+}
+
+func (e *fakeSpanExporter) ExportSpans(context.Context, []otelSdkTrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.exported++
+	return nil
+}
+
+func (e *fakeSpanExporter) Shutdown(context.Context) error {
+	close(e.shutdown)
+	return nil
+}
+
+func TestSpanExporterRebuildsOnHeaderChange(t *testing.T) {
+	var mu sync.Mutex
+	token := "first"
+	headers := func() (map[string]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return map[string]string{"Authorization": "Bearer " + token}, nil
+	}
+
+	var built []*fakeSpanExporter
+	exporter, err := NewSpanExporter(headers, func(headers map[string]string) (otelSdkTrace.SpanExporter, error) {
+		e := &fakeSpanExporter{headers: headers, shutdown: make(chan struct{})}
+		built = append(built, e)
+		return e, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for range 2 {
+		if err = exporter.ExportSpans(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(built) != 1 || built[0].exported != 2 {
+		t.Fatalf("the exporter is rebuilt while the headers do not change: %d built", len(built))
+	}
+
+	mu.Lock()
+	token = "second"
+	mu.Unlock()
+
+	if err = exporter.ExportSpans(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(built) != 2 || built[1].headers["Authorization"] != "Bearer second" || built[1].exported != 1 {
+		t.Fatalf("the exporter is not rebuilt with the new headers: %d built", len(built))
+	}
+
+	select {
+	case <-built[0].shutdown:
+	case <-time.After(5 * time.Second):
+		t.Error("the previous exporter is not shut down")
+	}
+}
+
+// TestSpanExporterBearerTokenOverTLS exports to a local TLS server with the OTLP HTTP exporter,
+// the rotated bearer token must be sent without restart.
+func TestSpanExporterBearerTokenOverTLS(t *testing.T) {
+	pki := newTestPKI(t, net.IPv4(127, 0, 0, 1))
+
+	var mu sync.Mutex
+	var authorizations []string
+	server := newTLSServer(t, pki, true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		mu.Unlock()
+	}))
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeToken(t, tokenFile, "first", time.Now().Add(-time.Minute))
+
+	cfg := Config{
+		CAFile:          pki.caFile,
+		CertFile:        pki.clientCertFile,
+		KeyFile:         pki.clientKeyFile,
+		BearerTokenFile: tokenFile,
+	}
+	tlsCfg, err := cfg.TLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	exporter, err := NewSpanExporter(cfg.HeaderFunc(), func(headers map[string]string) (otelSdkTrace.SpanExporter, error) {
+		return otlptrace.New(ctx, otlptracehttp.NewClient(
+			otlptracehttp.WithEndpoint(endpoint.Host),
+			otlptracehttp.WithHeaders(headers),
+			otlptracehttp.WithTLSClientConfig(tlsCfg),
+			otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}),
+		))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = exporter.Shutdown(ctx) }()
+
+	spans := tracetest.SpanStubs{{Name: "login"}}.Snapshots()
+	if err = exporter.ExportSpans(ctx, spans); err != nil {
+		t.Fatal(err)
+	}
+
+	writeToken(t, tokenFile, "second", time.Now())
+	if err = exporter.ExportSpans(ctx, spans); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(authorizations) != 2 || authorizations[0] != "Bearer first" || authorizations[1] != "Bearer second" {
+		t.Errorf("Authorization headers = %q", authorizations)
+	}
+}
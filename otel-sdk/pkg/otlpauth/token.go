@@ -0,0 +1,56 @@
+package otlpauth
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenFile reads a token from a file, for example a projected Kubernetes service account token,
+// and reads it again only when the file changes.
+type TokenFile struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// NewTokenFile creates a TokenFile, the file is read on the first call of Token.
+func NewTokenFile(path string) *TokenFile {
+	return &TokenFile{path: path}
+}
+
+// Token returns the current token without the trailing new line.
+// When the file cannot be read anymore, the last known token is returned along with the error.
+func (t *TokenFile) Token() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return t.token, fmt.Errorf("stat otlp bearer token file: %w", err)
+	}
+
+	if t.token != "" && info.ModTime().Equal(t.modTime) && info.Size() == t.size {
+		return t.token, nil
+	}
+
+	content, err := os.ReadFile(t.path)
+	if err != nil {
+		return t.token, fmt.Errorf("read otlp bearer token file: %w", err)
+	}
+
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return t.token, fmt.Errorf("otlp bearer token file %s is empty", t.path)
+	}
+
+	t.token = token
+	t.modTime = info.ModTime()
+	t.size = info.Size()
+	return t.token, nil
+}
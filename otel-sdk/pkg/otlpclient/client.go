@@ -0,0 +1,183 @@
+// Package otlpclient sends already serialized OTLP protobuf requests over HTTP.
+// Unlike the SDK exporters it does not retry, the caller (for example the export queue) owns the retry policy.
+package otlpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of one OTLP HTTP endpoint.
+type Config struct {
+	// Endpoint is host and port, for example "localhost:4318", without the scheme.
+	Endpoint string
+
+	// URLPath is the signal path, for example "/v1/traces".
+	URLPath string
+
+	// Insecure sends the request using "http://" instead of "https://".
+	Insecure bool
+
+	// Gzip compresses the request body.
+	Gzip bool
+
+	// Timeout per request, default 10 seconds.
+	Timeout time.Duration
+
+	// TLSConfig is used for "https://", nil uses the system defaults.
+	TLSConfig *tls.Config
+
+	// Headers returns the headers of the next request, it is called for every request
+	// so a rotated credential is picked up, see otlpauth.Config.HeaderFunc.
+	Headers func() (map[string]string, error)
+}
+
+// Client posts OTLP protobuf payloads to a single endpoint.
+type Client struct {
+	url        string
+	gzip       bool
+	headers    func() (map[string]string, error)
+	httpClient *http.Client
+}
+
+// New creates a Client from the Config.
+func New(cfg Config) *Client {
+	scheme := "https"
+	if cfg.Insecure {
+		scheme = "http"
+	}
+
+	urlPath := cfg.URLPath
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLSConfig != nil {
+		transport.TLSClientConfig = cfg.TLSConfig.Clone()
+	}
+
+	return &Client{
+		url:     fmt.Sprintf("%s://%s%s", scheme, strings.TrimSpace(cfg.Endpoint), urlPath),
+		gzip:    cfg.Gzip,
+		headers: cfg.Headers,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+	}
+}
+
+// URL returns the full URL where the payloads are sent.
+func (c *Client) URL() string {
+	return c.url
+}
+
+// Send posts one serialized OTLP export request.
+// Errors returned by the server are *StatusError, use Temporary to know whether it is worth to retry.
+func (c *Client) Send(ctx context.Context, body []byte) error {
+	payload := body
+	if c.gzip {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(body); err != nil {
+			return fmt.Errorf("gzip otlp payload: %w", err)
+		}
+
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("gzip otlp payload: %w", err)
+		}
+		payload = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create otlp request: %w", err)
+	}
+
+	if c.headers != nil {
+		headers, headersErr := c.headers()
+		if headersErr != nil {
+			return fmt.Errorf("otlp request headers: %w", headersErr)
+		}
+
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+	}
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if c.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send otlp request to %s: %w", c.url, err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	// Drain the body, so the connection can be reused.
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	statusErr := &StatusError{
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(respBody)),
+	}
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, _err := strconv.Atoi(retryAfter); _err == nil {
+			statusErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return statusErr
+}
+
+// StatusError is returned when the OTLP endpoint responds with a non 2xx status code.
+type StatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("otlp endpoint responded %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// Backoff returns the delay requested by the server through the Retry-After header.
+func (e *StatusError) Backoff() time.Duration {
+	return e.RetryAfter
+}
+
+// Temporary reports whether the same request may succeed later, following the OTLP/HTTP specification.
+func (e *StatusError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,101 @@
+package otlpconv
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Resource transforms an SDK resource into an OTLP resource.
+func Resource(res *resource.Resource) *resourcepb.Resource {
+	if res == nil {
+		return &resourcepb.Resource{}
+	}
+
+	return &resourcepb.Resource{
+		Attributes: AttrIter(res.Iter()),
+	}
+}
+
+// AttrIter transforms an attribute iterator into OTLP key-values.
+func AttrIter(iter attribute.Iterator) []*commonpb.KeyValue {
+	if iter.Len() == 0 {
+		return nil
+	}
+
+	out := make([]*commonpb.KeyValue, 0, iter.Len())
+	for iter.Next() {
+		out = append(out, KeyValue(iter.Attribute()))
+	}
+	return out
+}
+
+// KeyValues transforms a slice of attribute key-values into OTLP key-values.
+func KeyValues(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	out := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		out = append(out, KeyValue(kv))
+	}
+	return out
+}
+
+// KeyValue transforms an attribute key-value into an OTLP key-value.
+func KeyValue(kv attribute.KeyValue) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: string(kv.Key), Value: Value(kv.Value)}
+}
+
+// Value transforms an attribute value into an OTLP AnyValue.
+func Value(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return boolValue(v.AsBool())
+	case attribute.INT64:
+		return intValue(v.AsInt64())
+	case attribute.FLOAT64:
+		return doubleValue(v.AsFloat64())
+	case attribute.STRING:
+		return stringValue(v.AsString())
+	case attribute.BOOLSLICE:
+		return arrayValue(v.AsBoolSlice(), boolValue)
+	case attribute.INT64SLICE:
+		return arrayValue(v.AsInt64Slice(), intValue)
+	case attribute.FLOAT64SLICE:
+		return arrayValue(v.AsFloat64Slice(), doubleValue)
+	case attribute.STRINGSLICE:
+		return arrayValue(v.AsStringSlice(), stringValue)
+	default:
+		return stringValue("INVALID")
+	}
+}
+
+func boolValue(v bool) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+}
+
+func intValue(v int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+}
+
+func doubleValue(v float64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+}
+
+func stringValue(v string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+}
+
+func arrayValue[T any](vals []T, conv func(T) *commonpb.AnyValue) *commonpb.AnyValue {
+	values := make([]*commonpb.AnyValue, len(vals))
+	for i, v := range vals {
+		values[i] = conv(v)
+	}
+
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{
+		ArrayValue: &commonpb.ArrayValue{Values: values},
+	}}
+}
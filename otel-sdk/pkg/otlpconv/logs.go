@@ -0,0 +1,106 @@
+package otlpconv
+
+import (
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	otelSdkLog "go.opentelemetry.io/otel/sdk/log"
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// LogsRequest converts the records into an OTLP export request.
+// Records emitted by one LoggerProvider share the same resource, so they are grouped by instrumentation scope only.
+func LogsRequest(records []otelSdkLog.Record) *collectorlogspb.ExportLogsServiceRequest {
+	if len(records) == 0 {
+		return &collectorlogspb.ExportLogsServiceRequest{}
+	}
+
+	res := records[0].Resource()
+	resourceLogs := &logspb.ResourceLogs{
+		Resource:  Resource(&res),
+		SchemaUrl: res.SchemaURL(),
+	}
+
+	scopes := make(map[instrumentation.Scope]*logspb.ScopeLogs)
+	for i := range records {
+		scope := records[i].InstrumentationScope()
+
+		scopeLogs, ok := scopes[scope]
+		if !ok {
+			scopeLogs = &logspb.ScopeLogs{
+				Scope: &commonpb.InstrumentationScope{
+					Name:       scope.Name,
+					Version:    scope.Version,
+					Attributes: AttrIter(scope.Attributes.Iter()),
+				},
+				SchemaUrl: scope.SchemaURL,
+			}
+			scopes[scope] = scopeLogs
+			resourceLogs.ScopeLogs = append(resourceLogs.ScopeLogs, scopeLogs)
+		}
+
+		scopeLogs.LogRecords = append(scopeLogs.LogRecords, LogRecord(&records[i]))
+	}
+
+	return &collectorlogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{resourceLogs},
+	}
+}
+
+// LogRecord converts one SDK log record into an OTLP log record.
+func LogRecord(record *otelSdkLog.Record) *logspb.LogRecord {
+	out := &logspb.LogRecord{
+		TimeUnixNano:         unixNano(record.Timestamp()),
+		ObservedTimeUnixNano: unixNano(record.ObservedTimestamp()),
+		SeverityNumber:       logspb.SeverityNumber(record.Severity()),
+		SeverityText:         record.SeverityText(),
+		Body:                 LogValue(record.Body()),
+		Attributes:           make([]*commonpb.KeyValue, 0, record.AttributesLen()),
+		Flags:                uint32(record.TraceFlags()),
+	}
+
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		out.Attributes = append(out.Attributes, &commonpb.KeyValue{Key: kv.Key, Value: LogValue(kv.Value)})
+		return true
+	})
+
+	if traceID := record.TraceID(); traceID.IsValid() {
+		out.TraceId = traceID[:]
+	}
+
+	if spanID := record.SpanID(); spanID.IsValid() {
+		out.SpanId = spanID[:]
+	}
+
+	return out
+}
+
+// LogValue converts a log API value into an OTLP AnyValue, an empty value becomes nil.
+func LogValue(v log.Value) *commonpb.AnyValue {
+	switch v.Kind() {
+	case log.KindBool:
+		return boolValue(v.AsBool())
+	case log.KindInt64:
+		return intValue(v.AsInt64())
+	case log.KindFloat64:
+		return doubleValue(v.AsFloat64())
+	case log.KindString:
+		return stringValue(v.AsString())
+	case log.KindBytes:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v.AsBytes()}}
+	case log.KindSlice:
+		return arrayValue(v.AsSlice(), LogValue)
+	case log.KindMap:
+		kvs := v.AsMap()
+		values := make([]*commonpb.KeyValue, 0, len(kvs))
+		for _, kv := range kvs {
+			values = append(values, &commonpb.KeyValue{Key: kv.Key, Value: LogValue(kv.Value)})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{
+			KvlistValue: &commonpb.KeyValueList{Values: values},
+		}}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,286 @@
+// Package otlpconv converts OpenTelemetry SDK data into OTLP protobuf messages,
+// so it can be serialized and sent without going through the SDK exporters.
+package otlpconv
+
+import (
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	collectormetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// MetricsRequest wraps the converted ResourceMetrics into an OTLP export request.
+func MetricsRequest(rm *metricdata.ResourceMetrics) (*collectormetricpb.ExportMetricsServiceRequest, error) {
+	out, err := ResourceMetrics(rm)
+	return &collectormetricpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricpb.ResourceMetrics{out},
+	}, err
+}
+
+// ResourceMetrics returns an OTLP ResourceMetrics generated from rm.
+// Metrics with unknown aggregation or temporality are skipped and reported in the returned error,
+// the rest of the data is still converted.
+func ResourceMetrics(rm *metricdata.ResourceMetrics) (*metricpb.ResourceMetrics, error) {
+	var firstErr error
+	scopeMetrics := make([]*metricpb.ScopeMetrics, 0, len(rm.ScopeMetrics))
+	for _, sm := range rm.ScopeMetrics {
+		metrics := make([]*metricpb.Metric, 0, len(sm.Metrics))
+		for _, m := range sm.Metrics {
+			out, err := metric(m)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+
+			metrics = append(metrics, out)
+		}
+
+		scopeMetrics = append(scopeMetrics, &metricpb.ScopeMetrics{
+			Scope: &commonpb.InstrumentationScope{
+				Name:       sm.Scope.Name,
+				Version:    sm.Scope.Version,
+				Attributes: AttrIter(sm.Scope.Attributes.Iter()),
+			},
+			Metrics:   metrics,
+			SchemaUrl: sm.Scope.SchemaURL,
+		})
+	}
+
+	var schemaURL string
+	if rm.Resource != nil {
+		schemaURL = rm.Resource.SchemaURL()
+	}
+
+	return &metricpb.ResourceMetrics{
+		Resource:     Resource(rm.Resource),
+		ScopeMetrics: scopeMetrics,
+		SchemaUrl:    schemaURL,
+	}, firstErr
+}
+
+func metric(m metricdata.Metrics) (*metricpb.Metric, error) {
+	out := &metricpb.Metric{
+		Name:        m.Name,
+		Description: m.Description,
+		Unit:        m.Unit,
+	}
+
+	var err error
+	switch data := m.Data.(type) {
+	case metricdata.Gauge[int64]:
+		out.Data = gauge(data)
+	case metricdata.Gauge[float64]:
+		out.Data = gauge(data)
+	case metricdata.Sum[int64]:
+		out.Data, err = sum(data)
+	case metricdata.Sum[float64]:
+		out.Data, err = sum(data)
+	case metricdata.Histogram[int64]:
+		out.Data, err = histogram(data)
+	case metricdata.Histogram[float64]:
+		out.Data, err = histogram(data)
+	case metricdata.ExponentialHistogram[int64]:
+		out.Data, err = exponentialHistogram(data)
+	case metricdata.ExponentialHistogram[float64]:
+		out.Data, err = exponentialHistogram(data)
+	case metricdata.Summary:
+		out.Data = summary(data)
+	default:
+		err = fmt.Errorf("unknown aggregation %T for metric %q", data, m.Name)
+	}
+
+	return out, err
+}
+
+func gauge[N int64 | float64](g metricdata.Gauge[N]) *metricpb.Metric_Gauge {
+	return &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{
+		DataPoints: dataPoints(g.DataPoints),
+	}}
+}
+
+func sum[N int64 | float64](s metricdata.Sum[N]) (*metricpb.Metric_Sum, error) {
+	t, err := temporality(s.Temporality)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+		AggregationTemporality: t,
+		IsMonotonic:            s.IsMonotonic,
+		DataPoints:             dataPoints(s.DataPoints),
+	}}, nil
+}
+
+func histogram[N int64 | float64](h metricdata.Histogram[N]) (*metricpb.Metric_Histogram, error) {
+	t, err := temporality(h.Temporality)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]*metricpb.HistogramDataPoint, 0, len(h.DataPoints))
+	for _, dp := range h.DataPoints {
+		total := float64(dp.Sum)
+		out := &metricpb.HistogramDataPoint{
+			Attributes:        AttrIter(dp.Attributes.Iter()),
+			StartTimeUnixNano: unixNano(dp.StartTime),
+			TimeUnixNano:      unixNano(dp.Time),
+			Count:             dp.Count,
+			Sum:               &total,
+			BucketCounts:      dp.BucketCounts,
+			ExplicitBounds:    dp.Bounds,
+			Exemplars:         exemplars(dp.Exemplars),
+		}
+		if v, ok := dp.Min.Value(); ok {
+			out.Min = ptr(float64(v))
+		}
+		if v, ok := dp.Max.Value(); ok {
+			out.Max = ptr(float64(v))
+		}
+		points = append(points, out)
+	}
+
+	return &metricpb.Metric_Histogram{Histogram: &metricpb.Histogram{
+		AggregationTemporality: t,
+		DataPoints:             points,
+	}}, nil
+}
+
+func exponentialHistogram[N int64 | float64](h metricdata.ExponentialHistogram[N]) (*metricpb.Metric_ExponentialHistogram, error) {
+	t, err := temporality(h.Temporality)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]*metricpb.ExponentialHistogramDataPoint, 0, len(h.DataPoints))
+	for _, dp := range h.DataPoints {
+		total := float64(dp.Sum)
+		out := &metricpb.ExponentialHistogramDataPoint{
+			Attributes:        AttrIter(dp.Attributes.Iter()),
+			StartTimeUnixNano: unixNano(dp.StartTime),
+			TimeUnixNano:      unixNano(dp.Time),
+			Count:             dp.Count,
+			Sum:               &total,
+			Scale:             dp.Scale,
+			ZeroCount:         dp.ZeroCount,
+			Exemplars:         exemplars(dp.Exemplars),
+			Positive: &metricpb.ExponentialHistogramDataPoint_Buckets{
+				Offset:       dp.PositiveBucket.Offset,
+				BucketCounts: dp.PositiveBucket.Counts,
+			},
+			Negative: &metricpb.ExponentialHistogramDataPoint_Buckets{
+				Offset:       dp.NegativeBucket.Offset,
+				BucketCounts: dp.NegativeBucket.Counts,
+			},
+		}
+		if v, ok := dp.Min.Value(); ok {
+			out.Min = ptr(float64(v))
+		}
+		if v, ok := dp.Max.Value(); ok {
+			out.Max = ptr(float64(v))
+		}
+		points = append(points, out)
+	}
+
+	return &metricpb.Metric_ExponentialHistogram{ExponentialHistogram: &metricpb.ExponentialHistogram{
+		AggregationTemporality: t,
+		DataPoints:             points,
+	}}, nil
+}
+
+func summary(s metricdata.Summary) *metricpb.Metric_Summary {
+	points := make([]*metricpb.SummaryDataPoint, 0, len(s.DataPoints))
+	for _, dp := range s.DataPoints {
+		quantiles := make([]*metricpb.SummaryDataPoint_ValueAtQuantile, 0, len(dp.QuantileValues))
+		for _, q := range dp.QuantileValues {
+			quantiles = append(quantiles, &metricpb.SummaryDataPoint_ValueAtQuantile{
+				Quantile: q.Quantile,
+				Value:    q.Value,
+			})
+		}
+
+		points = append(points, &metricpb.SummaryDataPoint{
+			Attributes:        AttrIter(dp.Attributes.Iter()),
+			StartTimeUnixNano: unixNano(dp.StartTime),
+			TimeUnixNano:      unixNano(dp.Time),
+			Count:             dp.Count,
+			Sum:               dp.Sum,
+			QuantileValues:    quantiles,
+		})
+	}
+
+	return &metricpb.Metric_Summary{Summary: &metricpb.Summary{DataPoints: points}}
+}
+
+func dataPoints[N int64 | float64](dps []metricdata.DataPoint[N]) []*metricpb.NumberDataPoint {
+	out := make([]*metricpb.NumberDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		point := &metricpb.NumberDataPoint{
+			Attributes:        AttrIter(dp.Attributes.Iter()),
+			StartTimeUnixNano: unixNano(dp.StartTime),
+			TimeUnixNano:      unixNano(dp.Time),
+			Exemplars:         exemplars(dp.Exemplars),
+		}
+
+		switch v := any(dp.Value).(type) {
+		case int64:
+			point.Value = &metricpb.NumberDataPoint_AsInt{AsInt: v}
+		case float64:
+			point.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: v}
+		}
+		out = append(out, point)
+	}
+	return out
+}
+
+func exemplars[N int64 | float64](in []metricdata.Exemplar[N]) []*metricpb.Exemplar {
+	if len(in) == 0 {
+		return nil
+	}
+
+	out := make([]*metricpb.Exemplar, 0, len(in))
+	for _, e := range in {
+		exemplar := &metricpb.Exemplar{
+			FilteredAttributes: KeyValues(e.FilteredAttributes),
+			TimeUnixNano:       unixNano(e.Time),
+			SpanId:             e.SpanID,
+			TraceId:            e.TraceID,
+		}
+
+		switch v := any(e.Value).(type) {
+		case int64:
+			exemplar.Value = &metricpb.Exemplar_AsInt{AsInt: v}
+		case float64:
+			exemplar.Value = &metricpb.Exemplar_AsDouble{AsDouble: v}
+		}
+		out = append(out, exemplar)
+	}
+	return out
+}
+
+func temporality(t metricdata.Temporality) (metricpb.AggregationTemporality, error) {
+	switch t {
+	case metricdata.DeltaTemporality:
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, nil
+	case metricdata.CumulativeTemporality:
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, nil
+	default:
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED, fmt.Errorf("unknown temporality %s", t)
+	}
+}
+
+// unixNano returns t as nanoseconds since the Unix epoch, zero time becomes 0.
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(max(0, t.UnixNano()))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
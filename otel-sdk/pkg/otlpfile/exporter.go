@@ -0,0 +1,120 @@
+package otlpfile
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	otelSdkLog "go.opentelemetry.io/otel/sdk/log"
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/otlpconv"
+)
+
+// writeRequest writes one OTLP export request as a single JSON line, using the OTLP/HTTP JSON encoding.
+func writeRequest(w *RotatingWriter, req proto.Message) error {
+	line, err := marshalJSON(req)
+	if err != nil {
+		return fmt.Errorf("marshal otlp json: %w", err)
+	}
+
+	return w.WriteLine(line)
+}
+
+// TraceClient is an otlptrace.Client which writes the spans to a file.
+// Use it with otlptrace.New to get a regular span exporter.
+type TraceClient struct {
+	writer *RotatingWriter
+}
+
+var _ otlptrace.Client = (*TraceClient)(nil)
+
+// NewTraceClient creates an otlptrace.Client writing to the RotatingWriter.
+func NewTraceClient(writer *RotatingWriter) *TraceClient {
+	return &TraceClient{writer: writer}
+}
+
+func (c *TraceClient) Start(context.Context) error {
+	return nil
+}
+
+func (c *TraceClient) Stop(context.Context) error {
+	return c.writer.Close()
+}
+
+func (c *TraceClient) UploadTraces(_ context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	if len(protoSpans) == 0 {
+		return nil
+	}
+
+	return writeRequest(c.writer, &collectortracepb.ExportTraceServiceRequest{ResourceSpans: protoSpans})
+}
+
+// MetricExporter is a metric exporter which writes the metrics to a file.
+type MetricExporter struct {
+	writer *RotatingWriter
+}
+
+var _ otelSdkMetric.Exporter = (*MetricExporter)(nil)
+
+// NewMetricExporter creates a metric exporter writing to the RotatingWriter.
+func NewMetricExporter(writer *RotatingWriter) *MetricExporter {
+	return &MetricExporter{writer: writer}
+}
+
+func (e *MetricExporter) Temporality(kind otelSdkMetric.InstrumentKind) metricdata.Temporality {
+	return otelSdkMetric.DefaultTemporalitySelector(kind)
+}
+
+func (e *MetricExporter) Aggregation(kind otelSdkMetric.InstrumentKind) otelSdkMetric.Aggregation {
+	return otelSdkMetric.DefaultAggregationSelector(kind)
+}
+
+func (e *MetricExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	req, convErr := otlpconv.MetricsRequest(rm)
+	if err := writeRequest(e.writer, req); err != nil {
+		return err
+	}
+
+	return convErr
+}
+
+func (e *MetricExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (e *MetricExporter) Shutdown(context.Context) error {
+	return e.writer.Close()
+}
+
+// LogExporter is a log exporter which writes the log records to a file.
+type LogExporter struct {
+	writer *RotatingWriter
+}
+
+var _ otelSdkLog.Exporter = (*LogExporter)(nil)
+
+// NewLogExporter creates a log exporter writing to the RotatingWriter.
+func NewLogExporter(writer *RotatingWriter) *LogExporter {
+	return &LogExporter{writer: writer}
+}
+
+func (e *LogExporter) Export(_ context.Context, records []otelSdkLog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	return writeRequest(e.writer, otlpconv.LogsRequest(records))
+}
+
+func (e *LogExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (e *LogExporter) Shutdown(context.Context) error {
+	return e.writer.Close()
+}
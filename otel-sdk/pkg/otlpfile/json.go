@@ -0,0 +1,87 @@
+package otlpfile
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// idFields are the bytes fields which the OTLP/JSON encoding writes as hex instead of base64.
+var idFields = map[string]bool{
+	"traceId":      true,
+	"spanId":       true,
+	"parentSpanId": true,
+}
+
+// marshalJSON encodes the request following the OTLP/JSON encoding, which differs from the canonical protobuf JSON:
+// enums are integers, and trace and span IDs are hex strings.
+func marshalJSON(msg proto.Message) ([]byte, error) {
+	raw, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertIDs(raw, func(s string) (string, error) {
+		b, _err := base64.StdEncoding.DecodeString(s)
+		return hex.EncodeToString(b), _err
+	})
+}
+
+// unmarshalJSON decodes a request written in the OTLP/JSON encoding.
+func unmarshalJSON(line []byte, msg proto.Message) error {
+	raw, err := convertIDs(line, func(s string) (string, error) {
+		b, _err := hex.DecodeString(s)
+		return base64.StdEncoding.EncodeToString(b), _err
+	})
+	if err != nil {
+		return err
+	}
+
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(raw, msg)
+}
+
+func convertIDs(raw []byte, conv func(string) (string, error)) ([]byte, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	if err := walkIDs(doc, conv); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+func walkIDs(node any, conv func(string) (string, error)) error {
+	switch v := node.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if s, ok := child.(string); ok && idFields[key] {
+				converted, err := conv(s)
+				if err != nil {
+					return fmt.Errorf("invalid %s %q: %w", key, s, err)
+				}
+				v[key] = converted
+				continue
+			}
+
+			if err := walkIDs(child, conv); err != nil {
+				return err
+			}
+		}
+
+	case []any:
+		for _, child := range v {
+			if err := walkIDs(child, conv); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,289 @@
+package otlpfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	SignalTraces  = "traces"
+	SignalMetrics = "metrics"
+	SignalLogs    = "logs"
+)
+
+// Sender delivers one serialized OTLP protobuf export request, see otlpclient.Client.
+type Sender interface {
+	Send(ctx context.Context, body []byte) error
+}
+
+// Replayer reads OTLP JSON lines files and pushes every line to the OTLP endpoint of its signal.
+type Replayer struct {
+	// Senders per signal, lines of a signal without sender are skipped.
+	Senders map[string]Sender
+
+	// Rate is the maximum number of requests per second, zero is unlimited.
+	Rate float64
+
+	// RebaseTime shifts every timestamp by the same offset, so the first replayed request starts at the replay start time.
+	// The relative spacing between the data is preserved, otherwise the original timestamps are sent.
+	RebaseTime bool
+
+	offset    time.Duration
+	offsetSet bool
+}
+
+// ReplaySummary counts the replayed lines.
+type ReplaySummary struct {
+	Sent    map[string]int
+	Skipped int
+	Failed  int
+}
+
+// Replay replays the files in the given order. Plain and gzip compressed (".gz") files are supported.
+// A line which cannot be decoded or sent is counted as skipped or failed, and does not stop the replay.
+func (r *Replayer) Replay(ctx context.Context, paths ...string) (ReplaySummary, error) {
+	summary := ReplaySummary{Sent: map[string]int{}}
+
+	var ticker *time.Ticker
+	if r.Rate > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / r.Rate))
+		defer ticker.Stop()
+	}
+
+	start := time.Now()
+	for _, path := range paths {
+		err := readLines(path, func(line []byte) error {
+			signal, msg, err := decodeLine(line)
+			if err != nil {
+				slog.WarnContext(ctx, "skip invalid otlp json line", slog.String("file", path), slog.Any("error", err))
+				summary.Skipped++
+				return nil
+			}
+
+			sender, ok := r.Senders[signal]
+			if !ok {
+				summary.Skipped++
+				return nil
+			}
+
+			if r.RebaseTime {
+				r.rebase(msg, start)
+			}
+
+			body, err := proto.Marshal(msg)
+			if err != nil {
+				return fmt.Errorf("marshal %s request: %w", signal, err)
+			}
+
+			if ticker != nil {
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			if err = sender.Send(ctx, body); err != nil {
+				slog.WarnContext(ctx, "failed to replay otlp request", slog.String("signal", signal), slog.Any("error", err))
+				summary.Failed++
+				return nil
+			}
+
+			summary.Sent[signal]++
+			return nil
+		})
+
+		if err != nil {
+			return summary, fmt.Errorf("replay %s: %w", path, err)
+		}
+	}
+
+	return summary, nil
+}
+
+// rebase computes the offset from the first replayed request, and applies it to every request.
+func (r *Replayer) rebase(msg proto.Message, start time.Time) {
+	if !r.offsetSet {
+		if earliest := earliestTimestamp(msg); earliest > 0 {
+			r.offset = start.Sub(time.Unix(0, int64(earliest)))
+			r.offsetSet = true
+		}
+	}
+
+	shiftTimestamps(msg, r.offset)
+}
+
+func readLines(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var reader io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, gzErr := gzip.NewReader(f)
+		if gzErr != nil {
+			return gzErr
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		reader = gz
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 1<<20), 64<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if err = fn(line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// decodeLine detects the signal from the top level field of the OTLP JSON request.
+func decodeLine(line []byte) (string, proto.Message, error) {
+	var (
+		signal string
+		msg    proto.Message
+	)
+
+	switch {
+	case bytes.Contains(line, []byte(`"resourceSpans"`)):
+		signal, msg = SignalTraces, &collectortracepb.ExportTraceServiceRequest{}
+	case bytes.Contains(line, []byte(`"resourceMetrics"`)):
+		signal, msg = SignalMetrics, &collectormetricpb.ExportMetricsServiceRequest{}
+	case bytes.Contains(line, []byte(`"resourceLogs"`)):
+		signal, msg = SignalLogs, &collectorlogspb.ExportLogsServiceRequest{}
+	default:
+		return "", nil, fmt.Errorf("unknown otlp json line")
+	}
+
+	if err := unmarshalJSON(line, msg); err != nil {
+		return "", nil, fmt.Errorf("decode %s json: %w", signal, err)
+	}
+
+	return signal, msg, nil
+}
+
+// timestamps calls fn with a pointer to every timestamp of the request.
+func timestamps(msg proto.Message, fn func(ts *uint64)) {
+	switch req := msg.(type) {
+	case *collectortracepb.ExportTraceServiceRequest:
+		for _, rs := range req.GetResourceSpans() {
+			for _, ss := range rs.GetScopeSpans() {
+				for _, span := range ss.GetSpans() {
+					fn(&span.StartTimeUnixNano)
+					fn(&span.EndTimeUnixNano)
+					for _, event := range span.GetEvents() {
+						fn(&event.TimeUnixNano)
+					}
+				}
+			}
+		}
+
+	case *collectormetricpb.ExportMetricsServiceRequest:
+		for _, rm := range req.GetResourceMetrics() {
+			for _, sm := range rm.GetScopeMetrics() {
+				for _, m := range sm.GetMetrics() {
+					metricTimestamps(m, fn)
+				}
+			}
+		}
+
+	case *collectorlogspb.ExportLogsServiceRequest:
+		for _, rl := range req.GetResourceLogs() {
+			for _, sl := range rl.GetScopeLogs() {
+				for _, record := range sl.GetLogRecords() {
+					fn(&record.TimeUnixNano)
+					fn(&record.ObservedTimeUnixNano)
+				}
+			}
+		}
+	}
+}
+
+func metricTimestamps(m *metricpb.Metric, fn func(ts *uint64)) {
+	number := func(points []*metricpb.NumberDataPoint) {
+		for _, p := range points {
+			fn(&p.StartTimeUnixNano)
+			fn(&p.TimeUnixNano)
+			for _, e := range p.GetExemplars() {
+				fn(&e.TimeUnixNano)
+			}
+		}
+	}
+
+	switch data := m.GetData().(type) {
+	case *metricpb.Metric_Gauge:
+		number(data.Gauge.GetDataPoints())
+	case *metricpb.Metric_Sum:
+		number(data.Sum.GetDataPoints())
+	case *metricpb.Metric_Histogram:
+		for _, p := range data.Histogram.GetDataPoints() {
+			fn(&p.StartTimeUnixNano)
+			fn(&p.TimeUnixNano)
+			for _, e := range p.GetExemplars() {
+				fn(&e.TimeUnixNano)
+			}
+		}
+	case *metricpb.Metric_ExponentialHistogram:
+		for _, p := range data.ExponentialHistogram.GetDataPoints() {
+			fn(&p.StartTimeUnixNano)
+			fn(&p.TimeUnixNano)
+			for _, e := range p.GetExemplars() {
+				fn(&e.TimeUnixNano)
+			}
+		}
+	case *metricpb.Metric_Summary:
+		for _, p := range data.Summary.GetDataPoints() {
+			fn(&p.StartTimeUnixNano)
+			fn(&p.TimeUnixNano)
+		}
+	}
+}
+
+func earliestTimestamp(msg proto.Message) uint64 {
+	var earliest uint64
+	timestamps(msg, func(ts *uint64) {
+		if *ts > 0 && (earliest == 0 || *ts < earliest) {
+			earliest = *ts
+		}
+	})
+	return earliest
+}
+
+// shiftTimestamps moves every non-zero timestamp by offset, zero means "not set" in OTLP and stays zero.
+func shiftTimestamps(msg proto.Message, offset time.Duration) {
+	timestamps(msg, func(ts *uint64) {
+		if *ts == 0 {
+			return
+		}
+
+		shifted := int64(*ts) + int64(offset)
+		*ts = uint64(max(0, shifted))
+	})
+}
@@ -0,0 +1,214 @@
+// Package otlpfile writes traces, metrics and logs as OTLP JSON lines into size and age based rotating files,
+// and reads them back to replay them into an OTLP endpoint.
+package otlpfile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000"
+
+// RotateConfig is the rotation policy of one file.
+type RotateConfig struct {
+	// Path is the active file, for example "/var/log/otel-sdk/traces.jsonl".
+	Path string
+
+	// MaxBytes rotates the file before it grows beyond this size, default is 100 MiB.
+	MaxBytes int64
+
+	// MaxAge rotates the file when it was opened longer than this, zero disables age based rotation.
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files to keep, zero keeps everything.
+	MaxBackups int
+
+	// Gzip compresses the rotated files.
+	Gzip bool
+}
+
+// RotatingWriter appends lines to a file and rotates it according to the RotateConfig.
+type RotatingWriter struct {
+	cfg RotateConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingWriter opens (or creates) the active file in append mode.
+func NewRotatingWriter(cfg RotateConfig) (*RotatingWriter, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("otlp file path is required")
+	}
+
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 100 << 20
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("create otlp file directory: %w", err)
+	}
+
+	w := &RotatingWriter{cfg: cfg}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// WriteLine appends the line followed by a new line, rotating the file first when needed.
+func (w *RotatingWriter) WriteLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return fmt.Errorf("otlp file %s is closed", w.cfg.Path)
+	}
+
+	size := int64(len(line)) + 1
+	tooBig := w.size > 0 && w.size+size > w.cfg.MaxBytes
+	tooOld := w.cfg.MaxAge > 0 && time.Since(w.openedAt) > w.cfg.MaxAge
+	if tooBig || tooOld {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.file.Write(append(line, '\n'))
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write otlp file %s: %w", w.cfg.Path, err)
+	}
+
+	return nil
+}
+
+// Close closes the active file, it is not rotated.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotatingWriter) open() error {
+	f, err := os.OpenFile(w.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open otlp file %s: %w", w.cfg.Path, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat otlp file %s: %w", w.cfg.Path, err)
+	}
+
+	w.file = f
+	w.size = info.Size()
+	w.openedAt = time.Now()
+	return nil
+}
+
+// rotate must be called while holding the lock.
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("close otlp file %s: %w", w.cfg.Path, err)
+	}
+
+	ext := filepath.Ext(w.cfg.Path)
+	base := strings.TrimSuffix(w.cfg.Path, ext)
+	backup := fmt.Sprintf("%s-%s%s", base, time.Now().UTC().Format(backupTimeFormat), ext)
+	if err := os.Rename(w.cfg.Path, backup); err != nil {
+		return fmt.Errorf("rotate otlp file %s: %w", w.cfg.Path, err)
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	// Compressing and pruning do not block the writer.
+	go func() {
+		if w.cfg.Gzip {
+			if err := gzipFile(backup); err != nil {
+				slog.Error("failed to compress rotated otlp file", slog.String("file", backup), slog.Any("error", err))
+			}
+		}
+
+		w.prune(base, ext)
+	}()
+
+	return nil
+}
+
+// prune removes the oldest backups beyond MaxBackups.
+func (w *RotatingWriter) prune(base, ext string) {
+	if w.cfg.MaxBackups <= 0 {
+		return
+	}
+
+	backups, err := filepath.Glob(base + "-*" + ext + "*")
+	if err != nil {
+		return
+	}
+
+	// The timestamp in the name sorts chronologically.
+	sort.Strings(backups)
+	for len(backups) > w.cfg.MaxBackups {
+		if err = os.Remove(backups[0]); err != nil && !os.IsNotExist(err) {
+			slog.Error("failed to remove old otlp file", slog.String("file", backups[0]), slog.Any("error", err))
+		}
+		backups = backups[1:]
+	}
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+
+	if err = gz.Close(); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+
+	if err = dst.Close(); err != nil {
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+
+	return os.Remove(path)
+}
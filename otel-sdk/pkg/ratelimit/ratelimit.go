@@ -0,0 +1,316 @@
+// Package ratelimit limits the requests with token buckets, a global one and one per client address.
+// The client buckets are kept in a LRU, so the memory is bounded whatever the number of clients is.
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/ratelimit"
+
+const (
+	ScopeGlobal = "global"
+	ScopeClient = "client"
+
+	OutcomeAllowed     = "allowed"
+	OutcomeRateLimited = "rate_limited"
+)
+
+// Config of the Limiter, a zero rate disables the bucket of its scope.
+type Config struct {
+	// GlobalRate is the number of requests per second of all the clients, GlobalBurst is the size of the bucket.
+	GlobalRate  float64
+	GlobalBurst int
+
+	// ClientRate is the number of requests per second of one client address, ClientBurst is the size of its bucket.
+	ClientRate  float64
+	ClientBurst int
+
+	// MaxClients is the number of client buckets kept, the least recently used one is evicted. By default it is 10000.
+	MaxClients int
+
+	// TrustedProxies are the proxies whose X-Forwarded-For header is read to find the client address.
+	TrustedProxies []netip.Prefix
+}
+
+// ParsePrefixes parses a comma separated list of CIDR or IP addresses, for example "10.0.0.0/8,127.0.0.1".
+func ParsePrefixes(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket since the last call and takes one token, or returns how long to wait for one.
+func (b *bucket) take(now time.Time, rate float64, burst int) (bool, time.Duration) {
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+type clientBucket struct {
+	address string
+	bucket
+}
+
+// Decision of the Limiter for one request.
+type Decision struct {
+	Allowed bool
+
+	// Scope is the bucket which rejected the request, empty when it is allowed.
+	Scope string
+
+	// Client is the resolved client address.
+	Client string
+
+	// RetryAfter is the time until the rejecting bucket has a token.
+	RetryAfter time.Duration
+}
+
+// Limiter is safe for concurrent use.
+type Limiter struct {
+	// OnReject is called for every rejected request before the response, for example to count it as a failure of the endpoint.
+	OnReject func(r *http.Request, decision Decision)
+
+	cfg Config
+	now func() time.Time
+
+	mu      sync.Mutex
+	global  bucket
+	clients map[string]*list.Element
+	lru     *list.List
+
+	requests metric.Int64Counter
+}
+
+// New creates a Limiter, the counter "<metricPrefix>.ratelimit.requests" of the decisions by outcome (and scope when rate limited),
+// and the gauge "<metricPrefix>.ratelimit.tracked_clients".
+func New(cfg Config, metricPrefix string) *Limiter {
+	if cfg.MaxClients <= 0 {
+		cfg.MaxClients = 10000
+	}
+
+	if cfg.GlobalBurst <= 0 {
+		cfg.GlobalBurst = max(1, int(math.Ceil(cfg.GlobalRate)))
+	}
+
+	if cfg.ClientBurst <= 0 {
+		cfg.ClientBurst = max(1, int(math.Ceil(cfg.ClientRate)))
+	}
+
+	now := time.Now()
+	l := &Limiter{
+		cfg:     cfg,
+		now:     time.Now,
+		global:  bucket{tokens: float64(cfg.GlobalBurst), last: now},
+		clients: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+
+	meter := otel.Meter(instrumentationName)
+
+	var err error
+	l.requests, err = meter.Int64Counter(metricPrefix+".ratelimit.requests",
+		metric.WithDescription("Number of the rate limiter decisions, by outcome (allowed or rate_limited), and scope of the rejections."),
+	)
+	if err != nil {
+		slog.Error("failed to create ratelimit.requests counter", slog.Any("error", err))
+		l.requests = &noop.Int64Counter{}
+	}
+
+	_, err = meter.Int64ObservableGauge(metricPrefix+".ratelimit.tracked_clients",
+		metric.WithDescription("Number of the client buckets kept by the rate limiter."),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(l.TrackedClients()))
+			return nil
+		}),
+	)
+	if err != nil {
+		slog.Error("failed to create ratelimit.tracked_clients gauge", slog.Any("error", err))
+	}
+
+	return l
+}
+
+// TrackedClients is the number of the client buckets.
+func (l *Limiter) TrackedClients() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lru.Len()
+}
+
+// Allow takes a token from the bucket of the client, then from the global bucket.
+func (l *Limiter) Allow(client string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	if l.cfg.ClientRate > 0 {
+		if ok, wait := l.clientBucket(client, now).take(now, l.cfg.ClientRate, l.cfg.ClientBurst); !ok {
+			return Decision{Scope: ScopeClient, Client: client, RetryAfter: wait}
+		}
+	}
+
+	if l.cfg.GlobalRate > 0 {
+		if ok, wait := l.global.take(now, l.cfg.GlobalRate, l.cfg.GlobalBurst); !ok {
+			return Decision{Scope: ScopeGlobal, Client: client, RetryAfter: wait}
+		}
+	}
+
+	return Decision{Allowed: true, Client: client}
+}
+
+// clientBucket returns the bucket of the client as the most recently used, a new one evicts the least recently used.
+func (l *Limiter) clientBucket(client string, now time.Time) *bucket {
+	if elem, ok := l.clients[client]; ok {
+		l.lru.MoveToFront(elem)
+		return &elem.Value.(*clientBucket).bucket
+	}
+
+	if l.lru.Len() >= l.cfg.MaxClients {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.clients, oldest.Value.(*clientBucket).address)
+	}
+
+	b := &clientBucket{address: client, bucket: bucket{tokens: float64(l.cfg.ClientBurst), last: now}}
+	l.clients[client] = l.lru.PushFront(b)
+	return &b.bucket
+}
+
+// ClientAddress is the remote address, or the X-Forwarded-For address added by the last trusted proxy.
+// The header is read from the right, the addresses before the first untrusted one could be forged by the client.
+func (l *Limiter) ClientAddress(r *http.Request) string {
+	remote, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	addr := remote.Addr().Unmap()
+	if !l.trusted(addr) {
+		return addr.String()
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+
+		addr = hop.Unmap()
+		if !l.trusted(addr) {
+			break
+		}
+	}
+
+	return addr.String()
+}
+
+func (l *Limiter) trusted(addr netip.Addr) bool {
+	for _, prefix := range l.cfg.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+type clientKey struct{}
+
+// ClientFromContext returns the client address resolved by the Middleware, so the handler keys the client the same way as the limiter.
+func ClientFromContext(ctx context.Context) (string, bool) {
+	client, ok := ctx.Value(clientKey{}).(string)
+	return client, ok
+}
+
+// Middleware rejects the requests over the limit with 429 Too Many Requests and Retry-After.
+// The decision is recorded on the span of the request, and the client address of an allowed request is in its context, see ClientFromContext.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		decision := l.Allow(l.ClientAddress(r))
+
+		outcome := OutcomeAllowed
+		if !decision.Allowed {
+			outcome = OutcomeRateLimited
+		}
+
+		spanAttrs := []attribute.KeyValue{
+			attribute.String("ratelimit.outcome", outcome),
+			attribute.String("ratelimit.client", decision.Client),
+		}
+		metricAttrs := []attribute.KeyValue{attribute.String("outcome", outcome)}
+		if !decision.Allowed {
+			spanAttrs = append(spanAttrs,
+				attribute.String("ratelimit.scope", decision.Scope),
+				attribute.Float64("ratelimit.retry_after_s", decision.RetryAfter.Seconds()),
+			)
+			metricAttrs = append(metricAttrs, attribute.String("scope", decision.Scope))
+		}
+		trace.SpanFromContext(ctx).SetAttributes(spanAttrs...)
+		l.requests.Add(ctx, 1, metric.WithAttributes(metricAttrs...))
+
+		if !decision.Allowed {
+			if l.OnReject != nil {
+				l.OnReject(r, decision)
+			}
+
+			// Retry-After is in seconds, rounded up so the client does not retry too early.
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(decision.RetryAfter.Seconds())), 10))
+			http.Error(w, fmt.Sprintf("Too many requests (%s limit), retry later.", decision.Scope), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, clientKey{}, decision.Client)))
+	})
+}
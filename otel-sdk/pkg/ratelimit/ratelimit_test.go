@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestMiddlewareClientFromContext(t *testing.T) {
+	limiter := New(Config{
+		ClientRate:     1,
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	}, "test")
+
+	var clients []string
+	handler := limiter.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		client, ok := ClientFromContext(r.Context())
+		if !ok {
+			t.Error("no client address in the context")
+		}
+		clients = append(clients, client)
+	}))
+
+	tests := []struct {
+		remoteAddr, forwardedFor string
+		want                     string
+	}{
+		// Behind the trusted proxy, the clients are told apart by X-Forwarded-For, the forged address before is ignored.
+		{remoteAddr: "10.0.0.1:4000", forwardedFor: "1.1.1.1, 192.0.2.1", want: "192.0.2.1"},
+		{remoteAddr: "10.0.0.1:4000", forwardedFor: "192.0.2.2, 10.0.0.2", want: "192.0.2.2"},
+		{remoteAddr: "198.51.100.1:4000", forwardedFor: "192.0.2.3", want: "198.51.100.1"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = test.remoteAddr
+		r.Header.Set("X-Forwarded-For", test.forwardedFor)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s via %s: status %d, want 200 from a bucket of its own", test.forwardedFor, test.remoteAddr, w.Code)
+		}
+	}
+
+	if len(clients) != len(tests) {
+		t.Fatalf("%d requests handled, want %d", len(clients), len(tests))
+	}
+	for i, test := range tests {
+		if clients[i] != test.want {
+			t.Errorf("client of %s via %s = %q, want %q", test.forwardedFor, test.remoteAddr, clients[i], test.want)
+		}
+	}
+
+	if _, ok := ClientFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()); ok {
+		t.Error("client address in the context of a request without the middleware")
+	}
+}
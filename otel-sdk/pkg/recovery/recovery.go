@@ -0,0 +1,93 @@
+// Package recovery recovers the panics of the HTTP handlers, records them on the server span and counts them.
+package recovery
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/recovery"
+
+// Middleware recovers a panic of the next handlers and responds a JSON 500.
+// The panic value and the stack are recorded as an "exception" event of the span in the request context, the span status is set to error,
+// "<metricPrefix>.panics_total" is incremented by method and route, and the panic is logged with the trace and span ids.
+//
+// It must be installed after the tracing and metrics middlewares, so they see the 500 response.
+// http.ErrAbortHandler is not recovered, it is the way to abort a response on purpose.
+func Middleware(metricPrefix string) func(http.Handler) http.Handler {
+	panics, err := otel.Meter(instrumentationName).Int64Counter(metricPrefix+".panics_total",
+		metric.WithDescription("Number of panics recovered in the HTTP handlers."),
+	)
+	if err != nil {
+		slog.Error("failed to create panics_total counter", slog.Any("error", err))
+		panics = &noop.Int64Counter{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				value := recover()
+				if value == nil {
+					return
+				}
+
+				if value == http.ErrAbortHandler {
+					panic(value)
+				}
+
+				stack := string(debug.Stack())
+				ctx := r.Context()
+
+				// The route pattern is complete only after chi routed the request, it is empty when the panic happened before.
+				route := ""
+				if rctx := chi.RouteContext(ctx); rctx != nil {
+					route = rctx.RoutePattern()
+				}
+				if route == "" {
+					route = "unmatched"
+				}
+
+				errType := fmt.Sprintf("%T", value)
+				message := fmt.Sprint(value)
+
+				span := trace.SpanFromContext(ctx)
+				span.AddEvent(semconv.ExceptionEventName, trace.WithAttributes(
+					semconv.ExceptionType(errType),
+					semconv.ExceptionMessage(message),
+					semconv.ExceptionStacktrace(stack),
+					semconv.ExceptionEscaped(false),
+				))
+				span.SetStatus(codes.Error, "panic: "+message)
+
+				panics.Add(ctx, 1, metric.WithAttributes(
+					attribute.String("http.method", r.Method),
+					attribute.String("http.route", route),
+				))
+
+				slog.ErrorContext(ctx, "panic recovered",
+					slog.Any("panic", value),
+					slog.String("stack", stack),
+					slog.String("trace_id", span.SpanContext().TraceID().String()),
+					slog.String("span_id", span.SpanContext().SpanID().String()),
+				)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"error":"internal server error"}` + "\n"))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+// newRouter returns a router with a server span and the recovery middleware, the counter is read from the ManualReader.
+func newRouter(t *testing.T) (http.Handler, *tracetest.SpanRecorder, *otelSdkMetric.ManualReader) {
+	t.Helper()
+
+	reader := otelSdkMetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(otelSdkMetric.NewMeterProvider(otelSdkMetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := otelSdkTrace.NewTracerProvider(otelSdkTrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := tracer.Start(r.Context(), "server")
+			defer span.End()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	router.Use(Middleware("test"))
+	router.Get("/panic/{id}", func(http.ResponseWriter, *http.Request) { panic(errors.New("boom")) })
+	router.Get("/abort", func(http.ResponseWriter, *http.Request) { panic(http.ErrAbortHandler) })
+	router.Get("/ok", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) })
+
+	return router, recorder, reader
+}
+
+func serve(router http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+// panics returns the data points of test.panics_total.
+func panics(t *testing.T, reader *otelSdkMetric.ManualReader) []metricdata.DataPoint[int64] {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "test.panics_total" {
+				return m.Data.(metricdata.Sum[int64]).DataPoints
+			}
+		}
+	}
+	return nil
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	router, recorder, reader := newRouter(t)
+
+	w := serve(router, "/panic/1")
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/json" ||
+		w.Body.String() != `{"error":"internal server error"}`+"\n" {
+		t.Errorf("response = %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans ended, want 1", len(spans))
+	}
+	span := spans[0]
+
+	if span.Status() != (otelSdkTrace.Status{Code: codes.Error, Description: "panic: boom"}) {
+		t.Errorf("status = %+v", span.Status())
+	}
+
+	events := span.Events()
+	if len(events) != 1 || events[0].Name != semconv.ExceptionEventName {
+		t.Fatalf("events = %+v", events)
+	}
+	attrs := attribute.NewSet(events[0].Attributes...)
+	if v, _ := attrs.Value(semconv.ExceptionTypeKey); v.AsString() != "*errors.errorString" {
+		t.Errorf("exception.type = %q", v.AsString())
+	}
+	if v, _ := attrs.Value(semconv.ExceptionMessageKey); v.AsString() != "boom" {
+		t.Errorf("exception.message = %q", v.AsString())
+	}
+	if v, _ := attrs.Value(semconv.ExceptionStacktraceKey); !strings.Contains(v.AsString(), "newRouter.func") {
+		t.Errorf("exception.stacktrace is not the stack of the panic:\n%s", v.AsString())
+	}
+
+	points := panics(t, reader)
+	if len(points) != 1 || points[0].Value != 1 {
+		t.Fatalf("panics_total = %+v", points)
+	}
+	if route, _ := points[0].Attributes.Value("http.route"); route.AsString() != "/panic/{id}" {
+		t.Errorf("http.route = %q", route.AsString())
+	}
+}
+
+func TestMiddlewareNoPanic(t *testing.T) {
+	router, recorder, reader := newRouter(t)
+
+	if w := serve(router, "/ok"); w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("response = %d %q", w.Code, w.Body.String())
+	}
+
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Status().Code != codes.Unset || len(spans[0].Events()) != 0 {
+		t.Errorf("the span of a request without panic has an error")
+	}
+	if points := panics(t, reader); len(points) != 0 {
+		t.Errorf("panics_total = %+v", points)
+	}
+}
+
+func TestMiddlewareAbortHandler(t *testing.T) {
+	router, _, reader := newRouter(t)
+
+	defer func() {
+		if value := recover(); value != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", value)
+		}
+		if points := panics(t, reader); len(points) != 0 {
+			t.Error("http.ErrAbortHandler is counted as a panic")
+		}
+	}()
+
+	serve(router, "/abort")
+}
@@ -0,0 +1,295 @@
+// Package reqrecord records sanitised incoming HTTP requests as JSON lines, and replays them against a target
+// with the original pacing, to reproduce real traffic instead of a synthetic load.
+package reqrecord
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// PasswordMask replaces every password with "***", the replayed logins fail.
+	PasswordMask = "mask"
+
+	// PasswordReference replaces the password with a reference to the username, for example "${password:user1}",
+	// which is resolved from a credentials file on replay.
+	PasswordReference = "reference"
+
+	maskedValue = "***"
+
+	// maxBodyBytes is the largest body which is recorded, a larger body is truncated and redacted.
+	maxBodyBytes = 64 << 10
+)
+
+// DefaultHeaders are recorded when Config.Headers is empty. Authorization, cookies and trace headers are never recorded by default.
+var DefaultHeaders = []string{"Accept", "Content-Type", "User-Agent"}
+
+// Record is one recorded request, one JSON line.
+type Record struct {
+	// OffsetMs is the time since the first recorded request of the recorder, used to replay with the original pacing.
+	OffsetMs float64 `json:"offset_ms"`
+
+	Method string `json:"method"`
+
+	// Route is the chi route pattern, for example "/login", and Path is the requested path with the query.
+	Route string `json:"route"`
+	Path  string `json:"path"`
+
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+
+	// BodyTruncated is true when the body was larger than 64 KiB, the body is then redacted.
+	BodyTruncated bool `json:"body_truncated,omitempty"`
+
+	// BodyRedacted is true when the body is not recorded, because it is truncated or cannot be parsed to mask the passwords.
+	BodyRedacted bool `json:"body_redacted,omitempty"`
+
+	Status     int     `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// LineWriter is where the records are written, see otlpfile.RotatingWriter.
+type LineWriter interface {
+	WriteLine(line []byte) error
+}
+
+type Config struct {
+	// Headers is the allow-list of recorded request headers, DefaultHeaders when empty.
+	Headers []string
+
+	// Password is PasswordMask (default) or PasswordReference.
+	Password string
+
+	// Skip excludes requests from the recording, for example the probes.
+	Skip func(r *http.Request) bool
+}
+
+// Recorder is a middleware writing every request to the LineWriter.
+type Recorder struct {
+	cfg    Config
+	writer LineWriter
+
+	mu    sync.Mutex
+	start time.Time
+}
+
+func NewRecorder(writer LineWriter, cfg Config) *Recorder {
+	if len(cfg.Headers) == 0 {
+		cfg.Headers = DefaultHeaders
+	}
+
+	if cfg.Password != PasswordReference {
+		cfg.Password = PasswordMask
+	}
+
+	return &Recorder{cfg: cfg, writer: writer}
+}
+
+// Middleware records the request after the handler returns, a failed write is logged and does not fail the request.
+func (rec *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rec.cfg.Skip != nil && rec.cfg.Skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		offset := rec.offset(start)
+
+		// The handler reads the body again from the copy.
+		body, truncated := readBody(r)
+		sanitised, redacted := rec.sanitise(r.Header.Get("Content-Type"), body, truncated)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		record := Record{
+			OffsetMs:      durationMs(offset),
+			Method:        r.Method,
+			Route:         chi.RouteContext(r.Context()).RoutePattern(),
+			Path:          r.URL.RequestURI(),
+			Headers:       rec.headers(r),
+			Body:          sanitised,
+			BodyTruncated: truncated,
+			BodyRedacted:  redacted,
+			Status:        status,
+			DurationMs:    durationMs(time.Since(start)),
+		}
+
+		line, err := json.Marshal(record)
+		if err == nil {
+			err = rec.writer.WriteLine(line)
+		}
+
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to record request", slog.Any("error", err))
+		}
+	})
+}
+
+func (rec *Recorder) offset(now time.Time) time.Duration {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.start.IsZero() {
+		rec.start = now
+	}
+	return now.Sub(rec.start)
+}
+
+func (rec *Recorder) headers(r *http.Request) map[string]string {
+	headers := make(map[string]string, len(rec.cfg.Headers))
+	for _, name := range rec.cfg.Headers {
+		if v := r.Header.Get(name); v != "" {
+			headers[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+	return headers
+}
+
+// sanitise replaces the "password" fields of a JSON or form-encoded body.
+// A truncated body, or a body which cannot be parsed, is redacted: it may hold a password which cannot be masked.
+func (rec *Recorder) sanitise(contentType string, body []byte, truncated bool) (string, bool) {
+	if len(body) == 0 {
+		return "", false
+	}
+
+	if truncated {
+		return "", true
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		return rec.sanitiseForm(body)
+	}
+
+	// The numbers are kept as they are written, not converted to float64.
+	var v any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil || decoder.More() {
+		return "", true
+	}
+
+	if !maskPasswords(v, rec.cfg.Password, "") {
+		return string(body), false
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", true
+	}
+	return string(out), false
+}
+
+// sanitiseForm masks the "password" fields of a form-encoded body, the reference is written unescaped so the replay finds it.
+func (rec *Recorder) sanitiseForm(body []byte) (string, bool) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", true
+	}
+
+	username := values.Get("username")
+	found := false
+	for k := range values {
+		if !strings.EqualFold(k, "password") {
+			continue
+		}
+
+		found = true
+		for i := range values[k] {
+			values[k][i] = mask(rec.cfg.Password, username)
+		}
+	}
+
+	if !found {
+		return string(body), false
+	}
+
+	out := values.Encode()
+	if rec.cfg.Password == PasswordReference && username != "" {
+		out = strings.ReplaceAll(out, url.QueryEscape(PasswordRef(username)), PasswordRef(username))
+	}
+	return out, false
+}
+
+// maskPasswords replaces the passwords in place, in the nested objects and the arrays too, and reports whether one was found.
+// A reference uses the username of the closest object holding one.
+func maskPasswords(v any, mode, username string) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]any:
+		if name, ok := v["username"].(string); ok {
+			username = name
+		}
+
+		for k, value := range v {
+			if strings.EqualFold(k, "password") {
+				found = true
+				v[k] = mask(mode, username)
+				continue
+			}
+			found = maskPasswords(value, mode, username) || found
+		}
+
+	case []any:
+		for _, value := range v {
+			found = maskPasswords(value, mode, username) || found
+		}
+	}
+	return found
+}
+
+func mask(mode, username string) string {
+	if mode == PasswordReference && username != "" {
+		return PasswordRef(username)
+	}
+	return maskedValue
+}
+
+// PasswordRef is the reference recorded in place of the password of the username.
+func PasswordRef(username string) string {
+	return "${password:" + username + "}"
+}
+
+// readBody reads at most maxBodyBytes, and puts them back in front of the rest of the body for the handler.
+func readBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+	if err != nil {
+		return nil, false
+	}
+
+	if len(body) > maxBodyBytes {
+		return body[:maxBodyBytes], true
+	}
+	return body, false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,208 @@
+package reqrecord
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestSanitise(t *testing.T) {
+	const form = "application/x-www-form-urlencoded"
+
+	tests := []struct {
+		name        string
+		mode        string
+		contentType string
+		body        string
+		want        string
+		redacted    bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "json mask",
+			body: `{"username":"user1","password":"password1"}`,
+			want: `{"password":"***","username":"user1"}`,
+		},
+		{
+			name: "json reference",
+			mode: PasswordReference,
+			body: `{"username":"user1","Password":"password1"}`,
+			want: `{"Password":"${password:user1}","username":"user1"}`,
+		},
+		{
+			name: "json without password",
+			body: `{"username": "user1", "id": 12345678901234567890}`,
+			want: `{"username": "user1", "id": 12345678901234567890}`,
+		},
+		{
+			name: "json nested object",
+			mode: PasswordReference,
+			body: `{"username":"admin","user":{"username":"user1","password":"password1"},"old":{"password":"x"}}`,
+			want: `{"old":{"password":"${password:admin}"},"user":{"password":"${password:user1}","username":"user1"},"username":"admin"}`,
+		},
+		{
+			name: "json array",
+			mode: PasswordReference,
+			body: `[{"username":"user1","password":"password1"},{"users":[{"username":"user2","password":"password2"}]}]`,
+			want: `[{"password":"${password:user1}","username":"user1"},{"users":[{"password":"${password:user2}","username":"user2"}]}]`,
+		},
+		{
+			name: "json password object",
+			body: `{"password":{"value":"password1"}}`,
+			want: `{"password":"***"}`,
+		},
+		{
+			name:     "malformed json",
+			body:     `{"username":"user1","password":"password1"`,
+			redacted: true,
+		},
+		{
+			name:     "trailing data",
+			body:     `{"username":"user1"} password=password1`,
+			redacted: true,
+		},
+		{
+			name:        "plain text",
+			contentType: "text/plain",
+			body:        "password1",
+			redacted:    true,
+		},
+		{
+			name:        "form mask",
+			contentType: form,
+			body:        "username=user1&password=password1",
+			want:        "password=%2A%2A%2A&username=user1",
+		},
+		{
+			name:        "form reference",
+			mode:        PasswordReference,
+			contentType: form + "; charset=utf-8",
+			body:        "username=user1&password=password1",
+			want:        "password=${password:user1}&username=user1",
+		},
+		{
+			name:        "form without password",
+			contentType: form,
+			body:        "q=login&page=2",
+			want:        "q=login&page=2",
+		},
+		{
+			name:        "malformed form",
+			contentType: form,
+			body:        "username=user1&password=%zz",
+			redacted:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewRecorder(nil, Config{Password: tt.mode})
+
+			got, redacted := rec.sanitise(tt.contentType, []byte(tt.body), false)
+			if got != tt.want || redacted != tt.redacted {
+				t.Errorf("sanitise() = %q, %v, want %q, %v", got, redacted, tt.want, tt.redacted)
+			}
+		})
+	}
+}
+
+// lineRecorder keeps the written lines in memory.
+type lineRecorder struct {
+	mu    sync.Mutex
+	lines [][]byte
+}
+
+func (w *lineRecorder) WriteLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lines = append(w.lines, line)
+	return nil
+}
+
+func TestMiddlewareTruncatedBody(t *testing.T) {
+	writer := &lineRecorder{}
+	rec := NewRecorder(writer, Config{})
+
+	var received int
+	router := chi.NewRouter()
+	router.Use(rec.Middleware)
+	router.Post("/login", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	})
+
+	// The password is after the recorded part, it cannot be masked.
+	body := `{"padding":"` + strings.Repeat("a", maxBodyBytes) + `","password":"password1"}`
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if received != len(body) {
+		t.Errorf("the handler read %d bytes, want %d", received, len(body))
+	}
+
+	if len(writer.lines) != 1 {
+		t.Fatalf("%d records, want 1", len(writer.lines))
+	}
+
+	var record Record
+	if err := json.Unmarshal(writer.lines[0], &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Body != "" || !record.BodyTruncated || !record.BodyRedacted {
+		t.Errorf("record body = %d bytes, truncated %v, redacted %v", len(record.Body), record.BodyTruncated, record.BodyRedacted)
+	}
+	if record.Route != "/login" || record.Status != http.StatusRequestEntityTooLarge || record.Headers["Content-Type"] != "application/json" {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+func TestReplayResolvesPasswordReferences(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	replayer := &Replayer{
+		Target:      server.URL,
+		Concurrency: 1,
+		Credentials: map[string]string{"user1": `pass "&=1`},
+	}
+	replayer.Replay(context.Background(), []Record{
+		{Method: http.MethodPost, Path: "/login", Body: `{"password":"${password:user1}","username":"user1"}`},
+		{
+			Method:  http.MethodPost,
+			Path:    "/login",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			Body:    "password=${password:user1}&username=user1",
+		},
+		{Method: http.MethodPost, Path: "/login", Body: `{"password":"${password:unknown}"}`},
+	})
+
+	want := []string{
+		`{"password":"pass \"\u0026=1","username":"user1"}`,
+		"password=pass+%22%26%3D1&username=user1",
+		`{"password":"***"}`,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(bodies, "\n") != strings.Join(want, "\n") {
+		t.Errorf("replayed bodies:\n%s\nwant:\n%s", strings.Join(bodies, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,324 @@
+package reqrecord
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReadFile reads the records of a JSON lines file, a line which cannot be decoded is skipped with a warning.
+func ReadFile(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1<<20), 16<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record Record
+		if err = json.Unmarshal(line, &record); err != nil {
+			slog.Warn("skip invalid request record", slog.String("file", path), slog.Any("error", err))
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+// Replayer sends the records to the target.
+type Replayer struct {
+	// Target is the base URL, for example "http://localhost:8082".
+	Target string
+
+	// Speed multiplies the original pacing, 2 replays twice as fast. Zero sends the requests as fast as Concurrency allows.
+	Speed float64
+
+	// Concurrency is the maximum number of requests in flight, default is 10.
+	Concurrency int
+
+	// Credentials resolve the password references by username, see PasswordReference.
+	Credentials map[string]string
+
+	Client *http.Client
+}
+
+var passwordRef = regexp.MustCompile(`\$\{password:([^}]*)\}`)
+
+// Replay sends every record at its offset divided by Speed, each with a new sampled W3C trace context,
+// so the replayed requests are new traces in the backend.
+func (p *Replayer) Replay(ctx context.Context, records []Record) *Summary {
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	concurrency := p.Concurrency
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+
+	summary := newSummary()
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for _, record := range records {
+		if p.Speed > 0 {
+			due := start.Add(time.Duration(record.OffsetMs / p.Speed * float64(time.Millisecond)))
+			select {
+			case <-time.After(time.Until(due)):
+			case <-ctx.Done():
+			}
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			summary.add(p.send(ctx, client, record))
+		}()
+	}
+
+	wg.Wait()
+	summary.Elapsed = time.Since(start)
+	return summary
+}
+
+// Result is the outcome of one replayed request.
+type Result struct {
+	Route    string
+	Status   int
+	Duration time.Duration
+	TraceID  string
+	Err      error
+}
+
+func (p *Replayer) send(ctx context.Context, client *http.Client, record Record) Result {
+	result := Result{Route: record.Method + " " + record.Route}
+	if record.Route == "" {
+		result.Route = record.Method + " " + record.Path
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(record.Headers["Content-Type"])
+	body := passwordRef.ReplaceAllStringFunc(record.Body, func(ref string) string {
+		username := passwordRef.FindStringSubmatch(ref)[1]
+		password, ok := p.Credentials[username]
+		if !ok {
+			password = maskedValue
+		}
+
+		// The reference is a form value, or inside a JSON string.
+		if mediaType == "application/x-www-form-urlencoded" {
+			return url.QueryEscape(password)
+		}
+		quoted, _ := json.Marshal(password)
+		return string(quoted[1 : len(quoted)-1])
+	})
+
+	req, err := http.NewRequestWithContext(ctx, record.Method, strings.TrimSuffix(p.Target, "/")+record.Path, strings.NewReader(body))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	for k, v := range record.Headers {
+		req.Header.Set(k, v)
+	}
+
+	spanCtx := newSpanContext()
+	result.TraceID = spanCtx.TraceID().String()
+	propagation.TraceContext{}.Inject(trace.ContextWithSpanContext(ctx, spanCtx), propagation.HeaderCarrier(req.Header))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Err = err
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	result.Status = resp.StatusCode
+	result.Duration = time.Since(start)
+	return result
+}
+
+func newSpanContext() trace.SpanContext {
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	_, _ = rand.Read(traceID[:])
+	_, _ = rand.Read(spanID[:])
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+}
+
+// Summary counts the replayed requests by route and status, with the latency percentiles.
+type Summary struct {
+	Elapsed time.Duration
+
+	mu      sync.Mutex
+	routes  map[string]*routeSummary
+	slowest []Result
+}
+
+type routeSummary struct {
+	status    map[int]int
+	errors    int
+	durations []time.Duration
+}
+
+func newSummary() *Summary {
+	return &Summary{routes: map[string]*routeSummary{}}
+}
+
+// slowestKept is the number of slowest requests printed with their trace id, to find them in the backend.
+const slowestKept = 5
+
+func (s *Summary) add(r Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	route, ok := s.routes[r.Route]
+	if !ok {
+		route = &routeSummary{status: map[int]int{}}
+		s.routes[r.Route] = route
+	}
+
+	route.durations = append(route.durations, r.Duration)
+	if r.Err != nil {
+		route.errors++
+		slog.Warn("failed to replay request", slog.String("route", r.Route), slog.Any("error", r.Err))
+		return
+	}
+	route.status[r.Status]++
+
+	s.slowest = append(s.slowest, r)
+	sort.Slice(s.slowest, func(i, j int) bool { return s.slowest[i].Duration > s.slowest[j].Duration })
+	if len(s.slowest) > slowestKept {
+		s.slowest = s.slowest[:slowestKept]
+	}
+}
+
+// Errors is the number of requests which got no response.
+func (s *Summary) Errors() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	errors := 0
+	for _, route := range s.routes {
+		errors += route.errors
+	}
+	return errors
+}
+
+// Write prints one line per route: the count, the statuses and the latency percentiles, then the slowest requests.
+func (s *Summary) Write(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var all []time.Duration
+	routes := make([]string, 0, len(s.routes))
+	for name, route := range s.routes {
+		routes = append(routes, name)
+		all = append(all, route.durations...)
+	}
+	sort.Strings(routes)
+
+	_, _ = fmt.Fprintf(w, "replayed %d requests in %s\n\n", len(all), s.Elapsed.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(w, "%-30s %6s %8s %8s %8s %8s  %s\n", "ROUTE", "COUNT", "P50", "P90", "P99", "MAX", "STATUS")
+	for _, name := range routes {
+		route := s.routes[name]
+		writeLatencies(w, name, route.durations, statuses(route))
+	}
+	writeLatencies(w, "total", all, "")
+
+	if len(s.slowest) > 0 {
+		_, _ = fmt.Fprintf(w, "\nslowest requests:\n")
+		for _, r := range s.slowest {
+			_, _ = fmt.Fprintf(w, "  %-30s %3d %8s trace_id=%s\n", r.Route, r.Status, r.Duration.Round(time.Microsecond), r.TraceID)
+		}
+	}
+}
+
+func statuses(route *routeSummary) string {
+	codes := make([]int, 0, len(route.status))
+	for code := range route.status {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+
+	parts := make([]string, 0, len(codes)+1)
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%d=%d", code, route.status[code]))
+	}
+	if route.errors > 0 {
+		parts = append(parts, fmt.Sprintf("error=%d", route.errors))
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeLatencies(w io.Writer, name string, durations []time.Duration, status string) {
+	slices.Sort(durations)
+	line := fmt.Sprintf("%-30s %6d %8s %8s %8s %8s  %s", name, len(durations),
+		percentile(durations, 0.50), percentile(durations, 0.90), percentile(durations, 0.99), percentile(durations, 1), status)
+	_, _ = fmt.Fprintln(w, strings.TrimRight(line, " "))
+}
+
+// percentile uses the nearest rank of the sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(p*float64(len(sorted)) + 0.5)
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1].Round(time.Microsecond)
+}
@@ -0,0 +1,90 @@
+package resourcedetect
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"regexp"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+// containerIDPattern matches the 64 hex characters ID used by Docker, containerd and CRI-O,
+// for example "docker-<id>.scope", "cri-containerd-<id>.scope", "crio-<id>.scope" or "/docker/<id>".
+var containerIDPattern = regexp.MustCompile(`(?:^|[/\-:])([0-9a-f]{64})(?:\.scope)?(?:$|/)`)
+
+// mountContainerIDPattern matches the directory of the container mounted by Docker and Podman, for example
+// "/var/lib/docker/containers/<id>/hostname". The "sandboxes" directory of containerd is the pod, not the container.
+var mountContainerIDPattern = regexp.MustCompile(`/containers/([0-9a-f]{64})/`)
+
+// Container detects container.id from the cgroup of the process.
+// With cgroup v2 the cgroup path is usually "/", so the mount info is read as well:
+// the runtime mounts the "hostname" and "resolv.conf" files from the directory of the container.
+type Container struct {
+	// CgroupFile default is "/proc/self/cgroup".
+	CgroupFile string
+
+	// MountInfoFile default is "/proc/self/mountinfo".
+	MountInfoFile string
+}
+
+var _ resource.Detector = Container{}
+
+func (d Container) Detect(context.Context) (*resource.Resource, error) {
+	cgroupFile := d.CgroupFile
+	if cgroupFile == "" {
+		cgroupFile = "/proc/self/cgroup"
+	}
+
+	mountInfoFile := d.MountInfoFile
+	if mountInfoFile == "" {
+		mountInfoFile = "/proc/self/mountinfo"
+	}
+
+	sources := []struct {
+		path    string
+		pattern *regexp.Regexp
+	}{
+		{cgroupFile, containerIDPattern},
+		{mountInfoFile, mountContainerIDPattern},
+	}
+
+	for _, source := range sources {
+		id, err := findContainerID(source.path, source.pattern)
+		if err != nil {
+			return resource.Empty(), err
+		}
+
+		if id != "" {
+			return resource.NewWithAttributes(semconv.SchemaURL, semconv.ContainerID(id)), nil
+		}
+	}
+
+	return resource.Empty(), nil
+}
+
+// findContainerID returns the first container ID found in the file, or an empty string.
+func findContainerID(path string, pattern *regexp.Regexp) (string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if match := pattern.FindStringSubmatch(scanner.Text()); match != nil {
+			return match[1], nil
+		}
+	}
+
+	return "", scanner.Err()
+}
@@ -0,0 +1,76 @@
+package resourcedetect
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+// attr returns the value of the attribute, or an empty string when the resource does not have it.
+func attr(res *resource.Resource, key attribute.Key) string {
+	if v, ok := res.Set().Value(key); ok {
+		return v.Emit()
+	}
+	return ""
+}
+
+func TestContainer(t *testing.T) {
+	tests := []struct {
+		name      string
+		cgroup    string
+		mountInfo string
+		want      string
+	}{
+		{
+			name:   "cgroup v1 docker",
+			cgroup: "cgroup_v1_docker",
+			want:   "4b1e5c8f9d0a7e6c3b2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c",
+		},
+		{
+			name:   "cgroup v2 containerd in kubernetes",
+			cgroup: "cgroup_v2_containerd",
+			want:   "9f8e7d6c5b4a3f2e1d0c4b1e5c8f9d0a7e6c3b2a1f0e9d8c7b6a5f4e3d2c1b0a",
+		},
+		{
+			name:      "cgroup v2 docker read from the mount info",
+			cgroup:    "cgroup_v2_root",
+			mountInfo: "mountinfo_docker",
+			want:      "0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9",
+		},
+		{
+			name:      "the containerd sandbox is the pod, not the container",
+			cgroup:    "cgroup_v2_root",
+			mountInfo: "mountinfo_containerd_sandbox",
+		},
+		{
+			name:      "not in a container",
+			cgroup:    "missing",
+			mountInfo: "missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mountInfo := tt.mountInfo
+			if mountInfo == "" {
+				mountInfo = "missing"
+			}
+
+			res, err := Container{
+				CgroupFile:    filepath.Join("testdata", tt.cgroup),
+				MountInfoFile: filepath.Join("testdata", mountInfo),
+			}.Detect(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := attr(res, semconv.ContainerIDKey); got != tt.want {
+				t.Errorf("container.id = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Package resourcedetect populates the OpenTelemetry resource with the Kubernetes, container, host and process attributes,
+// and a stable service.instance.id, so the collector does not need to add them.
+package resourcedetect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+// Names of the detectors, as used in OTEL_RESOURCE_DETECTORS.
+const (
+	DetectorK8s             = "k8s"
+	DetectorContainer       = "container"
+	DetectorHost            = "host"
+	DetectorProcess         = "process"
+	DetectorServiceInstance = "service_instance"
+)
+
+// AllDetectors are the detectors enabled by default.
+var AllDetectors = []string{DetectorK8s, DetectorContainer, DetectorHost, DetectorProcess, DetectorServiceInstance}
+
+// ParseDetectors parses a comma separated list of detector names, for example "k8s,host".
+// "none" disables every detector, and an empty string enables all of them.
+func ParseDetectors(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return AllDetectors, nil
+	}
+
+	if s == "none" {
+		return nil, nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		known := false
+		for _, detector := range AllDetectors {
+			known = known || detector == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown resource detector %q, must be one of %s", name, strings.Join(AllDetectors, ","))
+		}
+
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// New creates the resource from the given attributes, usually the service name and version, and the enabled detectors.
+// The attributes take precedence over the detected ones, and OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME
+// take precedence over everything. When a detector fails, the resource is still returned with the other attributes.
+func New(ctx context.Context, detectors []string, attrs ...attribute.KeyValue) (*resource.Resource, error) {
+	var (
+		errs            []error
+		serviceInstance bool
+		opts            = []resource.Option{resource.WithSchemaURL(semconv.SchemaURL)}
+	)
+
+	for _, name := range detectors {
+		switch name {
+		case DetectorK8s:
+			opts = append(opts, resource.WithDetectors(K8s{}))
+		case DetectorContainer:
+			opts = append(opts, resource.WithDetectors(Container{}))
+		case DetectorHost:
+			opts = append(opts, resource.WithDetectors(Host{}))
+		case DetectorProcess:
+			opts = append(opts, resource.WithDetectors(Process{}))
+		case DetectorServiceInstance:
+			// Derived from the other attributes, see below.
+			serviceInstance = true
+		default:
+			errs = append(errs, fmt.Errorf("unknown resource detector %q", name))
+		}
+	}
+
+	res, err := resource.New(ctx, append(opts, resource.WithAttributes(attrs...))...)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if serviceInstance {
+		res, err = resource.Merge(res, resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceInstanceID(ServiceInstanceID(res))))
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// The operator has the last word, for example OTEL_RESOURCE_ATTRIBUTES="service.instance.id=blue-1".
+	fromEnv, err := resource.New(ctx, resource.WithFromEnv())
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	res, err = resource.Merge(res, fromEnv)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return res, errors.Join(errs...)
+}
+
+// readFile returns the trimmed content of the file, or an empty string when it does not exist.
+func readFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
+	return strings.TrimSpace(string(content)), err
+}
+
+// getenv returns the first non empty environment variable.
+func getenv(getenvFn func(string) string, keys ...string) string {
+	if getenvFn == nil {
+		getenvFn = os.Getenv
+	}
+
+	for _, key := range keys {
+		if value := strings.TrimSpace(getenvFn(key)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package resourcedetect
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+func TestParseDetectors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", AllDetectors},
+		{"none", nil},
+		{" k8s, host ,", []string{DetectorK8s, DetectorHost}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDetectors(tt.in)
+		if err != nil {
+			t.Errorf("ParseDetectors(%q): %v", tt.in, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseDetectors(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := ParseDetectors("k8s,gcp"); err == nil {
+		t.Error("an unknown detector is accepted")
+	}
+}
+
+func TestNewPrecedence(t *testing.T) {
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.instance.id=blue-1")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+
+	res, err := New(context.Background(), []string{DetectorServiceInstance}, semconv.ServiceName("poc_otel_sdk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := attr(res, semconv.ServiceNameKey); got != "poc_otel_sdk" {
+		t.Errorf("service.name = %q", got)
+	}
+	if got := attr(res, semconv.ServiceInstanceIDKey); got != "blue-1" {
+		t.Errorf("service.instance.id = %q, OTEL_RESOURCE_ATTRIBUTES must take precedence", got)
+	}
+}
+
+func TestNewServiceInstanceID(t *testing.T) {
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "")
+
+	res, err := New(context.Background(), []string{DetectorServiceInstance}, semconv.ServiceName("poc_otel_sdk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := attr(res, semconv.ServiceInstanceIDKey), ServiceInstanceID(res); got == "" || got != want {
+		t.Errorf("service.instance.id = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package resourcedetect
+
+import (
+	"context"
+	"os"
+	"runtime"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+// Host detects host.name, host.id, host.arch and os.type.
+type Host struct {
+	// Hostname default is os.Hostname.
+	Hostname func() (string, error)
+
+	// MachineIDFiles are tried in order for host.id, default is "/etc/machine-id" then "/var/lib/dbus/machine-id".
+	MachineIDFiles []string
+}
+
+var _ resource.Detector = Host{}
+
+func (d Host) Detect(context.Context) (*resource.Resource, error) {
+	hostname := d.Hostname
+	if hostname == nil {
+		hostname = os.Hostname
+	}
+
+	machineIDFiles := d.MachineIDFiles
+	if len(machineIDFiles) == 0 {
+		machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.HostArchKey.String(hostArch()),
+		semconv.OSTypeKey.String(runtime.GOOS),
+	}
+
+	name, err := hostname()
+	if err != nil {
+		return resource.NewWithAttributes(semconv.SchemaURL, attrs...), err
+	}
+	attrs = append(attrs, semconv.HostName(name))
+
+	for _, path := range machineIDFiles {
+		id, readErr := readFile(path)
+		if readErr != nil {
+			err = readErr
+			continue
+		}
+
+		if id != "" {
+			attrs = append(attrs, semconv.HostID(id))
+			break
+		}
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), err
+}
+
+// hostArch maps GOARCH to the host.arch values of the semantic conventions.
+func hostArch() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return semconv.HostArchAMD64.Value.AsString()
+	case "arm64":
+		return semconv.HostArchARM64.Value.AsString()
+	case "386":
+		return semconv.HostArchX86.Value.AsString()
+	case "arm":
+		return semconv.HostArchARM32.Value.AsString()
+	case "ppc64":
+		return semconv.HostArchPPC64.Value.AsString()
+	case "s390x":
+		return semconv.HostArchS390x.Value.AsString()
+	default:
+		return runtime.GOARCH
+	}
+}
@@ -0,0 +1,66 @@
+package resourcedetect
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+func TestHost(t *testing.T) {
+	hostname := func() (string, error) { return "node-1", nil }
+
+	tests := []struct {
+		name           string
+		machineIDFiles []string
+		want           string
+	}{
+		{"machine id", []string{"machine-id"}, "8f3c2a1b9d7e4f6a0b5c3d2e1f0a9b8c"},
+		{"the empty and missing files are skipped", []string{"missing", "machine-id-empty", "machine-id"}, "8f3c2a1b9d7e4f6a0b5c3d2e1f0a9b8c"},
+		{"no machine id", []string{"missing"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var files []string
+			for _, file := range tt.machineIDFiles {
+				files = append(files, filepath.Join("testdata", file))
+			}
+
+			res, err := Host{Hostname: hostname, MachineIDFiles: files}.Detect(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := attr(res, semconv.HostIDKey); got != tt.want {
+				t.Errorf("host.id = %q, want %q", got, tt.want)
+			}
+			if got := attr(res, semconv.HostNameKey); got != "node-1" {
+				t.Errorf("host.name = %q", got)
+			}
+			if got := attr(res, semconv.OSTypeKey); got != runtime.GOOS {
+				t.Errorf("os.type = %q", got)
+			}
+			if got := attr(res, semconv.HostArchKey); got == "" {
+				t.Error("host.arch is missing")
+			}
+		})
+	}
+}
+
+func TestHostnameError(t *testing.T) {
+	res, err := Host{
+		Hostname:       func() (string, error) { return "", errors.New("no hostname") },
+		MachineIDFiles: []string{filepath.Join("testdata", "machine-id")},
+	}.Detect(context.Background())
+	if err == nil {
+		t.Error("the hostname error is not returned")
+	}
+
+	if got := attr(res, semconv.OSTypeKey); got != runtime.GOOS {
+		t.Errorf("os.type = %q, the other attributes must be kept", got)
+	}
+}
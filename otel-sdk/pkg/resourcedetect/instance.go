@@ -0,0 +1,52 @@
+package resourcedetect
+
+import (
+	"crypto/sha1" //nolint:gosec // UUID v5 is defined with SHA-1.
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+// serviceInstanceNamespace is the UUID v5 namespace recommended by the semantic conventions for service.instance.id.
+var serviceInstanceNamespace = [16]byte{0x4d, 0x63, 0x00, 0x9a, 0x8d, 0x0f, 0x11, 0xee, 0xaa, 0xd7, 0x4c, 0x79, 0x6e, 0xd8, 0xe3, 0x20}
+
+// ServiceInstanceID returns a UUID v5 which stays the same across restarts of the same instance:
+// derived from "<k8s.namespace.name>.<k8s.pod.name>.<k8s.container.name>" in Kubernetes,
+// otherwise from the host and the service name.
+func ServiceInstanceID(res *resource.Resource) string {
+	value := func(key attribute.Key) string {
+		if v, ok := res.Set().Value(key); ok {
+			return v.Emit()
+		}
+		return ""
+	}
+
+	var name string
+	if podName := value(semconv.K8SPodNameKey); podName != "" {
+		name = strings.Join([]string{value(semconv.K8SNamespaceNameKey), podName, value(semconv.K8SContainerNameKey)}, ".")
+	} else {
+		host := value(semconv.HostIDKey)
+		if host == "" {
+			host = value(semconv.HostNameKey)
+		}
+		name = strings.Join([]string{host, value(semconv.ContainerIDKey), value(semconv.ServiceNameKey)}, ".")
+	}
+
+	return uuidV5(serviceInstanceNamespace, name)
+}
+
+func uuidV5(namespace [16]byte, name string) string {
+	h := sha1.New() //nolint:gosec // UUID v5 is defined with SHA-1.
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = (u[6] & 0x0f) | 0x50 // version 5
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
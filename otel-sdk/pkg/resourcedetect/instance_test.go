@@ -0,0 +1,59 @@
+package resourcedetect
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+func TestUUIDv5(t *testing.T) {
+	// The example of RFC 4122 errata, "python.org" in the DNS namespace.
+	dns := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	if got, want := uuidV5(dns, "python.org"), "886313e1-3b8a-5372-9b90-0c9aee199e5d"; got != want {
+		t.Errorf("uuidV5 = %s, want %s", got, want)
+	}
+}
+
+func TestServiceInstanceID(t *testing.T) {
+	newResource := func(attrs ...attribute.KeyValue) *resource.Resource {
+		return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+	}
+
+	pod := newResource(
+		semconv.K8SNamespaceName("otel-demo"),
+		semconv.K8SPodName("otel-sdk-7d9f"),
+		semconv.K8SContainerName("otel-sdk"),
+		semconv.HostName("node-1"),
+	)
+	samePod := newResource(
+		semconv.K8SNamespaceName("otel-demo"),
+		semconv.K8SPodName("otel-sdk-7d9f"),
+		semconv.K8SContainerName("otel-sdk"),
+		semconv.HostName("node-2"),
+	)
+	otherPod := newResource(
+		semconv.K8SNamespaceName("otel-demo"),
+		semconv.K8SPodName("otel-sdk-8a0b"),
+		semconv.K8SContainerName("otel-sdk"),
+	)
+
+	if ServiceInstanceID(pod) != ServiceInstanceID(samePod) {
+		t.Error("the ID of a pod depends on the host")
+	}
+	if ServiceInstanceID(pod) == ServiceInstanceID(otherPod) {
+		t.Error("two pods have the same ID")
+	}
+
+	host := newResource(semconv.HostID("8f3c2a1b"), semconv.HostName("node-1"), semconv.ServiceName("poc_otel_sdk"))
+	sameHost := newResource(semconv.HostID("8f3c2a1b"), semconv.HostName("renamed"), semconv.ServiceName("poc_otel_sdk"))
+	otherService := newResource(semconv.HostID("8f3c2a1b"), semconv.ServiceName("poc_dd_sdk"))
+
+	if ServiceInstanceID(host) != ServiceInstanceID(sameHost) {
+		t.Error("the host.id is not preferred over the host.name")
+	}
+	if ServiceInstanceID(host) == ServiceInstanceID(otherService) {
+		t.Error("two services on the same host have the same ID")
+	}
+}
@@ -0,0 +1,79 @@
+package resourcedetect
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+// DefaultNamespaceFile is mounted in every pod with a service account token.
+const DefaultNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// K8s detects the pod from the environment variables set with the downward API:
+//
+//	env:
+//	  - name: K8S_POD_NAME
+//	    valueFrom: { fieldRef: { fieldPath: metadata.name } }
+//	  - name: K8S_NAMESPACE_NAME
+//	    valueFrom: { fieldRef: { fieldPath: metadata.namespace } }
+//	  - name: K8S_NODE_NAME
+//	    valueFrom: { fieldRef: { fieldPath: spec.nodeName } }
+//	  - name: K8S_POD_UID
+//	    valueFrom: { fieldRef: { fieldPath: metadata.uid } }
+//
+// Without the downward API, the namespace is read from the service account, and the pod name is the host name.
+type K8s struct {
+	// Getenv reads the environment variables, default is os.Getenv.
+	Getenv func(string) string
+
+	// NamespaceFile is the service account namespace file, default is DefaultNamespaceFile.
+	NamespaceFile string
+}
+
+var _ resource.Detector = K8s{}
+
+func (d K8s) Detect(context.Context) (*resource.Resource, error) {
+	namespaceFile := d.NamespaceFile
+	if namespaceFile == "" {
+		namespaceFile = DefaultNamespaceFile
+	}
+
+	namespace := getenv(d.Getenv, "K8S_NAMESPACE_NAME", "POD_NAMESPACE")
+	if namespace == "" {
+		var err error
+		if namespace, err = readFile(namespaceFile); err != nil {
+			return resource.Empty(), err
+		}
+	}
+
+	// Not running in Kubernetes.
+	if namespace == "" && getenv(d.Getenv, "KUBERNETES_SERVICE_HOST") == "" {
+		return resource.Empty(), nil
+	}
+
+	var attrs []attribute.KeyValue
+	if namespace != "" {
+		attrs = append(attrs, semconv.K8SNamespaceName(namespace))
+	}
+
+	// The host name of a pod is the pod name, unless spec.hostname is set.
+	if podName := getenv(d.Getenv, "K8S_POD_NAME", "POD_NAME", "HOSTNAME"); podName != "" {
+		attrs = append(attrs, semconv.K8SPodName(podName))
+	}
+
+	if podUID := getenv(d.Getenv, "K8S_POD_UID", "POD_UID"); podUID != "" {
+		attrs = append(attrs, semconv.K8SPodUID(podUID))
+	}
+
+	if nodeName := getenv(d.Getenv, "K8S_NODE_NAME", "NODE_NAME"); nodeName != "" {
+		attrs = append(attrs, semconv.K8SNodeName(nodeName))
+	}
+
+	if containerName := getenv(d.Getenv, "K8S_CONTAINER_NAME"); containerName != "" {
+		attrs = append(attrs, semconv.K8SContainerName(containerName))
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
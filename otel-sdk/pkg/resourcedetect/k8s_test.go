@@ -0,0 +1,77 @@
+package resourcedetect
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestK8s(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		namespaceFile string
+		want          map[string]string
+	}{
+		{
+			name: "downward API",
+			env: map[string]string{
+				"K8S_NAMESPACE_NAME": "otel-demo",
+				"K8S_POD_NAME":       "otel-sdk-7d9f",
+				"K8S_POD_UID":        "5f0d6b8e-3c1a-4d2b-9e7f-1a2b3c4d5e6f",
+				"K8S_NODE_NAME":      "node-1",
+				"K8S_CONTAINER_NAME": "otel-sdk",
+				"HOSTNAME":           "ignored",
+			},
+			want: map[string]string{
+				"k8s.namespace.name": "otel-demo",
+				"k8s.pod.name":       "otel-sdk-7d9f",
+				"k8s.pod.uid":        "5f0d6b8e-3c1a-4d2b-9e7f-1a2b3c4d5e6f",
+				"k8s.node.name":      "node-1",
+				"k8s.container.name": "otel-sdk",
+			},
+		},
+		{
+			name:          "service account namespace and host name",
+			env:           map[string]string{"HOSTNAME": "otel-sdk-7d9f"},
+			namespaceFile: "namespace",
+			want: map[string]string{
+				"k8s.namespace.name": "otel-demo",
+				"k8s.pod.name":       "otel-sdk-7d9f",
+			},
+		},
+		{
+			name: "not in kubernetes",
+			env:  map[string]string{"HOSTNAME": "laptop"},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespaceFile := tt.namespaceFile
+			if namespaceFile == "" {
+				namespaceFile = "missing"
+			}
+
+			res, err := K8s{
+				Getenv:        func(key string) string { return tt.env[key] },
+				NamespaceFile: filepath.Join("testdata", namespaceFile),
+			}.Detect(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Len() != len(tt.want) {
+				t.Errorf("attributes = %v, want %v", res.Attributes(), tt.want)
+			}
+			for key, want := range tt.want {
+				if got := attr(res, attribute.Key(key)); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package resourcedetect
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+// Process detects the process and the Go runtime.
+// The command line arguments are not included, they may contain secrets.
+type Process struct{}
+
+var _ resource.Detector = Process{}
+
+func (Process) Detect(context.Context) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{
+		semconv.ProcessPID(os.Getpid()),
+		semconv.ProcessRuntimeName("go"),
+		semconv.ProcessRuntimeVersion(runtime.Version()),
+		semconv.ProcessRuntimeDescription("go compiler " + runtime.Compiler),
+	}
+
+	if executable, err := os.Executable(); err == nil {
+		attrs = append(attrs,
+			semconv.ProcessExecutablePath(executable),
+			semconv.ProcessExecutableName(filepath.Base(executable)),
+		)
+	}
+
+	// The user may not exist in the passwd file of a distroless image.
+	if owner, err := user.Current(); err == nil {
+		attrs = append(attrs, semconv.ProcessOwner(owner.Username))
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
@@ -0,0 +1,143 @@
+package selftelemetry
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+// maxSeenErrors bounds the distinct messages kept in a window, the messages carry URLs and sizes so they are not a bounded set.
+const maxSeenErrors = 1000
+
+// ErrorHandler logs the errors of the OpenTelemetry SDK, see otel.SetErrorHandler.
+// An export failing every few seconds would flood the logs, so the same error is logged at most once per window
+// and at most limit errors are logged per window. The number of suppressed occurrences is logged with the next one,
+// or when the error is forgotten after a window without occurrence.
+type ErrorHandler struct {
+	logger *slog.Logger
+	window time.Duration
+	limit  int
+	now    func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	logged      int
+	seen        map[string]*seenError
+
+	// overflow counts the errors of the messages not kept because of maxSeenErrors, logged at the end of the window.
+	overflow int
+}
+
+type seenError struct {
+	err        error
+	lastLogged time.Time
+	lastSeen   time.Time
+	suppressed int
+}
+
+var _ otel.ErrorHandler = (*ErrorHandler)(nil)
+
+// NewErrorHandler creates an ErrorHandler, the window defaults to 1 minute and the limit to 10 errors.
+// Use a logger which does not write to the OpenTelemetry logs, otherwise a failing log exporter reports its own errors.
+func NewErrorHandler(logger *slog.Logger, window time.Duration, limit int) *ErrorHandler {
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	return &ErrorHandler{
+		logger: logger,
+		window: window,
+		limit:  limit,
+		now:    time.Now,
+		seen:   map[string]*seenError{},
+	}
+}
+
+func (h *ErrorHandler) Handle(err error) {
+	if err == nil {
+		return
+	}
+
+	ctx := context.Background()
+	current.Load().errors.Add(ctx, 1)
+
+	msg := err.Error()
+	now := h.now()
+
+	h.mu.Lock()
+	var forgotten []seenError
+	var overflow int
+	if now.Sub(h.windowStart) >= h.window {
+		forgotten, overflow = h.prune(now, msg)
+	}
+
+	seen, ok := h.seen[msg]
+	switch {
+	case ok:
+		seen.lastSeen = now
+	case len(h.seen) < maxSeenErrors:
+		seen = &seenError{lastSeen: now}
+		h.seen[msg] = seen
+	default:
+		h.overflow++
+		h.mu.Unlock()
+		h.logForgotten(ctx, forgotten, overflow)
+		return
+	}
+	seen.err = err
+
+	if now.Sub(seen.lastLogged) < h.window || h.logged >= h.limit {
+		seen.suppressed++
+		h.mu.Unlock()
+		h.logForgotten(ctx, forgotten, overflow)
+		return
+	}
+
+	suppressed := seen.suppressed
+	seen.lastLogged = now
+	seen.suppressed = 0
+	h.logged++
+	h.mu.Unlock()
+
+	h.logForgotten(ctx, forgotten, overflow)
+	h.logger.ErrorContext(ctx, "opentelemetry sdk error", slog.Any("error", err), slog.Int("suppressed", suppressed))
+}
+
+// prune starts a new window, and forgets the errors without occurrence in the last window whatever their suppressed count is,
+// except msg which occurs now. It returns the forgotten errors with suppressed occurrences and the overflow of the last window, to be logged without the lock.
+func (h *ErrorHandler) prune(now time.Time, msg string) ([]seenError, int) {
+	h.windowStart = now
+	h.logged = 0
+
+	var forgotten []seenError
+	for key, seen := range h.seen {
+		if key == msg || now.Sub(seen.lastSeen) < h.window {
+			continue
+		}
+		if seen.suppressed > 0 {
+			forgotten = append(forgotten, *seen)
+		}
+		delete(h.seen, key)
+	}
+
+	overflow := h.overflow
+	h.overflow = 0
+	return forgotten, overflow
+}
+
+func (h *ErrorHandler) logForgotten(ctx context.Context, forgotten []seenError, overflow int) {
+	for _, seen := range forgotten {
+		h.logger.ErrorContext(ctx, "opentelemetry sdk error", slog.Any("error", seen.err), slog.Int("suppressed", seen.suppressed))
+	}
+
+	if overflow > 0 {
+		h.logger.ErrorContext(ctx, "opentelemetry sdk errors not logged, too many distinct errors", slog.Int("suppressed", overflow))
+	}
+}
@@ -0,0 +1,127 @@
+package selftelemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type logRecord struct {
+	Msg        string `json:"msg"`
+	Error      string `json:"error"`
+	Suppressed int    `json:"suppressed"`
+}
+
+// newTestErrorHandler returns a handler with a clock moved by advance, and a function reading the records logged since its last call.
+func newTestErrorHandler(t *testing.T, limit int) (h *ErrorHandler, advance func(time.Duration), records func() []logRecord) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	h = NewErrorHandler(slog.New(slog.NewJSONHandler(&buf, nil)), time.Minute, limit)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+
+	advance = func(d time.Duration) { now = now.Add(d) }
+	records = func() []logRecord {
+		var out []logRecord
+		decoder := json.NewDecoder(&buf)
+		for decoder.More() {
+			var r logRecord
+			if err := decoder.Decode(&r); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, r)
+		}
+		return out
+	}
+	return h, advance, records
+}
+
+func TestErrorHandlerDedup(t *testing.T) {
+	h, advance, records := newTestErrorHandler(t, 10)
+
+	for range 3 {
+		h.Handle(errors.New("connection refused"))
+		advance(time.Second)
+	}
+	if got := records(); len(got) != 1 || got[0].Error != "connection refused" || got[0].Suppressed != 0 {
+		t.Fatalf("records = %+v, want the first occurrence only", got)
+	}
+
+	// The next occurrence after the window is logged with the count of the suppressed ones.
+	advance(time.Minute)
+	h.Handle(errors.New("connection refused"))
+	if got := records(); len(got) != 1 || got[0].Suppressed != 2 {
+		t.Errorf("records = %+v, want 2 suppressed", got)
+	}
+}
+
+func TestErrorHandlerLimit(t *testing.T) {
+	h, _, records := newTestErrorHandler(t, 2)
+
+	for i := range 4 {
+		h.Handle(fmt.Errorf("error %d", i))
+	}
+	if got := records(); len(got) != 2 || got[0].Error != "error 0" || got[1].Error != "error 1" {
+		t.Errorf("records = %+v, want the first 2 errors of the window", got)
+	}
+}
+
+func TestErrorHandlerPrune(t *testing.T) {
+	h, advance, records := newTestErrorHandler(t, 1)
+
+	// "unique" is over the limit of its window, so it is only suppressed.
+	h.Handle(errors.New("first"))
+	h.Handle(errors.New("unique"))
+	records()
+
+	// Once the window without occurrence is over, it is logged with its suppressed count and forgotten.
+	advance(time.Minute)
+	h.Handle(errors.New("first"))
+	advance(time.Minute)
+	h.Handle(errors.New("other"))
+
+	got := records()
+	var forgotten []logRecord
+	for _, r := range got {
+		if r.Error == "unique" {
+			forgotten = append(forgotten, r)
+		}
+	}
+	if len(forgotten) != 1 || forgotten[0].Suppressed != 1 {
+		t.Errorf("records = %+v, want unique with 1 suppressed", got)
+	}
+	if _, ok := h.seen["other"]; !ok || len(h.seen) != 1 {
+		t.Errorf("seen = %v, want other only", h.seen)
+	}
+}
+
+func TestErrorHandlerMaxSeen(t *testing.T) {
+	h, advance, records := newTestErrorHandler(t, 10)
+
+	for i := range maxSeenErrors + 5 {
+		h.Handle(fmt.Errorf("export of %d bytes failed", i))
+	}
+	if len(h.seen) != maxSeenErrors || h.overflow != 5 {
+		t.Fatalf("%d errors kept and %d overflow, want %d and 5", len(h.seen), h.overflow, maxSeenErrors)
+	}
+	records()
+
+	advance(time.Minute)
+	h.Handle(errors.New("after"))
+
+	var overflow int
+	for _, r := range records() {
+		if r.Error == "" {
+			overflow = r.Suppressed
+		}
+	}
+	if overflow != 5 || len(h.seen) != 1 {
+		t.Errorf("overflow logged %d, %d errors kept, want 5 and 1", overflow, len(h.seen))
+	}
+}
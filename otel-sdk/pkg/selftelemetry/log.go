@@ -0,0 +1,36 @@
+package selftelemetry
+
+import (
+	"context"
+	"time"
+
+	otelSdkLog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// LogExporter records the duration and the outcome of every log export.
+type LogExporter struct {
+	name string
+	next otelSdkLog.Exporter
+}
+
+var _ otelSdkLog.Exporter = (*LogExporter)(nil)
+
+// NewLogExporter wraps the log exporter, name is the exporter in the status and the metrics, for example "otlp".
+func NewLogExporter(name string, next otelSdkLog.Exporter) *LogExporter {
+	return &LogExporter{name: name, next: next}
+}
+
+func (e *LogExporter) Export(ctx context.Context, records []otelSdkLog.Record) error {
+	start := time.Now()
+	err := e.next.Export(ctx, records)
+	recordExport(ctx, "logs", e.name, start, err)
+	return err
+}
+
+func (e *LogExporter) ForceFlush(ctx context.Context) error {
+	return e.next.ForceFlush(ctx)
+}
+
+func (e *LogExporter) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
@@ -0,0 +1,45 @@
+package selftelemetry
+
+import (
+	"context"
+	"time"
+
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// MetricExporter records the duration and the outcome of every metric export.
+type MetricExporter struct {
+	name string
+	next otelSdkMetric.Exporter
+}
+
+var _ otelSdkMetric.Exporter = (*MetricExporter)(nil)
+
+// NewMetricExporter wraps the metric exporter, name is the exporter in the status and the metrics, for example "otlp".
+func NewMetricExporter(name string, next otelSdkMetric.Exporter) *MetricExporter {
+	return &MetricExporter{name: name, next: next}
+}
+
+func (e *MetricExporter) Temporality(kind otelSdkMetric.InstrumentKind) metricdata.Temporality {
+	return e.next.Temporality(kind)
+}
+
+func (e *MetricExporter) Aggregation(kind otelSdkMetric.InstrumentKind) otelSdkMetric.Aggregation {
+	return e.next.Aggregation(kind)
+}
+
+func (e *MetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	start := time.Now()
+	err := e.next.Export(ctx, rm)
+	recordExport(ctx, "metrics", e.name, start, err)
+	return err
+}
+
+func (e *MetricExporter) ForceFlush(ctx context.Context) error {
+	return e.next.ForceFlush(ctx)
+}
+
+func (e *MetricExporter) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
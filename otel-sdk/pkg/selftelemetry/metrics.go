@@ -0,0 +1,158 @@
+// Package selftelemetry observes the telemetry pipeline itself: the OpenTelemetry SDK errors are logged with
+// deduplication and rate limiting, and the span and export counters are exported as metrics,
+// so a broken pipeline can be alerted on instead of being found in the container logs.
+package selftelemetry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	otelMetricNoop "go.opentelemetry.io/otel/metric/noop"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/selftelemetry"
+
+type instruments struct {
+	spansStarted   metric.Int64Counter
+	spansEnded     metric.Int64Counter
+	spansExported  metric.Int64Counter
+	spansFailed    metric.Int64Counter
+	exportDuration metric.Float64Histogram
+	exportFailures metric.Int64Counter
+	errors         metric.Int64Counter
+}
+
+var (
+	current atomic.Pointer[instruments]
+
+	exportStatusMu sync.Mutex
+	exportStatus   = map[exportKey]ExportStatus{}
+)
+
+// exportKey identifies the exports of one exporter of a signal, the exporters of a failover chain have their own status.
+type exportKey struct {
+	signal   string
+	exporter string
+}
+
+func init() {
+	noopCounter := otelMetricNoop.Int64Counter{}
+	current.Store(&instruments{
+		spansStarted:   noopCounter,
+		spansEnded:     noopCounter,
+		spansExported:  noopCounter,
+		spansFailed:    noopCounter,
+		exportDuration: otelMetricNoop.Float64Histogram{},
+		exportFailures: noopCounter,
+		errors:         noopCounter,
+	})
+}
+
+// RegisterMetrics creates the self-observability metrics through the global MeterProvider.
+// metricPrefix is prepended to the metric names, usually the service name.
+// Until it is called, the span processor, the exporters and the error handler of this package record nothing.
+func RegisterMetrics(metricPrefix string) error {
+	meter := otel.Meter(instrumentationName)
+
+	var (
+		inst instruments
+		err  error
+	)
+
+	counters := []struct {
+		counter     *metric.Int64Counter
+		name        string
+		description string
+	}{
+		{&inst.spansStarted, ".telemetry.spans_started", "Number of spans started."},
+		{&inst.spansEnded, ".telemetry.spans_ended", "Number of spans ended."},
+		{&inst.spansExported, ".telemetry.spans_exported", "Number of spans accepted by the span exporter."},
+		{&inst.spansFailed, ".telemetry.spans_export_failed", "Number of spans in the failed exports, a fallback exporter may still export them."},
+		{&inst.exportFailures, ".telemetry.export_failures", "Number of failed exports."},
+		{&inst.errors, ".telemetry.errors", "Number of errors reported by the OpenTelemetry SDK, including the suppressed ones."},
+	}
+
+	for _, c := range counters {
+		*c.counter, err = meter.Int64Counter(metricPrefix+c.name, metric.WithDescription(c.description))
+		if err != nil {
+			return err
+		}
+	}
+
+	inst.exportDuration, err = meter.Float64Histogram(metricPrefix+".telemetry.export_duration",
+		metric.WithDescription("Duration of the exports."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+
+	lastExportSuccess, err := meter.Float64ObservableGauge(metricPrefix+".telemetry.last_export_success",
+		metric.WithDescription("Unix timestamp of the last successful export."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		exportStatusMu.Lock()
+		defer exportStatusMu.Unlock()
+
+		for key, status := range exportStatus {
+			if status.LastSuccess.IsZero() {
+				continue
+			}
+			o.ObserveFloat64(lastExportSuccess, float64(status.LastSuccess.UnixNano())/1e9, metric.WithAttributes(
+				attribute.String("signal", key.signal),
+				attribute.String("exporter", key.exporter),
+			))
+		}
+		return nil
+	}, lastExportSuccess)
+	if err != nil {
+		return err
+	}
+
+	current.Store(&inst)
+	return nil
+}
+
+// recordExport records the duration and the outcome of one export of the signal by the exporter.
+func recordExport(ctx context.Context, signal, exporter string, start time.Time, err error) {
+	inst := current.Load()
+
+	key := exportKey{signal: signal, exporter: exporter}
+	exportStatusMu.Lock()
+	status := exportStatus[key]
+	status.Exports++
+	if err != nil {
+		status.Failures++
+		status.LastFailure = time.Now()
+		status.LastError = err.Error()
+	} else {
+		status.LastSuccess = time.Now()
+	}
+	exportStatus[key] = status
+	exportStatusMu.Unlock()
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+		inst.exportFailures.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("signal", signal),
+			attribute.String("exporter", exporter),
+		))
+	}
+
+	inst.exportDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+		attribute.String("signal", signal),
+		attribute.String("exporter", exporter),
+		attribute.String("outcome", outcome),
+	))
+}
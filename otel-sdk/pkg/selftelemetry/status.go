@@ -0,0 +1,122 @@
+package selftelemetry
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/failover"
+)
+
+// Pipeline describes how one signal is exported, it is set up once at start.
+type Pipeline struct {
+	Signal    string   `json:"signal"`
+	Exporters []string `json:"exporters"`
+	Processor string   `json:"processor,omitempty"`
+	Sampler   string   `json:"sampler,omitempty"`
+	Readers   []string `json:"readers,omitempty"`
+}
+
+// ExportStatus is the outcome of the exports of one exporter, recorded by the exporters of this package.
+type ExportStatus struct {
+	Exports     int64     `json:"exports"`
+	Failures    int64     `json:"failures"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// MarshalJSON omits the zero timestamps.
+func (s ExportStatus) MarshalJSON() ([]byte, error) {
+	type alias ExportStatus
+	return json.Marshal(struct {
+		alias
+		LastSuccess *time.Time `json:"last_success,omitempty"`
+		LastFailure *time.Time `json:"last_failure,omitempty"`
+	}{alias(s), nonZero(s.LastSuccess), nonZero(s.LastFailure)})
+}
+
+func nonZero(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// PipelineStatus is the Pipeline with the export status of every exporter, and the failover chain when there is one.
+type PipelineStatus struct {
+	Pipeline
+	Exports  map[string]ExportStatus `json:"exports"`
+	Failover *failover.Status        `json:"failover,omitempty"`
+}
+
+// Status is the JSON body of the telemetry status endpoint.
+type Status struct {
+	Resource  map[string]string `json:"resource"`
+	Pipelines []PipelineStatus  `json:"pipelines"`
+}
+
+var (
+	pipelinesMu sync.Mutex
+	pipelines   = map[string]Pipeline{}
+)
+
+// RegisterPipeline records how the signal is exported, replacing the previous one of the same signal.
+func RegisterPipeline(p Pipeline) {
+	pipelinesMu.Lock()
+	defer pipelinesMu.Unlock()
+	pipelines[p.Signal] = p
+}
+
+// CurrentStatus returns the pipelines with their last export status.
+func CurrentStatus(res *resource.Resource) Status {
+	status := Status{Resource: map[string]string{}}
+	for _, kv := range res.Attributes() {
+		status.Resource[string(kv.Key)] = kv.Value.Emit()
+	}
+
+	failovers := map[string]failover.Status{}
+	for _, s := range failover.Statuses() {
+		failovers[s.Signal] = s
+	}
+
+	pipelinesMu.Lock()
+	exportStatusMu.Lock()
+	for signal, p := range pipelines {
+		ps := PipelineStatus{Pipeline: p, Exports: map[string]ExportStatus{}}
+		for key, export := range exportStatus {
+			if key.signal == signal {
+				ps.Exports[key.exporter] = export
+			}
+		}
+		if s, ok := failovers[signal]; ok {
+			ps.Failover = &s
+		}
+		status.Pipelines = append(status.Pipelines, ps)
+	}
+	exportStatusMu.Unlock()
+	pipelinesMu.Unlock()
+
+	sort.Slice(status.Pipelines, func(i, j int) bool {
+		return status.Pipelines[i].Signal < status.Pipelines[j].Signal
+	})
+
+	return status
+}
+
+// StatusHandler responds the CurrentStatus as JSON, for example at "/debug/telemetry".
+func StatusHandler(res *resource.Resource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(CurrentStatus(res)); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write telemetry status", slog.Any("error", err))
+		}
+	})
+}
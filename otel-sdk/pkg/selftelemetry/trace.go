@@ -0,0 +1,64 @@
+package selftelemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// SpanProcessor counts the started and ended spans, register it next to the processor which exports the spans.
+type SpanProcessor struct{}
+
+var _ otelSdkTrace.SpanProcessor = SpanProcessor{}
+
+func (SpanProcessor) OnStart(ctx context.Context, _ otelSdkTrace.ReadWriteSpan) {
+	current.Load().spansStarted.Add(ctx, 1)
+}
+
+func (SpanProcessor) OnEnd(otelSdkTrace.ReadOnlySpan) {
+	current.Load().spansEnded.Add(context.Background(), 1)
+}
+
+func (SpanProcessor) Shutdown(context.Context) error {
+	return nil
+}
+
+func (SpanProcessor) ForceFlush(context.Context) error {
+	return nil
+}
+
+// SpanExporter counts the exported and failed spans, and records the duration of every export.
+type SpanExporter struct {
+	name string
+	next otelSdkTrace.SpanExporter
+}
+
+var _ otelSdkTrace.SpanExporter = (*SpanExporter)(nil)
+
+// NewSpanExporter wraps the span exporter, name is the exporter in the status and the metrics, for example "otlp".
+func NewSpanExporter(name string, next otelSdkTrace.SpanExporter) *SpanExporter {
+	return &SpanExporter{name: name, next: next}
+}
+
+func (e *SpanExporter) ExportSpans(ctx context.Context, spans []otelSdkTrace.ReadOnlySpan) error {
+	start := time.Now()
+	err := e.next.ExportSpans(ctx, spans)
+	recordExport(ctx, "traces", e.name, start, err)
+
+	inst := current.Load()
+	attrs := metric.WithAttributes(attribute.String("exporter", e.name))
+	if err != nil {
+		inst.spansFailed.Add(ctx, int64(len(spans)), attrs)
+	} else {
+		inst.spansExported.Add(ctx, int64(len(spans)), attrs)
+	}
+
+	return err
+}
+
+func (e *SpanExporter) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
@@ -0,0 +1,235 @@
+// Package tracebuffer keeps the most recent spans in memory, so they can be browsed at "/debug/traces"
+// without any tracing backend, similar to the zPages.
+package tracebuffer
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Buffer is a span exporter which keeps the last spans in a ring buffer.
+// Use it with otelSdkTrace.NewSimpleSpanProcessor next to the real exporter.
+type Buffer struct {
+	mu    sync.Mutex
+	spans []otelSdkTrace.ReadOnlySpan
+	next  int
+	full  bool
+}
+
+var _ otelSdkTrace.SpanExporter = (*Buffer)(nil)
+
+// New creates a Buffer keeping the last capacity spans, default is 1000.
+func New(capacity int) *Buffer {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+
+	return &Buffer{spans: make([]otelSdkTrace.ReadOnlySpan, capacity)}
+}
+
+func (b *Buffer) ExportSpans(_ context.Context, spans []otelSdkTrace.ReadOnlySpan) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, span := range spans {
+		b.spans[b.next] = span
+		b.next = (b.next + 1) % len(b.spans)
+		b.full = b.full || b.next == 0
+	}
+
+	return nil
+}
+
+func (b *Buffer) Shutdown(context.Context) error {
+	return nil
+}
+
+// Spans returns the buffered spans, the oldest first.
+func (b *Buffer) Spans() []otelSdkTrace.ReadOnlySpan {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append([]otelSdkTrace.ReadOnlySpan(nil), b.spans[:b.next]...)
+	}
+
+	return append(append([]otelSdkTrace.ReadOnlySpan(nil), b.spans[b.next:]...), b.spans[:b.next]...)
+}
+
+// Trace returns the buffered spans of the trace, ordered by start time.
+func (b *Buffer) Trace(traceID trace.TraceID) []otelSdkTrace.ReadOnlySpan {
+	var out []otelSdkTrace.ReadOnlySpan
+	for _, span := range b.Spans() {
+		if span.SpanContext().TraceID() == traceID {
+			out = append(out, span)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].StartTime().Before(out[j].StartTime())
+	})
+	return out
+}
+
+// Filter selects the traces returned by Traces, the zero value selects everything.
+type Filter struct {
+	// Route keeps the traces whose route contains this value.
+	Route string
+
+	// Status is "ok", "error" or an HTTP status code such as "500".
+	Status string
+
+	// MinDuration keeps the traces lasting at least this long.
+	MinDuration time.Duration
+
+	// Limit is the maximum number of traces, default is 100, and at most the capacity of the buffer.
+	Limit int
+}
+
+// TraceSummary describes one trace by its root span.
+type TraceSummary struct {
+	TraceID    string    `json:"trace_id"`
+	Name       string    `json:"name"`
+	Route      string    `json:"route"`
+	Status     string    `json:"status"`
+	StatusCode int       `json:"http_status_code,omitempty"`
+	Start      time.Time `json:"start"`
+	DurationMs float64   `json:"duration_ms"`
+	Spans      int       `json:"spans"`
+}
+
+// Traces groups the buffered spans by trace and returns the matching traces, the most recent first.
+func (b *Buffer) Traces(filter Filter) []TraceSummary {
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+	filter.Limit = min(filter.Limit, len(b.spans))
+
+	byTrace := map[trace.TraceID][]otelSdkTrace.ReadOnlySpan{}
+	for _, span := range b.Spans() {
+		traceID := span.SpanContext().TraceID()
+		byTrace[traceID] = append(byTrace[traceID], span)
+	}
+
+	summaries := make([]TraceSummary, 0, len(byTrace))
+	for traceID, spans := range byTrace {
+		summary := summarize(traceID, spans)
+		if filter.match(summary) {
+			summaries = append(summaries, summary)
+		}
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Start.After(summaries[j].Start)
+	})
+
+	if len(summaries) > filter.Limit {
+		summaries = summaries[:filter.Limit]
+	}
+	return summaries
+}
+
+func (f Filter) match(s TraceSummary) bool {
+	if f.Route != "" && !strings.Contains(s.Route, f.Route) {
+		return false
+	}
+
+	if f.Status != "" && f.Status != s.Status && f.Status != strconv.Itoa(s.StatusCode) {
+		return false
+	}
+
+	return time.Duration(s.DurationMs*float64(time.Millisecond)) >= f.MinDuration
+}
+
+func summarize(traceID trace.TraceID, spans []otelSdkTrace.ReadOnlySpan) TraceSummary {
+	root := rootSpan(spans)
+	start, end := root.StartTime(), root.EndTime()
+	for _, span := range spans {
+		if span.StartTime().Before(start) {
+			start = span.StartTime()
+		}
+		if span.EndTime().After(end) {
+			end = span.EndTime()
+		}
+	}
+
+	summary := TraceSummary{
+		TraceID:    traceID.String(),
+		Name:       root.Name(),
+		Route:      route(root),
+		Status:     "ok",
+		StatusCode: httpStatusCode(root),
+		Start:      start,
+		DurationMs: float64(end.Sub(start)) / float64(time.Millisecond),
+		Spans:      len(spans),
+	}
+
+	for _, span := range spans {
+		if span.Status().Code == codes.Error {
+			summary.Status = "error"
+		}
+	}
+	if summary.StatusCode >= 500 {
+		summary.Status = "error"
+	}
+
+	return summary
+}
+
+// rootSpan returns the earliest span whose parent is not buffered, the root may be dropped from the ring buffer already.
+func rootSpan(spans []otelSdkTrace.ReadOnlySpan) otelSdkTrace.ReadOnlySpan {
+	ids := make(map[trace.SpanID]bool, len(spans))
+	for _, span := range spans {
+		ids[span.SpanContext().SpanID()] = true
+	}
+
+	var root otelSdkTrace.ReadOnlySpan
+	for _, span := range spans {
+		if ids[span.Parent().SpanID()] {
+			continue
+		}
+		if root == nil || span.StartTime().Before(root.StartTime()) {
+			root = span
+		}
+	}
+
+	if root == nil {
+		root = spans[0]
+	}
+	return root
+}
+
+func route(span otelSdkTrace.ReadOnlySpan) string {
+	for _, key := range []string{"http.route", "url.path", "http.target"} {
+		if value := attribute(span, key); value != "" {
+			return value
+		}
+	}
+	return span.Name()
+}
+
+func httpStatusCode(span otelSdkTrace.ReadOnlySpan) int {
+	for _, key := range []string{"http.response.status_code", "http.status_code"} {
+		if code, err := strconv.Atoi(attribute(span, key)); err == nil {
+			return code
+		}
+	}
+	return 0
+}
+
+func attribute(span otelSdkTrace.ReadOnlySpan, key string) string {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,168 @@
+package tracebuffer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	otelAttribute "go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// rootSpanStub is the server span of a request, started n seconds after start.
+func rootSpanStub(n byte, route string, statusCode int, duration time.Duration) tracetest.SpanStub {
+	return tracetest.SpanStub{
+		Name: "GET " + route,
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{n},
+			SpanID:  trace.SpanID{n},
+		}),
+		StartTime: start.Add(time.Duration(n) * time.Second),
+		EndTime:   start.Add(time.Duration(n)*time.Second + duration),
+		Attributes: []otelAttribute.KeyValue{
+			otelAttribute.String("http.route", route),
+			otelAttribute.Int("http.response.status_code", statusCode),
+		},
+	}
+}
+
+func export(t *testing.T, b *Buffer, stubs ...tracetest.SpanStub) {
+	t.Helper()
+
+	if err := b.ExportSpans(context.Background(), tracetest.SpanStubs(stubs).Snapshots()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func names(spans []otelSdkTrace.ReadOnlySpan) []string {
+	out := make([]string, 0, len(spans))
+	for _, span := range spans {
+		out = append(out, span.Name())
+	}
+	return out
+}
+
+func TestBufferWraparound(t *testing.T) {
+	b := New(3)
+
+	export(t, b, rootSpanStub(1, "/1", 200, time.Millisecond), rootSpanStub(2, "/2", 200, time.Millisecond))
+	if got := names(b.Spans()); len(got) != 2 || got[0] != "GET /1" {
+		t.Fatalf("spans = %v before the wraparound", got)
+	}
+
+	// The oldest spans are overwritten, the spans stay ordered from the oldest.
+	export(t, b, rootSpanStub(3, "/3", 200, time.Millisecond), rootSpanStub(4, "/4", 200, time.Millisecond))
+	export(t, b, rootSpanStub(5, "/5", 200, time.Millisecond))
+
+	got := names(b.Spans())
+	want := []string{"GET /3", "GET /4", "GET /5"}
+	if len(got) != len(want) {
+		t.Fatalf("spans = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("spans = %v, want %v", got, want)
+		}
+	}
+
+	if spans := b.Trace(trace.TraceID{1}); len(spans) != 0 {
+		t.Errorf("the evicted trace still has %d spans", len(spans))
+	}
+}
+
+func TestBufferTraces(t *testing.T) {
+	b := New(100)
+
+	child := rootSpanStub(1, "/login", 200, 0)
+	child.Name = "Check Credentials"
+	child.Parent = trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}})
+	child.SpanContext = trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{9}})
+	child.Status = otelSdkTrace.Status{Code: codes.Error}
+	child.Attributes = nil
+
+	export(t, b,
+		rootSpanStub(1, "/login", 401, 300*time.Millisecond), child,
+		rootSpanStub(2, "/login", 200, 20*time.Millisecond),
+		rootSpanStub(3, "/me", 500, 5*time.Millisecond),
+		rootSpanStub(4, "/", 200, time.Millisecond),
+	)
+
+	tests := map[string]struct {
+		filter Filter
+		want   []string
+	}{
+		"all, most recent first": {Filter{}, []string{"04", "03", "02", "01"}},
+		"route":                  {Filter{Route: "login"}, []string{"02", "01"}},
+		"error":                  {Filter{Status: "error"}, []string{"03", "01"}},
+		"status code":            {Filter{Status: "200"}, []string{"04", "02"}},
+		"min duration":           {Filter{MinDuration: 20 * time.Millisecond}, []string{"02", "01"}},
+		"limit":                  {Filter{Limit: 2}, []string{"04", "03"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			summaries := b.Traces(test.filter)
+			if len(summaries) != len(test.want) {
+				t.Fatalf("%d traces, want %v", len(summaries), test.want)
+			}
+			for i, summary := range summaries {
+				if summary.TraceID[:2] != test.want[i] {
+					t.Errorf("trace %d = %s, want %s", i, summary.TraceID, test.want[i])
+				}
+			}
+		})
+	}
+
+	login := b.Traces(Filter{Route: "login", Status: "401"})
+	if len(login) != 1 || login[0].Name != "GET /login" || login[0].Spans != 2 || login[0].Status != "error" || login[0].DurationMs != 300 {
+		t.Errorf("summary = %+v, want the root span with its child", login)
+	}
+}
+
+func TestListHandlerLimit(t *testing.T) {
+	b := New(2)
+	export(t, b, rootSpanStub(1, "/1", 200, 0), rootSpanStub(2, "/2", 200, 0), rootSpanStub(3, "/3", 200, 0))
+
+	tests := map[string]struct {
+		query  string
+		code   int
+		traces int
+	}{
+		"clamped to the buffer": {"?limit=1000000000", http.StatusOK, 2},
+		"limit":                 {"?limit=1", http.StatusOK, 1},
+		"invalid limit":         {"?limit=many", http.StatusBadRequest, 0},
+		"invalid min_duration":  {"?min_duration=long", http.StatusBadRequest, 0},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			b.ListHandler(w, httptest.NewRequest(http.MethodGet, "/debug/traces"+test.query, nil))
+
+			if w.Code != test.code {
+				t.Fatalf("status = %d, want %d", w.Code, test.code)
+			}
+			if test.code != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Traces []TraceSummary `json:"traces"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Traces) != test.traces {
+				t.Errorf("%d traces, want %d", len(body.Traces), test.traces)
+			}
+		})
+	}
+}
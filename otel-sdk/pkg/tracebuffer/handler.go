@@ -0,0 +1,191 @@
+package tracebuffer
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/codes"
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ListHandler responds the buffered traces as JSON, the most recent first.
+// Query parameters: "route", "status" (ok, error or an HTTP status code), "min_duration" (for example "100ms") and "limit",
+// which is clamped to the capacity of the buffer.
+func (b *Buffer) ListHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := Filter{
+		Route:  query.Get("route"),
+		Status: query.Get("status"),
+	}
+
+	if minDuration := query.Get("min_duration"); minDuration != "" {
+		d, err := time.ParseDuration(minDuration)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid min_duration: %s", err), http.StatusBadRequest)
+			return
+		}
+		filter.MinDuration = d
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit: %s", err), http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"traces": b.Traces(filter)}); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write traces", slog.Any("error", err))
+	}
+}
+
+// TraceHandler renders the spans of the trace in the "traceID" URL parameter as an HTML waterfall.
+func (b *Buffer) TraceHandler(w http.ResponseWriter, r *http.Request) {
+	traceID, err := trace.TraceIDFromHex(chi.URLParam(r, "traceID"))
+	if err != nil {
+		http.Error(w, "invalid trace id", http.StatusBadRequest)
+		return
+	}
+
+	spans := b.Trace(traceID)
+	if len(spans) == 0 {
+		http.Error(w, "trace not found, it may have been evicted from the buffer", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err = waterfallTemplate.Execute(w, waterfall(traceID, spans)); err != nil {
+		slog.ErrorContext(r.Context(), "failed to render trace", slog.Any("error", err))
+	}
+}
+
+type waterfallPage struct {
+	TraceID    string
+	DurationMs float64
+	Rows       []waterfallRow
+}
+
+type waterfallRow struct {
+	Name       string
+	Depth      int
+	Kind       string
+	Error      bool
+	Offset     float64 // percentage of the trace duration
+	Width      float64 // percentage of the trace duration
+	DurationMs float64
+	Details    string
+}
+
+// waterfall orders the spans depth first, the children by start time, and positions them on the trace timeline.
+func waterfall(traceID trace.TraceID, spans []otelSdkTrace.ReadOnlySpan) waterfallPage {
+	start, end := spans[0].StartTime(), spans[0].EndTime()
+	ids := make(map[trace.SpanID]bool, len(spans))
+	for _, span := range spans {
+		ids[span.SpanContext().SpanID()] = true
+		if span.StartTime().Before(start) {
+			start = span.StartTime()
+		}
+		if span.EndTime().After(end) {
+			end = span.EndTime()
+		}
+	}
+
+	// spans are already ordered by start time, so are the children.
+	children := map[trace.SpanID][]otelSdkTrace.ReadOnlySpan{}
+	var roots []otelSdkTrace.ReadOnlySpan
+	for _, span := range spans {
+		if parent := span.Parent().SpanID(); ids[parent] {
+			children[parent] = append(children[parent], span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+
+	total := max(end.Sub(start), time.Nanosecond)
+	page := waterfallPage{
+		TraceID:    traceID.String(),
+		DurationMs: float64(total) / float64(time.Millisecond),
+	}
+
+	var walk func(span otelSdkTrace.ReadOnlySpan, depth int)
+	walk = func(span otelSdkTrace.ReadOnlySpan, depth int) {
+		duration := span.EndTime().Sub(span.StartTime())
+		page.Rows = append(page.Rows, waterfallRow{
+			Name:       span.Name(),
+			Depth:      depth,
+			Kind:       span.SpanKind().String(),
+			Error:      span.Status().Code == codes.Error,
+			Offset:     100 * float64(span.StartTime().Sub(start)) / float64(total),
+			Width:      max(100*float64(duration)/float64(total), 0.2),
+			DurationMs: float64(duration) / float64(time.Millisecond),
+			Details:    details(span),
+		})
+
+		for _, child := range children[span.SpanContext().SpanID()] {
+			walk(child, depth+1)
+		}
+	}
+
+	for _, root := range roots {
+		walk(root, 0)
+	}
+
+	return page
+}
+
+// details lists the span ID, status and attributes, shown when hovering the row.
+func details(span otelSdkTrace.ReadOnlySpan) string {
+	lines := []string{"span_id=" + span.SpanContext().SpanID().String()}
+	if status := span.Status(); status.Code != codes.Unset {
+		lines = append(lines, fmt.Sprintf("status=%s %s", status.Code, status.Description))
+	}
+
+	attrs := make([]string, 0, len(span.Attributes()))
+	for _, kv := range span.Attributes() {
+		attrs = append(attrs, fmt.Sprintf("%s=%s", kv.Key, kv.Value.Emit()))
+	}
+	sort.Strings(attrs)
+
+	return strings.Join(append(lines, attrs...), "\n")
+}
+
+var waterfallTemplate = template.Must(template.New("waterfall").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Trace {{.TraceID}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 16px; }
+table { border-collapse: collapse; width: 100%; }
+td { padding: 3px 6px; border-bottom: 1px solid #eee; white-space: nowrap; }
+td.timeline { width: 60%; position: relative; }
+.bar { position: absolute; top: 5px; height: 12px; background: #4a90d9; border-radius: 2px; }
+.bar.error { background: #d9534f; }
+.kind { color: #888; }
+</style>
+</head>
+<body>
+<h2>Trace {{.TraceID}}</h2>
+<p>{{len .Rows}} spans, {{printf "%.3f" .DurationMs}} ms. <a href="../traces">All traces (JSON)</a></p>
+<table>
+{{range .Rows}}<tr title="{{.Details}}">
+<td style="padding-left: {{.Depth}}em">{{.Name}} <span class="kind">{{.Kind}}</span></td>
+<td>{{printf "%.3f" .DurationMs}} ms</td>
+<td class="timeline"><div class="bar{{if .Error}} error{{end}}" style="left: {{printf "%.2f" .Offset}}%; width: {{printf "%.2f" .Width}}%"></div></td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,137 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/userstore"
+
+// Authenticator checks the credentials against the UserStore, with a span and a latency histogram for the lookup and for the hash verification.
+type Authenticator struct {
+	store UserStore
+
+	// dummyHash is verified when the user does not exist, so an unknown username takes as long as a wrong password.
+	// It has the algorithm and the parameters of the most expensive hash of the store.
+	dummyHash string
+
+	tracer         trace.Tracer
+	lookupDuration metric.Float64Histogram
+	verifyDuration metric.Float64Histogram
+}
+
+// NewAuthenticator creates the instruments "<metricPrefix>.userstore.lookup_duration_ms" and "<metricPrefix>.userstore.verify_duration_ms".
+func NewAuthenticator(store UserStore, metricPrefix string) *Authenticator {
+	meter := otel.Meter(instrumentationName)
+
+	lookupDuration, err := meter.Float64Histogram(metricPrefix+".userstore.lookup_duration_ms",
+		metric.WithDescription("Duration of the user lookups."),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		slog.Error("failed to create userstore.lookup_duration_ms histogram", slog.Any("error", err))
+		lookupDuration = &noop.Float64Histogram{}
+	}
+
+	verifyDuration, err := meter.Float64Histogram(metricPrefix+".userstore.verify_duration_ms",
+		metric.WithDescription("Duration of the password hash verifications."),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		slog.Error("failed to create userstore.verify_duration_ms histogram", slog.Any("error", err))
+		verifyDuration = &noop.Float64Histogram{}
+	}
+
+	dummyHash, err := DummyHash("dummy password of the unknown users", passwordHashes(store))
+	if err != nil {
+		panic(err)
+	}
+
+	return &Authenticator{
+		store:          store,
+		dummyHash:      dummyHash,
+		tracer:         otel.Tracer(instrumentationName),
+		lookupDuration: lookupDuration,
+		verifyDuration: verifyDuration,
+	}
+}
+
+// Authenticate returns the user when the password matches, ErrUnknownUser when the user does not exist, or ErrWrongPassword.
+// Both errors are ErrInvalidCredentials, and both cases verify one hash, so the response time does not tell whether the username exists.
+func (a *Authenticator) Authenticate(ctx context.Context, username, password string) (User, error) {
+	user, err := a.lookup(ctx, username)
+	found := err == nil
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return User{}, err
+	}
+
+	hash := a.dummyHash
+	if found {
+		hash = user.PasswordHash
+	}
+
+	match, err := a.verify(ctx, hash, password)
+	if err != nil {
+		return User{}, err
+	}
+
+	if !found {
+		return User{}, ErrUnknownUser
+	}
+	if !match {
+		return User{}, ErrWrongPassword
+	}
+	return user, nil
+}
+
+func (a *Authenticator) lookup(ctx context.Context, username string) (User, error) {
+	ctx, span := a.tracer.Start(ctx, "Lookup User [Otel SDK]")
+	defer span.End()
+
+	start := time.Now()
+	user, err := a.store.Lookup(ctx, username)
+	found := err == nil
+
+	span.SetAttributes(attribute.Bool("user.found", found))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	a.lookupDuration.Record(ctx, float64(time.Since(start).Microseconds())/1000, metric.WithAttributes(
+		attribute.Bool("found", found),
+	))
+
+	return user, err
+}
+
+func (a *Authenticator) verify(ctx context.Context, hash, password string) (bool, error) {
+	ctx, span := a.tracer.Start(ctx, "Verify Password [Otel SDK]")
+	defer span.End()
+
+	algorithm, _ := Algorithm(hash)
+	span.SetAttributes(attribute.String("password.algorithm", algorithm))
+
+	start := time.Now()
+	match, err := VerifyPassword(hash, password)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	a.verifyDuration.Record(ctx, float64(time.Since(start).Microseconds())/1000, metric.WithAttributes(
+		attribute.String("algorithm", algorithm),
+		attribute.Bool("match", match),
+	))
+
+	return match, err
+}
@@ -0,0 +1,244 @@
+package userstore
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// The argon2id parameters of the new hashes, the OWASP recommendation of 19 MiB, 2 iterations and 1 thread.
+const (
+	argon2Memory  = 19 * 1024
+	argon2Time    = 2
+	argon2Threads = 1
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// The bounds of the argon2id parameters of a stored hash. argon2.IDKey panics without iteration or thread,
+// a larger cost would let every login exhaust the memory or the CPU, and an empty key would match any password.
+const (
+	argon2MaxMemory  = 1024 * 1024 // KiB, 1 GiB
+	argon2MaxTime    = 10
+	argon2MinSaltLen = 8
+	argon2MinKeyLen  = 16
+)
+
+// HashPassword hashes the password with bcrypt (default cost) or argon2id, in the format read by VerifyPassword.
+func HashPassword(algorithm, password string) (string, error) {
+	switch algorithm {
+	case AlgorithmBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		return string(hash), err
+
+	case AlgorithmArgon2id:
+		return argon2Params{memory: argon2Memory, iterations: argon2Time, threads: argon2Threads}.hash(password, argon2SaltLen, argon2KeyLen)
+
+	default:
+		return "", fmt.Errorf("unknown password hash algorithm %q, must be %q or %q", algorithm, AlgorithmBcrypt, AlgorithmArgon2id)
+	}
+}
+
+// Algorithm detects the algorithm of the hash, bcrypt ("$2a$", "$2b$", "$2y$") or argon2id (PHC string "$argon2id$").
+func Algorithm(hash string) (string, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return AlgorithmBcrypt, nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return AlgorithmArgon2id, nil
+	default:
+		return "", fmt.Errorf("unsupported password hash, must be bcrypt or argon2id")
+	}
+}
+
+// ValidateHash checks that VerifyPassword can verify the hash: a bcrypt hash, or an argon2id hash with parameters in bounds.
+func ValidateHash(hash string) error {
+	algorithm, err := Algorithm(hash)
+	if err != nil {
+		return err
+	}
+
+	if algorithm == AlgorithmBcrypt {
+		_, err = bcrypt.Cost([]byte(hash))
+		return err
+	}
+
+	_, _, _, err = parseArgon2id(hash)
+	return err
+}
+
+// VerifyPassword compares the password with the hash in constant time.
+func VerifyPassword(hash, password string) (bool, error) {
+	algorithm, err := Algorithm(hash)
+	if err != nil {
+		return false, err
+	}
+
+	if algorithm == AlgorithmBcrypt {
+		err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+type argon2Params struct {
+	memory, iterations uint32
+	threads            uint8
+}
+
+// hash hashes the password with a new salt, as a PHC string.
+func (p argon2Params) hash(password string, saltLen, keyLen int) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.threads, uint32(keyLen))
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.iterations, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// parseArgon2id decodes $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>, and checks the parameters against the bounds.
+func parseArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %q", parts[2])
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters %q", parts[3])
+	}
+
+	switch {
+	case params.iterations < 1 || params.iterations > argon2MaxTime:
+		return params, nil, nil, fmt.Errorf("argon2id iterations t=%d must be between 1 and %d", params.iterations, argon2MaxTime)
+	case params.threads < 1:
+		return params, nil, nil, fmt.Errorf("argon2id parallelism p=%d must be at least 1", params.threads)
+	case params.memory < 8*uint32(params.threads) || params.memory > argon2MaxMemory:
+		return params, nil, nil, fmt.Errorf("argon2id memory m=%d must be between 8*p and %d KiB", params.memory, argon2MaxMemory)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if len(salt) < argon2MinSaltLen {
+		return params, nil, nil, fmt.Errorf("argon2id salt of %d bytes, must be at least %d", len(salt), argon2MinSaltLen)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+	if len(key) < argon2MinKeyLen {
+		return params, nil, nil, fmt.Errorf("argon2id key of %d bytes, must be at least %d", len(key), argon2MinKeyLen)
+	}
+
+	return params, salt, key, nil
+}
+
+// DummyHash hashes the password like the most expensive of the hashes, measured by verifying once every algorithm and parameters.
+// An unknown user is then verified against it, and takes at least as long as the users of the store. Without hashes, it is a bcrypt hash.
+func DummyHash(password string, hashes []string) (string, error) {
+	var dummy string
+	var slowest time.Duration
+	seen := map[string]bool{}
+
+	for _, hash := range hashes {
+		params, err := hashParams(hash)
+		if err != nil {
+			return "", err
+		}
+		if seen[params] {
+			continue
+		}
+		seen[params] = true
+
+		candidate, err := hashLike(hash, password)
+		if err != nil {
+			return "", err
+		}
+
+		start := time.Now()
+		if _, err = VerifyPassword(candidate, password); err != nil {
+			return "", err
+		}
+		if elapsed := time.Since(start); dummy == "" || elapsed > slowest {
+			dummy, slowest = candidate, elapsed
+		}
+	}
+
+	if dummy == "" {
+		return HashPassword(AlgorithmBcrypt, password)
+	}
+	return dummy, nil
+}
+
+// hashParams returns the algorithm and the parameters of the hash, without the salt and the key.
+func hashParams(hash string) (string, error) {
+	algorithm, err := Algorithm(hash)
+	if err != nil {
+		return "", err
+	}
+
+	if algorithm == AlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return fmt.Sprintf("bcrypt cost=%d", cost), err
+	}
+
+	params, salt, key, err := parseArgon2id(hash)
+	return fmt.Sprintf("argon2id m=%d,t=%d,p=%d salt=%d key=%d", params.memory, params.iterations, params.threads, len(salt), len(key)), err
+}
+
+// hashLike hashes the password with the algorithm and the parameters of the hash, and a new salt.
+func hashLike(hash, password string) (string, error) {
+	algorithm, err := Algorithm(hash)
+	if err != nil {
+		return "", err
+	}
+
+	if algorithm == AlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return "", err
+		}
+
+		dummy, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+		return string(dummy), err
+	}
+
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return "", err
+	}
+	return params.hash(password, len(salt), len(key))
+}
@@ -0,0 +1,103 @@
+package userstore
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			hash, err := HashPassword(algorithm, "password1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, _ := Algorithm(hash); got != algorithm {
+				t.Errorf("Algorithm() = %q, want %q", got, algorithm)
+			}
+			if err = ValidateHash(hash); err != nil {
+				t.Errorf("ValidateHash() = %v", err)
+			}
+
+			for password, want := range map[string]bool{"password1": true, "password2": false} {
+				if match, err := VerifyPassword(hash, password); err != nil || match != want {
+					t.Errorf("VerifyPassword(%q) = %v, %v, want %v", password, match, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateHash(t *testing.T) {
+	const salt = "CjJMOiYpBesYRcCDpL9mWA"
+	const key = "fuPIbln2RiVzrWJJlMzAfSpn73PYq7n/3BiX05qeBgg"
+
+	tests := map[string]string{
+		"unknown algorithm":  "$1$abc",
+		"truncated bcrypt":   "$2a$10$.4gtWDzcfc3tdvCxMQNnve",
+		"argon2id parts":     "$argon2id$v=19$m=19456,t=2,p=1$" + salt,
+		"argon2id version":   "$argon2id$v=16$m=19456,t=2,p=1$" + salt + "$" + key,
+		"argon2id params":    "$argon2id$v=19$m=19456$" + salt + "$" + key,
+		"no iteration":       "$argon2id$v=19$m=19456,t=0,p=1$" + salt + "$" + key,
+		"too many iteration": "$argon2id$v=19$m=19456,t=11,p=1$" + salt + "$" + key,
+		"no thread":          "$argon2id$v=19$m=19456,t=2,p=0$" + salt + "$" + key,
+		"memory below 8*p":   "$argon2id$v=19$m=15,t=2,p=2$" + salt + "$" + key,
+		"memory above 1 GiB": "$argon2id$v=19$m=1048577,t=2,p=1$" + salt + "$" + key,
+		"salt not base64":    "$argon2id$v=19$m=19456,t=2,p=1$!!!$" + key,
+		"short salt":         "$argon2id$v=19$m=19456,t=2,p=1$YWJj$" + key,
+		"key not base64":     "$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$!!!",
+		"empty key":          "$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$",
+	}
+
+	for name, hash := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := ValidateHash(hash); err == nil {
+				t.Error("ValidateHash() succeeded")
+			}
+
+			// VerifyPassword returns the same error instead of a panic of argon2.IDKey, or a match of an empty key.
+			if match, err := VerifyPassword(hash, ""); err == nil || match {
+				t.Errorf("VerifyPassword() = %v, %v", match, err)
+			}
+		})
+	}
+}
+
+func TestDummyHash(t *testing.T) {
+	cheap, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expensive, err := HashPassword(AlgorithmArgon2id, "password2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The argon2id hash of 19 MiB takes far longer than a bcrypt hash of the minimum cost.
+	dummy, err := DummyHash("dummy", []string{string(cheap), expensive, string(cheap)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := hashParams(expensive)
+	if got, _ := hashParams(dummy); got != want {
+		t.Errorf("dummy hash %s, want %s", got, want)
+	}
+	if dummy == expensive || !strings.HasPrefix(dummy, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("dummy hash = %q", dummy)
+	}
+	if match, err := VerifyPassword(dummy, "dummy"); err != nil || !match {
+		t.Errorf("VerifyPassword(dummy) = %v, %v", match, err)
+	}
+
+	dummy, err = DummyHash("dummy", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := hashParams(dummy); got != "bcrypt cost=10" {
+		t.Errorf("dummy hash without hashes %s, want the bcrypt default cost", got)
+	}
+}
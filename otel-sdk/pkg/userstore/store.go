@@ -0,0 +1,164 @@
+// Package userstore looks up the users and verifies their bcrypt or argon2id password hashes.
+package userstore
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrNotFound           = errors.New("user not found")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrUnknownUser and ErrWrongPassword are ErrInvalidCredentials, they are only told apart in the telemetry, never in the response.
+	ErrUnknownUser   = fmt.Errorf("%w: unknown user", ErrInvalidCredentials)
+	ErrWrongPassword = fmt.Errorf("%w: wrong password", ErrInvalidCredentials)
+)
+
+// User is one entry of the user file.
+type User struct {
+	Username     string `json:"username" yaml:"username"`
+	PasswordHash string `json:"password_hash" yaml:"password_hash"`
+}
+
+// UserStore finds the users by username.
+type UserStore interface {
+	// Lookup returns ErrNotFound when the user does not exist.
+	Lookup(ctx context.Context, username string) (User, error)
+}
+
+// MemoryStore is a UserStore in memory, it is read only after it is loaded.
+type MemoryStore struct {
+	users map[string]User
+}
+
+var _ UserStore = (*MemoryStore)(nil)
+
+//go:embed users.yaml
+var defaultUsers []byte
+
+// Default returns the demo users: user1, user2 and user3 with the passwords password1, password2 and password3.
+func Default() *MemoryStore {
+	store, err := Parse(defaultUsers, "yaml")
+	if err != nil {
+		panic(fmt.Errorf("invalid embedded users.yaml: %w", err))
+	}
+	return store
+}
+
+// LoadFile reads a JSON (".json") or YAML (".yaml", ".yml") file: a list of users with their username and password_hash.
+func LoadFile(path string) (*MemoryStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := strings.TrimPrefix(filepath.Ext(path), ".")
+	if format == "yml" {
+		format = "yaml"
+	}
+
+	store, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Parse decodes the users in the "json" or "yaml" format, every password hash must be bcrypt or argon2id, see ValidateHash.
+// The usernames must be unique.
+func Parse(data []byte, format string) (*MemoryStore, error) {
+	var users []User
+
+	var err error
+	switch format {
+	case "json":
+		err = json.Unmarshal(data, &users)
+	case "yaml":
+		err = yaml.Unmarshal(data, &users)
+	default:
+		return nil, fmt.Errorf("unsupported user file format %q, must be json or yaml", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode users: %w", err)
+	}
+
+	store := &MemoryStore{users: make(map[string]User, len(users))}
+	for _, user := range users {
+		if user.Username == "" {
+			return nil, fmt.Errorf("user without username")
+		}
+		if _, ok := store.users[user.Username]; ok {
+			return nil, fmt.Errorf("duplicate user %s", user.Username)
+		}
+		if err = ValidateHash(user.PasswordHash); err != nil {
+			return nil, fmt.Errorf("user %s: %w", user.Username, err)
+		}
+		store.users[user.Username] = user
+	}
+
+	return store, nil
+}
+
+func (s *MemoryStore) Lookup(_ context.Context, username string) (User, error) {
+	user, ok := s.users[username]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+// PasswordHashes returns the password hashes of all the users, see DummyHash.
+func (s *MemoryStore) PasswordHashes() []string {
+	hashes := make([]string, 0, len(s.users))
+	for _, user := range s.users {
+		hashes = append(hashes, user.PasswordHash)
+	}
+	return hashes
+}
+
+// LatencyStore delays every lookup by a random duration between Min and Max, like a remote database would.
+type LatencyStore struct {
+	Store    UserStore
+	Min, Max time.Duration
+}
+
+var _ UserStore = (*LatencyStore)(nil)
+
+func (s *LatencyStore) Lookup(ctx context.Context, username string) (User, error) {
+	delay := s.Min
+	if s.Max > s.Min {
+		delay += rand.N(s.Max - s.Min)
+	}
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return User{}, ctx.Err()
+	}
+
+	return s.Store.Lookup(ctx, username)
+}
+
+// PasswordHashes returns the password hashes of Store, if it knows them.
+func (s *LatencyStore) PasswordHashes() []string {
+	return passwordHashes(s.Store)
+}
+
+// passwordHashes returns the password hashes of the store when it has a PasswordHashes method, like MemoryStore.
+func passwordHashes(store UserStore) []string {
+	if store, ok := store.(interface{ PasswordHashes() []string }); ok {
+		return store.PasswordHashes()
+	}
+	return nil
+}
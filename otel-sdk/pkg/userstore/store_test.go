@@ -0,0 +1,78 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	store := Default()
+
+	for _, username := range []string{"user1", "user2", "user3"} {
+		if _, err := store.Lookup(context.Background(), username); err != nil {
+			t.Errorf("Lookup(%q) = %v", username, err)
+		}
+	}
+	if _, err := store.Lookup(context.Background(), "nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup(nobody) = %v, want ErrNotFound", err)
+	}
+
+	latency := &LatencyStore{Store: store}
+	if got := len(latency.PasswordHashes()); got != 3 {
+		t.Errorf("LatencyStore.PasswordHashes() has %d hashes, want 3", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	const bcryptHash = "$2a$10$.4gtWDzcfc3tdvCxMQNnveyMU3SEXGiDTn2CpV2l3.AJaC.nQxoSy"
+
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		wantErr string
+	}{
+		{
+			name:   "json",
+			format: "json",
+			data:   `[{"username":"user1","password_hash":"` + bcryptHash + `"}]`,
+		},
+		{
+			name:    "unknown format",
+			format:  "toml",
+			wantErr: "unsupported user file format",
+		},
+		{
+			name:    "without username",
+			format:  "json",
+			data:    `[{"password_hash":"` + bcryptHash + `"}]`,
+			wantErr: "user without username",
+		},
+		{
+			name:    "duplicate username",
+			format:  "json",
+			data:    `[{"username":"user1","password_hash":"` + bcryptHash + `"},{"username":"user1","password_hash":"` + bcryptHash + `"}]`,
+			wantErr: "duplicate user user1",
+		},
+		{
+			name:    "argon2id without thread",
+			format:  "yaml",
+			data:    "- username: user2\n  password_hash: $argon2id$v=19$m=19456,t=2,p=0$CjJMOiYpBesYRcCDpL9mWA$fuPIbln2RiVzrWJJlMzAfSpn73PYq7n/3BiX05qeBgg\n",
+			wantErr: "user user2: argon2id parallelism",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), tt.format)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Parse() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Parse() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
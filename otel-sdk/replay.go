@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/otlpauth"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/otlpclient"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/otlpfile"
+)
+
+// replayCommand pushes the OTLP JSON lines files written by the file exporter to an OTLP HTTP endpoint.
+//
+//	app.bin replay -endpoint localhost:4318 -rate 5 -rebase-time /var/log/otel-sdk/traces-*.jsonl.gz
+func replayCommand(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+
+	var (
+		endpoint    = flags.String("endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTLP HTTP endpoint without scheme, for example localhost:4318")
+		insecure    = flags.Bool("insecure", true, "use http:// instead of https://")
+		caFile      = flags.String("ca-file", os.Getenv("OTLP_CA_FILE"), "PEM bundle to verify the endpoint certificate")
+		certFile    = flags.String("cert-file", os.Getenv("OTLP_CLIENT_CERT_FILE"), "PEM client certificate for mTLS")
+		keyFile     = flags.String("key-file", os.Getenv("OTLP_CLIENT_KEY_FILE"), "PEM client key for mTLS")
+		serverName  = flags.String("server-name", os.Getenv("OTLP_TLS_SERVER_NAME"), "server name to verify the endpoint certificate")
+		skipVerify  = flags.Bool("insecure-skip-verify", false, "do not verify the endpoint certificate, only for testing")
+		headers     = flags.String("headers", os.Getenv("OTLP_HEADERS"), "headers sent with every request, for example key1=value1,key2=value2")
+		tokenFile   = flags.String("bearer-token-file", os.Getenv("OTLP_BEARER_TOKEN_FILE"), "file containing the bearer token")
+		tracesPath  = flags.String("traces-path", "/v1/traces", "path of the traces endpoint, empty to skip traces")
+		metricsPath = flags.String("metrics-path", "/v1/metrics", "path of the metrics endpoint, empty to skip metrics")
+		logsPath    = flags.String("logs-path", "/v1/logs", "path of the logs endpoint, empty to skip logs")
+		rate        = flags.Float64("rate", 10, "maximum requests per second, 0 is unlimited")
+		rebaseTime  = flags.Bool("rebase-time", false, "shift the timestamps so the first request starts now, otherwise keep the original timestamps")
+	)
+
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s replay [flags] FILE...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *endpoint == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	auth := otlpauth.Config{
+		Insecure:           *insecure,
+		CAFile:             *caFile,
+		CertFile:           *certFile,
+		KeyFile:            *keyFile,
+		ServerName:         *serverName,
+		InsecureSkipVerify: *skipVerify,
+		BearerTokenFile:    *tokenFile,
+	}
+
+	var err error
+	if auth.Headers, err = otlpauth.ParseHeaders(*headers); err != nil {
+		slog.Error("invalid headers", slog.Any("error", err))
+		return 2
+	}
+
+	tlsCfg, err := auth.TLSConfig()
+	if err != nil {
+		slog.Error("invalid tls configuration", slog.Any("error", err))
+		return 2
+	}
+
+	senders := map[string]otlpfile.Sender{}
+	for signal, path := range map[string]string{
+		otlpfile.SignalTraces:  *tracesPath,
+		otlpfile.SignalMetrics: *metricsPath,
+		otlpfile.SignalLogs:    *logsPath,
+	} {
+		if path == "" {
+			continue
+		}
+
+		senders[signal] = otlpclient.New(otlpclient.Config{
+			Endpoint:  *endpoint,
+			URLPath:   path,
+			Insecure:  auth.Insecure,
+			Gzip:      true,
+			TLSConfig: tlsCfg,
+			Headers:   auth.HeaderFunc(),
+		})
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	replayer := &otlpfile.Replayer{
+		Senders:    senders,
+		Rate:       *rate,
+		RebaseTime: *rebaseTime,
+	}
+
+	summary, err := replayer.Replay(ctx, flags.Args()...)
+	slog.InfoContext(ctx, "replay done",
+		slog.Int("traces", summary.Sent[otlpfile.SignalTraces]),
+		slog.Int("metrics", summary.Sent[otlpfile.SignalMetrics]),
+		slog.Int("logs", summary.Sent[otlpfile.SignalLogs]),
+		slog.Int("skipped", summary.Skipped),
+		slog.Int("failed", summary.Failed),
+	)
+
+	if err != nil {
+		slog.ErrorContext(ctx, "replay failed", slog.Any("error", err))
+		return 1
+	}
+
+	if summary.Failed > 0 {
+		return 1
+	}
+
+	return 0
+}
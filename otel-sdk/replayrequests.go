@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/reqrecord"
+)
+
+// replayRequestsCommand replays the requests recorded with REQUEST_RECORD_FILE against a target, and prints a latency and status summary.
+//
+//	app.bin replay-requests -target http://localhost:8082 -speed 2 -concurrency 20 -credentials users.json requests.jsonl
+func replayRequestsCommand(args []string) int {
+	flags := flag.NewFlagSet("replay-requests", flag.ContinueOnError)
+
+	var (
+		target      = flags.String("target", "", "base URL of the target, for example http://localhost:8082")
+		speed       = flags.Float64("speed", 1, "multiplier of the original pacing, 0 sends as fast as the concurrency allows")
+		concurrency = flags.Int("concurrency", 10, "maximum requests in flight")
+		credentials = flags.String("credentials", "", `JSON file {"username": "password"} resolving the recorded password references`)
+		timeout     = flags.Duration("timeout", 30*time.Second, "timeout of one request")
+	)
+
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s replay-requests [flags] FILE...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *target == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	var users map[string]string
+	if *credentials != "" {
+		data, err := os.ReadFile(*credentials)
+		if err == nil {
+			err = json.Unmarshal(data, &users)
+		}
+
+		if err != nil {
+			slog.Error("invalid credentials file", slog.Any("error", err))
+			return 2
+		}
+	}
+
+	var records []reqrecord.Record
+	for _, path := range flags.Args() {
+		fileRecords, err := reqrecord.ReadFile(path)
+		if err != nil {
+			slog.Error("failed to read request records", slog.String("file", path), slog.Any("error", err))
+			return 2
+		}
+		records = append(records, fileRecords...)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	replayer := &reqrecord.Replayer{
+		Target:      *target,
+		Speed:       *speed,
+		Concurrency: *concurrency,
+		Credentials: users,
+		Client:      &http.Client{Timeout: *timeout},
+	}
+
+	summary := replayer.Replay(ctx, records)
+	summary.Write(os.Stdout)
+
+	if summary.Errors() > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/lockout"
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/ratelimit"
+)
+
+const (
+	// SecurityEventAccountLocked is emitted when a username reaches the failures to be locked.
+	SecurityEventAccountLocked = "security.account_locked"
+
+	// SecurityEventClientBlocked is emitted when a client IP reaches the failures to be blocked, a brute-force or a credential stuffing.
+	SecurityEventClientBlocked = "security.client_blocked"
+
+	// SecurityEventLockedLoginRejected is emitted for every login rejected while the username or the client IP is locked.
+	SecurityEventLockedLoginRejected = "security.locked_login_rejected"
+)
+
+// securityEvent adds the event to the span, and logs it as a warning so it is an OpenTelemetry log record correlated with the trace.
+func securityEvent(ctx context.Context, span trace.Span, name string, attrs ...attribute.KeyValue) {
+	span.AddEvent(name, trace.WithAttributes(attrs...))
+
+	logAttrs := make([]any, 0, len(attrs)+1)
+	logAttrs = append(logAttrs, slog.String("event.name", name))
+	for _, attr := range attrs {
+		logAttrs = append(logAttrs, slog.Any(string(attr.Key), attr.Value.AsInterface()))
+	}
+	slog.WarnContext(ctx, "security event", logAttrs...)
+}
+
+// lockoutAttributes describes the lock of the decision, with the username as enduser.id according to Handler.EnduserID.
+func (h *Handler) lockoutAttributes(username, clientIP string, decision lockout.Decision) []attribute.KeyValue {
+	attrs := append(h.enduserID(username),
+		semconv.ClientAddress(clientIP),
+		attribute.String("lockout.scope", decision.Scope),
+		attribute.Int64("lockout.retry_after_s", retryAfterSeconds(decision.RetryAfter)),
+	)
+	if decision.Failures > 0 {
+		attrs = append(attrs, attribute.Int("lockout.failures", decision.Failures))
+	}
+	return attrs
+}
+
+// writeLocked responds 423 Locked for a locked username, 429 Too Many Requests for a blocked client IP, with Retry-After.
+func writeLocked(w http.ResponseWriter, decision lockout.Decision) {
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfterSeconds(decision.RetryAfter), 10))
+
+	if decision.Scope == lockout.ScopeClientIP {
+		http.Error(w, "Too many failed logins from this client, retry later (from otel-sdk example).", http.StatusTooManyRequests)
+		return
+	}
+	http.Error(w, "The account is locked, retry later (from otel-sdk example).", http.StatusLocked)
+}
+
+// retryAfterSeconds rounds up, so the client does not retry before the end of the lock.
+func retryAfterSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// clientIP is the client address resolved by the rate limiter middleware, with its trusted proxies.
+// Without the middleware, it is the host of the remote address, the X-Forwarded-For header is not trusted.
+func clientIP(r *http.Request) string {
+	if client, ok := ratelimit.ClientFromContext(r.Context()); ok {
+		return client
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/otel-sdk/pkg/userstore"
+)
+
+// hashPasswordCommand reads the password from stdin and prints its hash, to be pasted as password_hash in USER_STORE_FILE.
+//
+//	echo -n password1 | app.bin hash-password -algorithm argon2id
+func hashPasswordCommand(args []string) int {
+	flags := flag.NewFlagSet("hash-password", flag.ContinueOnError)
+
+	algorithm := flags.String("algorithm", userstore.AlgorithmBcrypt, "bcrypt or argon2id")
+
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s hash-password [flags] < password\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		slog.Error("empty password on stdin", slog.Any("error", err))
+		return 2
+	}
+
+	hash, err := userstore.HashPassword(*algorithm, password)
+	if err != nil {
+		slog.Error("failed to hash the password", slog.Any("error", err))
+		return 2
+	}
+
+	fmt.Println(hash)
+	return 0
+}
+
+// parseLatencyRange parses "5ms-50ms" as a range, or "20ms" as a fixed latency.
+func parseLatencyRange(value string) (time.Duration, time.Duration, error) {
+	minValue, maxValue, isRange := strings.Cut(value, "-")
+
+	minLatency, err := time.ParseDuration(strings.TrimSpace(minValue))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if !isRange {
+		return minLatency, minLatency, nil
+	}
+
+	maxLatency, err := time.ParseDuration(strings.TrimSpace(maxValue))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if minLatency < 0 || maxLatency < minLatency {
+		return 0, 0, fmt.Errorf("invalid latency range %q, the minimum must be positive and not above the maximum", value)
+	}
+
+	return minLatency, maxLatency, nil
+}
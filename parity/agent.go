@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// ddSpan is the subset of a span of the trace-agent v0.4 payload used by the comparison.
+type ddSpan struct {
+	Name     string            `json:"name"`
+	Resource string            `json:"resource"`
+	TraceID  uint64            `json:"trace_id"`
+	SpanID   uint64            `json:"span_id"`
+	ParentID uint64            `json:"parent_id"`
+	Error    int32             `json:"error"`
+	Meta     map[string]string `json:"meta"`
+}
+
+// FakeAgent is a Datadog Agent listening on the trace-agent (8126) and DogStatsD (8125) ports,
+// keeping everything it receives in memory.
+type FakeAgent struct {
+	traceServer *http.Server
+	statsdConn  net.PacketConn
+
+	mu     sync.Mutex
+	spans  []ddSpan
+	statsd []string
+}
+
+// StartFakeAgent listens on host:8126 and host:8125, because dd-sdk always uses these ports.
+func StartFakeAgent(host string) (*FakeAgent, error) {
+	a := &FakeAgent{}
+
+	statsdConn, err := net.ListenPacket("udp", net.JoinHostPort(host, "8125"))
+	if err != nil {
+		return nil, fmt.Errorf("listen dogstatsd: %w", err)
+	}
+	a.statsdConn = statsdConn
+
+	traceListener, err := net.Listen("tcp", net.JoinHostPort(host, "8126"))
+	if err != nil {
+		_ = statsdConn.Close()
+		return nil, fmt.Errorf("listen trace-agent: %w", err)
+	}
+
+	a.traceServer = &http.Server{Handler: http.HandlerFunc(a.serveTraceAgent)}
+	go func() {
+		_ = a.traceServer.Serve(traceListener)
+	}()
+
+	go a.readStatsd()
+
+	return a, nil
+}
+
+// serveTraceAgent accepts the v0.4 traces. /info is not found, so the tracer falls back to the v0.4 protocol,
+// and everything else (telemetry, stats) is accepted and dropped.
+func (a *FakeAgent) serveTraceAgent(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/info":
+		http.NotFound(w, r)
+		return
+
+	case strings.HasPrefix(r.URL.Path, "/v0.4/traces"):
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var payload bytes.Buffer
+		if _, err = msgp.CopyToJSON(&payload, bytes.NewReader(body)); err != nil {
+			slog.WarnContext(r.Context(), "failed to decode dd traces", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var traces [][]ddSpan
+		if err = json.Unmarshal(payload.Bytes(), &traces); err != nil {
+			slog.WarnContext(r.Context(), "failed to decode dd traces", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		a.mu.Lock()
+		for _, trace := range traces {
+			a.spans = append(a.spans, trace...)
+		}
+		a.mu.Unlock()
+	}
+
+	_, _ = w.Write([]byte("{}"))
+}
+
+func (a *FakeAgent) readStatsd() {
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := a.statsdConn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		a.mu.Lock()
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			if line != "" {
+				a.statsd = append(a.statsd, line)
+			}
+		}
+		a.mu.Unlock()
+	}
+}
+
+func (a *FakeAgent) Close() error {
+	_ = a.traceServer.Shutdown(context.Background())
+	return a.statsdConn.Close()
+}
+
+// Result normalises the DogStatsD lines and the spans into the common model.
+func (a *FakeAgent) Result(namespace string, script Script) *Result {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	res := newResult()
+	for _, line := range a.statsd {
+		m, err := parseStatsd(line, namespace, script)
+		if err != nil {
+			slog.Warn("skip invalid dogstatsd line", slog.String("line", line), slog.Any("error", err))
+			continue
+		}
+
+		if !script.ignore(m) {
+			res.addMetric(m)
+		}
+	}
+
+	// The server span of the chi middleware is named "http.request", its resource is the route, like the otelhttp span name.
+	names := make(map[uint64]string, len(a.spans))
+	for _, s := range a.spans {
+		name := s.Name
+		if s.Meta["span.kind"] == "server" {
+			name = s.Resource
+		}
+		names[s.SpanID] = normaliseSpanName(name)
+	}
+
+	for _, s := range a.spans {
+		span := Span{Name: names[s.SpanID], Parent: names[s.ParentID], Error: s.Error != 0}
+		res.Spans[span.Key()]++
+	}
+
+	return res
+}
+
+// parseStatsd parses "namespace.name:value|type|@rate|#tag:value,tag2:value", the sample rate is ignored.
+func parseStatsd(line, namespace string, script Script) (Metric, error) {
+	nameValue, rest, _ := strings.Cut(line, "|")
+	name, value, ok := strings.Cut(nameValue, ":")
+	if !ok {
+		return Metric{}, fmt.Errorf("no value")
+	}
+
+	fields := strings.Split(rest, "|")
+	m := Metric{Name: normaliseMetricName(name, namespace), Tags: map[string]string{}}
+	switch fields[0] {
+	case "c":
+		m.Kind = KindCount
+	case "d", "h", "ms":
+		m.Kind = KindDistribution
+	case "g":
+		m.Kind = KindGauge
+	default:
+		return Metric{}, fmt.Errorf("unsupported type %q", fields[0])
+	}
+
+	// The client packs several samples of a distribution as "name:1:2:3".
+	for _, s := range strings.Split(value, ":") {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Metric{}, fmt.Errorf("parse value: %w", err)
+		}
+
+		// A distribution is compared by its number of samples.
+		switch m.Kind {
+		case KindDistribution:
+			m.Value++
+		case KindGauge:
+			m.Value = v
+		default:
+			m.Value += v
+		}
+	}
+
+	for _, field := range fields[1:] {
+		if !strings.HasPrefix(field, "#") {
+			continue
+		}
+		for _, tag := range strings.Split(field[1:], ",") {
+			k, v, _ := strings.Cut(tag, ":")
+			m.Tags[k] = v
+		}
+	}
+	m.Tags = normaliseTags(m.Tags, script)
+
+	return m, nil
+}
@@ -0,0 +1,257 @@
+// Command parity proves that moving a service from the dd-sdk style to the otel-sdk style loses nothing.
+//
+// It starts a fake Datadog Agent and an in-memory OTLP receiver in this process, runs dd-sdk and otel-sdk against them,
+// sends the same request script to both, normalises the DogStatsD metrics, the Datadog spans, the OTLP metrics
+// and the OTLP spans into one model and prints the differences. The exit code is 1 when they differ.
+//
+// The applications are built from their directories and run as child processes: both are package main,
+// and both own process wide state (the global Datadog tracer and the global OpenTelemetry providers),
+// so they cannot share one process without changing what they emit.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	fs := flag.NewFlagSet("parity", flag.ContinueOnError)
+	scriptPath := fs.String("script", "", "request script JSON file, by default the embedded script.json")
+	ddSdkDir := fs.String("dd-sdk-dir", "../dd-sdk", "directory of the dd-sdk application")
+	otelSdkDir := fs.String("otel-sdk-dir", "../otel-sdk", "directory of the otel-sdk application")
+	agentHost := fs.String("dd-agent-host", "127.0.0.1", "host of the fake Datadog Agent, ports 8125 and 8126 must be free")
+	ddNamespace := fs.String("dd-namespace", "poc_dd_sdk_statsd", "DogStatsD namespace of dd-sdk")
+	otelPrefix := fs.String("otel-prefix", "poc_otel_sdk", "metric name prefix of otel-sdk")
+	flushWait := fs.Duration("flush-wait", 12*time.Second, "time to wait for the applications to flush after the last request, longer than the 10s interval of the dd-sdk gauges")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	ddResult, otelResult, err := capture(ctx, captureConfig{
+		ScriptPath:  *scriptPath,
+		DDSdkDir:    *ddSdkDir,
+		OtelSdkDir:  *otelSdkDir,
+		AgentHost:   *agentHost,
+		DDNamespace: *ddNamespace,
+		OtelPrefix:  *otelPrefix,
+		FlushWait:   *flushWait,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "parity check failed", slog.Any("error", err))
+		return 2
+	}
+
+	metrics, spans := compare(ddResult, otelResult)
+	writeReport(os.Stdout, ddResult, otelResult, metrics, spans)
+
+	if len(metrics)+len(spans) > 0 {
+		return 1
+	}
+	return 0
+}
+
+type captureConfig struct {
+	ScriptPath  string
+	DDSdkDir    string
+	OtelSdkDir  string
+	AgentHost   string
+	DDNamespace string
+	OtelPrefix  string
+	FlushWait   time.Duration
+}
+
+// capture runs both applications with the script, and returns what each one emitted.
+func capture(ctx context.Context, cfg captureConfig) (*Result, *Result, error) {
+	script, err := loadScript(cfg.ScriptPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	agent, err := StartFakeAgent(cfg.AgentHost)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = agent.Close()
+	}()
+
+	receiver, err := StartReceiver(cfg.OtelPrefix, script)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = receiver.Close()
+	}()
+
+	workDir, err := os.MkdirTemp("", "parity-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(workDir)
+	}()
+
+	ddSdk := &app{Name: "dd-sdk", Dir: cfg.DDSdkDir, Env: []string{
+		"DATADOG_AGENT_HOST=" + cfg.AgentHost,
+		"DD_INSTRUMENTATION_TELEMETRY_ENABLED=false",
+	}}
+
+	otelSdk := &app{Name: "otel-sdk", Dir: cfg.OtelSdkDir, Env: []string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT=" + receiver.Addr(),
+		"OTLP_TRACE_HTTP_ENABLED=true",
+		"OTLP_METRIC_HTTP_ENABLED=true",
+		"OTEL_BSP_SCHEDULE_DELAY=500",
+	}}
+
+	for _, a := range []*app{ddSdk, otelSdk} {
+		if err = a.Start(ctx, workDir); err != nil {
+			return nil, nil, err
+		}
+		defer a.Stop()
+	}
+
+	for _, a := range []*app{ddSdk, otelSdk} {
+		if err = a.WaitReady(ctx, time.Minute); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, a := range []*app{ddSdk, otelSdk} {
+		if err = a.Drive(ctx, script); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	slog.InfoContext(ctx, "waiting for the applications to flush", slog.Duration("wait", cfg.FlushWait))
+	select {
+	case <-time.After(cfg.FlushWait):
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	return agent.Result(cfg.DDNamespace, script), receiver.Result(), nil
+}
+
+// app is one application running as a child process.
+type app struct {
+	Name string
+	Dir  string
+	Env  []string
+
+	addr    string
+	cmd     *exec.Cmd
+	logPath string
+}
+
+// Start builds the application and runs it on a free local port, its output goes to a log file in workDir.
+func (a *app) Start(ctx context.Context, workDir string) error {
+	bin := filepath.Join(workDir, a.Name)
+	build := exec.CommandContext(ctx, "go", "build", "-o", bin, ".")
+	build.Dir = a.Dir
+	if out, err := build.CombinedOutput(); err != nil {
+		return fmt.Errorf("build %s: %w\n%s", a.Name, err, out)
+	}
+
+	addr, err := freeAddr()
+	if err != nil {
+		return err
+	}
+	a.addr = addr
+
+	a.logPath = filepath.Join(workDir, a.Name+".log")
+	logFile, err := os.Create(a.logPath)
+	if err != nil {
+		return err
+	}
+
+	a.cmd = exec.CommandContext(ctx, bin)
+	a.cmd.Env = append(append(os.Environ(), "PORT="+addr), a.Env...)
+	a.cmd.Stdout = logFile
+	a.cmd.Stderr = logFile
+	if err = a.cmd.Start(); err != nil {
+		_ = logFile.Close()
+		return fmt.Errorf("start %s: %w", a.Name, err)
+	}
+
+	go func() {
+		_ = a.cmd.Wait()
+		_ = logFile.Close()
+	}()
+
+	slog.InfoContext(ctx, "started", slog.String("app", a.Name), slog.String("addr", addr), slog.String("log", a.logPath))
+	return nil
+}
+
+// Stop kills the process, neither application flushes on a signal, so the flush wait happens before.
+func (a *app) Stop() {
+	if a.cmd != nil && a.cmd.Process != nil {
+		_ = a.cmd.Process.Kill()
+	}
+}
+
+func (a *app) WaitReady(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get("http://" + a.addr + "/healthz")
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return fmt.Errorf("%s is not ready after %s, see %s", a.Name, timeout, a.logPath)
+}
+
+// Drive sends the requests of the script in order, the response status is not checked, only the telemetry is.
+func (a *app) Drive(ctx context.Context, script Script) error {
+	for _, r := range script.Requests {
+		for range max(r.Repeat, 1) {
+			req, err := http.NewRequestWithContext(ctx, r.Method, "http://"+a.addr+r.Path, strings.NewReader(r.Body))
+			if err != nil {
+				return fmt.Errorf("script request %s %s: %w", r.Method, r.Path, err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return fmt.Errorf("%s %s %s: %w", a.Name, r.Method, r.Path, err)
+			}
+			_ = resp.Body.Close()
+		}
+	}
+
+	return nil
+}
+
+// freeAddr returns a local address with a port which was free a moment ago.
+func freeAddr() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	addr := l.Addr().String()
+	return addr, l.Close()
+}
@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+// TestRunDefaultScript builds and runs both applications, they must emit the same telemetry for the default script.
+func TestRunDefaultScript(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs dd-sdk and otel-sdk")
+	}
+
+	if code := run(nil); code != 0 {
+		t.Errorf("run() = %d, want 0, see the report above", code)
+	}
+}
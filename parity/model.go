@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	KindCount        = "count"
+	KindDistribution = "distribution"
+	KindGauge        = "gauge"
+)
+
+// Metric is one series in the common model. Value is the total of a count, the number of samples of a distribution,
+// and is not compared for a gauge.
+type Metric struct {
+	Name  string
+	Kind  string
+	Tags  map[string]string
+	Value float64
+}
+
+func (m Metric) Key() string {
+	keys := slices.Sorted(maps.Keys(m.Tags))
+	tags := make([]string, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, k+"="+m.Tags[k])
+	}
+	return fmt.Sprintf("%s %s{%s}", m.Kind, m.Name, strings.Join(tags, ","))
+}
+
+// Span is one span in the common model, the spans are compared by name, parent name and error.
+type Span struct {
+	Name   string
+	Parent string
+	Error  bool
+}
+
+func (s Span) Key() string {
+	return fmt.Sprintf("%s <- %q error=%t", s.Name, s.Parent, s.Error)
+}
+
+// Result is what was captured from one application.
+type Result struct {
+	Metrics map[string]Metric
+	Spans   map[string]int
+}
+
+func newResult() *Result {
+	return &Result{Metrics: map[string]Metric{}, Spans: map[string]int{}}
+}
+
+// resourceTags are the same on every series, they identify the application and are not compared.
+var resourceTags = map[string]bool{
+	"service": true, "version": true, "env": true, "team": true,
+}
+
+// tagNames maps the tag keys of both applications to one name, for example otel-sdk "http.route" and dd-sdk "route".
+var tagNames = map[string]string{
+	"http.method":               "method",
+	"http.request.method":       "method",
+	"http.route":                "route",
+	"http.status_code":          "status_code",
+	"http.response.status_code": "status_code",
+}
+
+// normaliseMetricName removes the namespace (dd-sdk) or the service name prefix (otel-sdk),
+// and replaces the dots with underscores: "poc_otel_sdk.login.success" becomes "login_success".
+func normaliseMetricName(name, prefix string) string {
+	name = strings.TrimPrefix(name, prefix+".")
+	return strings.ReplaceAll(name, ".", "_")
+}
+
+func normaliseTags(tags map[string]string, script Script) map[string]string {
+	out := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if resourceTags[k] {
+			continue
+		}
+		if name, ok := tagNames[k]; ok {
+			k = name
+		}
+		if slices.Contains(script.IgnoreTags, k) {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// addMetric sums the counts and the distributions with the same key, the last gauge wins.
+func (r *Result) addMetric(m Metric) {
+	prev, ok := r.Metrics[m.Key()]
+	if ok && m.Kind != KindGauge {
+		m.Value += prev.Value
+	}
+	r.Metrics[m.Key()] = m
+}
+
+var spanSuffix = regexp.MustCompile(`\s*\[[^\]]*\]$`)
+
+// normaliseSpanName removes the "[DD-SDK]" or "[Otel SDK]" suffix and lower cases the name.
+func normaliseSpanName(name string) string {
+	return strings.ToLower(spanSuffix.ReplaceAllString(name, ""))
+}
+
+// Diff is one difference between the applications.
+type Diff struct {
+	Key   string
+	DD    string
+	Otel  string
+	Issue string
+}
+
+// compare returns the differences, sorted by key.
+func compare(dd, otel *Result) (metrics, spans []Diff) {
+	for _, key := range unionKeys(dd.Metrics, otel.Metrics) {
+		d, inDD := dd.Metrics[key]
+		o, inOtel := otel.Metrics[key]
+		switch {
+		case !inOtel:
+			metrics = append(metrics, Diff{Key: key, DD: formatValue(d), Otel: "-", Issue: "missing in otel-sdk"})
+		case !inDD:
+			metrics = append(metrics, Diff{Key: key, DD: "-", Otel: formatValue(o), Issue: "missing in dd-sdk"})
+		case d.Kind != KindGauge && d.Value != o.Value:
+			metrics = append(metrics, Diff{Key: key, DD: formatValue(d), Otel: formatValue(o), Issue: "value differs"})
+		}
+	}
+
+	for _, key := range unionKeys(dd.Spans, otel.Spans) {
+		d, o := dd.Spans[key], otel.Spans[key]
+		switch {
+		case o == 0:
+			spans = append(spans, Diff{Key: key, DD: fmt.Sprint(d), Otel: "-", Issue: "missing in otel-sdk"})
+		case d == 0:
+			spans = append(spans, Diff{Key: key, DD: "-", Otel: fmt.Sprint(o), Issue: "missing in dd-sdk"})
+		case d != o:
+			spans = append(spans, Diff{Key: key, DD: fmt.Sprint(d), Otel: fmt.Sprint(o), Issue: "count differs"})
+		}
+	}
+
+	return metrics, spans
+}
+
+func formatValue(m Metric) string {
+	if m.Kind == KindGauge {
+		return "present"
+	}
+	return fmt.Sprintf("%g", m.Value)
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := slices.Collect(maps.Keys(a))
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeReport writes the differences as a table, followed by a one line verdict.
+func writeReport(w io.Writer, dd, otel *Result, metrics, spans []Diff) {
+	section := func(title string, total int, diffs []Diff) {
+		_, _ = fmt.Fprintf(w, "%s: %d compared, %d differ\n", title, total, len(diffs))
+		for _, d := range diffs {
+			_, _ = fmt.Fprintf(w, "  %-20s %s\n  %-20s dd-sdk=%s otel-sdk=%s\n", d.Issue, d.Key, "", d.DD, d.Otel)
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+
+	section("metrics", len(unionKeys(dd.Metrics, otel.Metrics)), metrics)
+	section("spans", len(unionKeys(dd.Spans, otel.Spans)), spans)
+
+	if len(metrics)+len(spans) == 0 {
+		_, _ = fmt.Fprintln(w, "PASS: dd-sdk and otel-sdk emit the same telemetry")
+		return
+	}
+	_, _ = fmt.Fprintf(w, "FAIL: %d differences\n", len(metrics)+len(spans))
+}
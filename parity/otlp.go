@@ -0,0 +1,232 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	collectormetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Receiver is an OTLP HTTP receiver keeping the spans and the last data point of every metric series in memory.
+type Receiver struct {
+	server *http.Server
+	addr   string
+
+	mu      sync.Mutex
+	spans   []*tracepb.Span
+	metrics map[string]Metric
+	script  Script
+	prefix  string
+}
+
+// StartReceiver listens on a random local port, the metric names are normalised with the service name prefix.
+func StartReceiver(prefix string, script Script) (*Receiver, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen otlp: %w", err)
+	}
+
+	r := &Receiver{
+		addr:    listener.Addr().String(),
+		metrics: map[string]Metric{},
+		script:  script,
+		prefix:  prefix,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/traces", r.serveTraces)
+	mux.HandleFunc("POST /v1/metrics", r.serveMetrics)
+	mux.HandleFunc("POST /v1/logs", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	})
+
+	r.server = &http.Server{Handler: mux}
+	go func() {
+		_ = r.server.Serve(listener)
+	}()
+
+	return r, nil
+}
+
+// Addr is the endpoint without scheme, for example "127.0.0.1:4318".
+func (r *Receiver) Addr() string {
+	return r.addr
+}
+
+func (r *Receiver) Close() error {
+	return r.server.Shutdown(context.Background())
+}
+
+func (r *Receiver) serveTraces(w http.ResponseWriter, req *http.Request) {
+	var msg collectortracepb.ExportTraceServiceRequest
+	if err := readProto(req, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	for _, rs := range msg.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			r.spans = append(r.spans, ss.GetSpans()...)
+		}
+	}
+	r.mu.Unlock()
+
+	writeProto(w, &collectortracepb.ExportTraceServiceResponse{})
+}
+
+// serveMetrics keeps the last data point of each series, the temporality of otel-sdk is cumulative.
+func (r *Receiver) serveMetrics(w http.ResponseWriter, req *http.Request) {
+	var msg collectormetricpb.ExportMetricsServiceRequest
+	if err := readProto(req, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	for _, rm := range msg.GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				for _, metric := range r.convert(m) {
+					if !r.script.ignore(metric) {
+						r.metrics[metric.Key()] = metric
+					}
+				}
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	writeProto(w, &collectormetricpb.ExportMetricsServiceResponse{})
+}
+
+func (r *Receiver) convert(m *metricpb.Metric) []Metric {
+	name := normaliseMetricName(m.GetName(), r.prefix)
+
+	var out []Metric
+	number := func(kind string, points []*metricpb.NumberDataPoint) {
+		for _, p := range points {
+			v := p.GetAsDouble()
+			if _, ok := p.GetValue().(*metricpb.NumberDataPoint_AsInt); ok {
+				v = float64(p.GetAsInt())
+			}
+			out = append(out, Metric{Name: name, Kind: kind, Tags: attributes(p.GetAttributes(), r.script), Value: v})
+		}
+	}
+
+	switch data := m.GetData().(type) {
+	case *metricpb.Metric_Sum:
+		// An UpDownCounter is compared like the DogStatsD gauge.
+		kind := KindCount
+		if !data.Sum.GetIsMonotonic() {
+			kind = KindGauge
+		}
+		number(kind, data.Sum.GetDataPoints())
+	case *metricpb.Metric_Gauge:
+		number(KindGauge, data.Gauge.GetDataPoints())
+	case *metricpb.Metric_Histogram:
+		for _, p := range data.Histogram.GetDataPoints() {
+			out = append(out, Metric{Name: name, Kind: KindDistribution, Tags: attributes(p.GetAttributes(), r.script), Value: float64(p.GetCount())})
+		}
+	case *metricpb.Metric_ExponentialHistogram:
+		for _, p := range data.ExponentialHistogram.GetDataPoints() {
+			out = append(out, Metric{Name: name, Kind: KindDistribution, Tags: attributes(p.GetAttributes(), r.script), Value: float64(p.GetCount())})
+		}
+	}
+
+	return out
+}
+
+// Result normalises the received spans and metrics into the common model.
+func (r *Receiver) Result() *Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := newResult()
+	for _, m := range r.metrics {
+		res.addMetric(m)
+	}
+
+	names := make(map[string]string, len(r.spans))
+	for _, s := range r.spans {
+		names[hex.EncodeToString(s.GetSpanId())] = normaliseSpanName(s.GetName())
+	}
+
+	for _, s := range r.spans {
+		// RecordError without SetStatus leaves the status unset, Datadog shows both as an error.
+		failed := s.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR
+		for _, event := range s.GetEvents() {
+			failed = failed || event.GetName() == "exception"
+		}
+
+		span := Span{
+			Name:   normaliseSpanName(s.GetName()),
+			Parent: names[hex.EncodeToString(s.GetParentSpanId())],
+			Error:  failed,
+		}
+		res.Spans[span.Key()]++
+	}
+
+	return res
+}
+
+func attributes(kvs []*commonpb.KeyValue, script Script) map[string]string {
+	tags := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		tags[kv.GetKey()] = anyValue(kv.GetValue())
+	}
+	return normaliseTags(tags, script)
+}
+
+func anyValue(v *commonpb.AnyValue) string {
+	switch value := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue
+	case *commonpb.AnyValue_IntValue:
+		return fmt.Sprint(value.IntValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return fmt.Sprint(value.DoubleValue)
+	case *commonpb.AnyValue_BoolValue:
+		return fmt.Sprint(value.BoolValue)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func readProto(req *http.Request, msg proto.Message) error {
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		body = gz
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(data, msg)
+}
+
+func writeProto(w http.ResponseWriter, msg proto.Message) {
+	data, _ := proto.Marshal(msg)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+//go:embed script.json
+var defaultScript []byte
+
+// Script is the request script sent to both applications, and what is left out of the comparison.
+type Script struct {
+	Requests []ScriptRequest `json:"requests"`
+
+	// IgnoreMetrics are prefixes of normalised metric names which exist only in one backend by design,
+	// for example the Datadog tracer health metrics or the otelhttp metrics.
+	IgnoreMetrics []string `json:"ignore_metrics"`
+
+	// IgnoreTags are normalised tag keys removed before the comparison.
+	IgnoreTags []string `json:"ignore_tags"`
+
+	// IgnoreRoutes are left out of the HTTP metrics, for example the probes polled while waiting for the applications.
+	IgnoreRoutes []string `json:"ignore_routes"`
+}
+
+type ScriptRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body"`
+
+	// Repeat sends the request more than once, zero means once.
+	Repeat int `json:"repeat"`
+}
+
+// loadScript reads the script file, an empty path is the embedded script.json.
+func loadScript(path string) (Script, error) {
+	data := defaultScript
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return Script{}, fmt.Errorf("read script: %w", err)
+		}
+	}
+
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return Script{}, fmt.Errorf("decode script: %w", err)
+	}
+
+	if len(script.Requests) == 0 {
+		return Script{}, fmt.Errorf("script has no requests")
+	}
+
+	return script, nil
+}
+
+// ignore reports whether the normalised series is left out of the comparison.
+func (s Script) ignore(m Metric) bool {
+	for _, prefix := range s.IgnoreMetrics {
+		if strings.HasPrefix(m.Name, prefix) {
+			return true
+		}
+	}
+
+	route, ok := m.Tags["route"]
+	return ok && slices.Contains(s.IgnoreRoutes, route)
+}
@@ -0,0 +1,158 @@
+// Package datadog implements telemetry.Telemetry with dd-trace-go and DogStatsD.
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/telemetry"
+)
+
+type Config struct {
+	// AgentHost is the Datadog Agent, it MUST open port 8125 (statsd) and 8126 (trace-agent).
+	AgentHost string
+
+	Service string
+	Version string
+	Env     string
+
+	// Tags are added to every span and metric, for example "team".
+	Tags map[string]string
+}
+
+// Telemetry sends the spans to the trace-agent and the metrics to DogStatsD.
+// The statsd namespace is the service name, the same as in dd-sdk.
+type Telemetry struct {
+	statsd *statsd.Client
+}
+
+var _ telemetry.Telemetry = (*Telemetry)(nil)
+
+// New starts the global Datadog tracer and the statsd client.
+func New(cfg Config) (*Telemetry, error) {
+	tags := make([]string, 0, len(cfg.Tags)+2)
+	tracerOpts := []tracer.StartOption{
+		tracer.WithAgentAddr(fmt.Sprintf("%s:8126", cfg.AgentHost)),
+		tracer.WithService(cfg.Service),
+		tracer.WithUniversalVersion(cfg.Version),
+		tracer.WithEnv(cfg.Env),
+		tracer.WithLogStartup(false),
+	}
+
+	for k, v := range cfg.Tags {
+		tracerOpts = append(tracerOpts, tracer.WithGlobalTag(k, v))
+		tags = append(tags, k+":"+v)
+	}
+	sort.Strings(tags)
+	tags = append(tags, "service:"+cfg.Service, "version:"+cfg.Version)
+
+	statsdClient, err := statsd.New(fmt.Sprintf("%s:8125", cfg.AgentHost),
+		statsd.WithNamespace(cfg.Service),
+		statsd.WithTags(tags),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create statsd client: %w", err)
+	}
+
+	tracer.Start(tracerOpts...)
+
+	return &Telemetry{statsd: statsdClient}, nil
+}
+
+func (t *Telemetry) StartSpan(ctx context.Context, name string, opts ...telemetry.SpanOption) (context.Context, telemetry.Span) {
+	cfg := telemetry.NewSpanConfig(opts...)
+
+	var startOpts []ddtrace.StartSpanOption
+	if cfg.ResourceName != "" {
+		startOpts = append(startOpts, tracer.ResourceName(cfg.ResourceName))
+	}
+
+	ddSpan, ctx := tracer.StartSpanFromContext(ctx, name, startOpts...)
+	s := &span{span: ddSpan}
+	s.SetAttributes(cfg.Attrs...)
+
+	return telemetry.ContextWithSpan(ctx, s), s
+}
+
+func (t *Telemetry) Counter(name string) telemetry.Counter {
+	return &counter{statsd: t.statsd, name: name}
+}
+
+// Histogram is sent as a DogStatsD distribution, the unit is only part of the name in Datadog.
+func (t *Telemetry) Histogram(name, _ string) telemetry.Histogram {
+	return &histogram{statsd: t.statsd, name: name}
+}
+
+func (t *Telemetry) Shutdown(context.Context) error {
+	tracer.Stop()
+	return t.statsd.Close()
+}
+
+type span struct {
+	span ddtrace.Span
+	err  error
+}
+
+func (s *span) SetAttributes(attrs ...telemetry.Attr) {
+	for _, attr := range attrs {
+		s.span.SetTag(attr.Key, attr.Value)
+	}
+}
+
+func (s *span) RecordError(err error) {
+	s.err = err
+}
+
+func (s *span) End() {
+	s.span.Finish(tracer.WithError(s.err))
+}
+
+// LogAttrs uses the attribute names of the Datadog log and trace correlation.
+func (s *span) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String(ext.LogKeyTraceID, strconv.FormatUint(s.span.Context().TraceID(), 10)),
+		slog.String(ext.LogKeySpanID, strconv.FormatUint(s.span.Context().SpanID(), 10)),
+	}
+}
+
+type counter struct {
+	statsd *statsd.Client
+	name   string
+}
+
+func (c *counter) Add(ctx context.Context, n int64, attrs ...telemetry.Attr) {
+	if err := c.statsd.Count(c.name, n, tags(attrs), 1); err != nil {
+		slog.ErrorContext(ctx, "failed to send statsd count", slog.String("metric", c.name), slog.Any("error", err))
+	}
+}
+
+type histogram struct {
+	statsd *statsd.Client
+	name   string
+}
+
+func (h *histogram) Record(ctx context.Context, v float64, attrs ...telemetry.Attr) {
+	if err := h.statsd.Distribution(h.name, v, tags(attrs), 1); err != nil {
+		slog.ErrorContext(ctx, "failed to send statsd distribution", slog.String("metric", h.name), slog.Any("error", err))
+	}
+}
+
+func tags(attrs []telemetry.Attr) []string {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	out := make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		out = append(out, fmt.Sprintf("%s:%v", attr.Key, attr.Value))
+	}
+	return out
+}
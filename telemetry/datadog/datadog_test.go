@@ -0,0 +1,131 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/telemetry"
+)
+
+// statsdWriter collects the datagrams sent by the statsd client.
+type statsdWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *statsdWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *statsdWriter) Close() error { return nil }
+
+func (w *statsdWriter) lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return strings.Split(strings.TrimSpace(w.buf.String()), "\n")
+}
+
+func newTestTelemetry(t *testing.T) (*Telemetry, mocktracer.Tracer, *statsdWriter) {
+	t.Helper()
+
+	mt := mocktracer.Start()
+	t.Cleanup(mt.Stop)
+
+	w := &statsdWriter{}
+	client, err := statsd.NewWithWriter(w, statsd.WithNamespace("telemetry-example"), statsd.WithoutTelemetry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return &Telemetry{statsd: client}, mt, w
+}
+
+func TestSpan(t *testing.T) {
+	tel, mt, _ := newTestTelemetry(t)
+
+	ctx, parent := tel.StartSpan(context.Background(), "http.request", telemetry.WithResourceName("POST /login"))
+	_, child := tel.StartSpan(ctx, "login", telemetry.WithAttributes(telemetry.String("username", "user1")))
+	child.SetAttributes(telemetry.Bool("login.success", false))
+	errInvalid := errors.New("invalid credentials")
+	child.RecordError(errInvalid)
+
+	logAttrs := child.LogAttrs()
+	child.End()
+	parent.End()
+
+	spans := mt.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans finished, want 2", len(spans))
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+
+	if parentSpan.OperationName() != "http.request" || parentSpan.Tag(ext.ResourceName) != "POST /login" {
+		t.Errorf("parent = %s %v", parentSpan.OperationName(), parentSpan.Tag(ext.ResourceName))
+	}
+	if parentSpan.Tag(ext.Error) != nil {
+		t.Errorf("the parent span has the error %v", parentSpan.Tag(ext.Error))
+	}
+
+	if childSpan.OperationName() != "login" || childSpan.ParentID() != parentSpan.SpanID() || childSpan.TraceID() != parentSpan.TraceID() {
+		t.Errorf("child %s is not the child of the parent span", childSpan.OperationName())
+	}
+	if childSpan.Tag("username") != "user1" || childSpan.Tag("login.success") != false {
+		t.Errorf("child tags = %v", childSpan.Tags())
+	}
+	if childSpan.Tag(ext.Error) != errInvalid {
+		t.Errorf("child error = %v, want %v", childSpan.Tag(ext.Error), errInvalid)
+	}
+
+	want := map[string]string{
+		ext.LogKeyTraceID: strconv.FormatUint(childSpan.TraceID(), 10),
+		ext.LogKeySpanID:  strconv.FormatUint(childSpan.SpanID(), 10),
+	}
+	if len(logAttrs) != len(want) {
+		t.Fatalf("LogAttrs() = %v", logAttrs)
+	}
+	for _, attr := range logAttrs {
+		if attr.Value.String() != want[attr.Key] {
+			t.Errorf("LogAttrs() %s = %s, want %s", attr.Key, attr.Value, want[attr.Key])
+		}
+	}
+
+	if telemetry.SpanFromContext(ctx) != parent {
+		t.Error("the context does not hold the parent span")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	tel, _, w := newTestTelemetry(t)
+	ctx := context.Background()
+
+	tel.Counter("login.failure").Add(ctx, 2, telemetry.String("failure_reason", "locked"))
+	tel.Histogram("login.duration", "s").Record(ctx, 0.25, telemetry.Int("attempt", 3))
+	if err := tel.statsd.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]bool{}
+	for _, line := range w.lines() {
+		got[line] = true
+	}
+	for _, want := range []string{
+		"telemetry-example.login.failure:2|c|#failure_reason:locked",
+		"telemetry-example.login.duration:0.25|d|#attempt:3",
+	} {
+		if !got[want] {
+			t.Errorf("datagram %q not sent, got %q", want, w.lines())
+		}
+	}
+}
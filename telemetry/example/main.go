@@ -0,0 +1,258 @@
+// Command example is the login service of dd-sdk and otel-sdk instrumented once with the telemetry facade.
+// TELEMETRY_BACKEND selects where the telemetry is sent: "datadog", "otel" (default) or "both".
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/telemetry"
+	"github.com/yusufsyaifudin/demo-otel-collector/telemetry/datadog"
+	"github.com/yusufsyaifudin/demo-otel-collector/telemetry/opentelemetry"
+)
+
+func main() {
+	var (
+		Port = os.Getenv("PORT")
+
+		// TelemetryBackend is "datadog", "otel" or "both", by default it is "otel".
+		TelemetryBackend = os.Getenv("TELEMETRY_BACKEND")
+
+		// DatadogAgentHost MUST open port 8125 (statsd) and 8126 (trace-agent) on Datadog Agent
+		// For example, 127.0.0.1
+		DatadogAgentHost = os.Getenv("DATADOG_AGENT_HOST")
+
+		// OpenTemeletryHTTPEndpoint contains OpenTelemetry HTTP Exporter, for example: "localhost:4318"
+		// No need scheme "http://" or "https://" prefix.
+		OpenTemeletryHTTPEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+
+		// OtlpInsecure sends the OTLP requests without TLS, by default it is true.
+		OtlpInsecure = os.Getenv("OTLP_INSECURE")
+	)
+
+	const (
+		teamName    = "go_sandbox"
+		serviceName = "poc_telemetry"
+		serviceEnv  = "dev"
+	)
+
+	// Stop on SIGINT or SIGTERM, so the deferred Shutdown flushes the buffered spans and metrics.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	backend, err := telemetry.ParseBackend(TelemetryBackend)
+	if err != nil {
+		slog.ErrorContext(ctx, "invalid TELEMETRY_BACKEND", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	insecure := true
+	if OtlpInsecure != "" {
+		var insecureErr error
+		insecure, insecureErr = strconv.ParseBool(OtlpInsecure)
+		if insecureErr != nil {
+			slog.WarnContext(ctx, "failed to parse OtlpInsecure", slog.Any("error", insecureErr))
+			insecure = true
+		}
+	}
+
+	var backends telemetry.Fanout
+	if backend == telemetry.BackendDatadog || backend == telemetry.BackendBoth {
+		dd, ddErr := datadog.New(datadog.Config{
+			AgentHost: DatadogAgentHost,
+			Service:   serviceName,
+			Version:   "1.0.0",
+			Env:       serviceEnv,
+			Tags:      map[string]string{"team": teamName},
+		})
+		if ddErr != nil {
+			slog.ErrorContext(ctx, "failed to start datadog telemetry", slog.Any("error", ddErr))
+			os.Exit(1)
+		}
+		backends = append(backends, dd)
+	}
+
+	if backend == telemetry.BackendOtel || backend == telemetry.BackendBoth {
+		ot, otErr := opentelemetry.New(ctx, opentelemetry.Config{
+			Endpoint:   OpenTemeletryHTTPEndpoint,
+			Insecure:   insecure,
+			Service:    serviceName,
+			Version:    "1.0.0",
+			Env:        serviceEnv,
+			Attributes: map[string]string{"team": teamName},
+		})
+		if otErr != nil {
+			slog.ErrorContext(ctx, "failed to start opentelemetry telemetry", slog.Any("error", otErr))
+			os.Exit(1)
+		}
+		backends = append(backends, ot)
+	}
+
+	var tel telemetry.Telemetry = backends
+	if len(backends) == 1 {
+		tel = backends[0]
+	}
+
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		if _err := tel.Shutdown(shutdownCtx); _err != nil {
+			slog.ErrorContext(ctx, "failed to shutdown telemetry", slog.Any("error", _err))
+		}
+	}()
+
+	slog.InfoContext(ctx, "telemetry started", slog.String("backend", backend))
+
+	handler := &Handler{Telemetry: tel}
+
+	router := chi.NewRouter()
+	router.Use(middleware.Logger)
+	router.Use(Middleware(tel))
+
+	router.Get("/", handler.Homepage)
+	router.Post("/login", handler.Login)
+
+	server := &http.Server{Addr: Port, Handler: router}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.WithoutCancel(ctx))
+	}()
+
+	if err = server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.ErrorContext(ctx, "http server stopped", slog.Any("error", err))
+	}
+}
+
+// Middleware starts the server span and records the request duration of every request.
+func Middleware(tel telemetry.Telemetry) func(http.Handler) http.Handler {
+	duration := tel.Histogram("http.server.request.duration", "s")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, span := tel.StartSpan(r.Context(), "http.request",
+				telemetry.WithAttributes(telemetry.String("http.request.method", r.Method)),
+			)
+			defer span.End()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			// The route is known only after chi routed the request.
+			route := chi.RouteContext(r.Context()).RoutePattern()
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			attrs := []telemetry.Attr{
+				telemetry.String("method", r.Method),
+				telemetry.String("route", route),
+				telemetry.Int("status_code", status),
+			}
+
+			span.SetAttributes(telemetry.String("http.route", route), telemetry.Int("http.response.status_code", status))
+			span.SetAttributes(telemetry.String("resource.name", r.Method+" "+route))
+			if status >= http.StatusInternalServerError {
+				span.RecordError(fmt.Errorf("http status %d", status))
+			}
+
+			duration.Record(ctx, time.Since(start).Seconds(), attrs...)
+		})
+	}
+}
+
+type Handler struct {
+	Telemetry telemetry.Telemetry
+}
+
+func (*Handler) Homepage(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte("Hello World! (from telemetry example).\n"))
+}
+
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	type User struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	const failureReasonKey = "failure_reason"
+
+	loginFailureCtr := h.Telemetry.Counter("login.failure")
+	loginSuccessCtr := h.Telemetry.Counter("login.success")
+
+	ctx, parentSpan := h.Telemetry.StartSpan(r.Context(), "Login Handler [Telemetry]",
+		telemetry.WithResourceName("login-handler"),
+	)
+	defer parentSpan.End()
+
+	var user User
+	{
+		_, decodeBodySpan := h.Telemetry.StartSpan(ctx, "Decode Body [Telemetry]",
+			telemetry.WithResourceName("decode-body"),
+		)
+
+		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+			loginFailureCtr.Add(ctx, 1, telemetry.String(failureReasonKey, "invalid_payload"))
+
+			decodeBodySpan.RecordError(err)
+			decodeBodySpan.SetAttributes(telemetry.String(failureReasonKey, "invalid_payload"))
+			decodeBodySpan.End()
+
+			http.Error(w, "Invalid request payload (from telemetry example).", http.StatusBadRequest)
+			return
+		}
+
+		defer func() {
+			if _err := r.Body.Close(); _err != nil {
+				parentSpan.RecordError(_err)
+				telemetry.Logger(ctx).ErrorContext(ctx, "failed to close request body", slog.Any("error", _err))
+			}
+		}()
+		decodeBodySpan.End()
+	}
+
+	{
+		_, checkCredentialsSpan := h.Telemetry.StartSpan(ctx, "Check Credentials [Telemetry]",
+			telemetry.WithResourceName("check-credentials"),
+		)
+		defer checkCredentialsSpan.End()
+
+		// In-memory user store
+		var users = map[string]string{
+			"user1": "password1",
+			"user2": "password2",
+			"user3": "password3",
+		}
+
+		// Validate the user credentials
+		if password, exists := users[user.Username]; exists && password == user.Password {
+			loginSuccessCtr.Add(ctx, 1)
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("Login successful (from telemetry example).\n"))
+			return
+		}
+	}
+
+	loginFailureCtr.Add(ctx, 1, telemetry.String(failureReasonKey, "invalid_credentials"))
+
+	err := fmt.Errorf("invalid credentials")
+	parentSpan.RecordError(err)
+	parentSpan.SetAttributes(telemetry.String(failureReasonKey, "invalid_credentials"))
+	telemetry.Logger(ctx).WarnContext(ctx, "login failed", slog.String("username", user.Username))
+
+	http.Error(w, "Invalid username or password (from telemetry example).", http.StatusUnauthorized)
+}
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Fanout sends everything to every backend, it is the "both" mode used during the cutover.
+type Fanout []Telemetry
+
+var _ Telemetry = Fanout(nil)
+
+// StartSpan starts the span in every backend in order, each one is the child of the span of the same backend in ctx.
+func (f Fanout) StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span) {
+	spans := make(fanoutSpan, 0, len(f))
+	for _, t := range f {
+		var span Span
+		ctx, span = t.StartSpan(ctx, name, opts...)
+		spans = append(spans, span)
+	}
+
+	return ContextWithSpan(ctx, spans), spans
+}
+
+func (f Fanout) Counter(name string) Counter {
+	counters := make(fanoutCounter, 0, len(f))
+	for _, t := range f {
+		counters = append(counters, t.Counter(name))
+	}
+	return counters
+}
+
+func (f Fanout) Histogram(name, unit string) Histogram {
+	histograms := make(fanoutHistogram, 0, len(f))
+	for _, t := range f {
+		histograms = append(histograms, t.Histogram(name, unit))
+	}
+	return histograms
+}
+
+func (f Fanout) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, t := range f {
+		errs = append(errs, t.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+type fanoutSpan []Span
+
+func (s fanoutSpan) SetAttributes(attrs ...Attr) {
+	for _, span := range s {
+		span.SetAttributes(attrs...)
+	}
+}
+
+func (s fanoutSpan) RecordError(err error) {
+	for _, span := range s {
+		span.RecordError(err)
+	}
+}
+
+func (s fanoutSpan) End() {
+	for _, span := range s {
+		span.End()
+	}
+}
+
+func (s fanoutSpan) LogAttrs() []slog.Attr {
+	var attrs []slog.Attr
+	for _, span := range s {
+		attrs = append(attrs, span.LogAttrs()...)
+	}
+	return attrs
+}
+
+type fanoutCounter []Counter
+
+func (f fanoutCounter) Add(ctx context.Context, n int64, attrs ...Attr) {
+	for _, counter := range f {
+		counter.Add(ctx, n, attrs...)
+	}
+}
+
+type fanoutHistogram []Histogram
+
+func (f fanoutHistogram) Record(ctx context.Context, v float64, attrs ...Attr) {
+	for _, histogram := range f {
+		histogram.Record(ctx, v, attrs...)
+	}
+}
@@ -0,0 +1,180 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// recorder is a backend writing every call to a shared log, prefixed with its name.
+type recorder struct {
+	name string
+	log  *[]string
+	err  error
+}
+
+type recorderKey string
+
+func (r *recorder) record(format string, args ...any) {
+	*r.log = append(*r.log, r.name+" "+fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span) {
+	cfg := NewSpanConfig(opts...)
+	parent, _ := ctx.Value(recorderKey(r.name)).(string)
+	r.record("start %s parent=%q resource=%s attrs=%v", name, parent, cfg.ResourceName, cfg.Attrs)
+
+	s := &recorderSpan{recorder: r, name: name}
+	ctx = context.WithValue(ctx, recorderKey(r.name), name)
+	return ContextWithSpan(ctx, s), s
+}
+
+func (r *recorder) Counter(name string) Counter {
+	return recorderInstrument{recorder: r, name: name}
+}
+
+func (r *recorder) Histogram(name, unit string) Histogram {
+	return recorderInstrument{recorder: r, name: name + "_" + unit}
+}
+
+func (r *recorder) Shutdown(context.Context) error {
+	r.record("shutdown")
+	return r.err
+}
+
+type recorderSpan struct {
+	*recorder
+	name string
+}
+
+func (s *recorderSpan) SetAttributes(attrs ...Attr) { s.record("set %s %v", s.name, attrs) }
+
+func (s *recorderSpan) RecordError(err error) { s.record("error %s %v", s.name, err) }
+
+func (s *recorderSpan) End() { s.record("end %s", s.name) }
+
+func (s *recorderSpan) LogAttrs() []slog.Attr {
+	return []slog.Attr{slog.String(s.recorder.name+".span", s.name)}
+}
+
+type recorderInstrument struct {
+	*recorder
+	name string
+}
+
+func (i recorderInstrument) Add(_ context.Context, n int64, attrs ...Attr) {
+	i.record("add %s %d %v", i.name, n, attrs)
+}
+
+func (i recorderInstrument) Record(_ context.Context, v float64, attrs ...Attr) {
+	i.record("record %s %g %v", i.name, v, attrs)
+}
+
+func newFanout() (Fanout, *[]string) {
+	var log []string
+	return Fanout{&recorder{name: "dd", log: &log}, &recorder{name: "otel", log: &log}}, &log
+}
+
+func checkLog(t *testing.T, log *[]string, want ...string) {
+	t.Helper()
+
+	if strings.Join(*log, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(*log, "\n"), strings.Join(want, "\n"))
+	}
+	*log = nil
+}
+
+func TestFanoutSpan(t *testing.T) {
+	fanout, log := newFanout()
+
+	ctx, parent := fanout.StartSpan(context.Background(), "http.request", WithResourceName("POST /login"))
+	_, child := fanout.StartSpan(ctx, "login", WithAttributes(String("username", "user1")))
+	checkLog(t, log,
+		`dd start http.request parent="" resource=POST /login attrs=[]`,
+		`otel start http.request parent="" resource=POST /login attrs=[]`,
+		`dd start login parent="http.request" resource= attrs=[{username user1}]`,
+		`otel start login parent="http.request" resource= attrs=[{username user1}]`,
+	)
+
+	if span, ok := SpanFromContext(ctx).(fanoutSpan); !ok || len(span) != 2 || span[1] != parent.(fanoutSpan)[1] {
+		t.Errorf("the context holds %T, not the fan-out span", SpanFromContext(ctx))
+	}
+
+	child.SetAttributes(Bool("login.success", false))
+	child.RecordError(errors.New("invalid credentials"))
+	child.End()
+	parent.End()
+	checkLog(t, log,
+		"dd set login [{login.success false}]",
+		"otel set login [{login.success false}]",
+		"dd error login invalid credentials",
+		"otel error login invalid credentials",
+		"dd end login",
+		"otel end login",
+		"dd end http.request",
+		"otel end http.request",
+	)
+
+	attrs := child.LogAttrs()
+	if len(attrs) != 2 || attrs[0].String() != "dd.span=login" || attrs[1].String() != "otel.span=login" {
+		t.Errorf("LogAttrs() = %v", attrs)
+	}
+}
+
+func TestFanoutInstruments(t *testing.T) {
+	fanout, log := newFanout()
+	ctx := context.Background()
+
+	fanout.Counter("login.failure").Add(ctx, 1, String("failure_reason", "locked"))
+	fanout.Histogram("login.duration", "s").Record(ctx, 0.5)
+	checkLog(t, log,
+		"dd add login.failure 1 [{failure_reason locked}]",
+		"otel add login.failure 1 [{failure_reason locked}]",
+		"dd record login.duration_s 0.5 []",
+		"otel record login.duration_s 0.5 []",
+	)
+}
+
+func TestFanoutShutdown(t *testing.T) {
+	fanout, log := newFanout()
+	errDD := errors.New("statsd closed")
+	fanout[0].(*recorder).err = errDD
+
+	// Every backend is shut down even when one fails.
+	if err := fanout.Shutdown(context.Background()); !errors.Is(err, errDD) {
+		t.Errorf("Shutdown() = %v, want %v", err, errDD)
+	}
+	checkLog(t, log, "dd shutdown", "otel shutdown")
+}
+
+func TestSpanFromContextNoop(t *testing.T) {
+	span := SpanFromContext(context.Background())
+	if _, ok := span.(noopSpan); !ok {
+		t.Errorf("SpanFromContext() = %T, want noopSpan", span)
+	}
+
+	if Logger(context.Background()) != slog.Default() {
+		t.Error("Logger() without span is not the default logger")
+	}
+}
+
+func TestParseBackend(t *testing.T) {
+	tests := map[string]string{
+		"":          BackendOtel,
+		" Datadog ": BackendDatadog,
+		"otel":      BackendOtel,
+		"BOTH":      BackendBoth,
+	}
+	for s, want := range tests {
+		if got, err := ParseBackend(s); err != nil || got != want {
+			t.Errorf("ParseBackend(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+
+	if _, err := ParseBackend("newrelic"); err == nil {
+		t.Error(`ParseBackend("newrelic") succeeded`)
+	}
+}
@@ -0,0 +1,37 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Noop discards everything.
+type Noop struct{}
+
+var _ Telemetry = Noop{}
+
+func (Noop) StartSpan(ctx context.Context, _ string, _ ...SpanOption) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (Noop) Counter(string) Counter { return noopInstrument{} }
+
+func (Noop) Histogram(string, string) Histogram { return noopInstrument{} }
+
+func (Noop) Shutdown(context.Context) error { return nil }
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attr) {}
+
+func (noopSpan) RecordError(error) {}
+
+func (noopSpan) End() {}
+
+func (noopSpan) LogAttrs() []slog.Attr { return nil }
+
+type noopInstrument struct{}
+
+func (noopInstrument) Add(context.Context, int64, ...Attr) {}
+
+func (noopInstrument) Record(context.Context, float64, ...Attr) {}
@@ -0,0 +1,242 @@
+// Package opentelemetry implements telemetry.Telemetry with the OpenTelemetry SDK and the OTLP HTTP exporters.
+package opentelemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	otelSdkResource "go.opentelemetry.io/otel/sdk/resource"
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/telemetry"
+)
+
+const instrumentationName = "github.com/yusufsyaifudin/demo-otel-collector/telemetry/opentelemetry"
+
+type Config struct {
+	// Endpoint is the host and port of the OTLP HTTP receiver, for example "localhost:4318".
+	Endpoint string
+	Insecure bool
+
+	Service string
+	Version string
+	Env     string
+
+	// Attributes are added to the resource, for example "team".
+	Attributes map[string]string
+}
+
+// Telemetry exports the spans and metrics with OTLP HTTP.
+// The metric names are prefixed with the service name and a dot, the same as in otel-sdk.
+type Telemetry struct {
+	tracerProvider *otelSdkTrace.TracerProvider
+	meterProvider  *otelSdkMetric.MeterProvider
+	tracer         trace.Tracer
+	meter          metric.Meter
+	prefix         string
+
+	mu         sync.Mutex
+	counters   map[string]metric.Int64Counter
+	histograms map[string]metric.Float64Histogram
+}
+
+var _ telemetry.Telemetry = (*Telemetry)(nil)
+
+// New creates the tracer and meter providers and registers them as the global providers.
+func New(ctx context.Context, cfg Config) (*Telemetry, error) {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(cfg.Service),
+		semconv.ServiceVersion(cfg.Version),
+		semconv.DeploymentEnvironmentName(cfg.Env),
+	}
+	for k, v := range cfg.Attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	res, err := otelSdkResource.New(ctx, otelSdkResource.WithAttributes(attrs...), otelSdkResource.WithFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+
+	traceOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+		metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+	}
+
+	traceExporter, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp trace exporter: %w", err)
+	}
+
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp metric exporter: %w", err)
+	}
+
+	tracerProvider := otelSdkTrace.NewTracerProvider(
+		otelSdkTrace.WithResource(res),
+		otelSdkTrace.WithBatcher(traceExporter),
+	)
+
+	meterProvider := otelSdkMetric.NewMeterProvider(
+		otelSdkMetric.WithResource(res),
+		otelSdkMetric.WithReader(otelSdkMetric.NewPeriodicReader(metricExporter)),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return newTelemetry(tracerProvider, meterProvider, cfg.Service), nil
+}
+
+func newTelemetry(tracerProvider *otelSdkTrace.TracerProvider, meterProvider *otelSdkMetric.MeterProvider, service string) *Telemetry {
+	return &Telemetry{
+		tracerProvider: tracerProvider,
+		meterProvider:  meterProvider,
+		tracer:         tracerProvider.Tracer(instrumentationName),
+		meter:          meterProvider.Meter(instrumentationName),
+		prefix:         service + ".",
+		counters:       map[string]metric.Int64Counter{},
+		histograms:     map[string]metric.Float64Histogram{},
+	}
+}
+
+func (t *Telemetry) StartSpan(ctx context.Context, name string, opts ...telemetry.SpanOption) (context.Context, telemetry.Span) {
+	cfg := telemetry.NewSpanConfig(opts...)
+
+	attrs := attributes(cfg.Attrs)
+	if cfg.ResourceName != "" {
+		attrs = append(attrs, attribute.String("resource.name", cfg.ResourceName))
+	}
+
+	ctx, otelSpan := t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	s := &span{span: otelSpan}
+
+	return telemetry.ContextWithSpan(ctx, s), s
+}
+
+// Counter creates the instrument once per name, an instrument which cannot be created is a no-op.
+func (t *Telemetry) Counter(name string) telemetry.Counter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.counters[name]
+	if !ok {
+		var err error
+		c, err = t.meter.Int64Counter(t.prefix + name)
+		if err != nil {
+			slog.Error("failed to create counter", slog.String("metric", t.prefix+name), slog.Any("error", err))
+			return telemetry.Noop{}.Counter(name)
+		}
+		t.counters[name] = c
+	}
+
+	return &counter{counter: c}
+}
+
+func (t *Telemetry) Histogram(name, unit string) telemetry.Histogram {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.histograms[name]
+	if !ok {
+		var err error
+		h, err = t.meter.Float64Histogram(t.prefix+name, metric.WithUnit(unit))
+		if err != nil {
+			slog.Error("failed to create histogram", slog.String("metric", t.prefix+name), slog.Any("error", err))
+			return telemetry.Noop{}.Histogram(name, unit)
+		}
+		t.histograms[name] = h
+	}
+
+	return &histogram{histogram: h}
+}
+
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	return errors.Join(
+		t.tracerProvider.Shutdown(ctx),
+		t.meterProvider.Shutdown(ctx),
+	)
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s *span) SetAttributes(attrs ...telemetry.Attr) {
+	s.span.SetAttributes(attributes(attrs)...)
+}
+
+func (s *span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *span) End() {
+	s.span.End()
+}
+
+func (s *span) LogAttrs() []slog.Attr {
+	spanCtx := s.span.SpanContext()
+	return []slog.Attr{
+		slog.String("trace_id", spanCtx.TraceID().String()),
+		slog.String("span_id", spanCtx.SpanID().String()),
+	}
+}
+
+type counter struct {
+	counter metric.Int64Counter
+}
+
+func (c *counter) Add(ctx context.Context, n int64, attrs ...telemetry.Attr) {
+	c.counter.Add(ctx, n, metric.WithAttributes(attributes(attrs)...))
+}
+
+type histogram struct {
+	histogram metric.Float64Histogram
+}
+
+func (h *histogram) Record(ctx context.Context, v float64, attrs ...telemetry.Attr) {
+	h.histogram.Record(ctx, v, metric.WithAttributes(attributes(attrs)...))
+}
+
+func attributes(attrs []telemetry.Attr) []attribute.KeyValue {
+	out := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			out = append(out, attribute.String(attr.Key, v))
+		case int:
+			out = append(out, attribute.Int(attr.Key, v))
+		case int64:
+			out = append(out, attribute.Int64(attr.Key, v))
+		case bool:
+			out = append(out, attribute.Bool(attr.Key, v))
+		case float64:
+			out = append(out, attribute.Float64(attr.Key, v))
+		default:
+			out = append(out, attribute.String(attr.Key, fmt.Sprint(v)))
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package opentelemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelSdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	otelSdkTrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/yusufsyaifudin/demo-otel-collector/telemetry"
+)
+
+func newTestTelemetry(t *testing.T) (*Telemetry, *tracetest.SpanRecorder, *otelSdkMetric.ManualReader) {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	reader := otelSdkMetric.NewManualReader()
+	tel := newTelemetry(
+		otelSdkTrace.NewTracerProvider(otelSdkTrace.WithSpanProcessor(recorder)),
+		otelSdkMetric.NewMeterProvider(otelSdkMetric.WithReader(reader)),
+		"telemetry-example",
+	)
+	t.Cleanup(func() { _ = tel.Shutdown(context.Background()) })
+
+	return tel, recorder, reader
+}
+
+func TestSpan(t *testing.T) {
+	tel, recorder, _ := newTestTelemetry(t)
+
+	ctx, parent := tel.StartSpan(context.Background(), "http.request", telemetry.WithResourceName("POST /login"))
+	_, child := tel.StartSpan(ctx, "login", telemetry.WithAttributes(telemetry.String("username", "user1")))
+	child.SetAttributes(telemetry.Bool("login.success", false), telemetry.Int("attempt", 3))
+	child.RecordError(errors.New("invalid credentials"))
+	child.RecordError(nil)
+
+	logAttrs := child.LogAttrs()
+	child.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans ended, want 2", len(spans))
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+
+	if parentSpan.Name() != "http.request" || !hasAttribute(parentSpan.Attributes(), attribute.String("resource.name", "POST /login")) {
+		t.Errorf("parent = %s %v", parentSpan.Name(), parentSpan.Attributes())
+	}
+	if parentSpan.Status().Code != codes.Unset {
+		t.Errorf("parent status = %v", parentSpan.Status())
+	}
+
+	if childSpan.Name() != "login" || childSpan.Parent().SpanID() != parentSpan.SpanContext().SpanID() {
+		t.Errorf("child %s is not the child of the parent span", childSpan.Name())
+	}
+	for _, want := range []attribute.KeyValue{
+		attribute.String("username", "user1"),
+		attribute.Bool("login.success", false),
+		attribute.Int("attempt", 3),
+	} {
+		if !hasAttribute(childSpan.Attributes(), want) {
+			t.Errorf("child attributes %v, missing %v", childSpan.Attributes(), want)
+		}
+	}
+	if childSpan.Status() != (otelSdkTrace.Status{Code: codes.Error, Description: "invalid credentials"}) || len(childSpan.Events()) != 1 {
+		t.Errorf("child status = %v, %d events", childSpan.Status(), len(childSpan.Events()))
+	}
+
+	spanCtx := childSpan.SpanContext()
+	if len(logAttrs) != 2 ||
+		logAttrs[0].Key != "trace_id" || logAttrs[0].Value.String() != spanCtx.TraceID().String() ||
+		logAttrs[1].Key != "span_id" || logAttrs[1].Value.String() != spanCtx.SpanID().String() {
+		t.Errorf("LogAttrs() = %v", logAttrs)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	tel, _, reader := newTestTelemetry(t)
+	ctx := context.Background()
+
+	tel.Counter("login.failure").Add(ctx, 1, telemetry.String("failure_reason", "locked"))
+	tel.Counter("login.failure").Add(ctx, 2, telemetry.String("failure_reason", "locked"))
+	tel.Histogram("login.duration", "s").Record(ctx, 0.25)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	if len(rm.ScopeMetrics) != 1 {
+		t.Fatalf("%d scopes, want 1", len(rm.ScopeMetrics))
+	}
+
+	metrics := map[string]metricdata.Metrics{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	sum, ok := metrics["telemetry-example.login.failure"].Data.(metricdata.Sum[int64])
+	if !ok || !sum.IsMonotonic || len(sum.DataPoints) != 1 {
+		t.Fatalf("login.failure = %+v", metrics["telemetry-example.login.failure"])
+	}
+	if dp := sum.DataPoints[0]; dp.Value != 3 || !dp.Attributes.HasValue("failure_reason") {
+		t.Errorf("login.failure = %d %v", dp.Value, dp.Attributes.ToSlice())
+	}
+
+	duration := metrics["telemetry-example.login.duration"]
+	hist, ok := duration.Data.(metricdata.Histogram[float64])
+	if !ok || duration.Unit != "s" || len(hist.DataPoints) != 1 || hist.DataPoints[0].Sum != 0.25 {
+		t.Errorf("login.duration = %+v", duration)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	got := attributes([]telemetry.Attr{
+		telemetry.String("s", "v"),
+		telemetry.Int("i", 1),
+		{Key: "i64", Value: int64(2)},
+		telemetry.Bool("b", true),
+		telemetry.Float64("f", 0.5),
+		{Key: "other", Value: []int{1, 2}},
+	})
+
+	want := []attribute.KeyValue{
+		attribute.String("s", "v"),
+		attribute.Int("i", 1),
+		attribute.Int64("i64", 2),
+		attribute.Bool("b", true),
+		attribute.Float64("f", 0.5),
+		attribute.String("other", "[1 2]"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("attributes() = %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("attributes()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
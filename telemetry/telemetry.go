@@ -0,0 +1,141 @@
+// Package telemetry is a backend neutral facade over traces, metrics and log correlation,
+// so a handler is instrumented once and the vendor (Datadog, OpenTelemetry or both) is chosen at startup.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	BackendDatadog = "datadog"
+	BackendOtel    = "otel"
+	BackendBoth    = "both"
+)
+
+// ParseBackend validates the backend name, an empty name is BackendOtel.
+func ParseBackend(s string) (string, error) {
+	switch backend := strings.ToLower(strings.TrimSpace(s)); backend {
+	case "":
+		return BackendOtel, nil
+	case BackendDatadog, BackendOtel, BackendBoth:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("unknown telemetry backend %q, must be one of %s, %s or %s", s, BackendDatadog, BackendOtel, BackendBoth)
+	}
+}
+
+// Telemetry is implemented by every backend.
+type Telemetry interface {
+	// StartSpan starts a span as the child of the span in ctx, and returns the context holding the new span.
+	StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span)
+
+	// Counter returns the monotonic counter with the name, for example "login.failure".
+	// The backend adds its own prefix or namespace.
+	Counter(name string) Counter
+
+	// Histogram returns the histogram with the name and the UCUM unit, for example "s".
+	Histogram(name, unit string) Histogram
+
+	// Shutdown flushes and stops the backend.
+	Shutdown(ctx context.Context) error
+}
+
+// Span is one unit of work.
+type Span interface {
+	SetAttributes(attrs ...Attr)
+
+	// RecordError marks the span as failed.
+	RecordError(err error)
+
+	End()
+
+	// LogAttrs returns the attributes correlating a log record with this span in the backend.
+	LogAttrs() []slog.Attr
+}
+
+type Counter interface {
+	Add(ctx context.Context, n int64, attrs ...Attr)
+}
+
+type Histogram interface {
+	Record(ctx context.Context, v float64, attrs ...Attr)
+}
+
+// Attr is a span attribute or a metric tag.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attr { return Attr{Key: key, Value: value} }
+
+func Int(key string, value int) Attr { return Attr{Key: key, Value: value} }
+
+func Bool(key string, value bool) Attr { return Attr{Key: key, Value: value} }
+
+func Float64(key string, value float64) Attr { return Attr{Key: key, Value: value} }
+
+// SpanConfig is the result of the SpanOption, it is used by the backends.
+type SpanConfig struct {
+	// ResourceName is the Datadog resource name, OpenTelemetry has no equivalent and records it as the "resource.name" attribute.
+	ResourceName string
+
+	Attrs []Attr
+}
+
+type SpanOption func(*SpanConfig)
+
+// WithResourceName sets the resource name of the span.
+func WithResourceName(name string) SpanOption {
+	return func(c *SpanConfig) {
+		c.ResourceName = name
+	}
+}
+
+// WithAttributes sets the attributes when the span starts.
+func WithAttributes(attrs ...Attr) SpanOption {
+	return func(c *SpanConfig) {
+		c.Attrs = append(c.Attrs, attrs...)
+	}
+}
+
+// NewSpanConfig applies the options.
+func NewSpanConfig(opts ...SpanOption) SpanConfig {
+	var c SpanConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx holding the span, the backends call it in StartSpan.
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the current span, or a no-op span.
+func SpanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+// Logger returns the default logger with the attributes correlating the logs with the current span.
+func Logger(ctx context.Context) *slog.Logger {
+	attrs := SpanFromContext(ctx).LogAttrs()
+	if len(attrs) == 0 {
+		return slog.Default()
+	}
+
+	args := make([]any, 0, len(attrs))
+	for _, attr := range attrs {
+		args = append(args, attr)
+	}
+	return slog.Default().With(args...)
+}